@@ -2,7 +2,10 @@ package any
 
 import (
 	"testing"
+	"time"
 
+	"github.com/pgavlin/codec"
+	"github.com/pgavlin/codec/internal/codectest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -71,3 +74,108 @@ func TestEncode(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, map[string]any{"field": true}, v)
 }
+
+type timeStruct struct {
+	Time time.Time `codec:"time"`
+}
+
+func TestTime(t *testing.T) {
+	now := time.Now()
+
+	v, err := Encode(timeStruct{Time: now})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"time": now}, v)
+
+	ts, err := Decode[timeStruct](v)
+	require.NoError(t, err)
+	assert.Equal(t, timeStruct{Time: now}, ts)
+}
+
+func TestDecodeOptions(t *testing.T) {
+	_, err := DecodeWithOptions[boolStruct](map[string]any{"field": true, "extra": true}, codec.DecodeOptions{DisallowUnknownFields: true})
+	assert.EqualError(t, err, `codec: unknown field "extra" in Go struct boolStruct`)
+
+	structs, err := DecodeWithOptions[[]boolStruct]([]any{map[string]any{"Field": true}}, codec.DecodeOptions{CaseSensitive: true})
+	require.NoError(t, err)
+	assert.Equal(t, []boolStruct{{}}, structs)
+
+	structs, err = Decode[[]boolStruct]([]any{map[string]any{"Field": true}})
+	require.NoError(t, err)
+	assert.Equal(t, []boolStruct{{Field: true}}, structs)
+}
+
+type defaultsStruct struct {
+	Name    string `codec:"name,required"`
+	Mode    string `codec:"mode,default=auto"`
+	Enabled bool   `codec:"enabled,default=true"`
+}
+
+func TestDefaults(t *testing.T) {
+	struct_, err := Decode[defaultsStruct](map[string]any{"name": "test"})
+	require.NoError(t, err)
+	assert.Equal(t, defaultsStruct{Name: "test", Mode: "auto", Enabled: true}, struct_)
+
+	struct_, err = Decode[defaultsStruct](map[string]any{"name": "test", "mode": "manual", "enabled": false})
+	require.NoError(t, err)
+	assert.Equal(t, defaultsStruct{Name: "test", Mode: "manual"}, struct_)
+
+	_, err = Decode[defaultsStruct](map[string]any{"mode": "manual"})
+	assert.EqualError(t, err, `codec: missing required field "name" in Go struct defaultsStruct`)
+}
+
+type pathStruct struct {
+	Items map[string][]boolStruct `codec:"items"`
+}
+
+func TestErrorPaths(t *testing.T) {
+	_, err := Decode[pathStruct](map[string]any{
+		"items": map[string]any{"a": []any{map[string]any{"field": "yes"}}},
+	})
+	assert.EqualError(t, err, "codec: cannot unmarshal string into Go struct field boolStruct.items.a[0].field of type bool")
+}
+
+func TestVariant(t *testing.T) {
+	v, err := Encode(codectest.Option{Valid: true, Value: "x"})
+	require.NoError(t, err)
+	assert.Equal(t, Variant{Enum: "Option", Name: "Some", Index: 1, Value: "x"}, v)
+
+	o, err := Decode[codectest.Option](v)
+	require.NoError(t, err)
+	assert.Equal(t, codectest.Option{Valid: true, Value: "x"}, o)
+
+	v, err = Encode(codectest.Option{})
+	require.NoError(t, err)
+	assert.Equal(t, Variant{Enum: "Option", Name: "None"}, v)
+
+	o, err = Decode[codectest.Option]("None")
+	require.NoError(t, err)
+	assert.Equal(t, codectest.Option{}, o)
+
+	o, err = Decode[codectest.Option](map[string]any{"Some": "y"})
+	require.NoError(t, err)
+	assert.Equal(t, codectest.Option{Valid: true, Value: "y"}, o)
+
+	any_, err := Decode[any](Variant{Name: "Some", Value: "x"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"Some": "x"}, any_)
+
+	any_, err = Decode[any](Variant{Name: "None"})
+	require.NoError(t, err)
+	assert.Equal(t, "None", any_)
+}
+
+func TestNumbers(t *testing.T) {
+	i8, err := Decode[int8](int(5))
+	require.NoError(t, err)
+	assert.Equal(t, int8(5), i8)
+
+	u, err := Decode[uint](float64(42))
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), u)
+
+	_, err = Decode[int8](int(128))
+	assert.EqualError(t, err, "codec: cannot unmarshal number 128 into Go value of type int8")
+
+	_, err = Decode[uint](-1)
+	assert.EqualError(t, err, "codec: cannot unmarshal number -1 into Go value of type uint")
+}
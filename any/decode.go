@@ -7,11 +7,17 @@ import (
 )
 
 type Decoder struct {
-	v any
+	v       any
+	options codec.DecodeOptions
 }
 
 func Decode[T any](v any) (t T, err error) {
-	err = codec.GetDeserializer(&t).Deserialize(NewDecoder(v))
+	return DecodeWithOptions[T](v, codec.DecodeOptions{})
+}
+
+// DecodeWithOptions decodes a T from v using the given decode options.
+func DecodeWithOptions[T any](v any, options codec.DecodeOptions) (t T, err error) {
+	err = codec.GetDeserializer(&t, Format).Deserialize(NewDecoder(v).WithOptions(options))
 	return
 }
 
@@ -19,6 +25,17 @@ func NewDecoder(v any) Decoder {
 	return Decoder{v: v}
 }
 
+// WithOptions returns a copy of d that uses the given decode options.
+func (d Decoder) WithOptions(options codec.DecodeOptions) Decoder {
+	d.options = options
+	return d
+}
+
+// Format returns the format for plain Go values.
+func (d Decoder) Format() *codec.Format {
+	return Format
+}
+
 func (d Decoder) DecodeAny(v codec.Visitor) error {
 	switch dv := d.v.(type) {
 	case nil:
@@ -60,9 +77,11 @@ func (d Decoder) DecodeAny(v codec.Visitor) error {
 	case []byte:
 		return v.VisitBytes(dv)
 	case []any:
-		return v.VisitSeq(&SeqDecoder{v: dv})
+		return v.VisitSeq(&SeqDecoder{v: dv, options: d.options})
 	case map[string]any:
-		return v.VisitMap(&MapDecoder{size: len(dv), iter: reflect.ValueOf(dv).MapRange()})
+		return v.VisitMap(&MapDecoder{size: len(dv), iter: reflect.ValueOf(dv).MapRange(), options: d.options})
+	case Variant:
+		return v.VisitVariant(VariantDecoder{name: dv.Name, v: dv.Value, options: d.options})
 	default:
 		panic("unsupported")
 	}
@@ -91,23 +110,41 @@ func (d Decoder) DecodeSeq(v codec.Visitor) error                 { return d.Dec
 func (d Decoder) DecodeMap(v codec.Visitor) error                 { return d.DecodeAny(v) }
 func (d Decoder) DecodeStruct(name string, v codec.Visitor) error { return d.DecodeAny(v) }
 
+// DecodeVariant decodes a variant of a sum type. In addition to Variants, DecodeVariant accepts the externally tagged
+// representation used by codec.AnyCodec: unit variants may be represented by their names, and other variants by
+// single-entry maps from their names to their values.
+func (d Decoder) DecodeVariant(enum string, variants []string, v codec.Visitor) error {
+	switch dv := d.v.(type) {
+	case string:
+		return v.VisitVariant(VariantDecoder{name: dv, options: d.options})
+	case map[string]any:
+		if len(dv) == 1 {
+			for k, value := range dv {
+				return v.VisitVariant(VariantDecoder{name: k, v: value, options: d.options})
+			}
+		}
+	}
+	return d.DecodeAny(v)
+}
+
 func (d Decoder) DecodePtr(v codec.Visitor) error {
 	if d.v == nil {
 		return v.VisitNil()
 	}
-	return v.VisitElem(ElemDecoder{d.v})
+	return v.VisitElem(ElemDecoder{d})
 }
 
 type ElemDecoder struct {
-	v any
+	d Decoder
 }
 
 func (d ElemDecoder) Element(_ any, ds codec.Deserializer) error {
-	return ds.Deserialize(NewDecoder(d.v))
+	return ds.Deserialize(d.d)
 }
 
 type SeqDecoder struct {
-	v []any
+	v       []any
+	options codec.DecodeOptions
 }
 
 func (d *SeqDecoder) Size() (int, bool) {
@@ -120,25 +157,44 @@ func (d *SeqDecoder) NextElement(_ any, ds codec.Deserializer) (bool, error) {
 	}
 	v := d.v[0]
 	d.v = d.v[1:]
-	return true, ds.Deserialize(Decoder{v: v})
+	return true, ds.Deserialize(Decoder{v: v, options: d.options})
 }
 
 type MapDecoder struct {
-	size int
-	iter *reflect.MapIter
+	size    int
+	iter    *reflect.MapIter
+	options codec.DecodeOptions
 }
 
 func (d *MapDecoder) Size() (int, bool) {
 	return d.size, true
 }
 
+func (d *MapDecoder) Options() codec.DecodeOptions {
+	return d.options
+}
+
 func (d *MapDecoder) NextKey(_ any, ds codec.Deserializer) (bool, error) {
 	if !d.iter.Next() {
 		return false, nil
 	}
-	return true, ds.Deserialize(NewDecoder(d.iter.Key().String()))
+	return true, ds.Deserialize(Decoder{v: d.iter.Key().String(), options: d.options})
 }
 
 func (d *MapDecoder) NextValue(_ any, ds codec.Deserializer) error {
-	return ds.Deserialize(NewDecoder(d.iter.Value().Interface()))
+	return ds.Deserialize(Decoder{v: d.iter.Value().Interface(), options: d.options})
+}
+
+type VariantDecoder struct {
+	name    string
+	v       any
+	options codec.DecodeOptions
+}
+
+func (d VariantDecoder) Variant() string {
+	return d.name
+}
+
+func (d VariantDecoder) Value(_ any, ds codec.Deserializer) error {
+	return ds.Deserialize(Decoder{v: d.v, options: d.options})
 }
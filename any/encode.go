@@ -11,7 +11,7 @@ type Encoder struct {
 }
 
 func Encode[T any](v T) (res any, err error) {
-	err = codec.GetSerializer(v).Serialize(NewEncoder(&res))
+	err = codec.GetSerializer(v, Format).Serialize(NewEncoder(&res))
 	return
 }
 
@@ -19,6 +19,11 @@ func NewEncoder(v *any) *Encoder {
 	return &Encoder{v: v}
 }
 
+// Format returns the format for plain Go values.
+func (e *Encoder) Format() *codec.Format {
+	return Format
+}
+
 func (e *Encoder) EncodeNil() error {
 	*e.v = nil
 	return nil
@@ -140,6 +145,11 @@ func (e *Encoder) EncodeStruct(name string) (codec.StructEncoder, error) {
 	return &StructEncoder{v: e.v, m: make(map[string]any)}, nil
 }
 
+// EncodeVariant encodes a variant of a sum type as a Variant.
+func (e *Encoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	return &VariantEncoder{v: e.v, variant: Variant{Enum: enum, Name: variant, Index: index}}, nil
+}
+
 type SeqEncoder struct {
 	v  *any
 	vs []any
@@ -210,6 +220,28 @@ func (e *StructEncoder) EncodeField(key string, _ any, ser codec.Serializer) err
 	return nil
 }
 
+// A Variant represents a variant of a sum type. Unit variants have a nil Value.
+type Variant struct {
+	Enum  string // the name of the sum type
+	Name  string // the name of the variant
+	Index int    // the index of the variant
+	Value any    // the variant's value
+}
+
+type VariantEncoder struct {
+	v       *any
+	variant Variant
+}
+
+func (e *VariantEncoder) Close() error {
+	*e.v = e.variant
+	return nil
+}
+
+func (e *VariantEncoder) EncodeValue(_ any, ser codec.Serializer) error {
+	return ser.Serialize(NewEncoder(&e.variant.Value))
+}
+
 type mapKeyEncoder struct {
 	key *string
 }
@@ -253,6 +285,9 @@ func (e mapKeyEncoder) EncodeMap(len int) (codec.MapEncoder, error) {
 func (e mapKeyEncoder) EncodeStruct(name string) (codec.StructEncoder, error) {
 	return nil, errors.New("map key must be a string")
 }
+func (e mapKeyEncoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	return nil, errors.New("map key must be a string")
+}
 func (e mapKeyEncoder) EncodeAny(v any) (bool, error) {
 	return false, nil
 }
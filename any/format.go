@@ -0,0 +1,51 @@
+package any
+
+import (
+	"time"
+
+	"github.com/pgavlin/codec"
+)
+
+// Format is the codec format for plain Go values.
+var Format = codec.NewFormat("any")
+
+func init() {
+	codec.Override[time.Time](Format, timeCodec{})
+}
+
+// timeCodec passes time.Time values through as-is rather than decomposing them.
+type timeCodec struct {
+	codec.DefaultVisitor
+	value *time.Time
+}
+
+func (timeCodec) New(v *time.Time) codec.Codec[time.Time] {
+	return timeCodec{value: v}
+}
+
+func (c timeCodec) VisitString(v string) error {
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return err
+	}
+	*c.value = t
+	return nil
+}
+
+func (c timeCodec) Deserialize(d codec.Decoder) error {
+	if d, ok := d.(Decoder); ok {
+		if t, ok := d.v.(time.Time); ok {
+			*c.value = t
+			return nil
+		}
+	}
+	return d.DecodeString(c)
+}
+
+func (c timeCodec) Serialize(e codec.Encoder) error {
+	if e, ok := e.(*Encoder); ok {
+		*e.v = *c.value
+		return nil
+	}
+	return e.EncodeString(c.value.Format(time.RFC3339Nano))
+}
@@ -0,0 +1,201 @@
+// Package cbor implements the Concise Binary Object Representation (CBOR) as described in RFC 8949.
+package cbor
+
+import (
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/pgavlin/codec"
+)
+
+// Format is the codec format for CBOR. Values of type time.Time are encoded using the standard date/time tag, and
+// values of type big.Int are encoded as integers or bignums.
+var Format = codec.NewFormat("cbor")
+
+func init() {
+	codec.Override[time.Time](Format, timeCodec{})
+	codec.Override[big.Int](Format, bigIntCodec{})
+}
+
+// Major types.
+const (
+	majorUint   = 0 << 5
+	majorNegInt = 1 << 5
+	majorBytes  = 2 << 5
+	majorText   = 3 << 5
+	majorArray  = 4 << 5
+	majorMap    = 5 << 5
+	majorTag    = 6 << 5
+	majorSimple = 7 << 5
+)
+
+// Additional information values and simple values.
+const (
+	infoUint8      = 24
+	infoUint16     = 25
+	infoUint32     = 26
+	infoUint64     = 27
+	infoIndefinite = 31
+
+	simpleFalse     = majorSimple | 20
+	simpleTrue      = majorSimple | 21
+	simpleNull      = majorSimple | 22
+	simpleUndefined = majorSimple | 23
+	simpleFloat16   = majorSimple | infoUint16
+	simpleFloat32   = majorSimple | infoUint32
+	simpleFloat64   = majorSimple | infoUint64
+	simpleBreak     = majorSimple | infoIndefinite
+)
+
+// Tags. Tags other than these are ignored when decoding.
+const (
+	tagDateTime  = 0     // an RFC 3339 date/time string
+	tagPosBignum = 2     // a byte string holding an unsigned bignum
+	tagNegBignum = 3     // a byte string holding -1 minus an unsigned bignum
+	tagComplex   = 43000 // a two-element array holding the real and imaginary parts of a complex number
+)
+
+// EncodeFlags is a type used to represent configuration options that can be applied when encoding values.
+type EncodeFlags uint
+
+const (
+	// Canonical is an encoding flag that enables the core deterministic encoding described in section 4.2 of RFC 8949.
+	// Map entries are sorted by the bytewise lexicographic order of their encoded keys, and floating-point values are
+	// encoded using the shortest of the half-, single-, and double-precision forms that represents them exactly.
+	Canonical EncodeFlags = 1 << iota
+)
+
+// Append appends the CBOR encoding of x to b, using s to serialize x, and returns the extended buffer.
+func Append(b []byte, x any, s codec.Serializer, flags EncodeFlags) ([]byte, error) {
+	e := Encoder{out: b, flags: flags}
+	err := s.Serialize(&e)
+	return e.out, err
+}
+
+// Marshal returns the CBOR encoding of x.
+func Marshal(x any) ([]byte, error) {
+	return Append(nil, x, codec.GetSerializer(x, Format), 0)
+}
+
+// MarshalCanonical returns the deterministic CBOR encoding of x. See Canonical.
+func MarshalCanonical(x any) ([]byte, error) {
+	return Append(nil, x, codec.GetSerializer(x, Format), Canonical)
+}
+
+// Unmarshal decodes the single CBOR data item in b into the value pointed to by x. It is an error for b to contain
+// any data after the item.
+func Unmarshal(b []byte, x any) error {
+	r, err := Parse(b, x, codec.GetDeserializer(x, Format))
+	if err == nil && len(r) != 0 {
+		err = syntaxError(len(b)-len(r), "unexpected data after top-level item")
+	}
+	return err
+}
+
+// Parse decodes the first CBOR data item in b into the value pointed to by x using ds and returns the remaining data.
+// Parse can be used to decode sequences of concatenated items (RFC 8742).
+func Parse(b []byte, x any, ds codec.Deserializer) ([]byte, error) {
+	d := Decoder{in: b, rest: b}
+	err := ds.Deserialize(&d)
+	return d.rest, err
+}
+
+// timeCodec represents time.Time values as tagged RFC 3339 strings. Times may also be decoded from epoch-based
+// date/times and untagged strings.
+type timeCodec struct {
+	codec.DefaultVisitor
+	value *time.Time
+}
+
+func (timeCodec) New(v *time.Time) codec.Codec[time.Time] {
+	return timeCodec{value: v}
+}
+
+func (c timeCodec) VisitString(v string) error {
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return err
+	}
+	*c.value = t
+	return nil
+}
+
+func (c timeCodec) VisitInt64(v int64) error {
+	*c.value = time.Unix(v, 0)
+	return nil
+}
+
+func (c timeCodec) VisitUint64(v uint64) error {
+	if v > math.MaxInt64 {
+		return &codec.UnmarshalTypeError{Value: "epoch-based date/time"}
+	}
+	return c.VisitInt64(int64(v))
+}
+
+func (c timeCodec) VisitFloat32(v float32) error {
+	return c.VisitFloat64(float64(v))
+}
+
+func (c timeCodec) VisitFloat64(v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return &codec.UnmarshalTypeError{Value: "epoch-based date/time"}
+	}
+	sec, frac := math.Modf(v)
+	*c.value = time.Unix(int64(sec), int64(frac*1e9))
+	return nil
+}
+
+func (c timeCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeAny(c)
+}
+
+func (c timeCodec) Serialize(e codec.Encoder) error {
+	if e, ok := e.(*Encoder); ok {
+		e.out = appendHead(e.out, majorTag, tagDateTime)
+	}
+	return e.EncodeString(c.value.Format(time.RFC3339Nano))
+}
+
+// bigIntCodec represents big.Int values as integers if they fit in 64 bits, and as bignums otherwise. When used with
+// encoders and decoders other than those in this package, big.Int values are represented as decimal strings.
+type bigIntCodec struct {
+	codec.DefaultVisitor
+	value *big.Int
+}
+
+func (bigIntCodec) New(v *big.Int) codec.Codec[big.Int] {
+	return bigIntCodec{value: v}
+}
+
+func (c bigIntCodec) VisitInt64(v int64) error {
+	c.value.SetInt64(v)
+	return nil
+}
+
+func (c bigIntCodec) VisitUint64(v uint64) error {
+	c.value.SetUint64(v)
+	return nil
+}
+
+func (c bigIntCodec) VisitString(v string) error {
+	if _, ok := c.value.SetString(v, 10); !ok {
+		return &codec.UnmarshalTypeError{Value: "string " + v}
+	}
+	return nil
+}
+
+func (c bigIntCodec) Deserialize(d codec.Decoder) error {
+	if d, ok := d.(*Decoder); ok {
+		return d.decodeBigInt(c.value, c)
+	}
+	return d.DecodeAny(c)
+}
+
+func (c bigIntCodec) Serialize(e codec.Encoder) error {
+	if e, ok := e.(*Encoder); ok {
+		e.out = appendBigInt(e.out, c.value)
+		return nil
+	}
+	return e.EncodeString(c.value.String())
+}
@@ -0,0 +1,296 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pgavlin/codec"
+	"github.com/pgavlin/codec/internal/codectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bigInt(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic(s)
+	}
+	return n
+}
+
+// Examples from Appendix A of RFC 8949.
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		value     any
+		canonical bool
+		expected  string
+	}{
+		{value: 0, expected: "00"},
+		{value: 23, expected: "17"},
+		{value: 24, expected: "1818"},
+		{value: 100, expected: "1864"},
+		{value: 1000, expected: "1903e8"},
+		{value: 1000000, expected: "1a000f4240"},
+		{value: int64(1000000000000), expected: "1b000000e8d4a51000"},
+		{value: uint64(math.MaxUint64), expected: "1bffffffffffffffff"},
+		{value: bigInt("18446744073709551616"), expected: "c249010000000000000000"},
+		{value: bigInt("-18446744073709551616"), expected: "3bffffffffffffffff"},
+		{value: bigInt("-18446744073709551617"), expected: "c349010000000000000000"},
+		{value: -1, expected: "20"},
+		{value: -10, expected: "29"},
+		{value: int8(-100), expected: "3863"},
+		{value: -1000, expected: "3903e7"},
+		{value: 1.1, expected: "fb3ff199999999999a"},
+		{value: float32(100000.0), expected: "fa47c35000"},
+		{value: 1.5, expected: "fb3ff8000000000000"},
+		{value: 1.5, canonical: true, expected: "f93e00"},
+		{value: 65504.0, canonical: true, expected: "f97bff"},
+		{value: 100000.0, canonical: true, expected: "fa47c35000"},
+		{value: 3.4028234663852886e+38, canonical: true, expected: "fa7f7fffff"},
+		{value: 1.0e+300, canonical: true, expected: "fb7e37e43c8800759c"},
+		{value: 5.960464477539063e-8, canonical: true, expected: "f90001"},
+		{value: 0.00006103515625, canonical: true, expected: "f90400"},
+		{value: -4.0, canonical: true, expected: "f9c400"},
+		{value: -4.1, canonical: true, expected: "fbc010666666666666"},
+		{value: math.Inf(1), canonical: true, expected: "f97c00"},
+		{value: math.NaN(), canonical: true, expected: "f97e00"},
+		{value: math.Inf(-1), canonical: true, expected: "f9fc00"},
+		{value: false, expected: "f4"},
+		{value: true, expected: "f5"},
+		{value: nil, expected: "f6"},
+		{value: time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC), expected: "c074323031332d30332d32315432303a30343a30305a"},
+		{value: []byte{1, 2, 3, 4}, expected: "4401020304"},
+		{value: "", expected: "60"},
+		{value: "a", expected: "6161"},
+		{value: "IETF", expected: "6449455446"},
+		{value: "ü", expected: "62c3bc"},
+		{value: []int{}, expected: "80"},
+		{value: []int{1, 2, 3}, expected: "83010203"},
+		{value: []any{1, []int{2, 3}, []int{4, 5}}, expected: "8301820203820405"},
+		{value: map[string]any{"a": 1, "b": []int{2, 3}}, canonical: true, expected: "a26161016162820203"},
+		{value: complex64(complex(1, -2)), expected: "d9a7f882fa3f800000fac0000000"},
+		{value: complex(1, -2), canonical: true, expected: "d9a7f882f93c00f9c000"},
+	}
+	for _, c := range cases {
+		name := fmt.Sprintf("%v", c.value)
+		if c.canonical {
+			name += " (canonical)"
+		}
+		t.Run(name, func(t *testing.T) {
+			marshal := Marshal
+			if c.canonical {
+				marshal = MarshalCanonical
+			}
+			actual, err := marshal(c.value)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, hex.EncodeToString(actual))
+		})
+	}
+}
+
+func decodeHex[T any](t *testing.T, s string) T {
+	return codectest.DecodeHex[T](t, Unmarshal, s)
+}
+
+func TestDecode(t *testing.T) {
+	assert.Equal(t, uint64(math.MaxUint64), decodeHex[any](t, "1bffffffffffffffff"))
+	assert.Equal(t, int64(-1000), decodeHex[any](t, "3903e7"))
+	assert.Equal(t, 0, bigInt("-18446744073709551617").Cmp(ptr(decodeHex[big.Int](t, "c349010000000000000000"))))
+	assert.Equal(t, 0, bigInt("-18446744073709551616").Cmp(ptr(decodeHex[big.Int](t, "3bffffffffffffffff"))))
+	assert.Equal(t, 0, big.NewInt(42).Cmp(ptr(decodeHex[big.Int](t, "c2412a"))))
+	assert.Equal(t, uint64(42), decodeHex[any](t, "c2412a"))
+	assert.Equal(t, float32(5.960464477539063e-8), decodeHex[any](t, "f90001"))
+	assert.Equal(t, float32(-4.0), decodeHex[any](t, "f9c400"))
+	assert.Equal(t, 100000.0, decodeHex[float64](t, "fa47c35000"))
+	assert.True(t, math.IsNaN(decodeHex[float64](t, "f97e00")))
+	assert.Nil(t, decodeHex[any](t, "f7"))
+	assert.Equal(t, complex64(complex(1, -2)), decodeHex[any](t, "d9a7f882f93c00f9c000"))
+	assert.Equal(t, complex(1, -2), decodeHex[complex128](t, "d9a7f882f93c00f9c000"))
+
+	ts := time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)
+	assert.True(t, ts.Equal(decodeHex[time.Time](t, "c074323031332d30332d32315432303a30343a30305a")))
+	assert.True(t, ts.Equal(decodeHex[time.Time](t, "c11a514b67b0")))
+	assert.True(t, ts.Add(time.Second/2).Equal(decodeHex[time.Time](t, "c1fb41d452d9ec200000")))
+
+	// Indefinite-length strings, arrays, and maps.
+	assert.Equal(t, []byte{1, 2, 3, 4, 5}, decodeHex[[]byte](t, "5f42010243030405ff"))
+	assert.Equal(t, "streaming", decodeHex[string](t, "7f657374726561646d696e67ff"))
+	assert.Equal(t, []int{}, decodeHex[[]int](t, "9fff"))
+	assert.Equal(t, []any{uint64(1), []any{uint64(2), uint64(3)}, []any{uint64(4), uint64(5)}}, decodeHex[any](t, "9f018202039f0405ffff"))
+	assert.Equal(t, map[string]any{"a": uint64(1), "b": []any{uint64(2), uint64(3)}}, decodeHex[any](t, "bf61610161629f0203ffff"))
+	assert.Equal(t, map[string][]int{"Fun": {}, "Amt": {-2}}, decodeHex[map[string][]int](t, "bf6346756e9fff63416d749f21ffff"))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestSize(t *testing.T) {
+	b, err := hex.DecodeString("9f01a201029f03ff0405bf0607ffff")
+	require.NoError(t, err)
+
+	var sizes []string
+	_, err = Parse(b, nil, codectest.SizeVisitor{Sizes: &sizes})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0/false", "2/true", "0/false", "0/false"}, sizes)
+}
+
+type testStruct struct {
+	Name    string            `codec:"name"`
+	Count   int16             `codec:"count"`
+	Ratio   float32           `codec:"ratio"`
+	Data    []byte            `codec:"data"`
+	Tags    []string          `codec:"tags"`
+	Labels  map[string]string `codec:"labels"`
+	Complex complex128        `codec:"complex"`
+	Time    time.Time         `codec:"time"`
+	Big     *big.Int          `codec:"big"`
+	Next    *testStruct       `codec:"next"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	expected := testStruct{
+		Name:    "name",
+		Count:   -300,
+		Ratio:   0.25,
+		Data:    []byte("data"),
+		Tags:    []string{"a", "b"},
+		Labels:  map[string]string{"z": "1", "a": "2"},
+		Complex: complex(1.5, math.Pi),
+		Time:    time.Date(2023, 6, 1, 12, 30, 0, 123456789, time.UTC),
+		Big:     bigInt("123456789012345678901234567890"),
+		Next:    &testStruct{Name: "next", Tags: []string{}, Labels: map[string]string{}},
+	}
+
+	for _, marshal := range []func(any) ([]byte, error){Marshal, MarshalCanonical} {
+		b, err := marshal(expected)
+		require.NoError(t, err)
+
+		var actual testStruct
+		require.NoError(t, Unmarshal(b, &actual))
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	m := map[string]int{}
+	for i := 0; i < 100; i++ {
+		m[fmt.Sprint(i)] = i
+	}
+
+	expected, err := MarshalCanonical(m)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		actual, err := MarshalCanonical(m)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	// Keys are sorted by their encodings: shorter keys sort first.
+	b, err := MarshalCanonical(map[string]int{"b": 1, "aa": 3, "a": 2})
+	require.NoError(t, err)
+	assert.Equal(t, "a361610261620162616103", hex.EncodeToString(b))
+
+	// Struct fields are sorted as well.
+	b, err = MarshalCanonical(struct {
+		B int `codec:"b"`
+		A int `codec:"a"`
+	}{B: 1, A: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "a2616102616201", hex.EncodeToString(b))
+
+	// Containers whose heads grow when they are closed are shifted into place.
+	b, err = Marshal(map[string]unsizedSeq{"s": 30})
+	require.NoError(t, err)
+	assert.Equal(t, "a16173981e"+strings.Repeat("00", 30), hex.EncodeToString(b))
+}
+
+// unsizedSeq encodes a sequence of zeros without passing its length to EncodeSeq.
+type unsizedSeq int
+
+func (s unsizedSeq) Serialize(e codec.Encoder) error {
+	seq, err := e.EncodeSeq(0)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(s); i++ {
+		var zero int
+		if err := seq.EncodeElement(zero, codec.NewInt(&zero)); err != nil {
+			return err
+		}
+	}
+	return seq.Close()
+}
+
+func TestStream(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.SetCanonical(true)
+	require.NoError(t, e.Encode(1.5))
+	require.NoError(t, e.Encode("a"))
+	assert.Equal(t, "f93e006161", hex.EncodeToString(buf.Bytes()))
+
+	d := NewDecoder(buf.Bytes())
+	var values []any
+	for {
+		var v any
+		err := d.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		values = append(values, v)
+	}
+	assert.Equal(t, []any{float32(1.5), "a"}, values)
+}
+
+func TestErrors(t *testing.T) {
+	var v any
+	err := Unmarshal([]byte{0x83, 0x01, 0x02}, &v)
+	assert.EqualError(t, err, "cbor: unexpected end of input")
+	assert.Equal(t, int64(1), err.(*SyntaxError).Offset)
+
+	err = Unmarshal([]byte{0x01, 0x02}, &v)
+	assert.EqualError(t, err, "cbor: unexpected data after top-level item")
+
+	err = Unmarshal([]byte{0x1c}, &v)
+	assert.EqualError(t, err, "cbor: invalid additional information 28 for major type 0")
+
+	err = Unmarshal([]byte{0x62, 0xff, 0xfe}, &v)
+	assert.EqualError(t, err, "cbor: invalid UTF-8 in text string")
+
+	var i8 int8
+	err = Unmarshal([]byte{0x19, 0x01, 0x2c}, &i8)
+	assert.EqualError(t, err, "codec: cannot unmarshal number 300 into Go value of type int8")
+
+	err = Unmarshal([]byte{0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, &v)
+	assert.EqualError(t, err, "codec: cannot unmarshal number -18446744073709551616 into Go value of type interface {}")
+
+	b, err := Marshal(map[string]bool{"field": true, "extra": true})
+	require.NoError(t, err)
+	d := NewDecoder(b)
+	d.DisallowUnknownFields()
+	var s codectest.BoolStruct
+	assert.EqualError(t, d.Decode(&s), `codec: unknown field "extra" in Go struct BoolStruct`)
+}
+
+func TestVariant(t *testing.T) {
+	b, err := Marshal([]codectest.Option{{Valid: true, Value: "x"}, {}})
+	require.NoError(t, err)
+	assert.Equal(t, "82a164536f6d656178644e6f6e65", hex.EncodeToString(b))
+
+	var options []codectest.Option
+	require.NoError(t, Unmarshal(b, &options))
+	assert.Equal(t, []codectest.Option{{Valid: true, Value: "x"}, {}}, options)
+
+	var v any
+	require.NoError(t, Unmarshal(b, &v))
+	assert.Equal(t, []any{map[string]any{"Some": "x"}, "None"}, v)
+}
@@ -0,0 +1,531 @@
+package cbor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"unicode/utf8"
+
+	"github.com/pgavlin/codec"
+)
+
+// A SyntaxError describes malformed CBOR input.
+type SyntaxError struct {
+	msg    string
+	Offset int64 // the offset in the input at which the error was detected
+}
+
+func (e *SyntaxError) Error() string {
+	return "cbor: " + e.msg
+}
+
+func syntaxError(offset int, format string, args ...any) error {
+	return &SyntaxError{msg: fmt.Sprintf(format, args...), Offset: int64(offset)}
+}
+
+// A Decoder decodes data items from a buffer.
+//
+// Decoders returned by NewDecoder decode a sequence of concatenated data items (RFC 8742); each call to Decode decodes
+// the next item.
+type Decoder struct {
+	in      []byte // the complete input
+	rest    []byte // the input that has not yet been decoded
+	options codec.DecodeOptions
+}
+
+// NewDecoder returns a new decoder that reads data items from b.
+func NewDecoder(b []byte) *Decoder {
+	return &Decoder{in: b, rest: b}
+}
+
+// DisallowUnknownFields causes the Decoder to return an error when the destination is a struct and the input contains
+// map keys which do not match any of the struct's fields.
+func (d *Decoder) DisallowUnknownFields() {
+	d.options.DisallowUnknownFields = true
+}
+
+// Buffered returns the data that has not yet been decoded.
+func (d *Decoder) Buffered() []byte {
+	return d.rest
+}
+
+// Decode decodes the next data item into the value pointed to by v. At the end of the input, Decode returns io.EOF.
+func (d *Decoder) Decode(v any) error {
+	if len(d.rest) == 0 {
+		return io.EOF
+	}
+	return codec.GetDeserializer(v, Format).Deserialize(d)
+}
+
+func (d *Decoder) syntaxError(format string, args ...any) error {
+	return syntaxError(len(d.in)-len(d.rest), format, args...)
+}
+
+func (d *Decoder) unexpectedEOF() error {
+	return d.syntaxError("unexpected end of input")
+}
+
+// readHead reads the head of the next data item and returns its major type, additional information, and argument. The
+// argument of a floating-point value holds its bits. The argument of an indefinite-length item is zero.
+func (d *Decoder) readHead() (major, info byte, arg uint64, err error) {
+	if len(d.rest) == 0 {
+		return 0, 0, 0, d.unexpectedEOF()
+	}
+
+	major, info = d.rest[0]&0xe0, d.rest[0]&0x1f
+	n := 0
+	switch {
+	case info < infoUint8:
+		arg = uint64(info)
+	case info <= infoUint64:
+		n = 1 << (info - infoUint8)
+	case info == infoIndefinite && (major == majorBytes || major == majorText || major == majorArray ||
+		major == majorMap || major == majorSimple):
+		// Indefinite-length items and breaks have no argument.
+	default:
+		return 0, 0, 0, d.syntaxError("invalid additional information %d for major type %d", info, major>>5)
+	}
+
+	if len(d.rest) < 1+n {
+		return 0, 0, 0, d.unexpectedEOF()
+	}
+	switch n {
+	case 1:
+		arg = uint64(d.rest[1])
+	case 2:
+		arg = uint64(binary.BigEndian.Uint16(d.rest[1:]))
+	case 4:
+		arg = uint64(binary.BigEndian.Uint32(d.rest[1:]))
+	case 8:
+		arg = binary.BigEndian.Uint64(d.rest[1:])
+	}
+	d.rest = d.rest[1+n:]
+	return major, info, arg, nil
+}
+
+// readString reads the content of a byte or text string whose head has been read. The chunks of indefinite-length
+// strings are concatenated. The result never aliases the input.
+func (d *Decoder) readString(major, info byte, arg uint64) ([]byte, error) {
+	if info != infoIndefinite {
+		if arg > uint64(len(d.rest)) {
+			return nil, d.unexpectedEOF()
+		}
+		s := append([]byte(nil), d.rest[:arg]...)
+		d.rest = d.rest[arg:]
+		return s, nil
+	}
+
+	s := []byte{}
+	for {
+		if len(d.rest) != 0 && d.rest[0] == simpleBreak {
+			d.rest = d.rest[1:]
+			return s, nil
+		}
+		chunkMajor, chunkInfo, chunkArg, err := d.readHead()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor != major || chunkInfo == infoIndefinite {
+			return nil, d.syntaxError("invalid chunk in indefinite-length string")
+		}
+		if chunkArg > uint64(len(d.rest)) {
+			return nil, d.unexpectedEOF()
+		}
+		s = append(s, d.rest[:chunkArg]...)
+		d.rest = d.rest[chunkArg:]
+	}
+}
+
+func (d *Decoder) readText(info byte, arg uint64) (string, error) {
+	s, err := d.readString(majorText, info, arg)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(s) {
+		return "", d.syntaxError("invalid UTF-8 in text string")
+	}
+	return string(s), nil
+}
+
+// readBignum reads the content of a bignum whose tag has been read.
+func (d *Decoder) readBignum(tag uint64) (*big.Int, error) {
+	major, info, arg, err := d.readHead()
+	if err != nil {
+		return nil, err
+	}
+	if major != majorBytes {
+		return nil, d.syntaxError("bignum content must be a byte string")
+	}
+	b, err := d.readString(major, info, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	n := new(big.Int).SetBytes(b)
+	if tag == tagNegBignum {
+		n.Neg(n).Sub(n, big.NewInt(1))
+	}
+	return n, nil
+}
+
+// negInt returns the value of a negative integer with the given argument.
+func negInt(arg uint64) *big.Int {
+	n := new(big.Int).SetUint64(arg)
+	return n.Neg(n).Sub(n, big.NewInt(1))
+}
+
+// readFloat reads a floating-point value. The boolean result is true if the value was encoded at half or single
+// precision.
+func (d *Decoder) readFloat() (float64, bool, error) {
+	major, info, arg, err := d.readHead()
+	if err != nil {
+		return 0, false, err
+	}
+	if major == majorSimple {
+		switch info {
+		case infoUint16:
+			return float16Value(uint16(arg)), true, nil
+		case infoUint32:
+			return float64(math.Float32frombits(uint32(arg))), true, nil
+		case infoUint64:
+			return math.Float64frombits(arg), false, nil
+		}
+	}
+	return 0, false, d.syntaxError("expected a floating-point value")
+}
+
+// peekTag returns the tag number of the next data item if the item is tagged.
+func (d *Decoder) peekTag() (uint64, bool) {
+	rest := d.rest
+	defer func() { d.rest = rest }()
+
+	major, _, tag, err := d.readHead()
+	return tag, err == nil && major == majorTag
+}
+
+// decodeValue decodes the next data item. If the item is a complex number, kind selects the visit method: Complex64
+// selects VisitComplex64, Complex128 selects VisitComplex128, and any other kind selects the method that matches the
+// precision of the encoded number.
+func (d *Decoder) decodeValue(v codec.Visitor, kind reflect.Kind) error {
+	major, info, arg, err := d.readHead()
+	if err != nil {
+		return err
+	}
+
+	switch major {
+	case majorUint:
+		return v.VisitUint64(arg)
+	case majorNegInt:
+		if arg > math.MaxInt64 {
+			return &codec.UnmarshalTypeError{Value: "number " + negInt(arg).String()}
+		}
+		return v.VisitInt64(-1 - int64(arg))
+	case majorBytes:
+		b, err := d.readString(major, info, arg)
+		if err != nil {
+			return err
+		}
+		return v.VisitBytes(b)
+	case majorText:
+		s, err := d.readText(info, arg)
+		if err != nil {
+			return err
+		}
+		return v.VisitString(s)
+	case majorArray:
+		if info != infoIndefinite && arg > uint64(len(d.rest)) {
+			return d.unexpectedEOF()
+		}
+		return v.VisitSeq(&SeqDecoder{d: d, remaining: length(info, arg)})
+	case majorMap:
+		if info != infoIndefinite && arg > uint64(len(d.rest))/2 {
+			return d.unexpectedEOF()
+		}
+		return v.VisitMap(&MapDecoder{d: d, remaining: length(info, arg)})
+	case majorTag:
+		return d.decodeTagged(arg, v, kind)
+	default:
+		switch info {
+		case simpleFalse & 0x1f:
+			return v.VisitBool(false)
+		case simpleTrue & 0x1f:
+			return v.VisitBool(true)
+		case simpleNull & 0x1f, simpleUndefined & 0x1f:
+			return v.VisitNil()
+		case infoUint16:
+			return v.VisitFloat32(float32(float16Value(uint16(arg))))
+		case infoUint32:
+			return v.VisitFloat32(math.Float32frombits(uint32(arg)))
+		case infoUint64:
+			return v.VisitFloat64(math.Float64frombits(arg))
+		case infoIndefinite:
+			return d.syntaxError("unexpected break")
+		default:
+			return d.syntaxError("unsupported simple value %d", arg)
+		}
+	}
+}
+
+// length returns the length of an array or map, or -1 if the array or map has indefinite length.
+func length(info byte, arg uint64) int {
+	if info == infoIndefinite {
+		return -1
+	}
+	return int(arg)
+}
+
+// decodeTagged decodes the content of a tagged data item whose tag has been read. Bignums are visited as 64-bit
+// integers, and complex numbers as complex values. The content of other tags, including the date/time tags, is decoded
+// as-is.
+func (d *Decoder) decodeTagged(tag uint64, v codec.Visitor, kind reflect.Kind) error {
+	switch tag {
+	case tagPosBignum, tagNegBignum:
+		n, err := d.readBignum(tag)
+		if err != nil {
+			return err
+		}
+		switch {
+		case n.IsUint64():
+			return v.VisitUint64(n.Uint64())
+		case n.IsInt64():
+			return v.VisitInt64(n.Int64())
+		default:
+			return &codec.UnmarshalTypeError{Value: "number " + n.String()}
+		}
+	case tagComplex:
+		major, info, arg, err := d.readHead()
+		if err != nil {
+			return err
+		}
+		if major != majorArray || info == infoIndefinite || arg != 2 {
+			return d.syntaxError("complex number content must be an array of two floating-point values")
+		}
+		re, reSingle, err := d.readFloat()
+		if err != nil {
+			return err
+		}
+		im, imSingle, err := d.readFloat()
+		if err != nil {
+			return err
+		}
+		if kind == reflect.Complex64 || kind != reflect.Complex128 && reSingle && imSingle {
+			return v.VisitComplex64(complex(float32(re), float32(im)))
+		}
+		return v.VisitComplex128(complex(re, im))
+	default:
+		return d.decodeValue(v, kind)
+	}
+}
+
+// decodeBigInt decodes an integer or bignum into v. Other data items are decoded using cv.
+func (d *Decoder) decodeBigInt(v *big.Int, cv codec.Visitor) error {
+	if tag, ok := d.peekTag(); ok && (tag == tagPosBignum || tag == tagNegBignum) {
+		d.readHead()
+		n, err := d.readBignum(tag)
+		if err != nil {
+			return err
+		}
+		v.Set(n)
+		return nil
+	}
+	if len(d.rest) != 0 && d.rest[0]&0xe0 == majorNegInt {
+		_, _, arg, err := d.readHead()
+		if err != nil {
+			return err
+		}
+		v.Set(negInt(arg))
+		return nil
+	}
+	return d.DecodeAny(cv)
+}
+
+// Format returns the CBOR format.
+func (d *Decoder) Format() *codec.Format {
+	return Format
+}
+
+func (d *Decoder) DecodeAny(v codec.Visitor) error {
+	return d.decodeValue(v, reflect.Invalid)
+}
+
+func (d *Decoder) DecodeNil(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d *Decoder) DecodeBool(v codec.Visitor) error                { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt8(v codec.Visitor) error                { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt16(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt32(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt64(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint(v codec.Visitor) error                { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint8(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint16(v codec.Visitor) error              { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint32(v codec.Visitor) error              { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint64(v codec.Visitor) error              { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUintptr(v codec.Visitor) error             { return d.DecodeAny(v) }
+func (d *Decoder) DecodeFloat32(v codec.Visitor) error             { return d.DecodeAny(v) }
+func (d *Decoder) DecodeFloat64(v codec.Visitor) error             { return d.DecodeAny(v) }
+func (d *Decoder) DecodeString(v codec.Visitor) error              { return d.DecodeAny(v) }
+func (d *Decoder) DecodeBytes(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d *Decoder) DecodeSeq(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d *Decoder) DecodeMap(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d *Decoder) DecodeStruct(name string, v codec.Visitor) error { return d.DecodeAny(v) }
+
+func (d *Decoder) DecodeComplex64(v codec.Visitor) error {
+	return d.decodeValue(v, reflect.Complex64)
+}
+
+func (d *Decoder) DecodeComplex128(v codec.Visitor) error {
+	return d.decodeValue(v, reflect.Complex128)
+}
+
+func (d *Decoder) DecodePtr(v codec.Visitor) error {
+	if len(d.rest) != 0 && (d.rest[0] == simpleNull || d.rest[0] == simpleUndefined) {
+		d.rest = d.rest[1:]
+		return v.VisitNil()
+	}
+	return v.VisitElem(ElemDecoder{d})
+}
+
+// nullItem is the encoding of null. It is decoded as the value of unit variants.
+var nullItem = []byte{simpleNull}
+
+// DecodeVariant decodes the externally tagged representation produced by Encoder.EncodeVariant: unit variants are
+// represented by their names, and other variants by single-entry maps from their names to their values. Other data
+// items are decoded as-is.
+func (d *Decoder) DecodeVariant(enum string, variants []string, v codec.Visitor) error {
+	rest := d.rest
+	major, info, arg, err := d.readHead()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case major == majorText:
+		name, err := d.readText(info, arg)
+		if err != nil {
+			return err
+		}
+		return v.VisitVariant(&VariantDecoder{d: &Decoder{in: nullItem, rest: nullItem, options: d.options}, name: name})
+	case major == majorMap && (info == infoIndefinite || arg == 1) && len(d.rest) != 0 && d.rest[0]&0xe0 == majorText:
+		_, keyInfo, keyArg, err := d.readHead()
+		if err != nil {
+			return err
+		}
+		name, err := d.readText(keyInfo, keyArg)
+		if err != nil {
+			return err
+		}
+
+		vd := &VariantDecoder{d: d, name: name}
+		if err := v.VisitVariant(vd); err != nil {
+			return err
+		}
+		if !vd.decoded {
+			if err := (codec.SkipCodec{}).Deserialize(d); err != nil {
+				return err
+			}
+		}
+		if info == infoIndefinite {
+			if len(d.rest) == 0 || d.rest[0] != simpleBreak {
+				return d.syntaxError("expected the end of a single-entry map")
+			}
+			d.rest = d.rest[1:]
+		}
+		return nil
+	default:
+		d.rest = rest
+		return d.DecodeAny(v)
+	}
+}
+
+type ElemDecoder struct {
+	d *Decoder
+}
+
+func (d ElemDecoder) Element(_ any, ds codec.Deserializer) error {
+	return ds.Deserialize(d.d)
+}
+
+// more reports whether an array or map with the given number of remaining elements or entries has more elements. For
+// indefinite-length arrays and maps, remaining is negative, and more consumes the break that ends the array or map.
+func (d *Decoder) more(remaining *int) (bool, error) {
+	switch {
+	case *remaining > 0:
+		*remaining--
+		return true, nil
+	case *remaining == 0:
+		return false, nil
+	case len(d.rest) == 0:
+		return false, d.unexpectedEOF()
+	case d.rest[0] == simpleBreak:
+		d.rest, *remaining = d.rest[1:], 0
+		return false, nil
+	default:
+		return true, nil
+	}
+}
+
+type SeqDecoder struct {
+	d         *Decoder
+	remaining int
+}
+
+// Size returns the number of remaining elements. The size of an indefinite-length array is unknown.
+func (d *SeqDecoder) Size() (int, bool) {
+	if d.remaining < 0 {
+		return 0, false
+	}
+	return d.remaining, true
+}
+
+func (d *SeqDecoder) NextElement(_ any, ds codec.Deserializer) (bool, error) {
+	if more, err := d.d.more(&d.remaining); !more || err != nil {
+		return false, err
+	}
+	return true, ds.Deserialize(d.d)
+}
+
+type MapDecoder struct {
+	d         *Decoder
+	remaining int
+}
+
+// Size returns the number of remaining entries. The size of an indefinite-length map is unknown.
+func (d *MapDecoder) Size() (int, bool) {
+	if d.remaining < 0 {
+		return 0, false
+	}
+	return d.remaining, true
+}
+
+func (d *MapDecoder) Options() codec.DecodeOptions {
+	return d.d.options
+}
+
+func (d *MapDecoder) NextKey(_ any, ds codec.Deserializer) (bool, error) {
+	if more, err := d.d.more(&d.remaining); !more || err != nil {
+		return false, err
+	}
+	return true, ds.Deserialize(d.d)
+}
+
+func (d *MapDecoder) NextValue(_ any, ds codec.Deserializer) error {
+	return ds.Deserialize(d.d)
+}
+
+type VariantDecoder struct {
+	d       *Decoder
+	name    string
+	decoded bool
+}
+
+func (d *VariantDecoder) Variant() string {
+	return d.name
+}
+
+func (d *VariantDecoder) Value(_ any, ds codec.Deserializer) error {
+	d.decoded = true
+	return ds.Deserialize(d.d)
+}
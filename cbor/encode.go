@@ -0,0 +1,383 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/pgavlin/codec"
+)
+
+// An Encoder appends the CBOR encoding of values to a buffer.
+//
+// Encoders returned by NewEncoder write each value passed to Encode to an underlying writer as a separate data item.
+type Encoder struct {
+	out   []byte
+	flags EncodeFlags
+	w     io.Writer // non-nil if the encoder was returned by NewEncoder
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// SetCanonical enables or disables the deterministic encoding described by Canonical.
+func (e *Encoder) SetCanonical(on bool) {
+	if on {
+		e.flags |= Canonical
+	} else {
+		e.flags &^= Canonical
+	}
+}
+
+// Encode writes the CBOR encoding of v to the stream.
+//
+// Encode may only be called on encoders returned by NewEncoder.
+func (e *Encoder) Encode(v any) error {
+	e.out = e.out[:0]
+	if err := codec.GetSerializer(v, Format).Serialize(e); err != nil {
+		return err
+	}
+	_, err := e.w.Write(e.out)
+	return err
+}
+
+func (e *Encoder) canonical() bool {
+	return (e.flags & Canonical) != 0
+}
+
+// appendHead appends the head of a data item with the given major type and argument to b. The argument is encoded in
+// the fewest bytes possible.
+func appendHead(b []byte, major byte, v uint64) []byte {
+	switch {
+	case v < infoUint8:
+		return append(b, major|byte(v))
+	case v <= math.MaxUint8:
+		return append(b, major|infoUint8, byte(v))
+	case v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|infoUint16), uint16(v))
+	case v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|infoUint32), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, major|infoUint64), v)
+	}
+}
+
+func appendInt(b []byte, v int64) []byte {
+	if v < 0 {
+		return appendHead(b, majorNegInt, uint64(-1-v))
+	}
+	return appendHead(b, majorUint, uint64(v))
+}
+
+// appendBigInt appends v as an integer if it fits in a head, and as a bignum otherwise.
+func appendBigInt(b []byte, v *big.Int) []byte {
+	if v.Sign() >= 0 {
+		if v.IsUint64() {
+			return appendHead(b, majorUint, v.Uint64())
+		}
+		b = appendHead(b, majorTag, tagPosBignum)
+		return appendString(b, majorBytes, v.Bytes())
+	}
+
+	// Negative integers are encoded as -1 minus their argument.
+	n := new(big.Int).Neg(v)
+	n.Sub(n, big.NewInt(1))
+	if n.IsUint64() {
+		return appendHead(b, majorNegInt, n.Uint64())
+	}
+	b = appendHead(b, majorTag, tagNegBignum)
+	return appendString(b, majorBytes, n.Bytes())
+}
+
+func appendString[T string | []byte](b []byte, major byte, v T) []byte {
+	b = appendHead(b, major, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendFloat32(b []byte, v float32) []byte {
+	return binary.BigEndian.AppendUint32(append(b, simpleFloat32), math.Float32bits(v))
+}
+
+func appendFloat64(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, simpleFloat64), math.Float64bits(v))
+}
+
+// appendShortestFloat appends v using the shortest of the half-, single-, and double-precision forms that represents
+// it exactly. NaNs are encoded as the half-precision quiet NaN.
+func appendShortestFloat(b []byte, v float64) []byte {
+	if math.IsNaN(v) {
+		return append(b, simpleFloat16, 0x7e, 0x00)
+	}
+	f := float32(v)
+	if float64(f) != v {
+		return appendFloat64(b, v)
+	}
+	if h, ok := float16Bits(f); ok {
+		return binary.BigEndian.AppendUint16(append(b, simpleFloat16), h)
+	}
+	return appendFloat32(b, f)
+}
+
+// Format returns the CBOR format.
+func (e *Encoder) Format() *codec.Format {
+	return Format
+}
+
+func (e *Encoder) EncodeNil() error {
+	e.out = append(e.out, simpleNull)
+	return nil
+}
+
+func (e *Encoder) EncodeBool(v bool) error {
+	if v {
+		e.out = append(e.out, simpleTrue)
+	} else {
+		e.out = append(e.out, simpleFalse)
+	}
+	return nil
+}
+
+func (e *Encoder) EncodeInt(v int) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e *Encoder) EncodeInt8(v int8) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e *Encoder) EncodeInt16(v int16) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e *Encoder) EncodeInt32(v int32) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e *Encoder) EncodeInt64(v int64) error {
+	e.out = appendInt(e.out, v)
+	return nil
+}
+
+func (e *Encoder) EncodeUint(v uint) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e *Encoder) EncodeUint8(v uint8) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e *Encoder) EncodeUint16(v uint16) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e *Encoder) EncodeUint32(v uint32) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e *Encoder) EncodeUint64(v uint64) error {
+	e.out = appendHead(e.out, majorUint, v)
+	return nil
+}
+
+func (e *Encoder) EncodeUintptr(v uintptr) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e *Encoder) EncodeFloat32(v float32) error {
+	if e.canonical() {
+		e.out = appendShortestFloat(e.out, float64(v))
+	} else {
+		e.out = appendFloat32(e.out, v)
+	}
+	return nil
+}
+
+func (e *Encoder) EncodeFloat64(v float64) error {
+	if e.canonical() {
+		e.out = appendShortestFloat(e.out, v)
+	} else {
+		e.out = appendFloat64(e.out, v)
+	}
+	return nil
+}
+
+// EncodeComplex64 encodes a complex number as a tagged array that holds its real and imaginary parts.
+func (e *Encoder) EncodeComplex64(v complex64) error {
+	e.out = appendHead(e.out, majorTag, tagComplex)
+	e.out = appendHead(e.out, majorArray, 2)
+	e.EncodeFloat32(real(v))
+	return e.EncodeFloat32(imag(v))
+}
+
+// EncodeComplex128 encodes a complex number as a tagged array that holds its real and imaginary parts.
+func (e *Encoder) EncodeComplex128(v complex128) error {
+	e.out = appendHead(e.out, majorTag, tagComplex)
+	e.out = appendHead(e.out, majorArray, 2)
+	e.EncodeFloat64(real(v))
+	return e.EncodeFloat64(imag(v))
+}
+
+func (e *Encoder) EncodeString(v string) error {
+	e.out = appendString(e.out, majorText, v)
+	return nil
+}
+
+func (e *Encoder) EncodeBytes(v []byte) error {
+	e.out = appendString(e.out, majorBytes, v)
+	return nil
+}
+
+func (e *Encoder) EncodeElem(_ any, s codec.Serializer) error {
+	return s.Serialize(e)
+}
+
+func (e *Encoder) EncodeSeq(len int) (codec.SeqEncoder, error) {
+	return &SeqEncoder{e.open(majorArray, len)}, nil
+}
+
+func (e *Encoder) EncodeMap(len int) (codec.MapEncoder, error) {
+	return &MapEncoder{container: e.open(majorMap, len)}, nil
+}
+
+func (e *Encoder) EncodeStruct(name string) (codec.StructEncoder, error) {
+	return &StructEncoder{MapEncoder{container: e.open(majorMap, 0)}}, nil
+}
+
+// EncodeVariant encodes a variant of a sum type using the externally tagged representation: unit variants are encoded
+// as their names, and other variants are encoded as single-entry maps from their names to their values.
+func (e *Encoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	return &VariantEncoder{e: e, name: variant}, nil
+}
+
+// A container is an array or map whose head is written before its contents. The length of a container is not known
+// until it is closed, so its head is written using the length hint passed to EncodeSeq or EncodeMap and patched when
+// the container is closed.
+type container struct {
+	e     *Encoder
+	major byte
+	start int // the offset of the container's head
+	size  int // the size of the container's head
+	count int // the number of elements or entries written
+}
+
+func (e *Encoder) open(major byte, n int) container {
+	start := len(e.out)
+	e.out = appendHead(e.out, major, uint64(n))
+	return container{e: e, major: major, start: start, size: len(e.out) - start}
+}
+
+func (c *container) close() {
+	var buf [9]byte
+	head := appendHead(buf[:0], c.major, uint64(c.count))
+	if len(head) == c.size {
+		copy(c.e.out[c.start:], head)
+		return
+	}
+	c.e.out = append(c.e.out[:c.start], append(head, c.e.out[c.start+c.size:]...)...)
+}
+
+type SeqEncoder struct {
+	container
+}
+
+func (e *SeqEncoder) Close() error {
+	e.close()
+	return nil
+}
+
+func (e *SeqEncoder) EncodeElement(_ any, s codec.Serializer) error {
+	e.count++
+	return s.Serialize(e.e)
+}
+
+type MapEncoder struct {
+	container
+
+	entries []mapEntry // the offsets of each entry, recorded in canonical mode
+}
+
+// mapEntry records the offsets of the key and value of an encoded map entry.
+type mapEntry struct {
+	key, value int
+}
+
+func (e *MapEncoder) Close() error {
+	if e.e.canonical() {
+		e.sort()
+	}
+	e.close()
+	return nil
+}
+
+// sort sorts the map's entries by the bytewise lexicographic order of their encoded keys.
+func (e *MapEncoder) sort() {
+	type entry struct{ key, data []byte }
+
+	out := e.e.out
+	entries := make([]entry, len(e.entries))
+	for i, m := range e.entries {
+		end := len(out)
+		if i+1 < len(e.entries) {
+			end = e.entries[i+1].key
+		}
+		entries[i] = entry{key: out[m.key:m.value], data: out[m.key:end]}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+
+	sorted := make([]byte, 0, len(out)-e.start-e.size)
+	for _, m := range entries {
+		sorted = append(sorted, m.data...)
+	}
+	copy(out[e.start+e.size:], sorted)
+}
+
+func (e *MapEncoder) EncodeKey(_ any, s codec.Serializer) error {
+	if e.e.canonical() {
+		e.entries = append(e.entries, mapEntry{key: len(e.e.out)})
+	}
+	e.count++
+	return s.Serialize(e.e)
+}
+
+func (e *MapEncoder) EncodeValue(_ any, s codec.Serializer) error {
+	if e.e.canonical() {
+		e.entries[len(e.entries)-1].value = len(e.e.out)
+	}
+	return s.Serialize(e.e)
+}
+
+type StructEncoder struct {
+	MapEncoder
+}
+
+func (e *StructEncoder) EncodeField(key string, v any, s codec.Serializer) error {
+	if err := e.EncodeKey(key, codec.NewString(&key)); err != nil {
+		return err
+	}
+	return e.EncodeValue(v, s)
+}
+
+type VariantEncoder struct {
+	e        *Encoder
+	name     string
+	hasValue bool
+}
+
+func (e *VariantEncoder) Close() error {
+	if !e.hasValue {
+		return e.e.EncodeString(e.name)
+	}
+	return nil
+}
+
+func (e *VariantEncoder) EncodeValue(_ any, s codec.Serializer) error {
+	e.hasValue = true
+	e.e.out = appendHead(e.e.out, majorMap, 1)
+	e.e.EncodeString(e.name)
+	return s.Serialize(e.e)
+}
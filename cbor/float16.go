@@ -0,0 +1,68 @@
+package cbor
+
+import "math"
+
+// float16Bits returns the IEEE 754 half-precision representation of f. The boolean result is false if f cannot be
+// represented exactly at half precision. NaNs are reported as unrepresentable, as their payloads may be lost.
+func float16Bits(f float32) (uint16, bool) {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	switch {
+	case exp == 0xff:
+		if mant != 0 {
+			return 0, false
+		}
+		return sign | 0x7c00, true // infinity
+	case exp == 0 && mant == 0:
+		return sign, true // zero
+	case exp == 0:
+		return 0, false // single-precision subnormals are too small
+	}
+
+	// Normal half-precision values have unbiased exponents in [-14, 15] and 10 bits of mantissa.
+	e := exp - 127
+	if e >= -14 && e <= 15 {
+		if mant&0x1fff != 0 {
+			return 0, false
+		}
+		return sign | uint16(e+15)<<10 | uint16(mant>>13), true
+	}
+
+	// Subnormal half-precision values are multiples of 2^-24 less than 2^-14.
+	if e >= -24 && e < -14 {
+		full := mant | 0x800000
+		shift := uint(-1 - e)
+		if full&(1<<shift-1) != 0 {
+			return 0, false
+		}
+		return sign | uint16(full>>shift), true
+	}
+	return 0, false
+}
+
+// float16Value returns the value of the IEEE 754 half-precision number with the given bits.
+func float16Value(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant != 0 {
+			v = math.NaN()
+		} else {
+			v = math.Inf(1)
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		v = -v
+	}
+	return v
+}
@@ -0,0 +1,881 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/types"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pgavlin/codec"
+)
+
+const codecPath = "github.com/pgavlin/codec"
+
+// A step is one selector in the path from a struct value to one of its (possibly promoted) fields.
+type step struct {
+	name string
+	ptr  bool       // true if the field is a pointer to an embedded struct
+	elem types.Type // the type of the embedded struct if ptr is true
+}
+
+// A field is a serialized struct field. Fields mirror the structField values built by appendStructFields.
+type field struct {
+	name       string
+	tag        bool
+	omitempty  bool
+	required   bool
+	hasDefault bool
+	default_   string
+	path       []step
+	typ        types.Type
+	structTag  string // the field's complete tag
+}
+
+type generator struct {
+	pkg     *types.Package
+	structs map[*types.TypeName]bool
+
+	imports map[string]string // import path -> package name
+	used    map[string]bool   // package names in use
+
+	codec string // the name of the codec package
+
+	buf bytes.Buffer
+}
+
+// generate returns the source of the codecs for the named struct types in pkg. If no names are given, codecs are
+// generated for all tagged struct types.
+func generate(pkg *types.Package, names []string) ([]byte, error) {
+	if pkg.Path() == codecPath {
+		return nil, fmt.Errorf("cannot generate codecs for package %v", codecPath)
+	}
+
+	g := &generator{
+		pkg:     pkg,
+		structs: map[*types.TypeName]bool{},
+		imports: map[string]string{},
+		used:    map[string]bool{},
+	}
+
+	// Reserve the names of the receivers, parameters, and locals used by the generated code.
+	for _, name := range []string{"c", "d", "e", "k", "m", "s", "v", "ok", "err", "options", "found"} {
+		g.used[name] = true
+	}
+
+	var typeNames []*types.TypeName
+	if len(names) != 0 {
+		for _, name := range names {
+			obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+			if !ok || !isStruct(obj) {
+				return nil, fmt.Errorf("%v is not a struct type in package %v", name, pkg.Path())
+			}
+			typeNames = append(typeNames, obj)
+		}
+	} else {
+		typeNames = taggedStructs(pkg)
+	}
+	for _, obj := range typeNames {
+		g.structs[obj] = true
+	}
+
+	g.codec = g.importName(codecPath, "codec")
+	for _, obj := range typeNames {
+		if err := g.generateStruct(obj); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%v\n\npackage %v\n\n", generatedHeader, pkg.Name())
+	if len(g.imports) != 0 {
+		paths := make([]string, 0, len(g.imports))
+		for path := range g.imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		// Standard library packages are grouped before all others.
+		sort.SliceStable(paths, func(i, j int) bool { return isStd(paths[i]) && !isStd(paths[j]) })
+
+		out.WriteString("import (\n")
+		for i, path := range paths {
+			if i > 0 && isStd(paths[i-1]) && !isStd(path) {
+				out.WriteString("\n")
+			}
+			name := g.imports[path]
+			if name == defaultImportName(path) {
+				fmt.Fprintf(&out, "\t%q\n", path)
+			} else {
+				fmt.Fprintf(&out, "\t%v %q\n", name, path)
+			}
+		}
+		out.WriteString(")\n\n")
+	}
+	out.Write(g.buf.Bytes())
+
+	source, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return source, nil
+}
+
+func isStruct(obj *types.TypeName) bool {
+	named, ok := obj.Type().(*types.Named)
+	if !ok || obj.IsAlias() || named.TypeParams().Len() != 0 {
+		return false
+	}
+	_, ok = named.Underlying().(*types.Struct)
+	return ok
+}
+
+// taggedStructs returns the struct types in pkg that have at least one field with a codec tag, along with the struct
+// types that embed them. The latter must be generated as well, as they would otherwise inherit the promoted Serialize
+// and Deserialize methods of the embedded struct.
+func taggedStructs(pkg *types.Package) []*types.TypeName {
+	var candidates []*types.TypeName
+	selected := map[*types.TypeName]bool{}
+	for _, name := range pkg.Scope().Names() {
+		obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok || !isStruct(obj) {
+			continue
+		}
+		candidates = append(candidates, obj)
+
+		st := obj.Type().Underlying().(*types.Struct)
+		for i := 0; i < st.NumFields(); i++ {
+			if _, ok := reflect.StructTag(st.Tag(i)).Lookup("codec"); ok {
+				selected[obj] = true
+				break
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, obj := range candidates {
+			if selected[obj] {
+				continue
+			}
+			st := obj.Type().Underlying().(*types.Struct)
+			for i := 0; i < st.NumFields(); i++ {
+				f := st.Field(i)
+				if !f.Embedded() {
+					continue
+				}
+				t := f.Type()
+				if p, ok := t.(*types.Pointer); ok {
+					t = p.Elem()
+				}
+				if named, ok := t.(*types.Named); ok && selected[named.Obj()] {
+					selected[obj], changed = true, true
+					break
+				}
+			}
+		}
+	}
+
+	var structs []*types.TypeName
+	for _, obj := range candidates {
+		if selected[obj] {
+			structs = append(structs, obj)
+		}
+	}
+	return structs
+}
+
+func isStd(path string) bool {
+	elem, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(elem, ".")
+}
+
+func defaultImportName(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// importName returns the name used to refer to the package with the given path, adding an import if necessary.
+func (g *generator) importName(path, name string) string {
+	if n, ok := g.imports[path]; ok {
+		return n
+	}
+
+	n := name
+	for i := 2; g.used[n] || g.pkg.Scope().Lookup(n) != nil; i++ {
+		n = name + strconv.Itoa(i)
+	}
+	g.imports[path], g.used[n] = n, true
+	return n
+}
+
+func (g *generator) qualifier(p *types.Package) string {
+	if p == g.pkg {
+		return ""
+	}
+	return g.importName(p.Path(), p.Name())
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, g.qualifier)
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// codecNames returns the names of the codec type and its constructor for the given struct type.
+func codecNames(obj *types.TypeName) (string, string) {
+	name := obj.Name()
+	if obj.Exported() {
+		return name + "Codec", "New" + name + "Codec"
+	}
+	return name + "Codec", "new" + string(unicode.ToUpper(rune(name[0]))) + name[1:] + "Codec"
+}
+
+func (g *generator) generateStruct(obj *types.TypeName) error {
+	name := obj.Name()
+	codecName, ctorName := codecNames(obj)
+	fieldsName := string(unicode.ToLower(rune(codecName[0]))) + codecName[1:] + "Fields"
+	for _, n := range []string{codecName, ctorName, fieldsName} {
+		if g.pkg.Scope().Lookup(n) != nil {
+			return fmt.Errorf("cannot generate codec for %v: %v is already declared", name, n)
+		}
+	}
+	for _, m := range []string{"Serialize", "Deserialize"} {
+		// Promoted fields and methods are shadowed by the generated methods.
+		if obj, index, _ := types.LookupFieldOrMethod(types.NewPointer(obj.Type()), false, g.pkg, m); obj != nil && len(index) == 1 {
+			return fmt.Errorf("cannot generate codec for %v: %v already has a %v field or method", name, name, m)
+		}
+	}
+
+	fields, err := g.structFields(obj.Type().Underlying().(*types.Struct), nil, map[types.Type]bool{obj.Type(): true})
+	if err != nil {
+		return fmt.Errorf("%v: %w", name, err)
+	}
+
+	c := g.codec
+	g.printf("// %v is a %v.Codec for %v values.\n", codecName, c, name)
+	g.printf("type %v struct {\n%v.DefaultVisitor\nvalue *%v\n}\n\n", codecName, c, name)
+
+	g.printf("// %v returns a codec for the %v at v.\n", ctorName, name)
+	g.printf("func %v(v *%v) %v {\nreturn %v{value: v}\n}\n\n", ctorName, name, codecName, codecName)
+
+	g.printf("func (%v) New(v *%v) %v.Codec[%v] {\nreturn %v{value: v}\n}\n\n", codecName, name, c, name, codecName)
+
+	g.generateStructFields(codecName, fieldsName, name, fields)
+
+	if err := g.generateVisitMap(obj, codecName, fields); err != nil {
+		return fmt.Errorf("%v: %w", name, err)
+	}
+
+	g.printf("func (c %v) Deserialize(d %v.Decoder) error {\nreturn d.DecodeStruct(%q, c)\n}\n\n", codecName, c, name)
+
+	g.generateSerialize(codecName, fieldsName, name, fields)
+
+	g.printf("// Serialize implements %v.Serializer.\n", c)
+	g.printf("func (v %v) Serialize(e %v.Encoder) error {\nreturn %v{value: &v}.Serialize(e)\n}\n\n", name, c, codecName)
+
+	g.printf("// Deserialize implements %v.Deserializer.\n", c)
+	g.printf("func (v *%v) Deserialize(d %v.Decoder) error {\nreturn %v{value: v}.Deserialize(d)\n}\n\n", name, c, codecName)
+
+	return nil
+}
+
+// structFields returns the serialized fields of st, flattening embedded structs. The rules for names, tags, and
+// embedded structs are the same as those in appendStructFields.
+func (g *generator) structFields(st *types.Struct, path []step, seen map[types.Type]bool) ([]field, error) {
+	type entry struct {
+		field    *field
+		embedded []field
+	}
+
+	names := map[string]struct{}{}
+	var entries []entry
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+
+		var (
+			name       = f.Name()
+			anonymous  = f.Embedded()
+			tag        = false
+			omitempty  = false
+			required   = false
+			hasDefault = false
+			default_   = ""
+			unexported = !f.Exported()
+		)
+
+		if unexported && !anonymous { // unexported
+			continue
+		}
+
+		if parts := strings.Split(reflect.StructTag(st.Tag(i)).Get("codec"), ","); len(parts) != 0 {
+			if len(parts[0]) != 0 {
+				name, tag = parts[0], true
+			}
+
+			if name == "-" && len(parts) == 1 { // ignored
+				continue
+			}
+
+			if !isValidTag(name) {
+				name = f.Name()
+			}
+
+			for _, tag := range parts[1:] {
+				switch {
+				case tag == "omitempty":
+					omitempty = true
+				case tag == "required":
+					required = true
+				case strings.HasPrefix(tag, "default="):
+					hasDefault, default_ = true, strings.TrimPrefix(tag, "default=")
+				}
+			}
+		}
+
+		fieldPath := append(path[:len(path):len(path)], step{name: f.Name()})
+
+		if anonymous && !tag { // embedded
+			typ := f.Type()
+			p, ptr := typ.(*types.Pointer)
+			if ptr {
+				typ = p.Elem()
+			}
+
+			if sub, ok := typ.Underlying().(*types.Struct); ok {
+				if unexported && f.Pkg() != g.pkg {
+					return nil, fmt.Errorf("unsupported embedded struct %v: unexported fields of other packages cannot be accessed", g.typeString(f.Type()))
+				}
+				if ptr {
+					fieldPath[len(fieldPath)-1].ptr, fieldPath[len(fieldPath)-1].elem = true, typ
+				}
+
+				// Recursive embedding contributes no fields, as in constructStructType.
+				var embedded []field
+				if !seen[typ] {
+					seen[typ] = true
+					subfields, err := g.structFields(sub, fieldPath, seen)
+					delete(seen, typ)
+					if err != nil {
+						return nil, err
+					}
+					embedded = subfields
+				}
+				entries = append(entries, entry{embedded: embedded})
+				continue
+			}
+
+			if unexported { // ignore unexported non-struct types
+				continue
+			}
+		}
+
+		entries = append(entries, entry{field: &field{
+			name:       name,
+			tag:        tag,
+			omitempty:  omitempty,
+			required:   required,
+			hasDefault: hasDefault,
+			default_:   default_,
+			path:       fieldPath,
+			typ:        f.Type(),
+			structTag:  st.Tag(i),
+		}})
+		names[name] = struct{}{}
+	}
+
+	// Only unambiguous embedded fields must be serialized.
+	ambiguousNames := map[string]int{}
+	ambiguousTags := map[string]int{}
+
+	// Embedded types can never override a field that was already present at the top-level.
+	for name := range names {
+		ambiguousNames[name]++
+		ambiguousTags[name]++
+	}
+
+	for _, e := range entries {
+		for _, f := range e.embedded {
+			ambiguousNames[f.name]++
+			if f.tag {
+				ambiguousTags[f.name]++
+			}
+		}
+	}
+
+	var fields []field
+	for _, e := range entries {
+		if e.field != nil {
+			fields = append(fields, *e.field)
+			continue
+		}
+		for _, f := range e.embedded {
+			if ambiguousNames[f.name] > 1 && !(f.tag && ambiguousTags[f.name] == 1) {
+				continue // ambiguous embedded field
+			}
+
+			// To prevent dominant flags more than one level below the embedded one.
+			f.tag = false
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
+}
+
+func isValidTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+			// Backslash and quote chars are reserved, but
+			// otherwise any punctuation chars are allowed
+			// in a tag name.
+		default:
+			if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (f *field) expr() string {
+	var b strings.Builder
+	b.WriteString("c.value")
+	for _, s := range f.path {
+		b.WriteByte('.')
+		b.WriteString(s.name)
+	}
+	return b.String()
+}
+
+// pointers returns the expressions for the embedded struct pointers that must be followed in order to reach f.
+func (f *field) pointers() []string {
+	var ptrs []string
+	expr := "c.value"
+	for _, s := range f.path {
+		expr += "." + s.name
+		if s.ptr {
+			ptrs = append(ptrs, expr)
+		}
+	}
+	return ptrs
+}
+
+// newCodec returns an expression that constructs a codec for the value at the address given by ptr.
+func (g *generator) newCodec(t types.Type, ptr string) string {
+	return fmt.Sprintf("%v(%v)", g.codecFor(t).ctor(), ptr)
+}
+
+// A codecSpec describes the codec for a Go type. The names of the codec's type and constructor are computed on demand
+// so that only the packages that are actually referenced by the generated code are imported.
+type codecSpec struct {
+	typ  func() string
+	ctor func() string
+}
+
+// codecFor returns the codec for values of type t.
+func (g *generator) codecFor(t types.Type) codecSpec {
+	c := g.codec
+	ts := func() string { return g.typeString(t) }
+
+	if named, ok := t.(*types.Named); ok && g.structs[named.Obj()] {
+		codecName, ctorName := codecNames(named.Obj())
+		return codecSpec{
+			typ:  func() string { return codecName },
+			ctor: func() string { return ctorName },
+		}
+	}
+
+	reflectCodec := codecSpec{
+		typ:  func() string { return fmt.Sprintf("%v.ReflectCodec[%v]", c, ts()) },
+		ctor: func() string { return c + ".NewReflect" },
+	}
+
+	// Types with Serialize or Deserialize methods of their own are handled by the reflection-based codecs.
+	if hasMethod(t, "Serialize") || hasMethod(types.NewPointer(t), "Deserialize") {
+		return reflectCodec
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		var kind string
+		switch u.Kind() {
+		case types.Bool:
+			kind = "Bool"
+		case types.Int:
+			kind = "Int"
+		case types.Int8:
+			kind = "Int8"
+		case types.Int16:
+			kind = "Int16"
+		case types.Int32:
+			kind = "Int32"
+		case types.Int64:
+			kind = "Int64"
+		case types.Uint:
+			kind = "Uint"
+		case types.Uint8:
+			kind = "Uint8"
+		case types.Uint16:
+			kind = "Uint16"
+		case types.Uint32:
+			kind = "Uint32"
+		case types.Uint64:
+			kind = "Uint64"
+		case types.Uintptr:
+			kind = "Uintptr"
+		case types.Float32:
+			kind = "Float32"
+		case types.Float64:
+			kind = "Float64"
+		case types.Complex64:
+			kind = "Complex64"
+		case types.Complex128:
+			kind = "Complex128"
+		case types.String:
+			kind = "String"
+		default:
+			return reflectCodec
+		}
+		return codecSpec{
+			typ:  func() string { return fmt.Sprintf("%v.%vCodec[%v]", c, kind, ts()) },
+			ctor: func() string { return fmt.Sprintf("%v.New%v", c, kind) },
+		}
+
+	case *types.Slice:
+		if types.Identical(u.Elem(), types.Typ[types.Byte]) {
+			return codecSpec{
+				typ:  func() string { return fmt.Sprintf("%v.BytesCodec[%v]", c, ts()) },
+				ctor: func() string { return c + ".NewBytes" },
+			}
+		}
+		elem := g.codecFor(u.Elem())
+		return codecSpec{
+			typ: func() string {
+				return fmt.Sprintf("%v.SeqCodec[%v, %v, %v]", c, ts(), g.typeString(u.Elem()), elem.typ())
+			},
+			ctor: func() string { return fmt.Sprintf("%v.NewSeq[%v]", c, elem.typ()) },
+		}
+
+	case *types.Map:
+		if b, ok := u.Key().Underlying().(*types.Basic); !ok || b.Info()&types.IsOrdered == 0 {
+			return reflectCodec
+		}
+		key, value := g.codecFor(u.Key()), g.codecFor(u.Elem())
+		return codecSpec{
+			typ: func() string {
+				return fmt.Sprintf("%v.MapCodec[%v, %v, %v, %v, %v]", c, ts(), g.typeString(u.Key()), g.typeString(u.Elem()), key.typ(), value.typ())
+			},
+			ctor: func() string { return fmt.Sprintf("%v.NewMap[%v, %v]", c, key.typ(), value.typ()) },
+		}
+
+	case *types.Pointer:
+		elem := g.codecFor(u.Elem())
+		return codecSpec{
+			typ: func() string {
+				return fmt.Sprintf("%v.PtrCodec[%v, %v, %v]", c, ts(), g.typeString(u.Elem()), elem.typ())
+			},
+			ctor: func() string { return fmt.Sprintf("%v.NewPtr[%v]", c, elem.typ()) },
+		}
+
+	case *types.Interface:
+		if types.Identical(t, types.Universe.Lookup("any").Type()) {
+			return codecSpec{
+				typ:  func() string { return c + ".AnyCodec" },
+				ctor: func() string { return c + ".NewAny" },
+			}
+		}
+		if u.NumMethods() != 0 {
+			// Non-empty interfaces may be registered unions. Their variants are decoded by the reflection-based codecs.
+			return codecSpec{
+				typ:  func() string { return fmt.Sprintf("%v.UnionCodec[%v]", c, ts()) },
+				ctor: func() string { return c + ".NewUnion" },
+			}
+		}
+		return reflectCodec
+
+	default:
+		return reflectCodec
+	}
+}
+
+func hasMethod(t types.Type, name string) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, false, nil, name)
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+// emptyCheck returns an expression that is true if the value of f is non-empty. The rules for emptiness are the same
+// as those used by emptyFuncOf. If the value is never empty, emptyCheck returns the empty string. If the value is
+// always empty, emptyCheck returns "false".
+func (f *field) emptyCheck() string {
+	x := f.expr()
+	switch u := f.typ.Underlying().(type) {
+	case *types.Array:
+		if u.Len() == 0 {
+			return "false"
+		}
+	case *types.Map, *types.Slice:
+		return fmt.Sprintf("len(%v) != 0", x)
+	case *types.Pointer, *types.Interface:
+		return fmt.Sprintf("%v != nil", x)
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return fmt.Sprintf("len(%v) != 0", x)
+		case u.Info()&types.IsBoolean != 0:
+			return x
+		case u.Info()&(types.IsInteger|types.IsFloat|types.IsComplex) != 0:
+			return fmt.Sprintf("%v != 0", x)
+		}
+	}
+	return ""
+}
+
+func (g *generator) generateVisitMap(obj *types.TypeName, codecName string, fields []field) error {
+	c, name := g.codec, obj.Name()
+
+	// As in constructStructType, the first field with a given name wins, and case-insensitive matches are only
+	// considered if there is no exact match.
+	var exact, folded []*field
+	seenExact, seenFolded := map[string]bool{}, map[string]bool{}
+	for i := range fields {
+		f := &fields[i]
+		if !seenExact[f.name] {
+			seenExact[f.name] = true
+			exact = append(exact, f)
+		}
+		if lower := codec.FoldKey(f.name); !seenFolded[lower] {
+			seenFolded[lower] = true
+			folded = append(folded, f)
+		}
+	}
+
+	// Required fields and fields with defaults are tracked so that they can be checked and defaulted once all of
+	// the keys have been decoded.
+	var tracked []*field
+	found := map[*field]int{}
+	for i := range fields {
+		if f := &fields[i]; f.required || f.hasDefault {
+			found[f] = len(tracked)
+			tracked = append(tracked, f)
+		}
+	}
+	defaulter := hasSetDefaults(obj.Type())
+
+	g.printf("func (c %v) VisitMap(m %v.MapDecoder) error {\n", codecName, c)
+	g.printf("options := %v.GetDecodeOptions(m)\n", c)
+	if len(tracked) != 0 {
+		g.printf("var found [%v]bool\n", len(tracked))
+	}
+	g.printf("for {\nvar k string\nok, err := m.NextKey(&k, %v.NewString(&k))\n", c)
+	if len(tracked) != 0 || defaulter {
+		g.printf("if err != nil {\nreturn err\n}\nif !ok {\nbreak\n}\n\n")
+	} else {
+		g.printf("if err != nil {\nreturn err\n}\nif !ok {\nreturn nil\n}\n\n")
+	}
+
+	if len(fields) == 0 {
+		g.printf("if err := %v.SkipUnknownField(m, options, %q, k); err != nil {\nreturn err\n}\n}\n", c, name)
+	} else {
+		g.printf("switch k {\n")
+		for _, f := range exact {
+			g.printf("case %q:\n", f.name)
+			g.generateNextValue(name, f, found)
+		}
+		g.printf("default:\nif options.CaseSensitive {\nerr = %v.SkipUnknownField(m, options, %q, k)\nbreak\n}\n\n", c, name)
+		g.printf("switch %v.FoldKey(k) {\n", c)
+		for _, f := range folded {
+			g.printf("case %q:\n", codec.FoldKey(f.name))
+			g.generateNextValue(name, f, found)
+		}
+		g.printf("default:\nerr = %v.SkipUnknownField(m, options, %q, k)\n}\n}\n", c, name)
+		g.printf("if err != nil {\nreturn err\n}\n}\n")
+	}
+
+	if len(tracked) == 0 && !defaulter {
+		g.printf("}\n\n")
+		return nil
+	}
+
+	// As in structCodec.finish, check required fields and apply defaults in field order, then call SetDefaults.
+	for i, f := range tracked {
+		g.printf("\nif !found[%v] {\n", i)
+		if f.required {
+			g.printf("return &%v.MissingFieldError{Struct: %q, Field: %q}\n", c, name, f.name)
+		} else {
+			if err := g.generateDefault(f); err != nil {
+				return fmt.Errorf("field %v: %w", f.name, err)
+			}
+		}
+		g.printf("}\n")
+	}
+	if defaulter {
+		g.printf("\nc.value.SetDefaults()\n")
+	}
+	g.printf("return nil\n}\n\n")
+	return nil
+}
+
+// hasSetDefaults returns true if *t implements codec.Defaulter.
+func hasSetDefaults(t types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), false, nil, "SetDefaults")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	return sig.Params().Len() == 0 && sig.Results().Len() == 0
+}
+
+// allocPath emits code that allocates the embedded structs on the path to f.
+func (g *generator) allocPath(f *field) {
+	expr := "c.value"
+	for _, s := range f.path {
+		expr += "." + s.name
+		if s.ptr {
+			g.printf("if %v == nil {\n%v = new(%v)\n}\n", expr, expr, g.typeString(s.elem))
+		}
+	}
+}
+
+// generateNextValue emits code that decodes the value for f. As in structCodec.VisitMap, errors are annotated with the
+// field's path by codec.DecodeField.
+func (g *generator) generateNextValue(structName string, f *field, found map[*field]int) {
+	if i, ok := found[f]; ok {
+		g.printf("found[%v] = true\n", i)
+	}
+	g.allocPath(f)
+
+	x := f.expr()
+	g.printf("err = %v.DecodeField(m, %q, %q, &%v, %v)\n", g.codec, structName, f.name, x, g.newCodec(f.typ, "&"+x))
+}
+
+// generateDefault emits code that sets f to its default value. The default is parsed using the same rules as
+// defaultFuncOf, but invalid defaults are reported at generation time.
+func (g *generator) generateDefault(f *field) error {
+	g.allocPath(f)
+
+	x, t := f.expr(), f.typ
+	if p, ok := t.(*types.Pointer); ok {
+		g.printf("%v = new(%v)\n", x, g.typeString(p.Elem()))
+		if !hasMethod(t, "UnmarshalText") {
+			x = "*" + x
+		}
+		t = p.Elem()
+	}
+
+	if hasMethod(types.NewPointer(t), "UnmarshalText") {
+		g.printf("if err := %v.UnmarshalText([]byte(%q)); err != nil {\n", x, f.default_)
+		g.printf("return %v.Errorf(\"codec: invalid default %%q for type %%v: %%w\", %q, %q, err)\n}\n", g.importName("fmt", "fmt"), f.default_, types.TypeString(t, (*types.Package).Name))
+		return nil
+	}
+
+	literal, err := defaultLiteral(t, f.default_)
+	if err != nil {
+		return fmt.Errorf("invalid default %q for type %v: %w", f.default_, g.typeString(t), err)
+	}
+	g.printf("%v = %v\n", x, literal)
+	return nil
+}
+
+// defaultLiteral returns a Go literal for the default s of a value of type t.
+func defaultLiteral(t types.Type, s string) (string, error) {
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return "", errors.New("defaults are not supported for this type")
+	}
+
+	size := func() int { return int(sizes.Sizeof(b)) * 8 }
+	switch info := b.Info(); {
+	case info&types.IsString != 0:
+		return strconv.Quote(s), nil
+	case info&types.IsBoolean != 0:
+		v, err := strconv.ParseBool(s)
+		return strconv.FormatBool(v), err
+	case info&types.IsUnsigned != 0:
+		v, err := strconv.ParseUint(s, 10, size())
+		return strconv.FormatUint(v, 10), err
+	case info&types.IsInteger != 0:
+		v, err := strconv.ParseInt(s, 10, size())
+		return strconv.FormatInt(v, 10), err
+	case info&types.IsFloat != 0:
+		v, err := strconv.ParseFloat(s, size())
+		return strconv.FormatFloat(v, 'g', -1, size()), err
+	default:
+		return "", errors.New("defaults are not supported for this type")
+	}
+}
+
+// sizes are the sizes of the basic types on the host, which match those used by the reflection-based codecs.
+var sizes = types.SizesFor("gc", runtime.GOARCH)
+
+// generateStructFields generates the descriptions of a struct's fields and the StructFields method that returns them.
+// The descriptions are passed to codec.EncodeStructField by the generated Serialize method.
+func (g *generator) generateStructFields(codecName, fieldsName, name string, fields []field) {
+	c, r := g.codec, g.importName("reflect", "reflect")
+
+	g.printf("// %v describes the fields of %v.\n", fieldsName, name)
+	g.printf("var %v = []%v.StructField{\n", fieldsName, c)
+	for _, f := range fields {
+		g.printf("{Name: %q, Type: %v.TypeOf((*%v)(nil)).Elem()", f.name, r, g.typeString(f.typ))
+		if f.structTag != "" {
+			g.printf(", Tag: %v", tagLiteral(f.structTag))
+		}
+		g.printf("},\n")
+	}
+	g.printf("}\n\n")
+
+	g.printf("// StructFields returns descriptions of the fields of %v.\n", name)
+	g.printf("func (%v) StructFields() []%v.StructField {\nreturn %v\n}\n\n", codecName, c, fieldsName)
+}
+
+// tagLiteral returns a Go string literal for the given struct tag. Tags are written as raw strings where possible.
+func tagLiteral(tag string) string {
+	if strconv.CanBackquote(tag) {
+		return "`" + tag + "`"
+	}
+	return strconv.Quote(tag)
+}
+
+func (g *generator) generateSerialize(codecName, fieldsName, name string, fields []field) {
+	c := g.codec
+
+	g.printf("func (c %v) Serialize(e %v.Encoder) error {\n", codecName, c)
+	g.printf("s, err := e.EncodeStruct(%q)\nif err != nil {\nreturn err\n}\n\n", name)
+	for i := range fields {
+		f := &fields[i]
+
+		conds := f.pointers()
+		for j := range conds {
+			conds[j] += " != nil"
+		}
+		if f.omitempty {
+			check := f.emptyCheck()
+			if check == "false" {
+				continue
+			}
+			if check != "" {
+				conds = append(conds, check)
+			}
+		}
+
+		x := f.expr()
+		stmt := fmt.Sprintf("if err := %v.EncodeStructField(s, &%v[%v], %v, %v); err != nil {\nreturn err\n}\n", c, fieldsName, i, x, g.newCodec(f.typ, "&"+x))
+		if len(conds) != 0 {
+			stmt = fmt.Sprintf("if %v {\n%v}\n", strings.Join(conds, " && "), stmt)
+		}
+		g.printf("%v", stmt)
+	}
+	g.printf("\nreturn s.Close()\n}\n\n")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := filepath.Join("internal", "example")
+
+	expected, err := os.ReadFile(filepath.Join(dir, "example_codec.go"))
+	require.NoError(t, err)
+
+	pkg, err := load(dir)
+	require.NoError(t, err)
+
+	actual, err := generate(pkg, nil)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(actual), "generated code is out of date; run go generate ./...")
+}
+
+func TestGenerateTypes(t *testing.T) {
+	pkg, err := load(filepath.Join("internal", "example"))
+	require.NoError(t, err)
+
+	source, err := generate(pkg, []string{"Port"})
+	require.NoError(t, err)
+	assert.Contains(t, string(source), "type PortCodec struct")
+	assert.NotContains(t, string(source), "type ResourceCodec struct")
+
+	_, err = generate(pkg, []string{"Kind"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,131 @@
+// Package example contains struct types used to test the code generated by codecgen.
+package example
+
+import (
+	"net/netip"
+	"reflect"
+	"time"
+
+	"github.com/pgavlin/codec"
+)
+
+//go:generate go run github.com/pgavlin/codec/cmd/codecgen
+
+type Kind string
+
+type Base struct {
+	ID      string `codec:"id"`
+	Version int    `codec:"version,omitempty"`
+}
+
+type Labels struct {
+	Labels map[string]string `codec:"labels,omitempty"`
+}
+
+type Port struct {
+	Name     string `codec:"name,omitempty"`
+	Port     uint16 `codec:"port"`
+	Protocol Kind   `codec:"protocol,omitempty"`
+}
+
+type Resource struct {
+	Base
+	*Labels
+
+	Kind     Kind              `codec:"kind"`
+	Enabled  bool              `codec:"enabled,omitempty"`
+	Weight   float64           `codec:"weight,omitempty"`
+	Data     []byte            `codec:"data,omitempty"`
+	Ports    []Port            `codec:"ports"`
+	Parent   *Resource         `codec:"parent,omitempty"`
+	Counts   map[string]int64  `codec:"counts,omitempty"`
+	Extra    any               `codec:"extra,omitempty"`
+	Addr     netip.Addr        `codec:"addr"`
+	Created  time.Time         `codec:"created"`
+	Bounds   [2]int            `codec:"bounds"`
+	Names    map[Kind][]string `codec:",omitempty"`
+	Ignored  string            `codec:"-"`
+	Dash     string            `codec:"-,"`
+	Untagged int
+
+	internal int
+}
+
+type Left struct {
+	Name   string `codec:"name"`
+	Shared string
+}
+
+type Right struct {
+	Name   string
+	Shared string
+}
+
+// Both has an ambiguous embedded field, Shared, which is not serialized.
+type Both struct {
+	Left
+	*Right
+}
+
+type Config struct {
+	Name    string        `codec:"name,required"`
+	Port    int           `codec:"port,default=8080"`
+	Debug   *bool         `codec:"debug,default=true"`
+	Ratio   float32       `codec:"ratio,default=0.5"`
+	Addr    netip.Addr    `codec:"addr,default=127.0.0.1"`
+	Timeout time.Duration `codec:"timeout"`
+}
+
+// SetDefaults implements codec.Defaulter.
+func (c *Config) SetDefaults() {
+	if c.Timeout == 0 {
+		c.Timeout = time.Second
+	}
+}
+
+type Signal struct {
+	Name  string     `codec:"name"`
+	Gain  complex64  `codec:"gain,omitempty"`
+	Phase complex128 `codec:"phase,omitempty"`
+}
+
+// Message has proto tags, which are read by the protowire format from the generated codec's StructFields.
+type Message struct {
+	ID     int32    `codec:"id" proto:"1"`
+	Name   string   `codec:"name" proto:"2"`
+	Counts []int64  `codec:"counts" proto:"3,zigzag,packed"`
+	Parent *Message `codec:"parent,omitempty" proto:"4"`
+}
+
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct {
+	Radius float64 `codec:"radius"`
+}
+
+func (c *Circle) Area() float64 {
+	return 3 * c.Radius * c.Radius
+}
+
+type Square struct {
+	Side float64 `codec:"side"`
+}
+
+func (s Square) Area() float64 {
+	return s.Side * s.Side
+}
+
+type Drawing struct {
+	Name    string  `codec:"name"`
+	Primary Shape   `codec:"primary,omitempty"`
+	Shapes  []Shape `codec:"shapes"`
+}
+
+func init() {
+	codec.RegisterUnion[Shape]("kind", map[string]reflect.Type{
+		"circle": reflect.TypeOf((*Circle)(nil)),
+		"square": reflect.TypeOf(Square{}),
+	})
+}
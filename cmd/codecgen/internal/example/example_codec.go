@@ -0,0 +1,1388 @@
+// Code generated by codecgen. DO NOT EDIT.
+
+package example
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"time"
+
+	"github.com/pgavlin/codec"
+)
+
+// BaseCodec is a codec.Codec for Base values.
+type BaseCodec struct {
+	codec.DefaultVisitor
+	value *Base
+}
+
+// NewBaseCodec returns a codec for the Base at v.
+func NewBaseCodec(v *Base) BaseCodec {
+	return BaseCodec{value: v}
+}
+
+func (BaseCodec) New(v *Base) codec.Codec[Base] {
+	return BaseCodec{value: v}
+}
+
+// baseCodecFields describes the fields of Base.
+var baseCodecFields = []codec.StructField{
+	{Name: "id", Type: reflect.TypeOf((*string)(nil)).Elem(), Tag: `codec:"id"`},
+	{Name: "version", Type: reflect.TypeOf((*int)(nil)).Elem(), Tag: `codec:"version,omitempty"`},
+}
+
+// StructFields returns descriptions of the fields of Base.
+func (BaseCodec) StructFields() []codec.StructField {
+	return baseCodecFields
+}
+
+func (c BaseCodec) VisitMap(m codec.MapDecoder) error {
+	options := codec.GetDecodeOptions(m)
+	for {
+		var k string
+		ok, err := m.NextKey(&k, codec.NewString(&k))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		switch k {
+		case "id":
+			err = codec.DecodeField(m, "Base", "id", &c.value.ID, codec.NewString(&c.value.ID))
+		case "version":
+			err = codec.DecodeField(m, "Base", "version", &c.value.Version, codec.NewInt(&c.value.Version))
+		default:
+			if options.CaseSensitive {
+				err = codec.SkipUnknownField(m, options, "Base", k)
+				break
+			}
+
+			switch codec.FoldKey(k) {
+			case "id":
+				err = codec.DecodeField(m, "Base", "id", &c.value.ID, codec.NewString(&c.value.ID))
+			case "version":
+				err = codec.DecodeField(m, "Base", "version", &c.value.Version, codec.NewInt(&c.value.Version))
+			default:
+				err = codec.SkipUnknownField(m, options, "Base", k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c BaseCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeStruct("Base", c)
+}
+
+func (c BaseCodec) Serialize(e codec.Encoder) error {
+	s, err := e.EncodeStruct("Base")
+	if err != nil {
+		return err
+	}
+
+	if err := codec.EncodeStructField(s, &baseCodecFields[0], c.value.ID, codec.NewString(&c.value.ID)); err != nil {
+		return err
+	}
+	if c.value.Version != 0 {
+		if err := codec.EncodeStructField(s, &baseCodecFields[1], c.value.Version, codec.NewInt(&c.value.Version)); err != nil {
+			return err
+		}
+	}
+
+	return s.Close()
+}
+
+// Serialize implements codec.Serializer.
+func (v Base) Serialize(e codec.Encoder) error {
+	return BaseCodec{value: &v}.Serialize(e)
+}
+
+// Deserialize implements codec.Deserializer.
+func (v *Base) Deserialize(d codec.Decoder) error {
+	return BaseCodec{value: v}.Deserialize(d)
+}
+
+// BothCodec is a codec.Codec for Both values.
+type BothCodec struct {
+	codec.DefaultVisitor
+	value *Both
+}
+
+// NewBothCodec returns a codec for the Both at v.
+func NewBothCodec(v *Both) BothCodec {
+	return BothCodec{value: v}
+}
+
+func (BothCodec) New(v *Both) codec.Codec[Both] {
+	return BothCodec{value: v}
+}
+
+// bothCodecFields describes the fields of Both.
+var bothCodecFields = []codec.StructField{
+	{Name: "name", Type: reflect.TypeOf((*string)(nil)).Elem(), Tag: `codec:"name"`},
+	{Name: "Name", Type: reflect.TypeOf((*string)(nil)).Elem()},
+}
+
+// StructFields returns descriptions of the fields of Both.
+func (BothCodec) StructFields() []codec.StructField {
+	return bothCodecFields
+}
+
+func (c BothCodec) VisitMap(m codec.MapDecoder) error {
+	options := codec.GetDecodeOptions(m)
+	for {
+		var k string
+		ok, err := m.NextKey(&k, codec.NewString(&k))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		switch k {
+		case "name":
+			err = codec.DecodeField(m, "Both", "name", &c.value.Left.Name, codec.NewString(&c.value.Left.Name))
+		case "Name":
+			if c.value.Right == nil {
+				c.value.Right = new(Right)
+			}
+			err = codec.DecodeField(m, "Both", "Name", &c.value.Right.Name, codec.NewString(&c.value.Right.Name))
+		default:
+			if options.CaseSensitive {
+				err = codec.SkipUnknownField(m, options, "Both", k)
+				break
+			}
+
+			switch codec.FoldKey(k) {
+			case "name":
+				err = codec.DecodeField(m, "Both", "name", &c.value.Left.Name, codec.NewString(&c.value.Left.Name))
+			default:
+				err = codec.SkipUnknownField(m, options, "Both", k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c BothCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeStruct("Both", c)
+}
+
+func (c BothCodec) Serialize(e codec.Encoder) error {
+	s, err := e.EncodeStruct("Both")
+	if err != nil {
+		return err
+	}
+
+	if err := codec.EncodeStructField(s, &bothCodecFields[0], c.value.Left.Name, codec.NewString(&c.value.Left.Name)); err != nil {
+		return err
+	}
+	if c.value.Right != nil {
+		if err := codec.EncodeStructField(s, &bothCodecFields[1], c.value.Right.Name, codec.NewString(&c.value.Right.Name)); err != nil {
+			return err
+		}
+	}
+
+	return s.Close()
+}
+
+// Serialize implements codec.Serializer.
+func (v Both) Serialize(e codec.Encoder) error {
+	return BothCodec{value: &v}.Serialize(e)
+}
+
+// Deserialize implements codec.Deserializer.
+func (v *Both) Deserialize(d codec.Decoder) error {
+	return BothCodec{value: v}.Deserialize(d)
+}
+
+// CircleCodec is a codec.Codec for Circle values.
+type CircleCodec struct {
+	codec.DefaultVisitor
+	value *Circle
+}
+
+// NewCircleCodec returns a codec for the Circle at v.
+func NewCircleCodec(v *Circle) CircleCodec {
+	return CircleCodec{value: v}
+}
+
+func (CircleCodec) New(v *Circle) codec.Codec[Circle] {
+	return CircleCodec{value: v}
+}
+
+// circleCodecFields describes the fields of Circle.
+var circleCodecFields = []codec.StructField{
+	{Name: "radius", Type: reflect.TypeOf((*float64)(nil)).Elem(), Tag: `codec:"radius"`},
+}
+
+// StructFields returns descriptions of the fields of Circle.
+func (CircleCodec) StructFields() []codec.StructField {
+	return circleCodecFields
+}
+
+func (c CircleCodec) VisitMap(m codec.MapDecoder) error {
+	options := codec.GetDecodeOptions(m)
+	for {
+		var k string
+		ok, err := m.NextKey(&k, codec.NewString(&k))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		switch k {
+		case "radius":
+			err = codec.DecodeField(m, "Circle", "radius", &c.value.Radius, codec.NewFloat64(&c.value.Radius))
+		default:
+			if options.CaseSensitive {
+				err = codec.SkipUnknownField(m, options, "Circle", k)
+				break
+			}
+
+			switch codec.FoldKey(k) {
+			case "radius":
+				err = codec.DecodeField(m, "Circle", "radius", &c.value.Radius, codec.NewFloat64(&c.value.Radius))
+			default:
+				err = codec.SkipUnknownField(m, options, "Circle", k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c CircleCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeStruct("Circle", c)
+}
+
+func (c CircleCodec) Serialize(e codec.Encoder) error {
+	s, err := e.EncodeStruct("Circle")
+	if err != nil {
+		return err
+	}
+
+	if err := codec.EncodeStructField(s, &circleCodecFields[0], c.value.Radius, codec.NewFloat64(&c.value.Radius)); err != nil {
+		return err
+	}
+
+	return s.Close()
+}
+
+// Serialize implements codec.Serializer.
+func (v Circle) Serialize(e codec.Encoder) error {
+	return CircleCodec{value: &v}.Serialize(e)
+}
+
+// Deserialize implements codec.Deserializer.
+func (v *Circle) Deserialize(d codec.Decoder) error {
+	return CircleCodec{value: v}.Deserialize(d)
+}
+
+// ConfigCodec is a codec.Codec for Config values.
+type ConfigCodec struct {
+	codec.DefaultVisitor
+	value *Config
+}
+
+// NewConfigCodec returns a codec for the Config at v.
+func NewConfigCodec(v *Config) ConfigCodec {
+	return ConfigCodec{value: v}
+}
+
+func (ConfigCodec) New(v *Config) codec.Codec[Config] {
+	return ConfigCodec{value: v}
+}
+
+// configCodecFields describes the fields of Config.
+var configCodecFields = []codec.StructField{
+	{Name: "name", Type: reflect.TypeOf((*string)(nil)).Elem(), Tag: `codec:"name,required"`},
+	{Name: "port", Type: reflect.TypeOf((*int)(nil)).Elem(), Tag: `codec:"port,default=8080"`},
+	{Name: "debug", Type: reflect.TypeOf((**bool)(nil)).Elem(), Tag: `codec:"debug,default=true"`},
+	{Name: "ratio", Type: reflect.TypeOf((*float32)(nil)).Elem(), Tag: `codec:"ratio,default=0.5"`},
+	{Name: "addr", Type: reflect.TypeOf((*netip.Addr)(nil)).Elem(), Tag: `codec:"addr,default=127.0.0.1"`},
+	{Name: "timeout", Type: reflect.TypeOf((*time.Duration)(nil)).Elem(), Tag: `codec:"timeout"`},
+}
+
+// StructFields returns descriptions of the fields of Config.
+func (ConfigCodec) StructFields() []codec.StructField {
+	return configCodecFields
+}
+
+func (c ConfigCodec) VisitMap(m codec.MapDecoder) error {
+	options := codec.GetDecodeOptions(m)
+	var found [5]bool
+	for {
+		var k string
+		ok, err := m.NextKey(&k, codec.NewString(&k))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		switch k {
+		case "name":
+			found[0] = true
+			err = codec.DecodeField(m, "Config", "name", &c.value.Name, codec.NewString(&c.value.Name))
+		case "port":
+			found[1] = true
+			err = codec.DecodeField(m, "Config", "port", &c.value.Port, codec.NewInt(&c.value.Port))
+		case "debug":
+			found[2] = true
+			err = codec.DecodeField(m, "Config", "debug", &c.value.Debug, codec.NewPtr[codec.BoolCodec[bool]](&c.value.Debug))
+		case "ratio":
+			found[3] = true
+			err = codec.DecodeField(m, "Config", "ratio", &c.value.Ratio, codec.NewFloat32(&c.value.Ratio))
+		case "addr":
+			found[4] = true
+			err = codec.DecodeField(m, "Config", "addr", &c.value.Addr, codec.NewReflect(&c.value.Addr))
+		case "timeout":
+			err = codec.DecodeField(m, "Config", "timeout", &c.value.Timeout, codec.NewInt64(&c.value.Timeout))
+		default:
+			if options.CaseSensitive {
+				err = codec.SkipUnknownField(m, options, "Config", k)
+				break
+			}
+
+			switch codec.FoldKey(k) {
+			case "name":
+				found[0] = true
+				err = codec.DecodeField(m, "Config", "name", &c.value.Name, codec.NewString(&c.value.Name))
+			case "port":
+				found[1] = true
+				err = codec.DecodeField(m, "Config", "port", &c.value.Port, codec.NewInt(&c.value.Port))
+			case "debug":
+				found[2] = true
+				err = codec.DecodeField(m, "Config", "debug", &c.value.Debug, codec.NewPtr[codec.BoolCodec[bool]](&c.value.Debug))
+			case "ratio":
+				found[3] = true
+				err = codec.DecodeField(m, "Config", "ratio", &c.value.Ratio, codec.NewFloat32(&c.value.Ratio))
+			case "addr":
+				found[4] = true
+				err = codec.DecodeField(m, "Config", "addr", &c.value.Addr, codec.NewReflect(&c.value.Addr))
+			case "timeout":
+				err = codec.DecodeField(m, "Config", "timeout", &c.value.Timeout, codec.NewInt64(&c.value.Timeout))
+			default:
+				err = codec.SkipUnknownField(m, options, "Config", k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if !found[0] {
+		return &codec.MissingFieldError{Struct: "Config", Field: "name"}
+	}
+
+	if !found[1] {
+		c.value.Port = 8080
+	}
+
+	if !found[2] {
+		c.value.Debug = new(bool)
+		*c.value.Debug = true
+	}
+
+	if !found[3] {
+		c.value.Ratio = 0.5
+	}
+
+	if !found[4] {
+		if err := c.value.Addr.UnmarshalText([]byte("127.0.0.1")); err != nil {
+			return fmt.Errorf("codec: invalid default %q for type %v: %w", "127.0.0.1", "netip.Addr", err)
+		}
+	}
+
+	c.value.SetDefaults()
+	return nil
+}
+
+func (c ConfigCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeStruct("Config", c)
+}
+
+func (c ConfigCodec) Serialize(e codec.Encoder) error {
+	s, err := e.EncodeStruct("Config")
+	if err != nil {
+		return err
+	}
+
+	if err := codec.EncodeStructField(s, &configCodecFields[0], c.value.Name, codec.NewString(&c.value.Name)); err != nil {
+		return err
+	}
+	if err := codec.EncodeStructField(s, &configCodecFields[1], c.value.Port, codec.NewInt(&c.value.Port)); err != nil {
+		return err
+	}
+	if err := codec.EncodeStructField(s, &configCodecFields[2], c.value.Debug, codec.NewPtr[codec.BoolCodec[bool]](&c.value.Debug)); err != nil {
+		return err
+	}
+	if err := codec.EncodeStructField(s, &configCodecFields[3], c.value.Ratio, codec.NewFloat32(&c.value.Ratio)); err != nil {
+		return err
+	}
+	if err := codec.EncodeStructField(s, &configCodecFields[4], c.value.Addr, codec.NewReflect(&c.value.Addr)); err != nil {
+		return err
+	}
+	if err := codec.EncodeStructField(s, &configCodecFields[5], c.value.Timeout, codec.NewInt64(&c.value.Timeout)); err != nil {
+		return err
+	}
+
+	return s.Close()
+}
+
+// Serialize implements codec.Serializer.
+func (v Config) Serialize(e codec.Encoder) error {
+	return ConfigCodec{value: &v}.Serialize(e)
+}
+
+// Deserialize implements codec.Deserializer.
+func (v *Config) Deserialize(d codec.Decoder) error {
+	return ConfigCodec{value: v}.Deserialize(d)
+}
+
+// DrawingCodec is a codec.Codec for Drawing values.
+type DrawingCodec struct {
+	codec.DefaultVisitor
+	value *Drawing
+}
+
+// NewDrawingCodec returns a codec for the Drawing at v.
+func NewDrawingCodec(v *Drawing) DrawingCodec {
+	return DrawingCodec{value: v}
+}
+
+func (DrawingCodec) New(v *Drawing) codec.Codec[Drawing] {
+	return DrawingCodec{value: v}
+}
+
+// drawingCodecFields describes the fields of Drawing.
+var drawingCodecFields = []codec.StructField{
+	{Name: "name", Type: reflect.TypeOf((*string)(nil)).Elem(), Tag: `codec:"name"`},
+	{Name: "primary", Type: reflect.TypeOf((*Shape)(nil)).Elem(), Tag: `codec:"primary,omitempty"`},
+	{Name: "shapes", Type: reflect.TypeOf((*[]Shape)(nil)).Elem(), Tag: `codec:"shapes"`},
+}
+
+// StructFields returns descriptions of the fields of Drawing.
+func (DrawingCodec) StructFields() []codec.StructField {
+	return drawingCodecFields
+}
+
+func (c DrawingCodec) VisitMap(m codec.MapDecoder) error {
+	options := codec.GetDecodeOptions(m)
+	for {
+		var k string
+		ok, err := m.NextKey(&k, codec.NewString(&k))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		switch k {
+		case "name":
+			err = codec.DecodeField(m, "Drawing", "name", &c.value.Name, codec.NewString(&c.value.Name))
+		case "primary":
+			err = codec.DecodeField(m, "Drawing", "primary", &c.value.Primary, codec.NewUnion(&c.value.Primary))
+		case "shapes":
+			err = codec.DecodeField(m, "Drawing", "shapes", &c.value.Shapes, codec.NewSeq[codec.UnionCodec[Shape]](&c.value.Shapes))
+		default:
+			if options.CaseSensitive {
+				err = codec.SkipUnknownField(m, options, "Drawing", k)
+				break
+			}
+
+			switch codec.FoldKey(k) {
+			case "name":
+				err = codec.DecodeField(m, "Drawing", "name", &c.value.Name, codec.NewString(&c.value.Name))
+			case "primary":
+				err = codec.DecodeField(m, "Drawing", "primary", &c.value.Primary, codec.NewUnion(&c.value.Primary))
+			case "shapes":
+				err = codec.DecodeField(m, "Drawing", "shapes", &c.value.Shapes, codec.NewSeq[codec.UnionCodec[Shape]](&c.value.Shapes))
+			default:
+				err = codec.SkipUnknownField(m, options, "Drawing", k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c DrawingCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeStruct("Drawing", c)
+}
+
+func (c DrawingCodec) Serialize(e codec.Encoder) error {
+	s, err := e.EncodeStruct("Drawing")
+	if err != nil {
+		return err
+	}
+
+	if err := codec.EncodeStructField(s, &drawingCodecFields[0], c.value.Name, codec.NewString(&c.value.Name)); err != nil {
+		return err
+	}
+	if c.value.Primary != nil {
+		if err := codec.EncodeStructField(s, &drawingCodecFields[1], c.value.Primary, codec.NewUnion(&c.value.Primary)); err != nil {
+			return err
+		}
+	}
+	if err := codec.EncodeStructField(s, &drawingCodecFields[2], c.value.Shapes, codec.NewSeq[codec.UnionCodec[Shape]](&c.value.Shapes)); err != nil {
+		return err
+	}
+
+	return s.Close()
+}
+
+// Serialize implements codec.Serializer.
+func (v Drawing) Serialize(e codec.Encoder) error {
+	return DrawingCodec{value: &v}.Serialize(e)
+}
+
+// Deserialize implements codec.Deserializer.
+func (v *Drawing) Deserialize(d codec.Decoder) error {
+	return DrawingCodec{value: v}.Deserialize(d)
+}
+
+// LabelsCodec is a codec.Codec for Labels values.
+type LabelsCodec struct {
+	codec.DefaultVisitor
+	value *Labels
+}
+
+// NewLabelsCodec returns a codec for the Labels at v.
+func NewLabelsCodec(v *Labels) LabelsCodec {
+	return LabelsCodec{value: v}
+}
+
+func (LabelsCodec) New(v *Labels) codec.Codec[Labels] {
+	return LabelsCodec{value: v}
+}
+
+// labelsCodecFields describes the fields of Labels.
+var labelsCodecFields = []codec.StructField{
+	{Name: "labels", Type: reflect.TypeOf((*map[string]string)(nil)).Elem(), Tag: `codec:"labels,omitempty"`},
+}
+
+// StructFields returns descriptions of the fields of Labels.
+func (LabelsCodec) StructFields() []codec.StructField {
+	return labelsCodecFields
+}
+
+func (c LabelsCodec) VisitMap(m codec.MapDecoder) error {
+	options := codec.GetDecodeOptions(m)
+	for {
+		var k string
+		ok, err := m.NextKey(&k, codec.NewString(&k))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		switch k {
+		case "labels":
+			err = codec.DecodeField(m, "Labels", "labels", &c.value.Labels, codec.NewMap[codec.StringCodec[string], codec.StringCodec[string]](&c.value.Labels))
+		default:
+			if options.CaseSensitive {
+				err = codec.SkipUnknownField(m, options, "Labels", k)
+				break
+			}
+
+			switch codec.FoldKey(k) {
+			case "labels":
+				err = codec.DecodeField(m, "Labels", "labels", &c.value.Labels, codec.NewMap[codec.StringCodec[string], codec.StringCodec[string]](&c.value.Labels))
+			default:
+				err = codec.SkipUnknownField(m, options, "Labels", k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c LabelsCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeStruct("Labels", c)
+}
+
+func (c LabelsCodec) Serialize(e codec.Encoder) error {
+	s, err := e.EncodeStruct("Labels")
+	if err != nil {
+		return err
+	}
+
+	if len(c.value.Labels) != 0 {
+		if err := codec.EncodeStructField(s, &labelsCodecFields[0], c.value.Labels, codec.NewMap[codec.StringCodec[string], codec.StringCodec[string]](&c.value.Labels)); err != nil {
+			return err
+		}
+	}
+
+	return s.Close()
+}
+
+// Serialize implements codec.Serializer.
+func (v Labels) Serialize(e codec.Encoder) error {
+	return LabelsCodec{value: &v}.Serialize(e)
+}
+
+// Deserialize implements codec.Deserializer.
+func (v *Labels) Deserialize(d codec.Decoder) error {
+	return LabelsCodec{value: v}.Deserialize(d)
+}
+
+// LeftCodec is a codec.Codec for Left values.
+type LeftCodec struct {
+	codec.DefaultVisitor
+	value *Left
+}
+
+// NewLeftCodec returns a codec for the Left at v.
+func NewLeftCodec(v *Left) LeftCodec {
+	return LeftCodec{value: v}
+}
+
+func (LeftCodec) New(v *Left) codec.Codec[Left] {
+	return LeftCodec{value: v}
+}
+
+// leftCodecFields describes the fields of Left.
+var leftCodecFields = []codec.StructField{
+	{Name: "name", Type: reflect.TypeOf((*string)(nil)).Elem(), Tag: `codec:"name"`},
+	{Name: "Shared", Type: reflect.TypeOf((*string)(nil)).Elem()},
+}
+
+// StructFields returns descriptions of the fields of Left.
+func (LeftCodec) StructFields() []codec.StructField {
+	return leftCodecFields
+}
+
+func (c LeftCodec) VisitMap(m codec.MapDecoder) error {
+	options := codec.GetDecodeOptions(m)
+	for {
+		var k string
+		ok, err := m.NextKey(&k, codec.NewString(&k))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		switch k {
+		case "name":
+			err = codec.DecodeField(m, "Left", "name", &c.value.Name, codec.NewString(&c.value.Name))
+		case "Shared":
+			err = codec.DecodeField(m, "Left", "Shared", &c.value.Shared, codec.NewString(&c.value.Shared))
+		default:
+			if options.CaseSensitive {
+				err = codec.SkipUnknownField(m, options, "Left", k)
+				break
+			}
+
+			switch codec.FoldKey(k) {
+			case "name":
+				err = codec.DecodeField(m, "Left", "name", &c.value.Name, codec.NewString(&c.value.Name))
+			case "shared":
+				err = codec.DecodeField(m, "Left", "Shared", &c.value.Shared, codec.NewString(&c.value.Shared))
+			default:
+				err = codec.SkipUnknownField(m, options, "Left", k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c LeftCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeStruct("Left", c)
+}
+
+func (c LeftCodec) Serialize(e codec.Encoder) error {
+	s, err := e.EncodeStruct("Left")
+	if err != nil {
+		return err
+	}
+
+	if err := codec.EncodeStructField(s, &leftCodecFields[0], c.value.Name, codec.NewString(&c.value.Name)); err != nil {
+		return err
+	}
+	if err := codec.EncodeStructField(s, &leftCodecFields[1], c.value.Shared, codec.NewString(&c.value.Shared)); err != nil {
+		return err
+	}
+
+	return s.Close()
+}
+
+// Serialize implements codec.Serializer.
+func (v Left) Serialize(e codec.Encoder) error {
+	return LeftCodec{value: &v}.Serialize(e)
+}
+
+// Deserialize implements codec.Deserializer.
+func (v *Left) Deserialize(d codec.Decoder) error {
+	return LeftCodec{value: v}.Deserialize(d)
+}
+
+// MessageCodec is a codec.Codec for Message values.
+type MessageCodec struct {
+	codec.DefaultVisitor
+	value *Message
+}
+
+// NewMessageCodec returns a codec for the Message at v.
+func NewMessageCodec(v *Message) MessageCodec {
+	return MessageCodec{value: v}
+}
+
+func (MessageCodec) New(v *Message) codec.Codec[Message] {
+	return MessageCodec{value: v}
+}
+
+// messageCodecFields describes the fields of Message.
+var messageCodecFields = []codec.StructField{
+	{Name: "id", Type: reflect.TypeOf((*int32)(nil)).Elem(), Tag: `codec:"id" proto:"1"`},
+	{Name: "name", Type: reflect.TypeOf((*string)(nil)).Elem(), Tag: `codec:"name" proto:"2"`},
+	{Name: "counts", Type: reflect.TypeOf((*[]int64)(nil)).Elem(), Tag: `codec:"counts" proto:"3,zigzag,packed"`},
+	{Name: "parent", Type: reflect.TypeOf((**Message)(nil)).Elem(), Tag: `codec:"parent,omitempty" proto:"4"`},
+}
+
+// StructFields returns descriptions of the fields of Message.
+func (MessageCodec) StructFields() []codec.StructField {
+	return messageCodecFields
+}
+
+func (c MessageCodec) VisitMap(m codec.MapDecoder) error {
+	options := codec.GetDecodeOptions(m)
+	for {
+		var k string
+		ok, err := m.NextKey(&k, codec.NewString(&k))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		switch k {
+		case "id":
+			err = codec.DecodeField(m, "Message", "id", &c.value.ID, codec.NewInt32(&c.value.ID))
+		case "name":
+			err = codec.DecodeField(m, "Message", "name", &c.value.Name, codec.NewString(&c.value.Name))
+		case "counts":
+			err = codec.DecodeField(m, "Message", "counts", &c.value.Counts, codec.NewSeq[codec.Int64Codec[int64]](&c.value.Counts))
+		case "parent":
+			err = codec.DecodeField(m, "Message", "parent", &c.value.Parent, codec.NewPtr[MessageCodec](&c.value.Parent))
+		default:
+			if options.CaseSensitive {
+				err = codec.SkipUnknownField(m, options, "Message", k)
+				break
+			}
+
+			switch codec.FoldKey(k) {
+			case "id":
+				err = codec.DecodeField(m, "Message", "id", &c.value.ID, codec.NewInt32(&c.value.ID))
+			case "name":
+				err = codec.DecodeField(m, "Message", "name", &c.value.Name, codec.NewString(&c.value.Name))
+			case "counts":
+				err = codec.DecodeField(m, "Message", "counts", &c.value.Counts, codec.NewSeq[codec.Int64Codec[int64]](&c.value.Counts))
+			case "parent":
+				err = codec.DecodeField(m, "Message", "parent", &c.value.Parent, codec.NewPtr[MessageCodec](&c.value.Parent))
+			default:
+				err = codec.SkipUnknownField(m, options, "Message", k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c MessageCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeStruct("Message", c)
+}
+
+func (c MessageCodec) Serialize(e codec.Encoder) error {
+	s, err := e.EncodeStruct("Message")
+	if err != nil {
+		return err
+	}
+
+	if err := codec.EncodeStructField(s, &messageCodecFields[0], c.value.ID, codec.NewInt32(&c.value.ID)); err != nil {
+		return err
+	}
+	if err := codec.EncodeStructField(s, &messageCodecFields[1], c.value.Name, codec.NewString(&c.value.Name)); err != nil {
+		return err
+	}
+	if err := codec.EncodeStructField(s, &messageCodecFields[2], c.value.Counts, codec.NewSeq[codec.Int64Codec[int64]](&c.value.Counts)); err != nil {
+		return err
+	}
+	if c.value.Parent != nil {
+		if err := codec.EncodeStructField(s, &messageCodecFields[3], c.value.Parent, codec.NewPtr[MessageCodec](&c.value.Parent)); err != nil {
+			return err
+		}
+	}
+
+	return s.Close()
+}
+
+// Serialize implements codec.Serializer.
+func (v Message) Serialize(e codec.Encoder) error {
+	return MessageCodec{value: &v}.Serialize(e)
+}
+
+// Deserialize implements codec.Deserializer.
+func (v *Message) Deserialize(d codec.Decoder) error {
+	return MessageCodec{value: v}.Deserialize(d)
+}
+
+// PortCodec is a codec.Codec for Port values.
+type PortCodec struct {
+	codec.DefaultVisitor
+	value *Port
+}
+
+// NewPortCodec returns a codec for the Port at v.
+func NewPortCodec(v *Port) PortCodec {
+	return PortCodec{value: v}
+}
+
+func (PortCodec) New(v *Port) codec.Codec[Port] {
+	return PortCodec{value: v}
+}
+
+// portCodecFields describes the fields of Port.
+var portCodecFields = []codec.StructField{
+	{Name: "name", Type: reflect.TypeOf((*string)(nil)).Elem(), Tag: `codec:"name,omitempty"`},
+	{Name: "port", Type: reflect.TypeOf((*uint16)(nil)).Elem(), Tag: `codec:"port"`},
+	{Name: "protocol", Type: reflect.TypeOf((*Kind)(nil)).Elem(), Tag: `codec:"protocol,omitempty"`},
+}
+
+// StructFields returns descriptions of the fields of Port.
+func (PortCodec) StructFields() []codec.StructField {
+	return portCodecFields
+}
+
+func (c PortCodec) VisitMap(m codec.MapDecoder) error {
+	options := codec.GetDecodeOptions(m)
+	for {
+		var k string
+		ok, err := m.NextKey(&k, codec.NewString(&k))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		switch k {
+		case "name":
+			err = codec.DecodeField(m, "Port", "name", &c.value.Name, codec.NewString(&c.value.Name))
+		case "port":
+			err = codec.DecodeField(m, "Port", "port", &c.value.Port, codec.NewUint16(&c.value.Port))
+		case "protocol":
+			err = codec.DecodeField(m, "Port", "protocol", &c.value.Protocol, codec.NewString(&c.value.Protocol))
+		default:
+			if options.CaseSensitive {
+				err = codec.SkipUnknownField(m, options, "Port", k)
+				break
+			}
+
+			switch codec.FoldKey(k) {
+			case "name":
+				err = codec.DecodeField(m, "Port", "name", &c.value.Name, codec.NewString(&c.value.Name))
+			case "port":
+				err = codec.DecodeField(m, "Port", "port", &c.value.Port, codec.NewUint16(&c.value.Port))
+			case "protocol":
+				err = codec.DecodeField(m, "Port", "protocol", &c.value.Protocol, codec.NewString(&c.value.Protocol))
+			default:
+				err = codec.SkipUnknownField(m, options, "Port", k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c PortCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeStruct("Port", c)
+}
+
+func (c PortCodec) Serialize(e codec.Encoder) error {
+	s, err := e.EncodeStruct("Port")
+	if err != nil {
+		return err
+	}
+
+	if len(c.value.Name) != 0 {
+		if err := codec.EncodeStructField(s, &portCodecFields[0], c.value.Name, codec.NewString(&c.value.Name)); err != nil {
+			return err
+		}
+	}
+	if err := codec.EncodeStructField(s, &portCodecFields[1], c.value.Port, codec.NewUint16(&c.value.Port)); err != nil {
+		return err
+	}
+	if len(c.value.Protocol) != 0 {
+		if err := codec.EncodeStructField(s, &portCodecFields[2], c.value.Protocol, codec.NewString(&c.value.Protocol)); err != nil {
+			return err
+		}
+	}
+
+	return s.Close()
+}
+
+// Serialize implements codec.Serializer.
+func (v Port) Serialize(e codec.Encoder) error {
+	return PortCodec{value: &v}.Serialize(e)
+}
+
+// Deserialize implements codec.Deserializer.
+func (v *Port) Deserialize(d codec.Decoder) error {
+	return PortCodec{value: v}.Deserialize(d)
+}
+
+// ResourceCodec is a codec.Codec for Resource values.
+type ResourceCodec struct {
+	codec.DefaultVisitor
+	value *Resource
+}
+
+// NewResourceCodec returns a codec for the Resource at v.
+func NewResourceCodec(v *Resource) ResourceCodec {
+	return ResourceCodec{value: v}
+}
+
+func (ResourceCodec) New(v *Resource) codec.Codec[Resource] {
+	return ResourceCodec{value: v}
+}
+
+// resourceCodecFields describes the fields of Resource.
+var resourceCodecFields = []codec.StructField{
+	{Name: "id", Type: reflect.TypeOf((*string)(nil)).Elem(), Tag: `codec:"id"`},
+	{Name: "version", Type: reflect.TypeOf((*int)(nil)).Elem(), Tag: `codec:"version,omitempty"`},
+	{Name: "labels", Type: reflect.TypeOf((*map[string]string)(nil)).Elem(), Tag: `codec:"labels,omitempty"`},
+	{Name: "kind", Type: reflect.TypeOf((*Kind)(nil)).Elem(), Tag: `codec:"kind"`},
+	{Name: "enabled", Type: reflect.TypeOf((*bool)(nil)).Elem(), Tag: `codec:"enabled,omitempty"`},
+	{Name: "weight", Type: reflect.TypeOf((*float64)(nil)).Elem(), Tag: `codec:"weight,omitempty"`},
+	{Name: "data", Type: reflect.TypeOf((*[]byte)(nil)).Elem(), Tag: `codec:"data,omitempty"`},
+	{Name: "ports", Type: reflect.TypeOf((*[]Port)(nil)).Elem(), Tag: `codec:"ports"`},
+	{Name: "parent", Type: reflect.TypeOf((**Resource)(nil)).Elem(), Tag: `codec:"parent,omitempty"`},
+	{Name: "counts", Type: reflect.TypeOf((*map[string]int64)(nil)).Elem(), Tag: `codec:"counts,omitempty"`},
+	{Name: "extra", Type: reflect.TypeOf((*any)(nil)).Elem(), Tag: `codec:"extra,omitempty"`},
+	{Name: "addr", Type: reflect.TypeOf((*netip.Addr)(nil)).Elem(), Tag: `codec:"addr"`},
+	{Name: "created", Type: reflect.TypeOf((*time.Time)(nil)).Elem(), Tag: `codec:"created"`},
+	{Name: "bounds", Type: reflect.TypeOf((*[2]int)(nil)).Elem(), Tag: `codec:"bounds"`},
+	{Name: "Names", Type: reflect.TypeOf((*map[Kind][]string)(nil)).Elem(), Tag: `codec:",omitempty"`},
+	{Name: "-", Type: reflect.TypeOf((*string)(nil)).Elem(), Tag: `codec:"-,"`},
+	{Name: "Untagged", Type: reflect.TypeOf((*int)(nil)).Elem()},
+}
+
+// StructFields returns descriptions of the fields of Resource.
+func (ResourceCodec) StructFields() []codec.StructField {
+	return resourceCodecFields
+}
+
+func (c ResourceCodec) VisitMap(m codec.MapDecoder) error {
+	options := codec.GetDecodeOptions(m)
+	for {
+		var k string
+		ok, err := m.NextKey(&k, codec.NewString(&k))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		switch k {
+		case "id":
+			err = codec.DecodeField(m, "Resource", "id", &c.value.Base.ID, codec.NewString(&c.value.Base.ID))
+		case "version":
+			err = codec.DecodeField(m, "Resource", "version", &c.value.Base.Version, codec.NewInt(&c.value.Base.Version))
+		case "labels":
+			if c.value.Labels == nil {
+				c.value.Labels = new(Labels)
+			}
+			err = codec.DecodeField(m, "Resource", "labels", &c.value.Labels.Labels, codec.NewMap[codec.StringCodec[string], codec.StringCodec[string]](&c.value.Labels.Labels))
+		case "kind":
+			err = codec.DecodeField(m, "Resource", "kind", &c.value.Kind, codec.NewString(&c.value.Kind))
+		case "enabled":
+			err = codec.DecodeField(m, "Resource", "enabled", &c.value.Enabled, codec.NewBool(&c.value.Enabled))
+		case "weight":
+			err = codec.DecodeField(m, "Resource", "weight", &c.value.Weight, codec.NewFloat64(&c.value.Weight))
+		case "data":
+			err = codec.DecodeField(m, "Resource", "data", &c.value.Data, codec.NewBytes(&c.value.Data))
+		case "ports":
+			err = codec.DecodeField(m, "Resource", "ports", &c.value.Ports, codec.NewSeq[PortCodec](&c.value.Ports))
+		case "parent":
+			err = codec.DecodeField(m, "Resource", "parent", &c.value.Parent, codec.NewPtr[ResourceCodec](&c.value.Parent))
+		case "counts":
+			err = codec.DecodeField(m, "Resource", "counts", &c.value.Counts, codec.NewMap[codec.StringCodec[string], codec.Int64Codec[int64]](&c.value.Counts))
+		case "extra":
+			err = codec.DecodeField(m, "Resource", "extra", &c.value.Extra, codec.NewAny(&c.value.Extra))
+		case "addr":
+			err = codec.DecodeField(m, "Resource", "addr", &c.value.Addr, codec.NewReflect(&c.value.Addr))
+		case "created":
+			err = codec.DecodeField(m, "Resource", "created", &c.value.Created, codec.NewReflect(&c.value.Created))
+		case "bounds":
+			err = codec.DecodeField(m, "Resource", "bounds", &c.value.Bounds, codec.NewReflect(&c.value.Bounds))
+		case "Names":
+			err = codec.DecodeField(m, "Resource", "Names", &c.value.Names, codec.NewMap[codec.StringCodec[Kind], codec.SeqCodec[[]string, string, codec.StringCodec[string]]](&c.value.Names))
+		case "-":
+			err = codec.DecodeField(m, "Resource", "-", &c.value.Dash, codec.NewString(&c.value.Dash))
+		case "Untagged":
+			err = codec.DecodeField(m, "Resource", "Untagged", &c.value.Untagged, codec.NewInt(&c.value.Untagged))
+		default:
+			if options.CaseSensitive {
+				err = codec.SkipUnknownField(m, options, "Resource", k)
+				break
+			}
+
+			switch codec.FoldKey(k) {
+			case "id":
+				err = codec.DecodeField(m, "Resource", "id", &c.value.Base.ID, codec.NewString(&c.value.Base.ID))
+			case "version":
+				err = codec.DecodeField(m, "Resource", "version", &c.value.Base.Version, codec.NewInt(&c.value.Base.Version))
+			case "labels":
+				if c.value.Labels == nil {
+					c.value.Labels = new(Labels)
+				}
+				err = codec.DecodeField(m, "Resource", "labels", &c.value.Labels.Labels, codec.NewMap[codec.StringCodec[string], codec.StringCodec[string]](&c.value.Labels.Labels))
+			case "kind":
+				err = codec.DecodeField(m, "Resource", "kind", &c.value.Kind, codec.NewString(&c.value.Kind))
+			case "enabled":
+				err = codec.DecodeField(m, "Resource", "enabled", &c.value.Enabled, codec.NewBool(&c.value.Enabled))
+			case "weight":
+				err = codec.DecodeField(m, "Resource", "weight", &c.value.Weight, codec.NewFloat64(&c.value.Weight))
+			case "data":
+				err = codec.DecodeField(m, "Resource", "data", &c.value.Data, codec.NewBytes(&c.value.Data))
+			case "ports":
+				err = codec.DecodeField(m, "Resource", "ports", &c.value.Ports, codec.NewSeq[PortCodec](&c.value.Ports))
+			case "parent":
+				err = codec.DecodeField(m, "Resource", "parent", &c.value.Parent, codec.NewPtr[ResourceCodec](&c.value.Parent))
+			case "counts":
+				err = codec.DecodeField(m, "Resource", "counts", &c.value.Counts, codec.NewMap[codec.StringCodec[string], codec.Int64Codec[int64]](&c.value.Counts))
+			case "extra":
+				err = codec.DecodeField(m, "Resource", "extra", &c.value.Extra, codec.NewAny(&c.value.Extra))
+			case "addr":
+				err = codec.DecodeField(m, "Resource", "addr", &c.value.Addr, codec.NewReflect(&c.value.Addr))
+			case "created":
+				err = codec.DecodeField(m, "Resource", "created", &c.value.Created, codec.NewReflect(&c.value.Created))
+			case "bounds":
+				err = codec.DecodeField(m, "Resource", "bounds", &c.value.Bounds, codec.NewReflect(&c.value.Bounds))
+			case "names":
+				err = codec.DecodeField(m, "Resource", "Names", &c.value.Names, codec.NewMap[codec.StringCodec[Kind], codec.SeqCodec[[]string, string, codec.StringCodec[string]]](&c.value.Names))
+			case "-":
+				err = codec.DecodeField(m, "Resource", "-", &c.value.Dash, codec.NewString(&c.value.Dash))
+			case "untagged":
+				err = codec.DecodeField(m, "Resource", "Untagged", &c.value.Untagged, codec.NewInt(&c.value.Untagged))
+			default:
+				err = codec.SkipUnknownField(m, options, "Resource", k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c ResourceCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeStruct("Resource", c)
+}
+
+func (c ResourceCodec) Serialize(e codec.Encoder) error {
+	s, err := e.EncodeStruct("Resource")
+	if err != nil {
+		return err
+	}
+
+	if err := codec.EncodeStructField(s, &resourceCodecFields[0], c.value.Base.ID, codec.NewString(&c.value.Base.ID)); err != nil {
+		return err
+	}
+	if c.value.Base.Version != 0 {
+		if err := codec.EncodeStructField(s, &resourceCodecFields[1], c.value.Base.Version, codec.NewInt(&c.value.Base.Version)); err != nil {
+			return err
+		}
+	}
+	if c.value.Labels != nil && len(c.value.Labels.Labels) != 0 {
+		if err := codec.EncodeStructField(s, &resourceCodecFields[2], c.value.Labels.Labels, codec.NewMap[codec.StringCodec[string], codec.StringCodec[string]](&c.value.Labels.Labels)); err != nil {
+			return err
+		}
+	}
+	if err := codec.EncodeStructField(s, &resourceCodecFields[3], c.value.Kind, codec.NewString(&c.value.Kind)); err != nil {
+		return err
+	}
+	if c.value.Enabled {
+		if err := codec.EncodeStructField(s, &resourceCodecFields[4], c.value.Enabled, codec.NewBool(&c.value.Enabled)); err != nil {
+			return err
+		}
+	}
+	if c.value.Weight != 0 {
+		if err := codec.EncodeStructField(s, &resourceCodecFields[5], c.value.Weight, codec.NewFloat64(&c.value.Weight)); err != nil {
+			return err
+		}
+	}
+	if len(c.value.Data) != 0 {
+		if err := codec.EncodeStructField(s, &resourceCodecFields[6], c.value.Data, codec.NewBytes(&c.value.Data)); err != nil {
+			return err
+		}
+	}
+	if err := codec.EncodeStructField(s, &resourceCodecFields[7], c.value.Ports, codec.NewSeq[PortCodec](&c.value.Ports)); err != nil {
+		return err
+	}
+	if c.value.Parent != nil {
+		if err := codec.EncodeStructField(s, &resourceCodecFields[8], c.value.Parent, codec.NewPtr[ResourceCodec](&c.value.Parent)); err != nil {
+			return err
+		}
+	}
+	if len(c.value.Counts) != 0 {
+		if err := codec.EncodeStructField(s, &resourceCodecFields[9], c.value.Counts, codec.NewMap[codec.StringCodec[string], codec.Int64Codec[int64]](&c.value.Counts)); err != nil {
+			return err
+		}
+	}
+	if c.value.Extra != nil {
+		if err := codec.EncodeStructField(s, &resourceCodecFields[10], c.value.Extra, codec.NewAny(&c.value.Extra)); err != nil {
+			return err
+		}
+	}
+	if err := codec.EncodeStructField(s, &resourceCodecFields[11], c.value.Addr, codec.NewReflect(&c.value.Addr)); err != nil {
+		return err
+	}
+	if err := codec.EncodeStructField(s, &resourceCodecFields[12], c.value.Created, codec.NewReflect(&c.value.Created)); err != nil {
+		return err
+	}
+	if err := codec.EncodeStructField(s, &resourceCodecFields[13], c.value.Bounds, codec.NewReflect(&c.value.Bounds)); err != nil {
+		return err
+	}
+	if len(c.value.Names) != 0 {
+		if err := codec.EncodeStructField(s, &resourceCodecFields[14], c.value.Names, codec.NewMap[codec.StringCodec[Kind], codec.SeqCodec[[]string, string, codec.StringCodec[string]]](&c.value.Names)); err != nil {
+			return err
+		}
+	}
+	if err := codec.EncodeStructField(s, &resourceCodecFields[15], c.value.Dash, codec.NewString(&c.value.Dash)); err != nil {
+		return err
+	}
+	if err := codec.EncodeStructField(s, &resourceCodecFields[16], c.value.Untagged, codec.NewInt(&c.value.Untagged)); err != nil {
+		return err
+	}
+
+	return s.Close()
+}
+
+// Serialize implements codec.Serializer.
+func (v Resource) Serialize(e codec.Encoder) error {
+	return ResourceCodec{value: &v}.Serialize(e)
+}
+
+// Deserialize implements codec.Deserializer.
+func (v *Resource) Deserialize(d codec.Decoder) error {
+	return ResourceCodec{value: v}.Deserialize(d)
+}
+
+// SignalCodec is a codec.Codec for Signal values.
+type SignalCodec struct {
+	codec.DefaultVisitor
+	value *Signal
+}
+
+// NewSignalCodec returns a codec for the Signal at v.
+func NewSignalCodec(v *Signal) SignalCodec {
+	return SignalCodec{value: v}
+}
+
+func (SignalCodec) New(v *Signal) codec.Codec[Signal] {
+	return SignalCodec{value: v}
+}
+
+// signalCodecFields describes the fields of Signal.
+var signalCodecFields = []codec.StructField{
+	{Name: "name", Type: reflect.TypeOf((*string)(nil)).Elem(), Tag: `codec:"name"`},
+	{Name: "gain", Type: reflect.TypeOf((*complex64)(nil)).Elem(), Tag: `codec:"gain,omitempty"`},
+	{Name: "phase", Type: reflect.TypeOf((*complex128)(nil)).Elem(), Tag: `codec:"phase,omitempty"`},
+}
+
+// StructFields returns descriptions of the fields of Signal.
+func (SignalCodec) StructFields() []codec.StructField {
+	return signalCodecFields
+}
+
+func (c SignalCodec) VisitMap(m codec.MapDecoder) error {
+	options := codec.GetDecodeOptions(m)
+	for {
+		var k string
+		ok, err := m.NextKey(&k, codec.NewString(&k))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		switch k {
+		case "name":
+			err = codec.DecodeField(m, "Signal", "name", &c.value.Name, codec.NewString(&c.value.Name))
+		case "gain":
+			err = codec.DecodeField(m, "Signal", "gain", &c.value.Gain, codec.NewComplex64(&c.value.Gain))
+		case "phase":
+			err = codec.DecodeField(m, "Signal", "phase", &c.value.Phase, codec.NewComplex128(&c.value.Phase))
+		default:
+			if options.CaseSensitive {
+				err = codec.SkipUnknownField(m, options, "Signal", k)
+				break
+			}
+
+			switch codec.FoldKey(k) {
+			case "name":
+				err = codec.DecodeField(m, "Signal", "name", &c.value.Name, codec.NewString(&c.value.Name))
+			case "gain":
+				err = codec.DecodeField(m, "Signal", "gain", &c.value.Gain, codec.NewComplex64(&c.value.Gain))
+			case "phase":
+				err = codec.DecodeField(m, "Signal", "phase", &c.value.Phase, codec.NewComplex128(&c.value.Phase))
+			default:
+				err = codec.SkipUnknownField(m, options, "Signal", k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c SignalCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeStruct("Signal", c)
+}
+
+func (c SignalCodec) Serialize(e codec.Encoder) error {
+	s, err := e.EncodeStruct("Signal")
+	if err != nil {
+		return err
+	}
+
+	if err := codec.EncodeStructField(s, &signalCodecFields[0], c.value.Name, codec.NewString(&c.value.Name)); err != nil {
+		return err
+	}
+	if c.value.Gain != 0 {
+		if err := codec.EncodeStructField(s, &signalCodecFields[1], c.value.Gain, codec.NewComplex64(&c.value.Gain)); err != nil {
+			return err
+		}
+	}
+	if c.value.Phase != 0 {
+		if err := codec.EncodeStructField(s, &signalCodecFields[2], c.value.Phase, codec.NewComplex128(&c.value.Phase)); err != nil {
+			return err
+		}
+	}
+
+	return s.Close()
+}
+
+// Serialize implements codec.Serializer.
+func (v Signal) Serialize(e codec.Encoder) error {
+	return SignalCodec{value: &v}.Serialize(e)
+}
+
+// Deserialize implements codec.Deserializer.
+func (v *Signal) Deserialize(d codec.Decoder) error {
+	return SignalCodec{value: v}.Deserialize(d)
+}
+
+// SquareCodec is a codec.Codec for Square values.
+type SquareCodec struct {
+	codec.DefaultVisitor
+	value *Square
+}
+
+// NewSquareCodec returns a codec for the Square at v.
+func NewSquareCodec(v *Square) SquareCodec {
+	return SquareCodec{value: v}
+}
+
+func (SquareCodec) New(v *Square) codec.Codec[Square] {
+	return SquareCodec{value: v}
+}
+
+// squareCodecFields describes the fields of Square.
+var squareCodecFields = []codec.StructField{
+	{Name: "side", Type: reflect.TypeOf((*float64)(nil)).Elem(), Tag: `codec:"side"`},
+}
+
+// StructFields returns descriptions of the fields of Square.
+func (SquareCodec) StructFields() []codec.StructField {
+	return squareCodecFields
+}
+
+func (c SquareCodec) VisitMap(m codec.MapDecoder) error {
+	options := codec.GetDecodeOptions(m)
+	for {
+		var k string
+		ok, err := m.NextKey(&k, codec.NewString(&k))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		switch k {
+		case "side":
+			err = codec.DecodeField(m, "Square", "side", &c.value.Side, codec.NewFloat64(&c.value.Side))
+		default:
+			if options.CaseSensitive {
+				err = codec.SkipUnknownField(m, options, "Square", k)
+				break
+			}
+
+			switch codec.FoldKey(k) {
+			case "side":
+				err = codec.DecodeField(m, "Square", "side", &c.value.Side, codec.NewFloat64(&c.value.Side))
+			default:
+				err = codec.SkipUnknownField(m, options, "Square", k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c SquareCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeStruct("Square", c)
+}
+
+func (c SquareCodec) Serialize(e codec.Encoder) error {
+	s, err := e.EncodeStruct("Square")
+	if err != nil {
+		return err
+	}
+
+	if err := codec.EncodeStructField(s, &squareCodecFields[0], c.value.Side, codec.NewFloat64(&c.value.Side)); err != nil {
+		return err
+	}
+
+	return s.Close()
+}
+
+// Serialize implements codec.Serializer.
+func (v Square) Serialize(e codec.Encoder) error {
+	return SquareCodec{value: &v}.Serialize(e)
+}
+
+// Deserialize implements codec.Deserializer.
+func (v *Square) Deserialize(d codec.Decoder) error {
+	return SquareCodec{value: v}.Deserialize(d)
+}
@@ -0,0 +1,223 @@
+package example
+
+import (
+	"encoding/hex"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/pgavlin/codec"
+	anycodec "github.com/pgavlin/codec/any"
+	"github.com/pgavlin/codec/json"
+	"github.com/pgavlin/codec/protowire"
+	"github.com/pgavlin/codec/pulumi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testResource() Resource {
+	return Resource{
+		Base:    Base{ID: "r1", Version: 2},
+		Labels:  &Labels{Labels: map[string]string{"app": "web"}},
+		Kind:    "service",
+		Enabled: true,
+		Data:    []byte("hi"),
+		Ports:   []Port{{Name: "http", Port: 80}, {Port: 443, Protocol: "tcp"}},
+		Parent:  &Resource{Base: Base{ID: "r0"}, Ports: []Port{}},
+		Counts:  map[string]int64{"b": 2, "a": 1},
+		Extra:   "extra",
+		Addr:    netip.MustParseAddr("10.0.0.1"),
+		Created: time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC),
+		Bounds:  [2]int{1, 2},
+		Names:   map[Kind][]string{"x": {"y"}},
+		Ignored: "ignored",
+		Dash:    "dash",
+
+		Untagged: 7,
+	}
+}
+
+func TestJSON(t *testing.T) {
+	r := testResource()
+
+	b, err := json.Marshal(r)
+	require.NoError(t, err)
+
+	expected := `{"id":"r1","version":2,"labels":{"app":"web"},"kind":"service","enabled":true,"data":"aGk=",` +
+		`"ports":[{"name":"http","port":80},{"port":443,"protocol":"tcp"}],` +
+		`"parent":{"id":"r0","kind":"","ports":[],"addr":"","created":"0001-01-01T00:00:00Z","bounds":[0,0],"-":"","Untagged":0},` +
+		`"counts":{"a":1,"b":2},"extra":"extra","addr":"10.0.0.1","created":"2023-06-01T12:00:00Z","bounds":[1,2],` +
+		`"Names":{"x":["y"]},"-":"dash","Untagged":7}`
+	assert.Equal(t, expected, string(b))
+
+}
+
+func TestJSONDecode(t *testing.T) {
+	var actual Resource
+	err := json.Unmarshal([]byte(`{"id":"r1","Labels":{"app":"web"},"kind":"service","enabled":true,"data":"aGk=",`+
+		`"ports":[{"name":"http"}],"parent":{"id":"r0"},"addr":"10.0.0.1","Names":{"x":["y"]},"unknown":{"a":[true]}}`), &actual)
+	require.NoError(t, err)
+
+	assert.Equal(t, Resource{
+		Base:    Base{ID: "r1"},
+		Labels:  &Labels{Labels: map[string]string{"app": "web"}},
+		Kind:    "service",
+		Enabled: true,
+		Data:    []byte("hi"),
+		Ports:   []Port{{Name: "http"}},
+		Parent:  &Resource{Base: Base{ID: "r0"}},
+		Addr:    netip.MustParseAddr("10.0.0.1"),
+		Names:   map[Kind][]string{"x": {"y"}},
+	}, actual)
+}
+
+func TestAny(t *testing.T) {
+	r := testResource()
+	r.Addr, r.Parent.Ports = netip.Addr{}, nil
+
+	v, err := anycodec.Encode(r)
+	require.NoError(t, err)
+
+	actual, err := anycodec.Decode[Resource](v)
+	require.NoError(t, err)
+
+	r.Ignored = ""
+	assert.Equal(t, r, actual)
+}
+
+func TestFormatOverrides(t *testing.T) {
+	created := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// The fields that are encoded by the reflection-based codecs use the overrides of the format in use.
+	v, err := anycodec.Encode(Resource{Created: created})
+	require.NoError(t, err)
+	assert.Equal(t, created, v.(map[string]any)["created"])
+
+	pv, err := pulumi.Encode(Resource{Created: created})
+	require.NoError(t, err)
+	assert.Equal(t, "2023-06-01T12:00:00Z", pv.ObjectValue()["created"].StringValue())
+
+	r, err := anycodec.Decode[Resource](map[string]any{"created": created})
+	require.NoError(t, err)
+	assert.Equal(t, Resource{Created: created}, r)
+}
+
+func TestOmitEmpty(t *testing.T) {
+	v, err := anycodec.Encode(Signal{Name: "zero"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "zero"}, v)
+
+	v, err = anycodec.Encode(Signal{Name: "signal", Gain: 1i, Phase: 2 + 1i})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "signal", "gain": complex64(1i), "phase": 2 + 1i}, v)
+}
+
+func TestDecodeFields(t *testing.T) {
+	r, err := anycodec.Decode[Resource](map[string]any{
+		"ID":      "r1",
+		"LABELS":  map[string]any{"app": "web"},
+		"unknown": true,
+		"Ignored": "ignored",
+		"ports":   []any{map[string]any{"Port": uint16(80)}},
+		"NAMEſ":   map[string]any{"x": []any{"y"}}, // keys are folded as they are by the reflection-based codecs
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Resource{
+		Base:   Base{ID: "r1"},
+		Labels: &Labels{Labels: map[string]string{"app": "web"}},
+		Ports:  []Port{{Port: 80}},
+		Names:  map[Kind][]string{"x": {"y"}},
+	}, r)
+}
+
+func TestEmbedded(t *testing.T) {
+	b, err := json.Marshal(Both{Left: Left{Name: "left", Shared: "left"}})
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"left"}`, string(b))
+
+	b, err = json.Marshal(Both{Left: Left{Name: "left"}, Right: &Right{Name: "right", Shared: "right"}})
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"left","Name":"right"}`, string(b))
+
+	var both Both
+	require.NoError(t, json.Unmarshal([]byte(`{"Name":"right","Shared":"shared"}`), &both))
+	assert.Equal(t, Both{Right: &Right{Name: "right"}}, both)
+}
+
+func TestDecodeOptions(t *testing.T) {
+	_, err := anycodec.DecodeWithOptions[Resource](map[string]any{"id": "r1", "unknown": true}, codec.DecodeOptions{DisallowUnknownFields: true})
+	assert.EqualError(t, err, `codec: unknown field "unknown" in Go struct Resource`)
+
+	r, err := anycodec.DecodeWithOptions[Resource](map[string]any{"ID": "r1", "kind": "service"}, codec.DecodeOptions{CaseSensitive: true})
+	require.NoError(t, err)
+	assert.Equal(t, Resource{Kind: "service"}, r)
+
+	_, err = anycodec.DecodeWithOptions[Resource](map[string]any{"ID": "r1"}, codec.DecodeOptions{DisallowUnknownFields: true, CaseSensitive: true})
+	assert.EqualError(t, err, `codec: unknown field "ID" in Go struct Resource`)
+}
+
+func TestDefaults(t *testing.T) {
+	c, err := anycodec.Decode[Config](map[string]any{"name": "test"})
+	require.NoError(t, err)
+	debug := true
+	assert.Equal(t, Config{
+		Name:    "test",
+		Port:    8080,
+		Debug:   &debug,
+		Ratio:   0.5,
+		Addr:    netip.MustParseAddr("127.0.0.1"),
+		Timeout: time.Second,
+	}, c)
+
+	_, err = anycodec.Decode[Config](map[string]any{"debug": false})
+	assert.EqualError(t, err, `codec: missing required field "name" in Go struct Config`)
+}
+
+func TestErrorPaths(t *testing.T) {
+	_, err := anycodec.Decode[Resource](map[string]any{
+		"ports": []any{map[string]any{"name": "http"}, map[string]any{"name": true}},
+	})
+	var typeErr *codec.UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, "Port", typeErr.Struct)
+	assert.Equal(t, "ports[1].name", typeErr.Field)
+	assert.EqualError(t, err, "codec: cannot unmarshal bool into Go struct field Port.ports[1].name of type string")
+}
+
+func TestUnion(t *testing.T) {
+	d := Drawing{
+		Name:    "shapes",
+		Primary: &Circle{Radius: 1.5},
+		Shapes:  []Shape{Square{Side: 2.5}, &Circle{Radius: 0.5}},
+	}
+
+	b, err := json.Marshal(d)
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"shapes","primary":{"kind":"circle","radius":1.5},"shapes":[{"kind":"square","side":2.5},{"kind":"circle","radius":0.5}]}`, string(b))
+
+	var decoded Drawing
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, d, decoded)
+
+	decoded, err = anycodec.Decode[Drawing](map[string]any{
+		"name":   "shapes",
+		"shapes": []any{map[string]any{"side": 1.5, "kind": "square"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Drawing{Name: "shapes", Shapes: []Shape{Square{Side: 1.5}}}, decoded)
+
+	_, err = anycodec.Decode[Drawing](map[string]any{"primary": map[string]any{"kind": "triangle"}})
+	assert.EqualError(t, err, `codec: unknown variant "triangle" of union example.Shape`)
+}
+
+func TestProtowire(t *testing.T) {
+	m := Message{ID: 150, Name: "hi", Counts: []int64{1, -1}, Parent: &Message{ID: 1}}
+
+	b, err := protowire.Marshal(m)
+	require.NoError(t, err)
+	assert.Equal(t, "089601120268691a020201220408011200", hex.EncodeToString(b))
+
+	var decoded Message
+	require.NoError(t, protowire.Unmarshal(b, &decoded))
+	assert.Equal(t, m, decoded)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// generatedHeader is the first line of every file written by codecgen.
+const generatedHeader = "// Code generated by codecgen. DO NOT EDIT."
+
+// listedPackage holds the subset of the output of `go list -json` that is needed to type-check a package.
+type listedPackage struct {
+	ImportPath string
+	Dir        string
+	GoFiles    []string
+	Export     string
+	DepOnly    bool
+	Error      *struct{ Err string }
+}
+
+// load type-checks the package in dir. Files previously written by codecgen are ignored so that stale output does
+// not affect the result.
+//
+// Dependencies are imported from the export data produced by `go list -export`, which keeps codecgen free of
+// dependencies outside of the standard library.
+func load(dir string) (*types.Package, error) {
+	cmd := exec.Command("go", "list", "-e", "-export", "-deps", "-json=ImportPath,Dir,GoFiles,Export,DepOnly,Error", ".")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var target *listedPackage
+	exports := map[string]string{}
+	for dec := json.NewDecoder(bytes.NewReader(out)); ; {
+		var p listedPackage
+		if err := dec.Decode(&p); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("go list: %w", err)
+		}
+		if p.Export != "" {
+			exports[p.ImportPath] = p.Export
+		}
+		if !p.DepOnly {
+			target = &p
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("no package in %v", dir)
+	}
+	if target.Error != nil && len(target.GoFiles) == 0 {
+		return nil, errors.New(target.Error.Err)
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range target.GoFiles {
+		path := filepath.Join(target.Dir, name)
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(src, []byte(generatedHeader)) {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, src, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	lookup := func(path string) (io.ReadCloser, error) {
+		export, ok := exports[path]
+		if !ok {
+			return nil, fmt.Errorf("no export data for %v", path)
+		}
+		return os.Open(export)
+	}
+
+	// Type errors are ignored: the package may refer to declarations that have not been generated yet.
+	config := types.Config{
+		Importer: importer.ForCompiler(fset, "gc", lookup),
+		Error:    func(error) {},
+	}
+	pkg, _ := config.Check(target.ImportPath, fset, files, nil)
+	return pkg, nil
+}
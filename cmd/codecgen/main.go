@@ -0,0 +1,72 @@
+// Codecgen generates reflection-free codecs for the struct types in a Go package.
+//
+// For each struct type Foo, codecgen emits a FooCodec type that implements codec.Codec[Foo] using the generic codecs
+// for Foo's fields, along with Serialize and Deserialize methods on Foo that delegate to FooCodec. Struct fields are
+// named and omitted using the same `codec:"name,omitempty"` tags as the reflection-based codecs, and embedded structs
+// are flattened using the same rules. Generated codecs honor the codec.DecodeOptions supplied by the decoder, and
+// describe Foo's fields and their complete struct tags using codec.StructField so that formats which read other tags,
+// e.g. the proto tags read by protowire, support generated types.
+//
+// Fields of non-empty interface types use codec.UnionCodec, which supports unions registered with codec.RegisterUnion.
+// Fields whose types have no generic codec (e.g. arrays, named empty interfaces, and structs that are not generated by
+// codecgen) fall back to codec.ReflectCodec. Both apply the overrides of the format in use.
+//
+// Codecgen is typically invoked from a go:generate directive:
+//
+//	//go:generate go run github.com/pgavlin/codec/cmd/codecgen
+//
+// By default, codecgen generates codecs for every struct type in the package that has at least one field with a codec
+// tag, along with every struct type that embeds such a struct. The -type flag selects an explicit list of types.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of struct type names; defaults to all tagged struct types")
+	output := flag.String("output", "", "output file name; defaults to <package>_codec.go")
+	flag.Parse()
+
+	dir := "."
+	if args := flag.Args(); len(args) > 1 {
+		fmt.Fprintln(os.Stderr, "usage: codecgen [-type T,U] [-output file] [directory]")
+		os.Exit(2)
+	} else if len(args) == 1 {
+		dir = args[0]
+	}
+
+	var names []string
+	if *typeNames != "" {
+		names = strings.Split(*typeNames, ",")
+	}
+
+	if err := run(dir, names, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "codecgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, names []string, output string) error {
+	pkg, err := load(dir)
+	if err != nil {
+		return err
+	}
+
+	source, err := generate(pkg, names)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = pkg.Name() + "_codec.go"
+	}
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(dir, output)
+	}
+	return os.WriteFile(output, source, 0o644)
+}
@@ -1,6 +1,9 @@
 package codec
 
 import (
+	"encoding"
+	"errors"
+	"fmt"
 	"reflect"
 	"sort"
 	"strconv"
@@ -12,7 +15,10 @@ import (
 	"github.com/segmentio/asm/keyset"
 )
 
-func GetDeserializer(v any, format Format) Deserializer {
+// GetDeserializer returns a Deserializer for v, which must be a pointer. If v implements Deserializer, it is returned
+// as-is. Otherwise, the returned Deserializer uses the codecs for the pointed-to type in the given format. If format
+// is nil, no format-specific overrides are applied.
+func GetDeserializer(v any, format *Format) Deserializer {
 	if d, ok := v.(Deserializer); ok {
 		return d
 	}
@@ -20,32 +26,52 @@ func GetDeserializer(v any, format Format) Deserializer {
 	if rv.Kind() != reflect.Pointer {
 		return unsupportedTypeCodec{t: rv.Type()}
 	}
-	return getCodec(rv.Type().Elem(), format).new(rv.UnsafePointer())
+	return rootDeserializer{
+		Deserializer: getCodec(rv.Type().Elem(), format).new(rv.UnsafePointer()),
+		t:            rv.Type().Elem(),
+	}
+}
+
+// rootDeserializer sets the Go type of UnmarshalTypeErrors returned by the codec for a root value.
+type rootDeserializer struct {
+	Deserializer
+
+	t reflect.Type
 }
 
-func GetSerializer(v any, format Format) Serializer {
+func (d rootDeserializer) Deserialize(decoder Decoder) error {
+	if err := d.Deserializer.Deserialize(decoder); err != nil {
+		return annotateError(err, "", "", d.t)
+	}
+	return nil
+}
+
+// GetSerializer returns a Serializer for v. If v implements Serializer, it is returned as-is. Otherwise, the returned
+// Serializer uses the codecs for v's type in the given format. If format is nil, no format-specific overrides are
+// applied.
+func GetSerializer(v any, format *Format) Serializer {
 	if s, ok := v.(Serializer); ok {
 		return s
 	}
 	if v == nil {
 		return NilCodec{}
 	}
-	return getCodec(reflect.TypeOf(v), format).new((*iface)(unsafe.Pointer(&v)).ptr)
-}
 
-type format struct {
-	codecs typecache.Cache[codec]
+	t := reflect.TypeOf(v)
+	p := (*iface)(unsafe.Pointer(&v)).ptr
+	if inlined(t) {
+		// Pointer-shaped values are stored directly in the interface rather than behind a pointer.
+		inline := p
+		p = unsafe.Pointer(&inline)
+	}
+	return getCodec(t, format).new(p)
 }
 
 var codecs typecache.Cache[codec]
 
-func getCodec(t reflect.Type, format Format) codec {
-	return codecs.GetOrCreate(t, func(t reflect.Type) codec {
-		// TODO: inlined...?
-		//	if inlined(t) {
-		//		c.encode = constructInlineValueEncodeFunc(c.encode)
-		//	}
-		return constructCodec(t, map[reflect.Type]*structType{}, t.Kind() == reflect.Ptr)
+func getCodec(t reflect.Type, format *Format) codec {
+	return format.cache().GetOrCreate(t, func(t reflect.Type) codec {
+		return constructCodec(t, format, map[reflect.Type]*structType{}, t.Kind() == reflect.Ptr)
 	})
 }
 
@@ -74,9 +100,17 @@ type encoder struct {
 type decoder struct{}
 
 type emptyFunc func(unsafe.Pointer) bool
+type defaultFunc func(unsafe.Pointer) error
 type sortFunc func([]reflect.Value)
 
-func constructCodec(t reflect.Type, seen map[reflect.Type]*structType, canAddr bool) (c codec) {
+func constructCodec(t reflect.Type, f *Format, seen map[reflect.Type]*structType, canAddr bool) (c codec) {
+	if c, ok := f.override(t); ok {
+		return c
+	}
+	if c, ok := registered(t); ok {
+		return c
+	}
+
 	switch t {
 	case nullType:
 		return nilCodec{}
@@ -143,20 +177,29 @@ func constructCodec(t reflect.Type, seen map[reflect.Type]*structType, canAddr b
 		c = float32Codec{}
 	case reflect.Float64:
 		c = float64Codec{}
+	case reflect.Complex64:
+		c = complex64Codec{}
+	case reflect.Complex128:
+		c = complex128Codec{}
 	case reflect.String:
 		c = stringCodec{}
 	case reflect.Interface:
-		c = AnyCodec{}
+		if t.NumMethod() == 0 {
+			c = AnyCodec{format: f}
+		} else {
+			u, _ := registeredUnion(t)
+			c = interfaceCodec{typ: t, union: u, format: f}
+		}
 	case reflect.Array:
-		c = constructArrayCodec(t, seen, canAddr)
+		c = constructArrayCodec(t, f, seen, canAddr)
 	case reflect.Slice:
-		c = constructSliceCodec(t, seen)
+		c = constructSliceCodec(t, f, seen)
 	case reflect.Map:
-		c = constructMapCodec(t, seen)
+		c = constructMapCodec(t, f, seen)
 	case reflect.Struct:
-		c = constructStructCodec(t, seen, canAddr)
+		c = constructStructCodec(t, f, seen, canAddr)
 	case reflect.Ptr:
-		c = constructPointerCodec(t, seen)
+		c = constructPointerCodec(t, f, seen)
 	default:
 		c = constructUnsupportedTypeCodec(t)
 	}
@@ -171,9 +214,9 @@ func constructCodec(t reflect.Type, seen map[reflect.Type]*structType, canAddr b
 	return
 }
 
-func constructArrayCodec(t reflect.Type, seen map[reflect.Type]*structType, canAddr bool) codec {
+func constructArrayCodec(t reflect.Type, f *Format, seen map[reflect.Type]*structType, canAddr bool) codec {
 	e := t.Elem()
-	c := constructCodec(e, seen, canAddr)
+	c := constructCodec(e, f, seen, canAddr)
 	n := t.Len()
 	return arrayCodec{
 		arrayType: &arrayType{
@@ -184,10 +227,10 @@ func constructArrayCodec(t reflect.Type, seen map[reflect.Type]*structType, canA
 	}
 }
 
-func constructSliceCodec(t reflect.Type, seen map[reflect.Type]*structType) codec {
+func constructSliceCodec(t reflect.Type, f *Format, seen map[reflect.Type]*structType) codec {
 	e := t.Elem()
 	s := alignedSize(e)
-	c := constructCodec(e, seen, true)
+	c := constructCodec(e, f, seen, true)
 
 	if e == uint8Type {
 		return bytesCodec{}
@@ -202,13 +245,13 @@ func constructSliceCodec(t reflect.Type, seen map[reflect.Type]*structType) code
 	}
 }
 
-func constructMapCodec(t reflect.Type, seen map[reflect.Type]*structType) codec {
+func constructMapCodec(t reflect.Type, f *Format, seen map[reflect.Type]*structType) codec {
 	var sortKeys sortFunc
 	kt := t.Key()
 	vt := t.Elem()
 
-	kc := constructCodec(kt, seen, false)
-	vc := constructCodec(vt, seen, false)
+	kc := constructCodec(kt, f, seen, false)
+	vc := constructCodec(vt, f, seen, false)
 
 	switch kt.Kind() {
 	case reflect.String:
@@ -263,14 +306,14 @@ func constructMapCodec(t reflect.Type, seen map[reflect.Type]*structType) codec
 	}
 }
 
-func constructStructCodec(t reflect.Type, seen map[reflect.Type]*structType, canAddr bool) codec {
-	st := constructStructType(t, seen, canAddr)
+func constructStructCodec(t reflect.Type, f *Format, seen map[reflect.Type]*structType, canAddr bool) codec {
+	st := constructStructType(t, f, seen, canAddr)
 	return structCodec{
 		structType: st,
 	}
 }
 
-func constructStructType(t reflect.Type, seen map[reflect.Type]*structType, canAddr bool) *structType {
+func constructStructType(t reflect.Type, f *Format, seen map[reflect.Type]*structType, canAddr bool) *structType {
 	// Used for preventing infinite recursion on types that have pointers to
 	// themselves.
 	st := seen[t]
@@ -280,15 +323,25 @@ func constructStructType(t reflect.Type, seen map[reflect.Type]*structType, canA
 			fields:      make([]structField, 0, t.NumField()),
 			fieldsIndex: make(map[string]*structField),
 			ficaseIndex: make(map[string]*structField),
+			name:        t.Name(),
 			typ:         t,
+			defaulter:   reflect.PtrTo(t).Implements(defaulterType),
 		}
 
 		seen[t] = st
-		st.fields = appendStructFields(st.fields, t, 0, seen, canAddr)
+		st.fields = appendStructFields(st.fields, t, 0, f, seen, canAddr)
 
+		st.structFields = make([]StructField, len(st.fields))
 		for i := range st.fields {
 			f := &st.fields[i]
-			s := strings.ToLower(f.name)
+			f.pos = i
+			st.structFields[i] = f.field
+			st.tracked = st.tracked || f.required || f.default_ != nil
+			if st.err == nil {
+				st.err = f.err
+			}
+
+			s := FoldKey(f.name)
 			st.fieldsIndex[f.name] = f
 			// When there is ambiguity because multiple fields have the same
 			// case-insensitive representation, the first field must win.
@@ -312,7 +365,7 @@ func constructStructType(t reflect.Type, seen map[reflect.Type]*structType, canA
 	return st
 }
 
-func appendStructFields(fields []structField, t reflect.Type, offset uintptr, seen map[reflect.Type]*structType, canAddr bool) []structField {
+func appendStructFields(fields []structField, t reflect.Type, offset uintptr, format *Format, seen map[reflect.Type]*structType, canAddr bool) []structField {
 	type embeddedField struct {
 		index      int
 		offset     uintptr
@@ -333,6 +386,9 @@ func appendStructFields(fields []structField, t reflect.Type, offset uintptr, se
 			anonymous  = f.Anonymous
 			tag        = false
 			omitempty  = false
+			required   = false
+			default_   defaultFunc
+			defaultErr error
 			unexported = len(f.PkgPath) != 0
 		)
 
@@ -354,9 +410,13 @@ func appendStructFields(fields []structField, t reflect.Type, offset uintptr, se
 			}
 
 			for _, tag := range parts[1:] {
-				switch tag {
-				case "omitempty":
+				switch {
+				case tag == "omitempty":
 					omitempty = true
+				case tag == "required":
+					required = true
+				case strings.HasPrefix(tag, "default="):
+					default_, defaultErr = defaultFuncOf(f.Type, strings.TrimPrefix(tag, "default="))
 				}
 			}
 		}
@@ -374,7 +434,7 @@ func appendStructFields(fields []structField, t reflect.Type, offset uintptr, se
 				// up by offset from the address of the wrapping object, so we
 				// simply add the embedded struct fields to the list of fields
 				// of the current struct type.
-				subtype := constructStructType(typ, seen, canAddr)
+				subtype := constructStructType(typ, format, seen, canAddr)
 
 				for j := range subtype.fields {
 					embedded = append(embedded, embeddedField{
@@ -395,7 +455,7 @@ func appendStructFields(fields []structField, t reflect.Type, offset uintptr, se
 			}
 		}
 
-		codec := constructCodec(f.Type, seen, canAddr)
+		codec := constructCodec(f.Type, format, seen, canAddr)
 
 		fields = append(fields, structField{
 			codec:     codec,
@@ -403,7 +463,11 @@ func appendStructFields(fields []structField, t reflect.Type, offset uintptr, se
 			empty:     emptyFuncOf(f.Type),
 			tag:       tag,
 			omitempty: omitempty,
+			required:  required,
+			default_:  default_,
+			err:       defaultErr,
 			name:      name,
+			field:     StructField{Name: name, Type: f.Type, Tag: f.Tag},
 			index:     i << 32,
 			typ:       f.Type,
 			zero:      reflect.Zero(f.Type),
@@ -438,10 +502,15 @@ func appendStructFields(fields []structField, t reflect.Type, offset uintptr, se
 		}
 
 		if embfield.pointer {
+			// The field is reached through a pointer to the embedded struct:
+			// its offset becomes the offset of that pointer, and the offset of
+			// the field within the embedded struct is recorded separately.
 			subfield.embedded = &embeddedStructField{
+				typ:        embfield.subtype.typ,
 				unexported: embfield.unexported,
-				offset:     embfield.offset,
+				offset:     subfield.offset,
 			}
+			subfield.offset = embfield.offset
 		} else {
 			subfield.offset += embfield.offset
 		}
@@ -460,9 +529,9 @@ func appendStructFields(fields []structField, t reflect.Type, offset uintptr, se
 	return fields
 }
 
-func constructPointerCodec(t reflect.Type, seen map[reflect.Type]*structType) codec {
+func constructPointerCodec(t reflect.Type, f *Format, seen map[reflect.Type]*structType) codec {
 	e := t.Elem()
-	c := constructCodec(e, seen, true)
+	c := constructCodec(e, f, seen, true)
 	return ptrCodec{
 		ptrType: &ptrType{
 			t:    t,
@@ -489,18 +558,6 @@ func constructDeserializerCodec(t reflect.Type, next codec) codec {
 	}
 }
 
-// noescape hides a pointer from escape analysis.  noescape is
-// the identity function but escape analysis doesn't think the
-// output depends on the input. noescape is inlined and currently
-// compiles down to zero instructions.
-// USE CAREFULLY!
-// This was copied from the runtime; see issues 23382 and 7921.
-//go:nosplit
-func noescape(p unsafe.Pointer) unsafe.Pointer {
-	x := uintptr(p)
-	return unsafe.Pointer(x ^ 0)
-}
-
 func alignedSize(t reflect.Type) uintptr {
 	a := t.Align()
 	s := t.Size()
@@ -522,6 +579,8 @@ func inlined(t reflect.Type) bool {
 		return true
 	case reflect.Struct:
 		return t.NumField() == 1 && inlined(t.Field(0).Type)
+	case reflect.Array:
+		return t.Len() == 1 && inlined(t.Elem())
 	default:
 		return false
 	}
@@ -602,6 +661,12 @@ func emptyFuncOf(t reflect.Type) emptyFunc {
 	case reflect.Float64:
 		return func(p unsafe.Pointer) bool { return *(*float64)(p) == 0 }
 
+	case reflect.Complex64:
+		return func(p unsafe.Pointer) bool { return *(*complex64)(p) == 0 }
+
+	case reflect.Complex128:
+		return func(p unsafe.Pointer) bool { return *(*complex128)(p) == 0 }
+
 	case reflect.Ptr:
 		return func(p unsafe.Pointer) bool { return *(*unsafe.Pointer)(p) == nil }
 
@@ -612,6 +677,80 @@ func emptyFuncOf(t reflect.Type) emptyFunc {
 	return func(unsafe.Pointer) bool { return false }
 }
 
+// defaultFuncOf returns a function that sets a value of type t to the default given by s. The default is parsed
+// according to the kind of t, or using t's UnmarshalText method if *t implements encoding.TextUnmarshaler. Invalid
+// defaults are reported when the function is constructed.
+func defaultFuncOf(t reflect.Type, s string) (defaultFunc, error) {
+	if t.Kind() == reflect.Ptr {
+		elem, err := defaultFuncOf(t.Elem(), s)
+		if err != nil {
+			return nil, err
+		}
+		return func(p unsafe.Pointer) error {
+			v := reflect.New(t.Elem())
+			if err := elem(v.UnsafePointer()); err != nil {
+				return err
+			}
+			*(*unsafe.Pointer)(p) = v.UnsafePointer()
+			return nil
+		}, nil
+	}
+
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		unmarshal := func(v reflect.Value) error {
+			if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+				return fmt.Errorf("codec: invalid default %q for type %v: %w", s, t, err)
+			}
+			return nil
+		}
+		// The default is unmarshaled into a scratch value to check that it is valid. Defaults are unmarshaled anew
+		// for each value so that decoded values do not share any memory.
+		if err := unmarshal(reflect.New(t)); err != nil {
+			return nil, err
+		}
+		return func(p unsafe.Pointer) error {
+			return unmarshal(reflect.NewAt(t, p))
+		}, nil
+	}
+
+	v := reflect.New(t).Elem()
+	var err error
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(s); err == nil {
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(s, 10, t.Bits()); err == nil {
+			v.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		if u, err = strconv.ParseUint(s, 10, t.Bits()); err == nil {
+			v.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(s, t.Bits()); err == nil {
+			v.SetFloat(f)
+		}
+	default:
+		err = errors.New("defaults are not supported for this type")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("codec: invalid default %q for type %v: %w", s, t, err)
+	}
+
+	return func(p unsafe.Pointer) error {
+		reflect.NewAt(t, p).Elem().Set(v)
+		return nil
+	}, nil
+}
+
 type iface struct {
 	typ unsafe.Pointer
 	ptr unsafe.Pointer
@@ -688,6 +827,8 @@ var (
 	anyType               = reflect.TypeOf((*any)(nil)).Elem()
 	codecSerializerType   = reflect.TypeOf((*Serializer)(nil)).Elem()
 	codecDeserializerType = reflect.TypeOf((*Deserializer)(nil)).Elem()
+	defaulterType         = reflect.TypeOf((*Defaulter)(nil)).Elem()
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
 )
 
 // An UnsupportedTypeError is returned by Marshal when attempting
@@ -700,8 +841,9 @@ func (e *UnsupportedTypeError) Error() string {
 	return "codec: unsupported type: " + e.Type.String()
 }
 
-// An UnmarshalTypeError describes a JSON value that was
-// not appropriate for a value of a specific Go type.
+// An UnmarshalTypeError describes a value that was not appropriate for a value of a specific Go type. Errors returned
+// by the struct, sequence, and map codecs carry the path from the root value to the offending value, e.g.
+// "spec.containers[3].ports[0]".
 type UnmarshalTypeError struct {
 	Value  string       // description of value - "bool", "array", "number -5"
 	Type   reflect.Type // type of Go value it could not be assigned to
@@ -711,8 +853,86 @@ type UnmarshalTypeError struct {
 }
 
 func (e *UnmarshalTypeError) Error() string {
-	if e.Struct != "" || e.Field != "" {
-		return "codec: cannot unmarshal " + e.Value + " into Go struct field " + e.Struct + "." + e.Field + " of type " + e.Type.String()
+	msg := "codec: cannot unmarshal " + e.Value
+	switch {
+	case e.Struct != "":
+		// Paths that start with an index, e.g. those of structs within sequences, are not separated from the name.
+		sep := "."
+		if strings.HasPrefix(e.Field, "[") {
+			sep = ""
+		}
+		msg += " into Go struct field " + e.Struct + sep + e.Field
+	case e.Field != "":
+		msg += " into Go value at " + e.Field
+	default:
+		msg += " into Go value"
+	}
+	if e.Type != nil {
+		msg += " of type " + e.Type.String()
+	}
+	return msg
+}
+
+// annotateError adds path information to an UnmarshalTypeError returned while decoding an element of a struct,
+// sequence, or map. The element is named by elem, which is either a field name or map key, or an index of the form
+// "[i]". structName is the name of the struct that contains the element, if any. If the error does not already carry
+// a Go type, its type is set to t.
+//
+// Errors are annotated as they propagate towards the root value, so the innermost struct name is retained and each
+// enclosing element is prepended to the path. The error is copied rather than modified in place. Formats that return
+// type errors of their own must convert them to UnmarshalTypeErrors in order for them to be annotated.
+func annotateError(err error, structName, elem string, t reflect.Type) error {
+	typeErr, ok := err.(*UnmarshalTypeError)
+	if !ok {
+		return err
+	}
+
+	annotated := *typeErr
+	if annotated.Type == nil {
+		annotated.Type = t
+	}
+	if annotated.Struct == "" {
+		annotated.Struct = structName
+	}
+	switch {
+	case elem == "":
+	case annotated.Field == "":
+		annotated.Field = elem
+	case strings.HasPrefix(annotated.Field, "["):
+		annotated.Field = elem + annotated.Field
+	default:
+		annotated.Field = elem + "." + annotated.Field
+	}
+	return &annotated
+}
+
+func indexElem(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+// A MissingFieldError is returned when decoding a struct from a map that does not contain a key for a required field.
+type MissingFieldError struct {
+	Struct string // name of the struct type
+	Field  string // the name of the missing field
+}
+
+func (e *MissingFieldError) Error() string {
+	if e.Struct == "" {
+		return "codec: missing required field " + strconv.Quote(e.Field)
+	}
+	return "codec: missing required field " + strconv.Quote(e.Field) + " in Go struct " + e.Struct
+}
+
+// An UnknownFieldError is returned when decoding a struct from a map that contains a key that does not match any of
+// the struct's fields and unknown fields are disallowed.
+type UnknownFieldError struct {
+	Struct string // name of the struct type
+	Field  string // the unknown key
+}
+
+func (e *UnknownFieldError) Error() string {
+	if e.Struct == "" {
+		return "codec: unknown field " + strconv.Quote(e.Field)
 	}
-	return "codec: cannot unmarshal " + e.Value + " into Go value of type " + e.Type.String()
+	return "codec: unknown field " + strconv.Quote(e.Field) + " in Go struct " + e.Struct
 }
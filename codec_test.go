@@ -2,7 +2,10 @@ package codec
 
 import (
 	"fmt"
+	"math"
+	"net/netip"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,7 +13,7 @@ import (
 )
 
 func unmarshalAny(data reflect.Value, v any) error {
-	return testData{data}.decode(v, GetDeserializer(v))
+	return testData{data}.decode(v, GetDeserializer(v, nil))
 }
 
 type testData struct {
@@ -72,6 +75,11 @@ func (d testData) DecodeAny(v Visitor) error {
 			return v.VisitNil()
 		}
 		return testData{d.v.Elem()}.DecodeAny(v)
+	case reflect.Interface:
+		if d.v.IsNil() {
+			return v.VisitNil()
+		}
+		return testData{d.v.Elem()}.DecodeAny(v)
 	case reflect.Array, reflect.Slice:
 		return v.VisitSeq(&testSeqDecoder{d.v, d.v.Len(), 0})
 	case reflect.Map:
@@ -106,6 +114,10 @@ func (d testData) DecodeSeq(v Visitor) error                 { return d.DecodeAn
 func (d testData) DecodeMap(v Visitor) error                 { return d.DecodeAny(v) }
 func (d testData) DecodeStruct(name string, v Visitor) error { return d.DecodeAny(v) }
 
+func (d testData) DecodeVariant(enum string, variants []string, v Visitor) error {
+	return d.DecodeAny(v)
+}
+
 func (d testData) DecodePtr(v Visitor) error {
 	if d.v.Kind() == reflect.Pointer && d.v.IsNil() {
 		return v.VisitNil()
@@ -299,43 +311,43 @@ func TestCodec(t *testing.T) {
 
 func TestCodecReflect(t *testing.T) {
 	var b bool
-	err := GetDeserializer(&b).Deserialize(data(true))
+	err := GetDeserializer(&b, nil).Deserialize(data(true))
 	require.NoError(t, err)
 	assert.Equal(t, true, b)
 
 	var bp *bool
-	err = GetDeserializer(&bp).Deserialize(data(true))
+	err = GetDeserializer(&bp, nil).Deserialize(data(true))
 	require.NoError(t, err)
 	require.NotNil(t, bp)
 	assert.Equal(t, true, *bp)
 
 	var bools []bool
-	err = GetDeserializer(&bools).Deserialize(data([]bool{true, false}))
+	err = GetDeserializer(&bools, nil).Deserialize(data([]bool{true, false}))
 	require.NoError(t, err)
 	assert.Equal(t, []bool{true, false}, bools)
 
 	var boolMap map[int]bool
-	err = GetDeserializer(&boolMap).Deserialize(data(map[int]bool{42: true}))
+	err = GetDeserializer(&boolMap, nil).Deserialize(data(map[int]bool{42: true}))
 	require.NoError(t, err)
 	assert.Equal(t, map[int]bool{42: true}, boolMap)
 
 	var struct_ boolStruct
-	err = GetDeserializer(&struct_).Deserialize(data(boolStruct{Field: true}))
+	err = GetDeserializer(&struct_, nil).Deserialize(data(boolStruct{Field: true}))
 	require.NoError(t, err)
 	assert.Equal(t, boolStruct{Field: true}, struct_)
 
 	var any_ any
-	err = GetDeserializer(&any_).Deserialize(data(boolStruct{Field: true}))
+	err = GetDeserializer(&any_, nil).Deserialize(data(boolStruct{Field: true}))
 	require.NoError(t, err)
 	assert.Equal(t, map[string]any{"Field": true}, any_)
 
 	var ms myString
-	err = GetDeserializer(&ms).Deserialize(data("hello"))
+	err = GetDeserializer(&ms, nil).Deserialize(data("hello"))
 	require.NoError(t, err)
 	assert.Equal(t, myString("hello"), ms)
 
 	var special testSpecial
-	err = GetDeserializer(&special).Deserialize(data(make(testSpecial)))
+	err = GetDeserializer(&special, nil).Deserialize(data(make(testSpecial)))
 	assert.Error(t, err)
 }
 
@@ -381,3 +393,431 @@ func TestUnmarshal(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotNil(t, special)
 }
+
+type upperCodec struct {
+	DefaultVisitor
+	value *string
+}
+
+func (upperCodec) New(v *string) Codec[string] {
+	return upperCodec{value: v}
+}
+
+func (c upperCodec) VisitString(v string) error {
+	*c.value = strings.ToUpper(v)
+	return nil
+}
+
+func (c upperCodec) Deserialize(d Decoder) error {
+	return d.DecodeString(c)
+}
+
+func (c upperCodec) Serialize(e Encoder) error {
+	return e.EncodeString(*c.value)
+}
+
+type stringStruct struct {
+	Field  string
+	Fields []string
+}
+
+func TestFormatOverride(t *testing.T) {
+	format := NewFormat("upper")
+	Override[string](format, upperCodec{})
+
+	var s stringStruct
+	err := GetDeserializer(&s, format).Deserialize(data(stringStruct{Field: "hello", Fields: []string{"a", "b"}}))
+	require.NoError(t, err)
+	assert.Equal(t, stringStruct{Field: "HELLO", Fields: []string{"A", "B"}}, s)
+
+	s = stringStruct{}
+	err = GetDeserializer(&s, nil).Deserialize(data(stringStruct{Field: "hello", Fields: []string{"a", "b"}}))
+	require.NoError(t, err)
+	assert.Equal(t, stringStruct{Field: "hello", Fields: []string{"a", "b"}}, s)
+}
+
+type addrCodec struct {
+	DefaultVisitor
+	value *netip.Addr
+}
+
+func (addrCodec) New(v *netip.Addr) Codec[netip.Addr] {
+	return addrCodec{value: v}
+}
+
+func (c addrCodec) VisitString(v string) error {
+	addr, err := netip.ParseAddr(v)
+	if err != nil {
+		return err
+	}
+	*c.value = addr
+	return nil
+}
+
+func (c addrCodec) Deserialize(d Decoder) error {
+	return d.DecodeString(c)
+}
+
+func (c addrCodec) Serialize(e Encoder) error {
+	return e.EncodeString(c.value.String())
+}
+
+type addrStruct struct {
+	Addr   netip.Addr
+	Addrs  []netip.Addr
+	ByName map[string]*netip.Addr
+}
+
+type addrStrings struct {
+	Addr   string
+	Addrs  []string
+	ByName map[string]string
+}
+
+func TestRegister(t *testing.T) {
+	Register[netip.Addr](addrCodec{})
+
+	var s addrStruct
+	err := GetDeserializer(&s, nil).Deserialize(data(addrStrings{
+		Addr:   "10.0.0.1",
+		Addrs:  []string{"10.0.0.2", "::1"},
+		ByName: map[string]string{"localhost": "127.0.0.1"},
+	}))
+	require.NoError(t, err)
+
+	localhost := netip.MustParseAddr("127.0.0.1")
+	assert.Equal(t, addrStruct{
+		Addr:   netip.MustParseAddr("10.0.0.1"),
+		Addrs:  []netip.Addr{netip.MustParseAddr("10.0.0.2"), netip.MustParseAddr("::1")},
+		ByName: map[string]*netip.Addr{"localhost": &localhost},
+	}, s)
+}
+
+type shout string
+
+type shoutCodec struct {
+	StringCodec[shout]
+}
+
+func (shoutCodec) New(v *shout) Codec[shout] {
+	return shoutCodec{StringCodec: NewString(v)}
+}
+
+func (c shoutCodec) VisitString(v string) error {
+	return c.StringCodec.VisitString(strings.ToUpper(v) + "!")
+}
+
+func (c shoutCodec) Deserialize(d Decoder) error {
+	return d.DecodeString(c)
+}
+
+func TestRegisterFormat(t *testing.T) {
+	format := NewFormat("scoped")
+	Register[shout](shoutCodec{}, format)
+
+	var s struct{ Field shout }
+	err := GetDeserializer(&s, format).Deserialize(data(struct{ Field string }{Field: "hello"}))
+	require.NoError(t, err)
+	assert.Equal(t, shout("HELLO!"), s.Field)
+
+	err = GetDeserializer(&s, nil).Deserialize(data(struct{ Field string }{Field: "hello"}))
+	require.NoError(t, err)
+	assert.Equal(t, shout("hello"), s.Field)
+}
+
+type defaultsStruct struct {
+	Name  string     `codec:"name,required"`
+	Port  int        `codec:"port,default=8080"`
+	Debug *bool      `codec:"debug,default=true"`
+	Addr  netip.Addr `codec:"addr,default=127.0.0.1"`
+	Count int
+}
+
+func (s *defaultsStruct) SetDefaults() {
+	if s.Count == 0 {
+		s.Count = 1
+	}
+}
+
+type invalidDefaultStruct struct {
+	Port int `codec:"port,default=http"`
+}
+
+func TestRequiredAndDefaults(t *testing.T) {
+	var s defaultsStruct
+	err := GetDeserializer(&s, nil).Deserialize(data(map[string]any{"name": "test"}))
+	require.NoError(t, err)
+	debug := true
+	assert.Equal(t, defaultsStruct{Name: "test", Port: 8080, Debug: &debug, Addr: netip.MustParseAddr("127.0.0.1"), Count: 1}, s)
+
+	s = defaultsStruct{}
+	err = GetDeserializer(&s, nil).Deserialize(data(map[string]any{"name": "test", "port": 80, "debug": false, "Count": 2}))
+	require.NoError(t, err)
+	debug = false
+	assert.Equal(t, defaultsStruct{Name: "test", Port: 80, Debug: &debug, Addr: netip.MustParseAddr("127.0.0.1"), Count: 2}, s)
+
+	s = defaultsStruct{}
+	err = GetDeserializer(&s, nil).Deserialize(data(map[string]any{"port": 80}))
+	var missing *MissingFieldError
+	require.ErrorAs(t, err, &missing)
+	assert.Equal(t, "defaultsStruct", missing.Struct)
+	assert.Equal(t, "name", missing.Field)
+
+	// Invalid defaults are reported whenever the struct is used, even if the field is present.
+	const invalidErr = `codec: invalid default "http" for type int: strconv.ParseInt: parsing "http": invalid syntax`
+	var invalid invalidDefaultStruct
+	err = GetDeserializer(&invalid, nil).Deserialize(data(map[string]any{}))
+	assert.EqualError(t, err, invalidErr)
+	err = GetDeserializer(&invalid, nil).Deserialize(data(map[string]any{"port": 80}))
+	assert.EqualError(t, err, invalidErr)
+	assert.EqualError(t, GetSerializer(invalid, nil).Serialize(nil), invalidErr)
+}
+
+type pathPort struct {
+	Port int `codec:"port"`
+}
+
+type pathContainer struct {
+	Ports []pathPort `codec:"ports"`
+}
+
+type pathSpec struct {
+	Containers []pathContainer       `codec:"containers"`
+	Labels     map[string][]pathPort `codec:"labels"`
+}
+
+type pathResource struct {
+	Spec pathSpec `codec:"spec"`
+}
+
+func TestErrorPaths(t *testing.T) {
+	var r pathResource
+	err := GetDeserializer(&r, nil).Deserialize(data(map[string]any{
+		"spec": map[string]any{
+			"containers": []any{
+				map[string]any{},
+				map[string]any{"ports": []any{map[string]any{"port": "http"}}},
+			},
+		},
+	}))
+	var typeErr *UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, "string", typeErr.Value)
+	assert.Equal(t, reflect.TypeOf(0), typeErr.Type)
+	assert.Equal(t, "pathPort", typeErr.Struct)
+	assert.Equal(t, "spec.containers[1].ports[0].port", typeErr.Field)
+	assert.EqualError(t, err, "codec: cannot unmarshal string into Go struct field pathPort.spec.containers[1].ports[0].port of type int")
+
+	r = pathResource{}
+	err = GetDeserializer(&r, nil).Deserialize(data(map[string]any{
+		"spec": map[string]any{"labels": map[string]any{"app": []any{true}}},
+	}))
+	require.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, reflect.TypeOf(pathPort{}), typeErr.Type)
+	assert.Equal(t, "pathSpec", typeErr.Struct)
+	assert.Equal(t, "spec.labels.app[0]", typeErr.Field)
+
+	// Paths that start at a sequence are not separated from the struct name.
+	var ports []pathPort
+	err = GetDeserializer(&ports, nil).Deserialize(data([]any{map[string]any{"port": 1}, map[string]any{"port": "x"}}))
+	require.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, "[1].port", typeErr.Field)
+	assert.EqualError(t, err, "codec: cannot unmarshal string into Go struct field pathPort[1].port of type int")
+
+	var ints []int
+	err = GetDeserializer(&ints, nil).Deserialize(data([]any{1, "two"}))
+	assert.EqualError(t, err, "codec: cannot unmarshal string into Go value at [1] of type int")
+
+	var i int
+	err = GetDeserializer(&i, nil).Deserialize(data("one"))
+	assert.EqualError(t, err, "codec: cannot unmarshal string into Go value of type int")
+}
+
+func TestAnnotateErrorCopies(t *testing.T) {
+	err := &UnmarshalTypeError{Value: "string", Field: "port"}
+	annotated := annotateError(err, "pathSpec", "ports", reflect.TypeOf(0))
+	assert.EqualError(t, annotated, "codec: cannot unmarshal string into Go struct field pathSpec.ports.port of type int")
+	assert.Equal(t, &UnmarshalTypeError{Value: "string", Field: "port"}, err)
+}
+
+func TestGenericErrorPaths(t *testing.T) {
+	var m map[string][]int
+	err := NewMap[StringCodec[string], SeqCodec[[]int, int, IntCodec[int]]](&m).Deserialize(data(map[string]any{
+		"a": []any{"one"},
+	}))
+	var typeErr *UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, reflect.TypeOf(0), typeErr.Type)
+	assert.Equal(t, "a[0]", typeErr.Field)
+}
+
+type testShape interface {
+	Sides() int
+}
+
+type testCircle struct {
+	Radius float64
+	Label  string
+}
+
+func (*testCircle) Sides() int { return 0 }
+
+type testSquare struct {
+	Side float64
+}
+
+func (testSquare) Sides() int { return 4 }
+
+type testDrawing struct {
+	Shapes []testShape
+}
+
+func TestRegisterUnion(t *testing.T) {
+	RegisterUnion[testShape]("Kind", map[string]reflect.Type{
+		"circle": reflect.TypeOf((*testCircle)(nil)),
+		"square": reflect.TypeOf(testSquare{}),
+	})
+
+	type circle struct {
+		Radius float64
+		Kind   string
+		Label  string
+	}
+	type square struct {
+		Kind string
+		Side float64
+	}
+
+	var d testDrawing
+	err := GetDeserializer(&d, nil).Deserialize(data(struct{ Shapes []any }{
+		Shapes: []any{circle{Radius: 1.5, Kind: "circle", Label: "c"}, square{Kind: "square", Side: 2}, nil},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, testDrawing{Shapes: []testShape{&testCircle{Radius: 1.5, Label: "c"}, testSquare{Side: 2}, nil}}, d)
+
+	var s testShape
+	err = GetDeserializer(&s, nil).Deserialize(data(square{Kind: "triangle"}))
+	var unknown *UnknownVariantError
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, "triangle", unknown.Variant)
+
+	err = GetDeserializer(&s, nil).Deserialize(data(struct{ Side float64 }{Side: 1}))
+	var missing *MissingFieldError
+	require.ErrorAs(t, err, &missing)
+	assert.Equal(t, "Kind", missing.Field)
+
+	var ss []testShape
+	err = NewSeq[UnionCodec[testShape]](&ss).Deserialize(data([]any{square{Kind: "square", Side: 3}}))
+	require.NoError(t, err)
+	assert.Equal(t, []testShape{testSquare{Side: 3}}, ss)
+
+	// UnionCodecs apply the overrides of the decoder's format, including to fields that precede the discriminator.
+	type labeled struct {
+		Label string
+		Kind  string
+	}
+	format := NewFormat("upper")
+	Override[string](format, upperCodec{})
+
+	s = nil
+	err = NewUnion(&s).Deserialize(formatData{testData: data(labeled{Label: "c", Kind: "circle"}), format: format})
+	require.NoError(t, err)
+	assert.Equal(t, &testCircle{Label: "C"}, s)
+}
+
+// formatData is a testData that reports a format.
+type formatData struct {
+	testData
+	format *Format
+}
+
+func (d formatData) Format() *Format {
+	return d.format
+}
+
+func TestNumericCoercion(t *testing.T) {
+	decode := func(v any, ptr any) error {
+		return GetDeserializer(ptr, nil).Deserialize(data(v))
+	}
+
+	var i8 int8
+	require.NoError(t, decode(int(5), &i8))
+	assert.Equal(t, int8(5), i8)
+	require.NoError(t, decode(uint64(127), &i8))
+	assert.Equal(t, int8(127), i8)
+	require.NoError(t, decode(float64(-128), &i8))
+	assert.Equal(t, int8(-128), i8)
+
+	var u16 uint16
+	require.NoError(t, decode(float32(8080), &u16))
+	assert.Equal(t, uint16(8080), u16)
+
+	var i64 int64
+	require.NoError(t, decode(uint64(math.MaxInt64), &i64))
+	assert.Equal(t, int64(math.MaxInt64), i64)
+
+	var f32 float32
+	require.NoError(t, decode(int64(42), &f32))
+	assert.Equal(t, float32(42), f32)
+	require.NoError(t, decode(0.5, &f32))
+	assert.Equal(t, float32(0.5), f32)
+
+	// Integers must be represented exactly by floats.
+	require.NoError(t, decode(int64(1<<24), &f32))
+	assert.Equal(t, float32(1<<24), f32)
+	require.NoError(t, decode(int64(-1<<24), &f32))
+	assert.Equal(t, float32(-1<<24), f32)
+	assert.EqualError(t, decode(int64(1<<24+1), &f32), "codec: cannot unmarshal number 16777217 into Go value of type float32")
+	assert.EqualError(t, decode(uint64(1<<24+1), &f32), "codec: cannot unmarshal number 16777217 into Go value of type float32")
+	assert.EqualError(t, NewFloat32(&f32).Deserialize(data(uint64(math.MaxUint64))),
+		"codec: cannot unmarshal number 18446744073709551615 into Go value")
+
+	var f64 float64
+	require.NoError(t, decode(int64(1<<53), &f64))
+	assert.Equal(t, float64(1<<53), f64)
+	require.NoError(t, decode(uint64(1<<63), &f64))
+	assert.Equal(t, float64(1<<63), f64)
+	require.NoError(t, decode(int64(math.MinInt64), &f64))
+	assert.Equal(t, float64(math.MinInt64), f64)
+	assert.EqualError(t, decode(int64(1<<53+1), &f64), "codec: cannot unmarshal number 9007199254740993 into Go value of type float64")
+	assert.EqualError(t, decode(int64(-1<<53-1), &f64), "codec: cannot unmarshal number -9007199254740993 into Go value of type float64")
+	assert.EqualError(t, decode(int64(math.MaxInt64), &f64), "codec: cannot unmarshal number 9223372036854775807 into Go value of type float64")
+	assert.EqualError(t, decode(uint64(math.MaxUint64), &f64), "codec: cannot unmarshal number 18446744073709551615 into Go value of type float64")
+
+	var uptr uintptr
+	require.NoError(t, decode(uint8(7), &uptr))
+	assert.Equal(t, uintptr(7), uptr)
+
+	cases := []struct {
+		value any
+		ptr   any
+		err   string
+	}{
+		{int(300), &i8, "codec: cannot unmarshal number 300 into Go value of type int8"},
+		{int(-1), &u16, "codec: cannot unmarshal number -1 into Go value of type uint16"},
+		{uint64(math.MaxUint64), &i64, "codec: cannot unmarshal number 18446744073709551615 into Go value of type int64"},
+		{1.5, &i8, "codec: cannot unmarshal number 1.5 into Go value of type int8"},
+		{65536.0, &u16, "codec: cannot unmarshal number 65536 into Go value of type uint16"},
+		{math.NaN(), &i64, "codec: cannot unmarshal number NaN into Go value of type int64"},
+		{1e300, &f32, "codec: cannot unmarshal number 1e+300 into Go value of type float32"},
+		{"42", &i8, "codec: cannot unmarshal string into Go value of type int8"},
+	}
+	for _, c := range cases {
+		assert.EqualError(t, decode(c.value, c.ptr), c.err)
+	}
+
+	var port uint16
+	err := NewUint16(&port).Deserialize(data(70000))
+	var typeErr *UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, "number 70000", typeErr.Value)
+
+	require.NoError(t, NewUint16(&port).Deserialize(data(443.0)))
+	assert.Equal(t, uint16(443), port)
+
+	var s struct {
+		Port uint16
+	}
+	err = decode(map[string]any{"Port": -80}, &s)
+	assert.EqualError(t, err, "codec: cannot unmarshal number -80 into Go value at Port of type uint16")
+}
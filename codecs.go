@@ -2,6 +2,7 @@ package codec
 
 import (
 	"fmt"
+	"reflect"
 	"unsafe"
 
 	"golang.org/x/exp/constraints"
@@ -69,12 +70,17 @@ func (SkipCodec) VisitMap(d MapDecoder) error {
 	}
 }
 
+func (SkipCodec) VisitVariant(d VariantDecoder) error {
+	return d.Value(nil, SkipCodec{})
+}
+
 func (SkipCodec) Deserialize(d Decoder) error {
 	return d.DecodeAny(SkipCodec{})
 }
 
 type AnyCodec struct {
-	value *any
+	value  *any
+	format *Format
 }
 
 func NewAny(v *any) AnyCodec {
@@ -82,11 +88,11 @@ func NewAny(v *any) AnyCodec {
 }
 
 func (c AnyCodec) new(v unsafe.Pointer) codec {
-	return AnyCodec{value: (*any)(v)}
+	return AnyCodec{value: (*any)(v), format: c.format}
 }
 
 func (c AnyCodec) New(v *any) Codec[any] {
-	return AnyCodec{value: v}
+	return AnyCodec{value: v, format: c.format}
 }
 
 func (c AnyCodec) VisitNil() error {
@@ -174,6 +180,11 @@ func (c AnyCodec) VisitComplex128(v complex128) error {
 	return nil
 }
 
+func (c AnyCodec) VisitNumber(n Number) error {
+	*c.value = n
+	return nil
+}
+
 func (c AnyCodec) VisitString(v string) error {
 	*c.value = v
 	return nil
@@ -195,7 +206,7 @@ func (c AnyCodec) VisitSeq(seq SeqDecoder) error {
 	}
 	for {
 		var v any
-		ok, err := seq.NextElement(&v, AnyCodec{value: &v})
+		ok, err := seq.NextElement(&v, AnyCodec{value: &v, format: c.format})
 		if err != nil {
 			return err
 		}
@@ -226,13 +237,28 @@ func (c AnyCodec) VisitMap(map_ MapDecoder) error {
 		}
 
 		var v any
-		if err = map_.NextValue(&v, AnyCodec{value: &v}); err != nil {
+		if err = map_.NextValue(&v, AnyCodec{value: &v, format: c.format}); err != nil {
 			return err
 		}
 		m[k] = v
 	}
 }
 
+// VisitVariant decodes a variant using the externally tagged representation: unit variants are decoded as their names,
+// and other variants are decoded as single-entry maps from their names to their values.
+func (c AnyCodec) VisitVariant(d VariantDecoder) error {
+	var v any
+	if err := d.Value(&v, AnyCodec{value: &v, format: c.format}); err != nil {
+		return err
+	}
+	if v == nil {
+		*c.value = d.Variant()
+	} else {
+		*c.value = map[string]any{d.Variant(): v}
+	}
+	return nil
+}
+
 func (c AnyCodec) Deserialize(d Decoder) error {
 	return d.DecodeAny(c)
 }
@@ -241,7 +267,11 @@ func (c AnyCodec) Serialize(e Encoder) error {
 	if *c.value == nil {
 		return e.EncodeNil()
 	}
-	return GetSerializer(*c.value).Serialize(e)
+	format := c.format
+	if format == nil {
+		format = FormatOf(e)
+	}
+	return GetSerializer(*c.value, format).Serialize(e)
 }
 
 type NilCodec struct {
@@ -287,21 +317,15 @@ func (c BoolCodec[T]) Serialize(e Encoder) error {
 }
 
 type IntCodec[T ~int] struct {
-	DefaultVisitor
-	value *T
+	numberVisitor[T]
 }
 
 func NewInt[T ~int](v *T) IntCodec[T] {
-	return IntCodec[T]{value: v}
+	return IntCodec[T]{numberVisitor[T]{value: v}}
 }
 
 func (IntCodec[T]) New(v *T) Codec[T] {
-	return IntCodec[T]{value: v}
-}
-
-func (c IntCodec[T]) VisitInt(v int) error {
-	*c.value = T(v)
-	return nil
+	return IntCodec[T]{numberVisitor[T]{value: v}}
 }
 
 func (c IntCodec[T]) Deserialize(d Decoder) error {
@@ -313,21 +337,15 @@ func (c IntCodec[T]) Serialize(e Encoder) error {
 }
 
 type Int8Codec[T ~int8] struct {
-	DefaultVisitor
-	value *T
+	numberVisitor[T]
 }
 
 func NewInt8[T ~int8](v *T) Int8Codec[T] {
-	return Int8Codec[T]{value: v}
+	return Int8Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (Int8Codec[T]) New(v *T) Codec[T] {
-	return Int8Codec[T]{value: v}
-}
-
-func (c Int8Codec[T]) VisitInt8(v int8) error {
-	*c.value = T(v)
-	return nil
+	return Int8Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (c Int8Codec[T]) Deserialize(d Decoder) error {
@@ -339,21 +357,15 @@ func (c Int8Codec[T]) Serialize(e Encoder) error {
 }
 
 type Int16Codec[T ~int16] struct {
-	DefaultVisitor
-	value *T
+	numberVisitor[T]
 }
 
 func NewInt16[T ~int16](v *T) Int16Codec[T] {
-	return Int16Codec[T]{value: v}
+	return Int16Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (Int16Codec[T]) New(v *T) Codec[T] {
-	return Int16Codec[T]{value: v}
-}
-
-func (c Int16Codec[T]) VisitInt16(v int16) error {
-	*c.value = T(v)
-	return nil
+	return Int16Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (c Int16Codec[T]) Deserialize(d Decoder) error {
@@ -365,21 +377,15 @@ func (c Int16Codec[T]) Serialize(e Encoder) error {
 }
 
 type Int32Codec[T ~int32] struct {
-	DefaultVisitor
-	value *T
+	numberVisitor[T]
 }
 
 func NewInt32[T ~int32](v *T) Int32Codec[T] {
-	return Int32Codec[T]{value: v}
+	return Int32Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (Int32Codec[T]) New(v *T) Codec[T] {
-	return Int32Codec[T]{value: v}
-}
-
-func (c Int32Codec[T]) VisitInt32(v int32) error {
-	*c.value = T(v)
-	return nil
+	return Int32Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (c Int32Codec[T]) Deserialize(d Decoder) error {
@@ -391,21 +397,15 @@ func (c Int32Codec[T]) Serialize(e Encoder) error {
 }
 
 type Int64Codec[T ~int64] struct {
-	DefaultVisitor
-	value *T
+	numberVisitor[T]
 }
 
 func NewInt64[T ~int64](v *T) Int64Codec[T] {
-	return Int64Codec[T]{value: v}
+	return Int64Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (Int64Codec[T]) New(v *T) Codec[T] {
-	return Int64Codec[T]{value: v}
-}
-
-func (c Int64Codec[T]) VisitInt64(v int64) error {
-	*c.value = T(v)
-	return nil
+	return Int64Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (c Int64Codec[T]) Deserialize(d Decoder) error {
@@ -417,21 +417,15 @@ func (c Int64Codec[T]) Serialize(e Encoder) error {
 }
 
 type UintCodec[T ~uint] struct {
-	DefaultVisitor
-	value *T
+	numberVisitor[T]
 }
 
 func NewUint[T ~uint](v *T) UintCodec[T] {
-	return UintCodec[T]{value: v}
+	return UintCodec[T]{numberVisitor[T]{value: v}}
 }
 
 func (UintCodec[T]) New(v *T) Codec[T] {
-	return UintCodec[T]{value: v}
-}
-
-func (c UintCodec[T]) VisitUint(v uint) error {
-	*c.value = T(v)
-	return nil
+	return UintCodec[T]{numberVisitor[T]{value: v}}
 }
 
 func (c UintCodec[T]) Deserialize(d Decoder) error {
@@ -443,21 +437,15 @@ func (c UintCodec[T]) Serialize(e Encoder) error {
 }
 
 type Uint8Codec[T ~uint8] struct {
-	DefaultVisitor
-	value *T
+	numberVisitor[T]
 }
 
 func NewUint8[T ~uint8](v *T) Uint8Codec[T] {
-	return Uint8Codec[T]{value: v}
+	return Uint8Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (Uint8Codec[T]) New(v *T) Codec[T] {
-	return Uint8Codec[T]{value: v}
-}
-
-func (c Uint8Codec[T]) VisitUint8(v uint8) error {
-	*c.value = T(v)
-	return nil
+	return Uint8Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (c Uint8Codec[T]) Deserialize(d Decoder) error {
@@ -469,21 +457,15 @@ func (c Uint8Codec[T]) Serialize(e Encoder) error {
 }
 
 type Uint16Codec[T ~uint16] struct {
-	DefaultVisitor
-	value *T
+	numberVisitor[T]
 }
 
 func NewUint16[T ~uint16](v *T) Uint16Codec[T] {
-	return Uint16Codec[T]{value: v}
+	return Uint16Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (Uint16Codec[T]) New(v *T) Codec[T] {
-	return Uint16Codec[T]{value: v}
-}
-
-func (c Uint16Codec[T]) VisitUint16(v uint16) error {
-	*c.value = T(v)
-	return nil
+	return Uint16Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (c Uint16Codec[T]) Deserialize(d Decoder) error {
@@ -495,21 +477,15 @@ func (c Uint16Codec[T]) Serialize(e Encoder) error {
 }
 
 type Uint32Codec[T ~uint32] struct {
-	DefaultVisitor
-	value *T
+	numberVisitor[T]
 }
 
 func NewUint32[T ~uint32](v *T) Uint32Codec[T] {
-	return Uint32Codec[T]{value: v}
+	return Uint32Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (Uint32Codec[T]) New(v *T) Codec[T] {
-	return Uint32Codec[T]{value: v}
-}
-
-func (c Uint32Codec[T]) VisitUint32(v uint32) error {
-	*c.value = T(v)
-	return nil
+	return Uint32Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (c Uint32Codec[T]) Deserialize(d Decoder) error {
@@ -521,21 +497,15 @@ func (c Uint32Codec[T]) Serialize(e Encoder) error {
 }
 
 type Uint64Codec[T ~uint64] struct {
-	DefaultVisitor
-	value *T
+	numberVisitor[T]
 }
 
 func NewUint64[T ~uint64](v *T) Uint64Codec[T] {
-	return Uint64Codec[T]{value: v}
+	return Uint64Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (Uint64Codec[T]) New(v *T) Codec[T] {
-	return Uint64Codec[T]{value: v}
-}
-
-func (c Uint64Codec[T]) VisitUint64(v uint64) error {
-	*c.value = T(v)
-	return nil
+	return Uint64Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (c Uint64Codec[T]) Deserialize(d Decoder) error {
@@ -547,21 +517,15 @@ func (c Uint64Codec[T]) Serialize(e Encoder) error {
 }
 
 type UintptrCodec[T ~uintptr] struct {
-	DefaultVisitor
-	value *T
+	numberVisitor[T]
 }
 
 func NewUintptr[T ~uintptr](v *T) UintptrCodec[T] {
-	return UintptrCodec[T]{value: v}
+	return UintptrCodec[T]{numberVisitor[T]{value: v}}
 }
 
 func (UintptrCodec[T]) New(v *T) Codec[T] {
-	return UintptrCodec[T]{value: v}
-}
-
-func (c UintptrCodec[T]) VisitUintptr(v uintptr) error {
-	*c.value = T(v)
-	return nil
+	return UintptrCodec[T]{numberVisitor[T]{value: v}}
 }
 
 func (c UintptrCodec[T]) Deserialize(d Decoder) error {
@@ -573,21 +537,15 @@ func (c UintptrCodec[T]) Serialize(e Encoder) error {
 }
 
 type Float32Codec[T ~float32] struct {
-	DefaultVisitor
-	value *T
+	numberVisitor[T]
 }
 
 func NewFloat32[T ~float32](v *T) Float32Codec[T] {
-	return Float32Codec[T]{value: v}
+	return Float32Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (Float32Codec[T]) New(v *T) Codec[T] {
-	return Float32Codec[T]{value: v}
-}
-
-func (c Float32Codec[T]) VisitFloat32(v float32) error {
-	*c.value = T(v)
-	return nil
+	return Float32Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (c Float32Codec[T]) Deserialize(d Decoder) error {
@@ -599,21 +557,15 @@ func (c Float32Codec[T]) Serialize(e Encoder) error {
 }
 
 type Float64Codec[T ~float64] struct {
-	DefaultVisitor
-	value *T
+	numberVisitor[T]
 }
 
 func NewFloat64[T ~float64](v *T) Float64Codec[T] {
-	return Float64Codec[T]{value: v}
+	return Float64Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (Float64Codec[T]) New(v *T) Codec[T] {
-	return Float64Codec[T]{value: v}
-}
-
-func (c Float64Codec[T]) VisitFloat64(v float64) error {
-	*c.value = T(v)
-	return nil
+	return Float64Codec[T]{numberVisitor[T]{value: v}}
 }
 
 func (c Float64Codec[T]) Deserialize(d Decoder) error {
@@ -702,6 +654,37 @@ func (c StringCodec[T]) Serialize(e Encoder) error {
 	return e.EncodeString(string(*c.value))
 }
 
+type BytesCodec[T ~[]byte] struct {
+	DefaultVisitor
+	value *T
+}
+
+func NewBytes[T ~[]byte](v *T) BytesCodec[T] {
+	return BytesCodec[T]{value: v}
+}
+
+func (BytesCodec[T]) New(v *T) Codec[T] {
+	return BytesCodec[T]{value: v}
+}
+
+func (c BytesCodec[T]) VisitNil() error {
+	*c.value = nil
+	return nil
+}
+
+func (c BytesCodec[T]) VisitBytes(v []byte) error {
+	*c.value = T(v)
+	return nil
+}
+
+func (c BytesCodec[T]) Deserialize(d Decoder) error {
+	return d.DecodeBytes(c)
+}
+
+func (c BytesCodec[T]) Serialize(e Encoder) error {
+	return e.EncodeBytes([]byte(*c.value))
+}
+
 type PtrCodec[P ~*T, T any, C Codec[T]] struct {
 	DefaultVisitor
 	value *P
@@ -716,7 +699,7 @@ func (c PtrCodec[P, T, C]) codec(v *T) Codec[T] {
 	return codec.New(v)
 }
 
-func (c PtrCodec[P, T, C]) New(v *P) PtrCodec[P, T, C] {
+func (c PtrCodec[P, T, C]) New(v *P) Codec[P] {
 	return PtrCodec[P, T, C]{value: v}
 }
 
@@ -742,7 +725,8 @@ func (c PtrCodec[P, T, C]) Serialize(e Encoder) error {
 	if *c.value == nil {
 		return e.EncodeNil()
 	}
-	return c.codec((*T)(*c.value)).Serialize(e)
+	v := (*T)(*c.value)
+	return e.EncodeElem(*v, c.codec(v))
 }
 
 type SeqCodec[Q ~[]T, T any, C Codec[T]] struct {
@@ -764,7 +748,7 @@ func (c SeqCodec[Q, T, C]) VisitNil() error {
 }
 
 func (c SeqCodec[Q, T, C]) VisitSeq(seq SeqDecoder) error {
-	var vals Q
+	vals := Q{}
 	if len, ok := seq.Size(); ok {
 		vals = make(Q, 0, len)
 	}
@@ -773,7 +757,7 @@ func (c SeqCodec[Q, T, C]) VisitSeq(seq SeqDecoder) error {
 		var v T
 		ok, err := seq.NextElement(&v, codec.New(&v))
 		if err != nil {
-			return err
+			return annotateError(err, "", indexElem(len(vals)), reflect.TypeOf(&v).Elem())
 		}
 		if !ok {
 			*c.value = vals
@@ -843,7 +827,7 @@ func (c MapCodec[M, K, V, CK, CV]) VisitMap(map_ MapDecoder) error {
 
 		var v V
 		if err = map_.NextValue(&v, valueCodec.New(&v)); err != nil {
-			return err
+			return annotateError(err, "", fmt.Sprint(k), reflect.TypeOf(&v).Elem())
 		}
 		m[k] = v
 	}
@@ -901,3 +885,35 @@ func (c ErrorCodec[T]) Serialize(e Encoder) error {
 	var t T
 	return fmt.Errorf("cannot serialize %T", t)
 }
+
+// ReflectCodec is a Codec[T] that uses the reflection-based codecs. It allows types that have no generic codec of
+// their own to be composed with the generic codecs. The overrides of the format reported by the Encoder or Decoder
+// passed to Serialize or Deserialize are applied (see FormatOf).
+type ReflectCodec[T any] struct {
+	codec
+	value *T
+}
+
+func NewReflect[T any](v *T) ReflectCodec[T] {
+	return ReflectCodec[T]{codec: getCodec(reflect.TypeOf(v).Elem(), nil).new(unsafe.Pointer(v)), value: v}
+}
+
+// formatCodec returns the reflection-based codec for the value in the given format.
+func (c ReflectCodec[T]) formatCodec(f *Format) codec {
+	if f == nil {
+		return c.codec
+	}
+	return getCodec(reflect.TypeOf(c.value).Elem(), f).new(unsafe.Pointer(c.value))
+}
+
+func (c ReflectCodec[T]) Serialize(e Encoder) error {
+	return c.formatCodec(FormatOf(e)).Serialize(e)
+}
+
+func (c ReflectCodec[T]) Deserialize(d Decoder) error {
+	return c.formatCodec(FormatOf(d)).Deserialize(d)
+}
+
+func (ReflectCodec[T]) New(v *T) Codec[T] {
+	return NewReflect(v)
+}
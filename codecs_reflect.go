@@ -1,7 +1,6 @@
 package codec
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
 	"unicode"
@@ -25,91 +24,95 @@ type unsafeCodec struct {
 }
 
 func (unsafeCodec) VisitNil() error {
-	return errors.New("unexpected nil")
+	return unexpected("nil")
 }
 
 func (unsafeCodec) VisitBool(v bool) error {
-	return errors.New("unexpected bool")
+	return unexpected("bool")
 }
 
 func (unsafeCodec) VisitInt(v int) error {
-	return errors.New("unexpected int")
+	return unexpected("int")
 }
 
 func (unsafeCodec) VisitInt8(v int8) error {
-	return errors.New("unexpected int8")
+	return unexpected("int8")
 }
 
 func (unsafeCodec) VisitInt16(v int16) error {
-	return errors.New("unexpected int16")
+	return unexpected("int16")
 }
 
 func (unsafeCodec) VisitInt32(v int32) error {
-	return errors.New("unexpected int32")
+	return unexpected("int32")
 }
 
 func (unsafeCodec) VisitInt64(v int64) error {
-	return errors.New("unexpected int64")
+	return unexpected("int64")
 }
 
 func (unsafeCodec) VisitUint(v uint) error {
-	return errors.New("unexpected uint")
+	return unexpected("uint")
 }
 
 func (unsafeCodec) VisitUint8(v uint8) error {
-	return errors.New("unexpected uint8")
+	return unexpected("uint8")
 }
 
 func (unsafeCodec) VisitUint16(v uint16) error {
-	return errors.New("unexpected uint16")
+	return unexpected("uint16")
 }
 
 func (unsafeCodec) VisitUint32(v uint32) error {
-	return errors.New("unexpected uint32")
+	return unexpected("uint32")
 }
 
 func (unsafeCodec) VisitUint64(v uint64) error {
-	return errors.New("unexpected uint64")
+	return unexpected("uint64")
 }
 
 func (unsafeCodec) VisitUintptr(v uintptr) error {
-	return errors.New("unexpected uintptr")
+	return unexpected("uintptr")
 }
 
 func (unsafeCodec) VisitFloat32(v float32) error {
-	return errors.New("unexpected float32")
+	return unexpected("float32")
 }
 
 func (unsafeCodec) VisitFloat64(v float64) error {
-	return errors.New("unexpected float64")
+	return unexpected("float64")
 }
 
 func (unsafeCodec) VisitComplex64(v complex64) error {
-	return errors.New("unexpected complex64")
+	return unexpected("complex64")
 }
 
 func (unsafeCodec) VisitComplex128(v complex128) error {
-	return errors.New("unexpected complex128")
+	return unexpected("complex128")
 }
 
 func (unsafeCodec) VisitString(v string) error {
-	return errors.New("unexpected string")
+	return unexpected("string")
 }
 
 func (unsafeCodec) VisitBytes(v []byte) error {
-	return errors.New("unexpected bytes")
+	return unexpected("bytes")
 }
 
 func (unsafeCodec) VisitElem(d ElemDecoder) error {
-	return errors.New("unexpected elem")
+	return unexpected("elem")
 }
 
 func (unsafeCodec) VisitSeq(d SeqDecoder) error {
-	return errors.New("unexpected sequence")
+	return unexpected("sequence")
 }
 
 func (unsafeCodec) VisitMap(d MapDecoder) error {
-	return errors.New("unexpected map")
+	return unexpected("map")
+}
+
+func (unsafeCodec) VisitVariant(d VariantDecoder) error {
+	return unexpected("variant")
 }
 
 type nilCodec struct {
@@ -151,15 +154,29 @@ func (c boolCodec) Serialize(e Encoder) error {
 	return e.EncodeBool(*(*bool)(c.value))
 }
 
-type intCodec struct{ unsafeCodec }
+// numberCodec implements the numeric visit methods for the reflection codecs. Any numeric value is accepted so long as
+// it can be converted to T without overflow or loss of a fractional part. Floats are rounded to the nearest representable
+// value, but integers must be represented exactly.
+type numberCodec[T number] struct{ unsafeCodec }
 
-func (c intCodec) new(v unsafe.Pointer) codec {
-	return intCodec{unsafeCodec: unsafeCodec{value: v}}
-}
+func (c numberCodec[T]) VisitInt(v int) error         { return setInt((*T)(c.value), int64(v)) }
+func (c numberCodec[T]) VisitInt8(v int8) error       { return setInt((*T)(c.value), int64(v)) }
+func (c numberCodec[T]) VisitInt16(v int16) error     { return setInt((*T)(c.value), int64(v)) }
+func (c numberCodec[T]) VisitInt32(v int32) error     { return setInt((*T)(c.value), int64(v)) }
+func (c numberCodec[T]) VisitInt64(v int64) error     { return setInt((*T)(c.value), v) }
+func (c numberCodec[T]) VisitUint(v uint) error       { return setUint((*T)(c.value), uint64(v)) }
+func (c numberCodec[T]) VisitUint8(v uint8) error     { return setUint((*T)(c.value), uint64(v)) }
+func (c numberCodec[T]) VisitUint16(v uint16) error   { return setUint((*T)(c.value), uint64(v)) }
+func (c numberCodec[T]) VisitUint32(v uint32) error   { return setUint((*T)(c.value), uint64(v)) }
+func (c numberCodec[T]) VisitUint64(v uint64) error   { return setUint((*T)(c.value), v) }
+func (c numberCodec[T]) VisitUintptr(v uintptr) error { return setUint((*T)(c.value), uint64(v)) }
+func (c numberCodec[T]) VisitFloat32(v float32) error { return setFloat((*T)(c.value), float64(v)) }
+func (c numberCodec[T]) VisitFloat64(v float64) error { return setFloat((*T)(c.value), v) }
 
-func (c intCodec) VisitInt(v int) error {
-	*(*int)(c.value) = v
-	return nil
+type intCodec struct{ numberCodec[int] }
+
+func (c intCodec) new(v unsafe.Pointer) codec {
+	return intCodec{numberCodec[int]{unsafeCodec{value: v}}}
 }
 
 func (c intCodec) Deserialize(d Decoder) error {
@@ -170,15 +187,10 @@ func (c intCodec) Serialize(e Encoder) error {
 	return e.EncodeInt(*(*int)(c.value))
 }
 
-type int8Codec struct{ unsafeCodec }
+type int8Codec struct{ numberCodec[int8] }
 
 func (c int8Codec) new(v unsafe.Pointer) codec {
-	return int8Codec{unsafeCodec: unsafeCodec{value: v}}
-}
-
-func (c int8Codec) VisitInt8(v int8) error {
-	*(*int8)(c.value) = v
-	return nil
+	return int8Codec{numberCodec[int8]{unsafeCodec{value: v}}}
 }
 
 func (c int8Codec) Deserialize(d Decoder) error {
@@ -189,15 +201,10 @@ func (c int8Codec) Serialize(e Encoder) error {
 	return e.EncodeInt8(*(*int8)(c.value))
 }
 
-type int16Codec struct{ unsafeCodec }
+type int16Codec struct{ numberCodec[int16] }
 
 func (c int16Codec) new(v unsafe.Pointer) codec {
-	return int16Codec{unsafeCodec: unsafeCodec{value: v}}
-}
-
-func (c int16Codec) VisitInt16(v int16) error {
-	*(*int16)(c.value) = v
-	return nil
+	return int16Codec{numberCodec[int16]{unsafeCodec{value: v}}}
 }
 
 func (c int16Codec) Deserialize(d Decoder) error {
@@ -208,15 +215,10 @@ func (c int16Codec) Serialize(e Encoder) error {
 	return e.EncodeInt16(*(*int16)(c.value))
 }
 
-type int32Codec struct{ unsafeCodec }
+type int32Codec struct{ numberCodec[int32] }
 
 func (c int32Codec) new(v unsafe.Pointer) codec {
-	return int32Codec{unsafeCodec: unsafeCodec{value: v}}
-}
-
-func (c int32Codec) VisitInt32(v int32) error {
-	*(*int32)(c.value) = v
-	return nil
+	return int32Codec{numberCodec[int32]{unsafeCodec{value: v}}}
 }
 
 func (c int32Codec) Deserialize(d Decoder) error {
@@ -227,15 +229,10 @@ func (c int32Codec) Serialize(e Encoder) error {
 	return e.EncodeInt32(*(*int32)(c.value))
 }
 
-type int64Codec struct{ unsafeCodec }
+type int64Codec struct{ numberCodec[int64] }
 
 func (c int64Codec) new(v unsafe.Pointer) codec {
-	return int64Codec{unsafeCodec: unsafeCodec{value: v}}
-}
-
-func (c int64Codec) VisitInt64(v int64) error {
-	*(*int64)(c.value) = v
-	return nil
+	return int64Codec{numberCodec[int64]{unsafeCodec{value: v}}}
 }
 
 func (c int64Codec) Deserialize(d Decoder) error {
@@ -246,15 +243,10 @@ func (c int64Codec) Serialize(e Encoder) error {
 	return e.EncodeInt64(*(*int64)(c.value))
 }
 
-type uintCodec struct{ unsafeCodec }
+type uintCodec struct{ numberCodec[uint] }
 
 func (c uintCodec) new(v unsafe.Pointer) codec {
-	return uintCodec{unsafeCodec: unsafeCodec{value: v}}
-}
-
-func (c uintCodec) VisitUint(v uint) error {
-	*(*uint)(c.value) = v
-	return nil
+	return uintCodec{numberCodec[uint]{unsafeCodec{value: v}}}
 }
 
 func (c uintCodec) Deserialize(d Decoder) error {
@@ -265,15 +257,10 @@ func (c uintCodec) Serialize(e Encoder) error {
 	return e.EncodeUint(*(*uint)(c.value))
 }
 
-type uint8Codec struct{ unsafeCodec }
+type uint8Codec struct{ numberCodec[uint8] }
 
 func (c uint8Codec) new(v unsafe.Pointer) codec {
-	return uint8Codec{unsafeCodec: unsafeCodec{value: v}}
-}
-
-func (c uint8Codec) VisitUint8(v uint8) error {
-	*(*uint8)(c.value) = v
-	return nil
+	return uint8Codec{numberCodec[uint8]{unsafeCodec{value: v}}}
 }
 
 func (c uint8Codec) Deserialize(d Decoder) error {
@@ -284,15 +271,10 @@ func (c uint8Codec) Serialize(e Encoder) error {
 	return e.EncodeUint8(*(*uint8)(c.value))
 }
 
-type uint16Codec struct{ unsafeCodec }
+type uint16Codec struct{ numberCodec[uint16] }
 
 func (c uint16Codec) new(v unsafe.Pointer) codec {
-	return uint16Codec{unsafeCodec: unsafeCodec{value: v}}
-}
-
-func (c uint16Codec) VisitUint16(v uint16) error {
-	*(*uint16)(c.value) = v
-	return nil
+	return uint16Codec{numberCodec[uint16]{unsafeCodec{value: v}}}
 }
 
 func (c uint16Codec) Deserialize(d Decoder) error {
@@ -303,15 +285,10 @@ func (c uint16Codec) Serialize(e Encoder) error {
 	return e.EncodeUint16(*(*uint16)(c.value))
 }
 
-type uint32Codec struct{ unsafeCodec }
+type uint32Codec struct{ numberCodec[uint32] }
 
 func (c uint32Codec) new(v unsafe.Pointer) codec {
-	return uint32Codec{unsafeCodec: unsafeCodec{value: v}}
-}
-
-func (c uint32Codec) VisitUint32(v uint32) error {
-	*(*uint32)(c.value) = v
-	return nil
+	return uint32Codec{numberCodec[uint32]{unsafeCodec{value: v}}}
 }
 
 func (c uint32Codec) Deserialize(d Decoder) error {
@@ -322,15 +299,10 @@ func (c uint32Codec) Serialize(e Encoder) error {
 	return e.EncodeUint32(*(*uint32)(c.value))
 }
 
-type uint64Codec struct{ unsafeCodec }
+type uint64Codec struct{ numberCodec[uint64] }
 
 func (c uint64Codec) new(v unsafe.Pointer) codec {
-	return uint64Codec{unsafeCodec: unsafeCodec{value: v}}
-}
-
-func (c uint64Codec) VisitUint64(v uint64) error {
-	*(*uint64)(c.value) = v
-	return nil
+	return uint64Codec{numberCodec[uint64]{unsafeCodec{value: v}}}
 }
 
 func (c uint64Codec) Deserialize(d Decoder) error {
@@ -341,15 +313,10 @@ func (c uint64Codec) Serialize(e Encoder) error {
 	return e.EncodeUint64(*(*uint64)(c.value))
 }
 
-type uintptrCodec struct{ unsafeCodec }
+type uintptrCodec struct{ numberCodec[uintptr] }
 
 func (c uintptrCodec) new(v unsafe.Pointer) codec {
-	return uintptrCodec{unsafeCodec: unsafeCodec{value: v}}
-}
-
-func (c uintptrCodec) VisitUintptr(v uintptr) error {
-	*(*uintptr)(c.value) = v
-	return nil
+	return uintptrCodec{numberCodec[uintptr]{unsafeCodec{value: v}}}
 }
 
 func (c uintptrCodec) Deserialize(d Decoder) error {
@@ -360,15 +327,10 @@ func (c uintptrCodec) Serialize(e Encoder) error {
 	return e.EncodeUintptr(*(*uintptr)(c.value))
 }
 
-type float32Codec struct{ unsafeCodec }
+type float32Codec struct{ numberCodec[float32] }
 
 func (c float32Codec) new(v unsafe.Pointer) codec {
-	return float32Codec{unsafeCodec: unsafeCodec{value: v}}
-}
-
-func (c float32Codec) VisitFloat32(v float32) error {
-	*(*float32)(c.value) = v
-	return nil
+	return float32Codec{numberCodec[float32]{unsafeCodec{value: v}}}
 }
 
 func (c float32Codec) Deserialize(d Decoder) error {
@@ -379,15 +341,10 @@ func (c float32Codec) Serialize(e Encoder) error {
 	return e.EncodeFloat32(*(*float32)(c.value))
 }
 
-type float64Codec struct{ unsafeCodec }
+type float64Codec struct{ numberCodec[float64] }
 
 func (c float64Codec) new(v unsafe.Pointer) codec {
-	return float64Codec{unsafeCodec: unsafeCodec{value: v}}
-}
-
-func (c float64Codec) VisitFloat64(v float64) error {
-	*(*float64)(c.value) = v
-	return nil
+	return float64Codec{numberCodec[float64]{unsafeCodec{value: v}}}
 }
 
 func (c float64Codec) Deserialize(d Decoder) error {
@@ -461,6 +418,11 @@ func (c bytesCodec) new(v unsafe.Pointer) codec {
 	return bytesCodec{unsafeCodec: unsafeCodec{value: v}}
 }
 
+func (c bytesCodec) VisitNil() error {
+	*(*[]byte)(c.value) = nil
+	return nil
+}
+
 func (c bytesCodec) VisitBytes(v []byte) error {
 	*(*[]byte)(c.value) = v
 	return nil
@@ -511,10 +473,11 @@ func (c ptrCodec) Deserialize(d Decoder) error {
 }
 
 func (c ptrCodec) Serialize(e Encoder) error {
-	if (*unsafe.Pointer)(c.value) == nil {
+	p := *(*unsafe.Pointer)(c.value)
+	if p == nil {
 		return e.EncodeNil()
 	}
-	return c.elem.new(c.value).Serialize(e)
+	return e.EncodeElem(reflect.NewAt(c.t.Elem(), p).Elem().Interface(), c.elem.new(p))
 }
 
 type arrayType struct {
@@ -536,12 +499,12 @@ func (c arrayCodec) new(v unsafe.Pointer) codec {
 }
 
 func (c arrayCodec) VisitSeq(seq SeqDecoder) error {
-	vals := reflect.NewAt(c.t, c.value)
+	vals := reflect.NewAt(c.t, c.value).Elem()
 	for i := 0; i < c.n; i++ {
 		elem := vals.Index(i).Addr()
 		ok, err := seq.NextElement(elem.Interface(), c.elem.new(elem.UnsafePointer()))
 		if err != nil {
-			return err
+			return annotateError(err, "", indexElem(i), c.t.Elem())
 		}
 		if !ok {
 			return nil
@@ -550,10 +513,10 @@ func (c arrayCodec) VisitSeq(seq SeqDecoder) error {
 
 	discard := reflect.New(c.t.Elem())
 	discardCodec := c.elem.new(discard.UnsafePointer())
-	for {
+	for i := c.n; ; i++ {
 		ok, err := seq.NextElement(discard.Interface(), discardCodec)
 		if err != nil {
-			return err
+			return annotateError(err, "", indexElem(i), c.t.Elem())
 		}
 		if !ok {
 			return nil
@@ -566,7 +529,7 @@ func (c arrayCodec) Deserialize(d Decoder) error {
 }
 
 func (c arrayCodec) Serialize(e Encoder) error {
-	vals := reflect.NewAt(c.t, c.value)
+	vals := reflect.NewAt(c.t, c.value).Elem()
 
 	enc, err := e.EncodeSeq(vals.Len())
 	if err != nil {
@@ -599,6 +562,11 @@ func (c sliceCodec) new(v unsafe.Pointer) codec {
 	}
 }
 
+func (c sliceCodec) VisitNil() error {
+	*(*slice)(c.value) = slice{}
+	return nil
+}
+
 func (c sliceCodec) VisitSeq(seq SeqDecoder) error {
 	s := (*slice)(c.value)
 	for {
@@ -617,7 +585,7 @@ func (c sliceCodec) VisitSeq(seq SeqDecoder) error {
 
 		ok, err := seq.NextElement(elem.Interface(), c.elem.new(p))
 		if err != nil {
-			return err
+			return annotateError(err, "", indexElem(s.len), c.t.Elem())
 		}
 		if !ok {
 			return nil
@@ -669,6 +637,11 @@ func (c mapCodec) new(v unsafe.Pointer) codec {
 	}
 }
 
+func (c mapCodec) VisitNil() error {
+	*(*unsafe.Pointer)(c.value) = nil
+	return nil
+}
+
 func (c mapCodec) VisitMap(map_ MapDecoder) error {
 	var m reflect.Value
 	if len, ok := map_.Size(); ok {
@@ -685,7 +658,7 @@ func (c mapCodec) VisitMap(map_ MapDecoder) error {
 		k.Set(c.kz)
 		v.Set(c.vz)
 
-		ok, err := map_.NextKey(k.Interface(), c.kc.new(kptr))
+		ok, err := map_.NextKey(k.Addr().Interface(), c.kc.new(kptr))
 		if err != nil {
 			return err
 		}
@@ -694,8 +667,8 @@ func (c mapCodec) VisitMap(map_ MapDecoder) error {
 			return nil
 		}
 
-		if err = map_.NextValue(v.Interface(), c.vc.new(vptr)); err != nil {
-			return err
+		if err = map_.NextValue(v.Addr().Interface(), c.vc.new(vptr)); err != nil {
+			return annotateError(err, "", fmt.Sprint(k.Interface()), c.vt)
 		}
 		m.SetMapIndex(k, v)
 	}
@@ -706,8 +679,7 @@ func (c mapCodec) Deserialize(d Decoder) error {
 }
 
 func (c mapCodec) Serialize(e Encoder) error {
-	p := c.value
-	m := reflect.NewAt(c.t, unsafe.Pointer(&p)).Elem()
+	m := reflect.NewAt(c.t, c.value).Elem()
 
 	keys := m.MapKeys()
 	c.sortKeys(keys)
@@ -716,12 +688,18 @@ func (c mapCodec) Serialize(e Encoder) error {
 	if err != nil {
 		return err
 	}
-	for _, k := range keys {
-		if err := enc.EncodeKey(k.Interface(), c.kc.new((*iface)(unsafe.Pointer(&k)).ptr)); err != nil {
+
+	k := reflect.New(c.kt).Elem()
+	v := reflect.New(c.vt).Elem()
+	kptr := k.Addr().UnsafePointer()
+	vptr := v.Addr().UnsafePointer()
+	for _, key := range keys {
+		k.Set(key)
+		if err := enc.EncodeKey(k.Interface(), c.kc.new(kptr)); err != nil {
 			return err
 		}
-		v := m.MapIndex(k)
-		if err := enc.EncodeValue(v.Interface(), c.vc.new((*iface)(unsafe.Pointer(&v)).ptr)); err != nil {
+		v.Set(m.MapIndex(key))
+		if err := enc.EncodeValue(v.Interface(), c.vc.new(vptr)); err != nil {
 			return err
 		}
 	}
@@ -729,13 +707,17 @@ func (c mapCodec) Serialize(e Encoder) error {
 }
 
 type structType struct {
-	name        string
-	fields      []structField
-	fieldsIndex map[string]*structField
-	ficaseIndex map[string]*structField
-	keyset      []byte
-	typ         reflect.Type
-	inlined     bool
+	name         string
+	fields       []structField
+	structFields []StructField // the descriptions of fields, returned by StructFields
+	fieldsIndex  map[string]*structField
+	ficaseIndex  map[string]*structField
+	keyset       []byte
+	typ          reflect.Type
+	inlined      bool
+	tracked      bool  // true if any field is required or has a default
+	defaulter    bool  // true if the struct implements Defaulter
+	err          error // the first error in the struct's field tags, which is returned whenever the struct is used
 }
 
 type structField struct {
@@ -744,14 +726,20 @@ type structField struct {
 	empty     emptyFunc
 	tag       bool
 	omitempty bool
+	required  bool
+	default_  defaultFunc
+	err       error // the error in the field's tag, if any
 	embedded  *embeddedStructField
 	name      string
+	field     StructField
 	typ       reflect.Type
 	zero      reflect.Value
 	index     int
+	pos       int
 }
 
 type embeddedStructField struct {
+	typ        reflect.Type
 	unexported bool
 	offset     uintptr
 }
@@ -769,6 +757,13 @@ func (c structCodec) new(v unsafe.Pointer) codec {
 }
 
 func (c structCodec) VisitMap(map_ MapDecoder) error {
+	options := GetDecodeOptions(map_)
+
+	var found []bool
+	if c.tracked {
+		found = make([]bool, len(c.fields))
+	}
+
 	var keybuf []byte
 	for {
 		var k string
@@ -777,67 +772,115 @@ func (c structCodec) VisitMap(map_ MapDecoder) error {
 			return err
 		}
 		if !ok {
-			return nil
+			return c.finish(found)
 		}
 
 		var f *structField
 		if len(c.keyset) != 0 {
 			if n := keyset.Lookup(c.keyset, stringBytes(k)); n < len(c.fields) {
 				f = &c.fields[n]
-			} else {
-				f = c.fieldsIndex[k]
 			}
+		} else {
+			f = c.fieldsIndex[k]
 		}
-		if f == nil {
-			// TODO: disallow case-insensitive match
+		if f == nil && !options.CaseSensitive {
 			f = c.ficaseIndex[appendToLower(keybuf[:0], k)]
 		}
 
 		if f == nil {
-			// TODO: disallow unknown fields
-			if err := map_.NextValue(nil, SkipCodec{}); err != nil {
+			if err := SkipUnknownField(map_, options, c.name, k); err != nil {
 				return err
 			}
 			continue
 		}
+		if found != nil {
+			found[f.pos] = true
+		}
 
-		v := unsafe.Pointer(uintptr(c.value) + f.offset)
-		if f.embedded != nil {
-			if p := (*unsafe.Pointer)(v); *p == nil {
-				if f.embedded.unexported {
-					return fmt.Errorf("codec: cannot set embedded pointer to unexported struct: %s", f.typ)
-				}
-				t := reflect.New(f.typ).UnsafePointer()
-				*p = t
-				v = unsafe.Pointer(uintptr(t) + f.embedded.offset)
-			}
+		v, err := c.field(f)
+		if err != nil {
+			return err
 		}
 
 		fv := reflect.NewAt(f.typ, v)
 		if err := map_.NextValue(fv.Interface(), f.codec.new(v)); err != nil {
-			return err
+			return annotateError(err, c.name, f.name, f.typ)
+		}
+	}
+}
+
+// field returns the address of the given field, allocating embedded structs as necessary.
+func (c structCodec) field(f *structField) (unsafe.Pointer, error) {
+	v := unsafe.Pointer(uintptr(c.value) + f.offset)
+	if f.embedded != nil {
+		p := (*unsafe.Pointer)(v)
+		if *p == nil {
+			if f.embedded.unexported {
+				return nil, fmt.Errorf("codec: cannot set embedded pointer to unexported struct: %s", f.embedded.typ)
+			}
+			*p = reflect.New(f.embedded.typ).UnsafePointer()
+		}
+		v = unsafe.Pointer(uintptr(*p) + f.embedded.offset)
+	}
+	return v, nil
+}
+
+// finish is called once all of the keys in a map have been decoded. It checks that all required fields were present,
+// sets missing fields to their defaults, and finally calls SetDefaults if the struct is a Defaulter.
+func (c structCodec) finish(found []bool) error {
+	for i, ok := range found {
+		if ok {
+			continue
+		}
+
+		f := &c.fields[i]
+		if f.required {
+			return &MissingFieldError{Struct: c.name, Field: f.name}
+		}
+		if f.default_ != nil {
+			v, err := c.field(f)
+			if err != nil {
+				return err
+			}
+			if err := f.default_(v); err != nil {
+				return err
+			}
 		}
 	}
+
+	if c.defaulter {
+		reflect.NewAt(c.typ, c.value).Interface().(Defaulter).SetDefaults()
+	}
+	return nil
+}
+
+// StructFields returns descriptions of the struct's fields.
+func (c structCodec) StructFields() []StructField {
+	return c.structFields
 }
 
 func (c structCodec) Deserialize(d Decoder) error {
+	if c.err != nil {
+		return c.err
+	}
 	return d.DecodeStruct(c.name, c)
 }
 
 func (c structCodec) Serialize(e Encoder) error {
+	if c.err != nil {
+		return c.err
+	}
+
 	enc, err := e.EncodeStruct(c.name)
 	if err != nil {
 		return err
 	}
+	fenc, _ := enc.(FieldStructEncoder)
 
 	for i := range c.fields {
 		f := &c.fields[i]
 		v := unsafe.Pointer(uintptr(c.value) + f.offset)
 
-		if f.omitempty && f.empty(v) {
-			continue
-		}
-
 		if f.embedded != nil {
 			p := *(*unsafe.Pointer)(v)
 			if p == nil {
@@ -846,8 +889,17 @@ func (c structCodec) Serialize(e Encoder) error {
 			v = unsafe.Pointer(uintptr(p) + f.embedded.offset)
 		}
 
+		if f.omitempty && f.empty(v) {
+			continue
+		}
+
 		fv := reflect.NewAt(f.typ, v).Elem()
-		if err := enc.EncodeField(f.name, fv.Interface(), f.codec.new(v)); err != nil {
+		if fenc != nil {
+			err = fenc.EncodeStructField(&f.field, fv.Interface(), f.codec.new(v))
+		} else {
+			err = enc.EncodeField(f.name, fv.Interface(), f.codec.new(v))
+		}
+		if err != nil {
 			return err
 		}
 	}
@@ -882,7 +934,7 @@ func (c serializerCodec) new(v unsafe.Pointer) codec {
 	return serializerCodec{
 		unsafeCodec: unsafeCodec{value: v},
 		t:           c.t,
-		next:        c.next,
+		next:        c.next.new(v),
 	}
 }
 
@@ -904,7 +956,7 @@ func (c deserializerCodec) new(v unsafe.Pointer) codec {
 	return deserializerCodec{
 		unsafeCodec: unsafeCodec{value: v},
 		t:           c.t,
-		next:        c.next,
+		next:        c.next.new(v),
 	}
 }
 
@@ -0,0 +1,198 @@
+package csv
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pgavlin/codec/internal/codectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	City string `codec:"city"`
+	Zip  string `codec:"zip"`
+}
+
+type employee struct {
+	ID      int               `codec:"id"`
+	Name    string            `codec:"name"`
+	Salary  float64           `codec:"salary"`
+	Active  bool              `codec:"active"`
+	Home    address           `codec:"home"`
+	Office  *address          `codec:"office"`
+	Manager *string           `codec:"manager"`
+	Badge   []byte            `codec:"badge"`
+	Labels  map[string]string `codec:"labels"`
+}
+
+func TestMarshal(t *testing.T) {
+	ann := "Ann"
+	b, err := Marshal([]employee{
+		{
+			ID:     1,
+			Name:   "Bob, Jr.",
+			Salary: 1.5,
+			Active: true,
+			Home:   address{City: "Oslo", Zip: "0150"},
+			Office: &address{City: "Bergen"},
+			Badge:  []byte("hi"),
+			Labels: map[string]string{"z": "1", "a": "2"},
+		},
+		{
+			ID:      2,
+			Name:    `Say "hi"`,
+			Home:    address{City: "Rome"},
+			Manager: &ann,
+			Labels:  map[string]string{"a": "3"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `id,name,salary,active,home.city,home.zip,office.city,office.zip,manager,badge,labels.a,labels.z
+1,"Bob, Jr.",1.5,true,Oslo,0150,Bergen,,,aGk=,2,1
+2,"Say ""hi""",0,false,Rome,,,,Ann,,3,
+`, string(b))
+
+	// Maps are records whose columns are sorted by key.
+	b, err = Marshal([]map[string]any{{"b": 1, "a": map[string]int{"y": 2, "x": 3}}})
+	require.NoError(t, err)
+	assert.Equal(t, "a.x,a.y,b\n3,2,1\n", string(b))
+
+	// A nil sequence contains no records.
+	b, err = Marshal([]employee(nil))
+	require.NoError(t, err)
+	assert.Empty(t, b)
+
+	_, err = Marshal([]int{1})
+	assert.EqualError(t, err, "csv: records must be structs or maps")
+
+	_, err = Marshal(map[string]any{"a": []int{1}})
+	assert.EqualError(t, err, `csv: field "a" cannot be encoded: sequences cannot be encoded within records`)
+
+	_, err = Marshal([]map[string]int{{"a": 1}, {"b": 2}})
+	assert.EqualError(t, err, `csv: field "b" is not in the header`)
+
+	// Nested structs that are nil in the first record have columns.
+	b, err = Marshal([]employee{{ID: 1}, {ID: 2, Office: &address{City: "Bergen", Zip: "5003"}}})
+	require.NoError(t, err)
+	assert.Equal(t, `id,name,salary,active,home.city,home.zip,office.city,office.zip,manager,badge
+1,,0,false,,,,,,
+2,,0,false,,,Bergen,5003,,
+`, string(b))
+
+	type node struct {
+		Name string `codec:"name"`
+		Next *node  `codec:"next"`
+	}
+	b, err = Marshal([]node{{Name: "a", Next: &node{Name: "b"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "name,next.name,next.next\na,b,\n", string(b))
+}
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.SetDelimiter('\t')
+	require.NoError(t, e.Encode(address{City: "Oslo", Zip: "0150"}))
+	require.NoError(t, e.Encode([]address{{City: "New York", Zip: "10001"}, {City: "a\tb"}}))
+	assert.Equal(t, "city\tzip\nOslo\t0150\nNew York\t10001\n\"a\tb\"\t\n", buf.String())
+}
+
+func TestUnmarshal(t *testing.T) {
+	const doc = `name,home.city,id,office.city,office.zip,manager,badge,labels.a,salary,active,unknown
+"Bob, Jr.",Oslo, 1 ,Bergen,,,aGk=,2,1.5,true,x
+Ann,Rome,2,,,Bob,,,0,false,y
+`
+
+	var employees []employee
+	require.NoError(t, Unmarshal([]byte(doc), &employees))
+	bob := "Bob"
+	assert.Equal(t, []employee{
+		{
+			ID:     1,
+			Name:   "Bob, Jr.",
+			Salary: 1.5,
+			Active: true,
+			Home:   address{City: "Oslo"},
+			Office: &address{City: "Bergen"},
+			Badge:  []byte("hi"),
+			Labels: map[string]string{"a": "2"},
+		},
+		{
+			ID:      2,
+			Name:    "Ann",
+			Home:    address{City: "Rome"},
+			Manager: &bob,
+			Labels:  map[string]string{"a": ""},
+		},
+	}, employees)
+
+	// Records are decoded as maps of strings, and dotted columns as nested maps.
+	var v any
+	require.NoError(t, Unmarshal([]byte("a.x,b\n1,2\n"), &v))
+	assert.Equal(t, []any{map[string]any{"a": map[string]any{"x": "1"}, "b": "2"}}, v)
+
+	// Single values receive the first record.
+	var a address
+	require.NoError(t, Unmarshal([]byte("zip,city\n0150,Oslo\n1000,Rome\n"), &a))
+	assert.Equal(t, address{City: "Oslo", Zip: "0150"}, a)
+	assert.Equal(t, io.EOF, Unmarshal([]byte("zip,city\n"), &a))
+
+	employees = nil
+	err := Unmarshal([]byte("id,name\n1,a\nx,b\n"), &employees)
+	assert.EqualError(t, err, "csv: line 3, column 1: codec: cannot unmarshal string into Go struct field employee[1].id of type int")
+	var derr *DecodeError
+	require.ErrorAs(t, err, &derr)
+	assert.Equal(t, 3, derr.Line)
+
+	d := NewDecoder(strings.NewReader("city,color\nOslo,red\n"))
+	d.DisallowUnknownFields()
+	assert.EqualError(t, d.Decode(&a), `csv: line 2: codec: unknown field "color" in Go struct address`)
+
+	err = Unmarshal([]byte("city,zip\nOslo\n"), &employees)
+	assert.EqualError(t, err, "record on line 2: wrong number of fields")
+}
+
+func TestRoundTrip(t *testing.T) {
+	expected := []employee{
+		{ID: 1, Name: "a\nb", Home: address{City: "Oslo"}, Office: &address{Zip: "1"}, Labels: map[string]string{"k": "v"}},
+		{ID: 2, Salary: -0.25, Labels: map[string]string{"k": ""}},
+	}
+
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.SetDelimiter(';')
+	for _, r := range expected {
+		require.NoError(t, e.Encode(r))
+	}
+
+	d := NewDecoder(&buf)
+	d.SetDelimiter(';')
+	for _, r := range expected {
+		var actual employee
+		require.NoError(t, d.Decode(&actual))
+		assert.Equal(t, r, actual)
+	}
+	var actual employee
+	assert.Equal(t, io.EOF, d.Decode(&actual))
+}
+
+func TestVariant(t *testing.T) {
+	type row struct {
+		Option codectest.Option `codec:"option"`
+	}
+
+	expected := []row{{}}
+	b, err := Marshal(expected)
+	require.NoError(t, err)
+	assert.Equal(t, "option\nNone\n", string(b))
+
+	var actual []row
+	require.NoError(t, Unmarshal(b, &actual))
+	assert.Equal(t, expected, actual)
+
+	_, err = Marshal([]row{{Option: codectest.Option{Valid: true, Value: "x"}}})
+	assert.EqualError(t, err, `csv: field "option" cannot be encoded: variant "Some" has a value`)
+}
@@ -0,0 +1,56 @@
+// Package csv implements the encoding of sequences of records as comma-separated values (RFC 4180), or as values
+// separated by other delimiters such as tabs.
+//
+// Each record is a struct or map. The first record determines the header, which names the record's fields; nested
+// structs and maps are flattened, and their fields are named by their paths joined with dots (e.g. "address.city").
+// Scalars are written as text, byte slices as base64-encoded text, and nil values as empty fields. Sequences cannot be
+// encoded within records.
+//
+// When decoding, the header's columns are mapped to struct fields by the struct codec, and dotted column names are
+// decoded into nested structs and maps. Fields are decoded from text according to the types of their destinations.
+package csv
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pgavlin/codec"
+)
+
+// Format is the codec format for CSV.
+var Format = codec.NewFormat("csv")
+
+// Marshal returns the CSV encoding of x, which must be a sequence of records or a single record.
+func Marshal(x any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(x); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the records in b into the value pointed to by x. If x points to a sequence, all of the records are
+// decoded into the sequence; otherwise, x receives the first record, and Unmarshal returns io.EOF if there are no
+// records.
+func Unmarshal(b []byte, x any) error {
+	return NewDecoder(bytes.NewReader(b)).Decode(x)
+}
+
+// A DecodeError describes an error that occurred while decoding a record, such as an UnmarshalTypeError, along with
+// the position of the field being decoded. If the error does not pertain to a single field, its column is zero.
+type DecodeError struct {
+	Line   int   // line of the record or field, starting at 1
+	Column int   // column of the field, starting at 1
+	Err    error // the underlying error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("csv: line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("csv: line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
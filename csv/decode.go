@@ -0,0 +1,385 @@
+package csv
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pgavlin/codec"
+)
+
+// A column is a column of the header or, if its index is negative, a group of columns whose names share a dotted
+// prefix.
+type column struct {
+	name    string
+	index   int
+	columns []*column
+}
+
+// parseHeader returns the columns named by a header. Columns whose names contain dots are grouped by their prefixes.
+func parseHeader(names []string) *column {
+	root := &column{index: -1}
+	for i, name := range names {
+		c := root
+		path := strings.Split(name, ".")
+		for _, p := range path[:len(path)-1] {
+			c = c.group(p)
+		}
+		c.columns = append(c.columns, &column{name: path[len(path)-1], index: i})
+	}
+	return root
+}
+
+// group returns the group of columns with the given name, adding it if necessary.
+func (c *column) group(name string) *column {
+	for _, g := range c.columns {
+		if g.index < 0 && g.name == name {
+			return g
+		}
+	}
+	g := &column{name: name, index: -1}
+	c.columns = append(c.columns, g)
+	return g
+}
+
+// A Decoder reads records from an input stream. The first record of the stream is its header, which names the
+// columns of the records that follow.
+type Decoder struct {
+	r       *csv.Reader
+	options codec.DecodeOptions
+	header  *column // nil until the header has been read
+
+	// The position of the innermost record or field that could not be decoded, if any. Errors are not wrapped in
+	// DecodeErrors until they reach Decode so that the codecs of enclosing values can add path information to them.
+	failed    bool
+	errLine   int
+	errColumn int
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: csv.NewReader(r)}
+}
+
+// SetDelimiter sets the field delimiter, which defaults to a comma. The delimiter must be a valid rune other than \r,
+// \n, or the Unicode replacement character, and must not be a double quote.
+func (d *Decoder) SetDelimiter(r rune) {
+	d.r.Comma = r
+}
+
+// DisallowUnknownFields causes the Decoder to return an error when the destination is a struct and the input contains
+// columns which do not match any of the struct's fields.
+func (d *Decoder) DisallowUnknownFields() {
+	d.options.DisallowUnknownFields = true
+}
+
+// Decode reads records from its input and stores them in the value pointed to by v. If v points to a sequence, all of
+// the remaining records are decoded into the sequence; otherwise, Decode reads the next record, and returns io.EOF at
+// the end of the input.
+func (d *Decoder) Decode(v any) error {
+	if d.header == nil {
+		names, err := d.r.Read()
+		switch {
+		case err == io.EOF:
+			d.header = parseHeader(nil)
+		case err != nil:
+			return err
+		default:
+			d.header = parseHeader(names)
+		}
+	}
+	d.failed = false
+	if err := codec.GetDeserializer(v, Format).Deserialize(d); err != nil {
+		if d.failed {
+			err = &DecodeError{Line: d.errLine, Column: d.errColumn, Err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// fail records the position of a record or field that could not be decoded unless a field nested within it has
+// already failed.
+func (d *Decoder) fail(line, column int) {
+	if !d.failed {
+		d.failed, d.errLine, d.errColumn = true, line, column
+	}
+}
+
+// decodeRecord reads the next record and decodes it using ds.
+func (d *Decoder) decodeRecord(ds func(d codec.Decoder) error) error {
+	record, err := d.r.Read()
+	if err != nil {
+		return err
+	}
+	if err := ds(fieldDecoder{d: d, record: record, col: d.header}); err != nil {
+		line, _ := d.r.FieldPos(0)
+		d.fail(line, 0)
+		return err
+	}
+	return nil
+}
+
+// Format returns the CSV format.
+func (d *Decoder) Format() *codec.Format {
+	return Format
+}
+
+// DecodeAny decodes the remaining records as a sequence.
+func (d *Decoder) DecodeAny(v codec.Visitor) error {
+	return v.VisitSeq(&SeqDecoder{d: d})
+}
+
+func (d *Decoder) DecodeNil(v codec.Visitor) error        { return d.DecodeAny(v) }
+func (d *Decoder) DecodeBool(v codec.Visitor) error       { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt(v codec.Visitor) error        { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt8(v codec.Visitor) error       { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt16(v codec.Visitor) error      { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt32(v codec.Visitor) error      { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt64(v codec.Visitor) error      { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint(v codec.Visitor) error       { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint8(v codec.Visitor) error      { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint16(v codec.Visitor) error     { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint32(v codec.Visitor) error     { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint64(v codec.Visitor) error     { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUintptr(v codec.Visitor) error    { return d.DecodeAny(v) }
+func (d *Decoder) DecodeFloat32(v codec.Visitor) error    { return d.DecodeAny(v) }
+func (d *Decoder) DecodeFloat64(v codec.Visitor) error    { return d.DecodeAny(v) }
+func (d *Decoder) DecodeComplex64(v codec.Visitor) error  { return d.DecodeAny(v) }
+func (d *Decoder) DecodeComplex128(v codec.Visitor) error { return d.DecodeAny(v) }
+func (d *Decoder) DecodeString(v codec.Visitor) error     { return d.DecodeAny(v) }
+func (d *Decoder) DecodeBytes(v codec.Visitor) error      { return d.DecodeAny(v) }
+func (d *Decoder) DecodeSeq(v codec.Visitor) error        { return d.DecodeAny(v) }
+
+// DecodeMap decodes the next record as a map.
+func (d *Decoder) DecodeMap(v codec.Visitor) error {
+	return d.decodeRecord(func(d codec.Decoder) error { return d.DecodeMap(v) })
+}
+
+// DecodeStruct decodes the next record as a struct.
+func (d *Decoder) DecodeStruct(name string, v codec.Visitor) error {
+	return d.decodeRecord(func(d codec.Decoder) error { return d.DecodeStruct(name, v) })
+}
+
+func (d *Decoder) DecodeVariant(enum string, variants []string, v codec.Visitor) error {
+	return d.DecodeAny(v)
+}
+
+func (d *Decoder) DecodePtr(v codec.Visitor) error {
+	return v.VisitElem(ElemDecoder{d})
+}
+
+// A fieldDecoder decodes a field of a record or, if its column is a group, the fields of the group as a map.
+type fieldDecoder struct {
+	d      *Decoder
+	record []string
+	col    *column
+}
+
+// text returns the text of a field.
+func (d fieldDecoder) text() (string, bool) {
+	if d.col.index < 0 {
+		return "", false
+	}
+	return d.record[d.col.index], true
+}
+
+// isEmpty returns true if the field or all of the fields in the group are empty.
+func (d fieldDecoder) isEmpty() bool {
+	if s, ok := d.text(); ok {
+		return s == ""
+	}
+	for _, c := range d.col.columns {
+		if !(fieldDecoder{d: d.d, record: d.record, col: c}).isEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+func (d fieldDecoder) Format() *codec.Format {
+	return Format
+}
+
+func (d fieldDecoder) DecodeAny(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		return v.VisitString(s)
+	}
+	return v.VisitMap(&MapDecoder{d: d, columns: d.col.columns})
+}
+
+func (d fieldDecoder) DecodeComplex64(v codec.Visitor) error           { return d.DecodeAny(v) }
+func (d fieldDecoder) DecodeComplex128(v codec.Visitor) error          { return d.DecodeAny(v) }
+func (d fieldDecoder) DecodeInt(v codec.Visitor) error                 { return d.decodeInt(v) }
+func (d fieldDecoder) DecodeInt8(v codec.Visitor) error                { return d.decodeInt(v) }
+func (d fieldDecoder) DecodeInt16(v codec.Visitor) error               { return d.decodeInt(v) }
+func (d fieldDecoder) DecodeInt32(v codec.Visitor) error               { return d.decodeInt(v) }
+func (d fieldDecoder) DecodeInt64(v codec.Visitor) error               { return d.decodeInt(v) }
+func (d fieldDecoder) DecodeUint(v codec.Visitor) error                { return d.decodeUint(v) }
+func (d fieldDecoder) DecodeUint8(v codec.Visitor) error               { return d.decodeUint(v) }
+func (d fieldDecoder) DecodeUint16(v codec.Visitor) error              { return d.decodeUint(v) }
+func (d fieldDecoder) DecodeUint32(v codec.Visitor) error              { return d.decodeUint(v) }
+func (d fieldDecoder) DecodeUint64(v codec.Visitor) error              { return d.decodeUint(v) }
+func (d fieldDecoder) DecodeUintptr(v codec.Visitor) error             { return d.decodeUint(v) }
+func (d fieldDecoder) DecodeFloat32(v codec.Visitor) error             { return d.decodeFloat(v) }
+func (d fieldDecoder) DecodeFloat64(v codec.Visitor) error             { return d.decodeFloat(v) }
+func (d fieldDecoder) DecodeSeq(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d fieldDecoder) DecodeMap(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d fieldDecoder) DecodeStruct(name string, v codec.Visitor) error { return d.DecodeAny(v) }
+
+// DecodeNil decodes an empty field as nil.
+func (d fieldDecoder) DecodeNil(v codec.Visitor) error {
+	if d.isEmpty() {
+		return v.VisitNil()
+	}
+	return d.DecodeAny(v)
+}
+
+// decodeInt decodes an integer from text. Text that is not an integer is visited as a string.
+func (d fieldDecoder) decodeInt(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+			return v.VisitInt64(i)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// decodeUint decodes an unsigned integer from text. Text that is not an unsigned integer is visited as a string.
+func (d fieldDecoder) decodeUint(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if u, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64); err == nil {
+			return v.VisitUint64(u)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// decodeFloat decodes a float from text. Text that is not a float is visited as a string.
+func (d fieldDecoder) decodeFloat(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return v.VisitFloat64(f)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeBool decodes a boolean from text. Text that is not a boolean is visited as a string.
+func (d fieldDecoder) DecodeBool(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+			return v.VisitBool(b)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+func (d fieldDecoder) DecodeString(v codec.Visitor) error {
+	return d.DecodeAny(v)
+}
+
+// DecodeBytes decodes a byte slice from base64-encoded text. Empty fields are decoded as nil.
+func (d fieldDecoder) DecodeBytes(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if s == "" {
+			return v.VisitNil()
+		}
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		return v.VisitBytes(b)
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeVariant decodes a unit variant from its name.
+func (d fieldDecoder) DecodeVariant(enum string, variants []string, v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		return v.VisitVariant(VariantDecoder{d: d, name: strings.TrimSpace(s)})
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodePtr decodes a pointer. Empty fields, and groups whose fields are all empty, are decoded as nil.
+func (d fieldDecoder) DecodePtr(v codec.Visitor) error {
+	if d.isEmpty() {
+		return v.VisitNil()
+	}
+	return v.VisitElem(ElemDecoder{d})
+}
+
+type ElemDecoder struct {
+	d codec.Decoder
+}
+
+func (d ElemDecoder) Element(v any, ds codec.Deserializer) error {
+	return ds.Deserialize(d.d)
+}
+
+// A SeqDecoder decodes the remaining records of its input.
+type SeqDecoder struct {
+	d *Decoder
+}
+
+func (d *SeqDecoder) Size() (int, bool) {
+	return 0, false
+}
+
+func (d *SeqDecoder) NextElement(x any, ds codec.Deserializer) (bool, error) {
+	err := d.d.decodeRecord(ds.Deserialize)
+	if err == io.EOF {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// A MapDecoder decodes the fields of a record or group as a map from column names to fields.
+type MapDecoder struct {
+	d       fieldDecoder
+	columns []*column
+}
+
+func (d *MapDecoder) Size() (int, bool) {
+	return len(d.columns), true
+}
+
+func (d *MapDecoder) Options() codec.DecodeOptions {
+	return d.d.d.options
+}
+
+func (d *MapDecoder) NextKey(k any, ds codec.Deserializer) (bool, error) {
+	if len(d.columns) == 0 {
+		return false, nil
+	}
+	key := fieldDecoder{d: d.d.d, record: []string{d.columns[0].name}, col: &column{}}
+	return true, ds.Deserialize(key)
+}
+
+func (d *MapDecoder) NextValue(v any, ds codec.Deserializer) error {
+	c := d.columns[0]
+	d.columns = d.columns[1:]
+
+	err := ds.Deserialize(fieldDecoder{d: d.d.d, record: d.d.record, col: c})
+	if err != nil && c.index >= 0 {
+		d.d.d.fail(d.d.d.r.FieldPos(c.index))
+	}
+	return err
+}
+
+type VariantDecoder struct {
+	d    fieldDecoder
+	name string
+}
+
+func (d VariantDecoder) Variant() string {
+	return d.name
+}
+
+// Value decodes the value of a unit variant.
+func (d VariantDecoder) Value(v any, ds codec.Deserializer) error {
+	return ds.Deserialize(fieldDecoder{d: d.d.d, record: []string{""}, col: &column{}})
+}
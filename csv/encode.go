@@ -0,0 +1,471 @@
+package csv
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pgavlin/codec"
+)
+
+var errNotRecord = errors.New("csv: records must be structs or maps")
+
+// A field is a named field of a record.
+type field struct {
+	name  string
+	value string
+	nil   bool // true if the field holds a nil value
+}
+
+// A record is a flattened struct or map.
+type record struct {
+	fields []field
+}
+
+func (r *record) add(f field) {
+	r.fields = append(r.fields, f)
+}
+
+// An Encoder writes records to an output stream. The header is derived from the first record that the encoder writes
+// and from the type of that record, so that the fields of nested structs that are nil in the first record still have
+// columns; the fields of later records are written to the columns of the same names.
+type Encoder struct {
+	w        *csv.Writer
+	header   map[string]int // the index of each column; nil until the header has been written
+	width    int
+	template *record // the fields of the type of the records being encoded; nil if the type is not a struct
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: csv.NewWriter(w)}
+}
+
+// SetDelimiter sets the field delimiter, which defaults to a comma. The delimiter must be a valid rune other than \r,
+// \n, or the Unicode replacement character, and must not be a double quote.
+func (e *Encoder) SetDelimiter(r rune) {
+	e.w.Comma = r
+}
+
+// Encode writes the CSV encoding of v to the stream. If v is a sequence, each of its elements is written as a record;
+// otherwise, v is written as a single record. The header is written before the first record.
+func (e *Encoder) Encode(v any) error {
+	if e.header == nil {
+		e.template = template(reflect.TypeOf(v))
+	}
+	if err := codec.GetSerializer(v, Format).Serialize(e); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// writeRecord writes a record to the stream. Fields that are not named by the header are an error unless they are nil,
+// and columns that are not present in the record are written as empty fields.
+func (e *Encoder) writeRecord(r *record) error {
+	if e.header == nil {
+		names := e.columns(r)
+		e.header = make(map[string]int, len(names))
+		for i, name := range names {
+			e.header[name] = i
+		}
+		if err := e.w.Write(names); err != nil {
+			return err
+		}
+		e.width = len(names)
+	}
+
+	values := make([]string, e.width)
+	for _, f := range r.fields {
+		i, ok := e.header[f.name]
+		if !ok {
+			if f.nil {
+				continue
+			}
+			return fmt.Errorf("csv: field %q is not in the header", f.name)
+		}
+		values[i] = f.value
+	}
+	return e.w.Write(values)
+}
+
+// columns returns the names of the columns of the header given the first record. The columns of the template are
+// replaced by the fields of the record that they contain, which name the entries of maps and the fields of values of
+// interface type; nil fields of the record that are expanded by the template do not have columns of their own.
+func (e *Encoder) columns(r *record) []string {
+	var names []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	if e.template != nil {
+		for _, t := range e.template.fields {
+			found := false
+			for _, f := range r.fields {
+				if f.name == t.name || strings.HasPrefix(f.name, t.name+".") {
+					add(f.name)
+					found = true
+				}
+			}
+			if !found {
+				add(t.name)
+			}
+		}
+	}
+
+outer:
+	for _, f := range r.fields {
+		if f.nil {
+			for _, name := range names {
+				if strings.HasPrefix(name, f.name+".") {
+					continue outer
+				}
+			}
+		}
+		add(f.name)
+	}
+	return names
+}
+
+// template returns the fields of a record of type t, or of the elements of t if t is a sequence type. Nil pointers to
+// structs are allocated so that their fields are present in the template. The template is nil if the records are not
+// structs or cannot be encoded.
+func template(t reflect.Type) *record {
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	v := reflect.New(t)
+	allocate(v.Elem(), map[reflect.Type]bool{t: true})
+
+	var r record
+	if err := codec.GetSerializer(v.Interface(), Format).Serialize(fieldEncoder{r: &r}); err != nil {
+		return nil
+	}
+	return &r
+}
+
+// allocate allocates the nil pointers to structs that are reachable from the exported fields of the struct v. The
+// types in path are not allocated again so that the fields of recursive types are finite.
+func allocate(v reflect.Value, path map[reflect.Type]bool) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !f.CanSet() {
+			continue
+		}
+		switch {
+		case f.Kind() == reflect.Struct:
+			allocate(f, path)
+		case f.Kind() == reflect.Pointer && f.Type().Elem().Kind() == reflect.Struct && !path[f.Type().Elem()]:
+			t := f.Type().Elem()
+			f.Set(reflect.New(t))
+			path[t] = true
+			allocate(f.Elem(), path)
+			delete(path, t)
+		}
+	}
+}
+
+// record returns an encoder for a single top-level record.
+func (e *Encoder) record() fieldEncoder {
+	return fieldEncoder{r: &record{}, e: e}
+}
+
+// Format returns the CSV format.
+func (e *Encoder) Format() *codec.Format {
+	return Format
+}
+
+// EncodeNil encodes a nil sequence, which contains no records.
+func (e *Encoder) EncodeNil() error {
+	return nil
+}
+
+func (e *Encoder) EncodeElem(v any, s codec.Serializer) error {
+	return s.Serialize(e)
+}
+
+func (e *Encoder) EncodeSeq(len int) (codec.SeqEncoder, error) {
+	return &SeqEncoder{e: e}, nil
+}
+
+func (e *Encoder) EncodeBool(v bool) error             { return errNotRecord }
+func (e *Encoder) EncodeInt(v int) error               { return errNotRecord }
+func (e *Encoder) EncodeInt8(v int8) error             { return errNotRecord }
+func (e *Encoder) EncodeInt16(v int16) error           { return errNotRecord }
+func (e *Encoder) EncodeInt32(v int32) error           { return errNotRecord }
+func (e *Encoder) EncodeInt64(v int64) error           { return errNotRecord }
+func (e *Encoder) EncodeUint(v uint) error             { return errNotRecord }
+func (e *Encoder) EncodeUint8(v uint8) error           { return errNotRecord }
+func (e *Encoder) EncodeUint16(v uint16) error         { return errNotRecord }
+func (e *Encoder) EncodeUint32(v uint32) error         { return errNotRecord }
+func (e *Encoder) EncodeUint64(v uint64) error         { return errNotRecord }
+func (e *Encoder) EncodeUintptr(v uintptr) error       { return errNotRecord }
+func (e *Encoder) EncodeFloat32(v float32) error       { return errNotRecord }
+func (e *Encoder) EncodeFloat64(v float64) error       { return errNotRecord }
+func (e *Encoder) EncodeComplex64(v complex64) error   { return errNotRecord }
+func (e *Encoder) EncodeComplex128(v complex128) error { return errNotRecord }
+func (e *Encoder) EncodeString(v string) error         { return errNotRecord }
+func (e *Encoder) EncodeBytes(v []byte) error          { return errNotRecord }
+
+func (e *Encoder) EncodeMap(len int) (codec.MapEncoder, error) {
+	return e.record().EncodeMap(len)
+}
+
+func (e *Encoder) EncodeStruct(name string) (codec.StructEncoder, error) {
+	return e.record().EncodeStruct(name)
+}
+
+func (e *Encoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	return nil, errNotRecord
+}
+
+// A SeqEncoder writes each element of a sequence as a record.
+type SeqEncoder struct {
+	e *Encoder
+}
+
+func (e *SeqEncoder) Close() error {
+	return nil
+}
+
+func (e *SeqEncoder) EncodeElement(x any, ser codec.Serializer) error {
+	return ser.Serialize(e.e.record())
+}
+
+// A fieldEncoder encodes a value into the fields of a record. Scalars are encoded as single fields, and the fields of
+// structs and maps are flattened into the record with names that are prefixed by the name of the value's field.
+type fieldEncoder struct {
+	r    *record
+	name string   // the name of the field; empty if the value is a record
+	e    *Encoder // the encoder to which a record is written when it is closed
+}
+
+func (e fieldEncoder) text(s string) error {
+	if e.name == "" {
+		return errNotRecord
+	}
+	e.r.add(field{name: e.name, value: s})
+	return nil
+}
+
+// prefix returns the prefix for the names of the fields of a struct or map.
+func (e fieldEncoder) prefix() string {
+	if e.name == "" {
+		return ""
+	}
+	return e.name + "."
+}
+
+func (e fieldEncoder) Format() *codec.Format {
+	return Format
+}
+
+// EncodeNil encodes a nil value as an empty field.
+func (e fieldEncoder) EncodeNil() error {
+	if e.name == "" {
+		return errNotRecord
+	}
+	e.r.add(field{name: e.name, nil: true})
+	return nil
+}
+
+func (e fieldEncoder) EncodeBool(v bool) error {
+	return e.text(strconv.FormatBool(v))
+}
+
+func (e fieldEncoder) EncodeInt(v int) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e fieldEncoder) EncodeInt8(v int8) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e fieldEncoder) EncodeInt16(v int16) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e fieldEncoder) EncodeInt32(v int32) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e fieldEncoder) EncodeInt64(v int64) error {
+	return e.text(strconv.FormatInt(v, 10))
+}
+
+func (e fieldEncoder) EncodeUint(v uint) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e fieldEncoder) EncodeUint8(v uint8) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e fieldEncoder) EncodeUint16(v uint16) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e fieldEncoder) EncodeUint32(v uint32) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e fieldEncoder) EncodeUint64(v uint64) error {
+	return e.text(strconv.FormatUint(v, 10))
+}
+
+func (e fieldEncoder) EncodeUintptr(v uintptr) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e fieldEncoder) EncodeFloat32(v float32) error {
+	return e.text(strconv.FormatFloat(float64(v), 'g', -1, 32))
+}
+
+func (e fieldEncoder) EncodeFloat64(v float64) error {
+	return e.text(strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+func (e fieldEncoder) EncodeComplex64(v complex64) error {
+	return &codec.UnsupportedTypeError{Type: reflect.TypeOf(v)}
+}
+
+func (e fieldEncoder) EncodeComplex128(v complex128) error {
+	return &codec.UnsupportedTypeError{Type: reflect.TypeOf(v)}
+}
+
+func (e fieldEncoder) EncodeString(v string) error {
+	return e.text(v)
+}
+
+// EncodeBytes encodes a byte slice as base64-encoded text.
+func (e fieldEncoder) EncodeBytes(v []byte) error {
+	return e.text(base64.StdEncoding.EncodeToString(v))
+}
+
+func (e fieldEncoder) EncodeElem(v any, s codec.Serializer) error {
+	return s.Serialize(e)
+}
+
+func (e fieldEncoder) EncodeSeq(len int) (codec.SeqEncoder, error) {
+	if e.name == "" {
+		return nil, errNotRecord
+	}
+	return nil, fmt.Errorf("csv: field %q cannot be encoded: sequences cannot be encoded within records", e.name)
+}
+
+func (e fieldEncoder) EncodeMap(len int) (codec.MapEncoder, error) {
+	return &MapEncoder{r: e.r, prefix: e.prefix(), e: e.e}, nil
+}
+
+func (e fieldEncoder) EncodeStruct(name string) (codec.StructEncoder, error) {
+	return &StructEncoder{r: e.r, prefix: e.prefix(), e: e.e}, nil
+}
+
+// EncodeVariant encodes a unit variant as its name. Variants with values cannot be encoded.
+func (e fieldEncoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	if e.name == "" {
+		return nil, errNotRecord
+	}
+	return &VariantEncoder{e: e, name: variant}, nil
+}
+
+// A StructEncoder flattens the fields of a struct into a record.
+type StructEncoder struct {
+	r      *record
+	prefix string
+	e      *Encoder // non-nil if the struct is a top-level record
+}
+
+func (e *StructEncoder) Close() error {
+	if e.e != nil {
+		return e.e.writeRecord(e.r)
+	}
+	return nil
+}
+
+func (e *StructEncoder) EncodeField(key string, x any, ser codec.Serializer) error {
+	return ser.Serialize(fieldEncoder{r: e.r, name: e.prefix + key})
+}
+
+// A MapEncoder flattens the entries of a map into a record. The entries are sorted by key when the encoder is closed
+// so that the output does not depend on the iteration order of the map being encoded.
+type MapEncoder struct {
+	r       *record
+	prefix  string
+	e       *Encoder // non-nil if the map is a top-level record
+	key     string
+	entries []mapEntry
+}
+
+type mapEntry struct {
+	key    string
+	fields record
+}
+
+func (e *MapEncoder) Close() error {
+	sort.SliceStable(e.entries, func(i, j int) bool { return e.entries[i].key < e.entries[j].key })
+	for _, entry := range e.entries {
+		e.r.fields = append(e.r.fields, entry.fields.fields...)
+	}
+	if e.e != nil {
+		return e.e.writeRecord(e.r)
+	}
+	return nil
+}
+
+func (e *MapEncoder) EncodeKey(x any, ser codec.Serializer) error {
+	var k record
+	if err := ser.Serialize(fieldEncoder{r: &k, name: "key"}); err != nil {
+		return err
+	}
+	if len(k.fields) != 1 || k.fields[0].name != "key" || k.fields[0].nil {
+		return errors.New("csv: map keys must be scalars")
+	}
+	e.key = k.fields[0].value
+	return nil
+}
+
+func (e *MapEncoder) EncodeValue(x any, ser codec.Serializer) error {
+	entry := mapEntry{key: e.key}
+	if err := ser.Serialize(fieldEncoder{r: &entry.fields, name: e.prefix + e.key}); err != nil {
+		return err
+	}
+	e.entries = append(e.entries, entry)
+	return nil
+}
+
+type VariantEncoder struct {
+	e    fieldEncoder
+	name string
+}
+
+func (e *VariantEncoder) Close() error {
+	return e.e.text(e.name)
+}
+
+func (e *VariantEncoder) EncodeValue(x any, ser codec.Serializer) error {
+	return fmt.Errorf("csv: field %q cannot be encoded: variant %q has a value", e.e.name, e.name)
+}
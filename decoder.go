@@ -1,7 +1,7 @@
 package codec
 
 import (
-	"errors"
+	"reflect"
 )
 
 type Visitor interface {
@@ -24,8 +24,10 @@ type Visitor interface {
 	VisitComplex128(v complex128) error
 	VisitString(v string) error
 	VisitBytes(v []byte) error
+	VisitElem(d ElemDecoder) error
 	VisitSeq(d SeqDecoder) error
 	VisitMap(d MapDecoder) error
+	VisitVariant(d VariantDecoder) error
 }
 
 type Decoder interface {
@@ -48,109 +50,221 @@ type Decoder interface {
 	DecodeComplex128(v Visitor) error
 	DecodeString(v Visitor) error
 	DecodeBytes(v Visitor) error
+	DecodePtr(v Visitor) error
 	DecodeSeq(v Visitor) error
 	DecodeMap(v Visitor) error
 	DecodeStruct(name string, v Visitor) error
+	DecodeVariant(enum string, variants []string, v Visitor) error
 	DecodeAny(v Visitor) error
 }
 
+type ElemDecoder interface {
+	Element(v any, de Deserializer) error
+}
+
 type SeqDecoder interface {
 	Size() (int, bool)
-	NextElement(de Deserializer) (bool, error)
+	NextElement(v any, de Deserializer) (bool, error)
 }
 
 type MapDecoder interface {
 	Size() (int, bool)
-	NextKey(de Deserializer) (bool, error)
-	NextValue(de Deserializer) error
+	NextKey(k any, de Deserializer) (bool, error)
+	NextValue(v any, de Deserializer) error
+}
+
+// A VariantDecoder decodes a variant of a sum type (an enum). Variant returns the name of the variant, and Value decodes
+// its value. Value may be called at most once, and need not be called for unit variants. If the variant has no value,
+// Value visits nil.
+type VariantDecoder interface {
+	Variant() string
+	Value(v any, de Deserializer) error
+}
+
+// A StructVisitor is a Visitor for a struct type that describes the struct's fields. The visitors passed to
+// DecodeStruct by the reflection-based struct codec and by the struct codecs generated by codecgen implement
+// StructVisitor. Formats that do not identify fields by their keys can use the descriptions to map the fields in their
+// input to keys.
+type StructVisitor interface {
+	Visitor
+
+	StructFields() []StructField
+}
+
+// A Number is a number literal that has not been converted to a Go numeric type, e.g. a json.Number.
+type Number interface {
+	String() string
+	Float64() (float64, error)
+	Int64() (int64, error)
+}
+
+// A NumberVisitor is a Visitor that accepts number literals. Decoders that can preserve the text of numbers, e.g. the
+// JSON decoder when its UseNumber flag is set, call VisitNumber instead of the numeric Visit methods if the visitor
+// implements NumberVisitor. AnyCodec implements NumberVisitor by storing the number as-is.
+type NumberVisitor interface {
+	Visitor
+
+	VisitNumber(n Number) error
+}
+
+// DecodeOptions control how structs are decoded from maps.
+type DecodeOptions struct {
+	// DisallowUnknownFields causes an UnknownFieldError to be returned when a map contains a key that does not match
+	// any of the fields of the struct being decoded.
+	DisallowUnknownFields bool
+
+	// CaseSensitive disables the case-insensitive matching of map keys to struct fields.
+	CaseSensitive bool
+}
+
+// An OptionsMapDecoder is a MapDecoder that supplies options for decoding structs. Decoders that support decode options
+// should return OptionsMapDecoders from their DecodeMap and DecodeStruct methods.
+type OptionsMapDecoder interface {
+	MapDecoder
+
+	Options() DecodeOptions
+}
+
+// GetDecodeOptions returns the decode options supplied by the given map decoder. If the decoder does not supply any
+// options, the zero value is returned.
+func GetDecodeOptions(d MapDecoder) DecodeOptions {
+	if d, ok := d.(OptionsMapDecoder); ok {
+		return d.Options()
+	}
+	return DecodeOptions{}
+}
+
+// SkipUnknownField skips the value for the key k of the named struct, which does not match any of the struct's fields.
+// If the options disallow unknown fields, SkipUnknownField returns an UnknownFieldError instead.
+func SkipUnknownField(d MapDecoder, options DecodeOptions, structName, k string) error {
+	if options.DisallowUnknownFields {
+		return &UnknownFieldError{Struct: structName, Field: k}
+	}
+	return d.NextValue(nil, SkipCodec{})
+}
+
+// FoldKey returns the case-folded form of the struct field key k. When decoding is not case-sensitive, a key matches
+// a field if the two fold to the same string. FoldKey is used by the struct codecs generated by codecgen.
+func FoldKey(k string) string {
+	return appendToLower(nil, k)
+}
+
+// DecodeField decodes the value for the named field of the named struct from the given map decoder. Errors returned
+// while decoding the value are annotated with the field's path and Go type. DecodeField is used by the struct codecs
+// generated by codecgen.
+func DecodeField[T any](d MapDecoder, structName, field string, v *T, de Deserializer) error {
+	if err := d.NextValue(v, de); err != nil {
+		return annotateError(err, structName, field, reflect.TypeOf(v).Elem())
+	}
+	return nil
+}
+
+// A Defaulter sets default values for the fields of a struct. The struct codecs, including those generated by codecgen,
+// call SetDefaults after a struct has been decoded from a map and any defaults given by struct tags have been applied.
+type Defaulter interface {
+	SetDefaults()
 }
 
 type Deserializer interface {
 	Deserialize(decoder Decoder) error
 }
 
+// unexpected returns an UnmarshalTypeError for an unexpected value of the given kind. The Go type of the error is set
+// by the enclosing codec.
+func unexpected(value string) error {
+	return &UnmarshalTypeError{Value: value}
+}
+
 type DefaultVisitor struct{}
 
 func (DefaultVisitor) VisitNil() error {
-	return errors.New("unexpected nil")
+	return unexpected("nil")
 }
 
 func (DefaultVisitor) VisitBool(v bool) error {
-	return errors.New("unexpected bool")
+	return unexpected("bool")
 }
 
 func (DefaultVisitor) VisitInt(v int) error {
-	return errors.New("unexpected int")
+	return unexpected("int")
 }
 
 func (DefaultVisitor) VisitInt8(v int8) error {
-	return errors.New("unexpected int8")
+	return unexpected("int8")
 }
 
 func (DefaultVisitor) VisitInt16(v int16) error {
-	return errors.New("unexpected int16")
+	return unexpected("int16")
 }
 
 func (DefaultVisitor) VisitInt32(v int32) error {
-	return errors.New("unexpected int32")
+	return unexpected("int32")
 }
 
 func (DefaultVisitor) VisitInt64(v int64) error {
-	return errors.New("unexpected int64")
+	return unexpected("int64")
 }
 
 func (DefaultVisitor) VisitUint(v uint) error {
-	return errors.New("unexpected uint")
+	return unexpected("uint")
 }
 
 func (DefaultVisitor) VisitUint8(v uint8) error {
-	return errors.New("unexpected uint8")
+	return unexpected("uint8")
 }
 
 func (DefaultVisitor) VisitUint16(v uint16) error {
-	return errors.New("unexpected uint16")
+	return unexpected("uint16")
 }
 
 func (DefaultVisitor) VisitUint32(v uint32) error {
-	return errors.New("unexpected uint32")
+	return unexpected("uint32")
 }
 
 func (DefaultVisitor) VisitUint64(v uint64) error {
-	return errors.New("unexpected uint64")
+	return unexpected("uint64")
 }
 
 func (DefaultVisitor) VisitUintptr(v uintptr) error {
-	return errors.New("unexpected uintptr")
+	return unexpected("uintptr")
 }
 
 func (DefaultVisitor) VisitFloat32(v float32) error {
-	return errors.New("unexpected float32")
+	return unexpected("float32")
 }
 
 func (DefaultVisitor) VisitFloat64(v float64) error {
-	return errors.New("unexpected float64")
+	return unexpected("float64")
 }
 
 func (DefaultVisitor) VisitComplex64(v complex64) error {
-	return errors.New("unexpected complex64")
+	return unexpected("complex64")
 }
 
 func (DefaultVisitor) VisitComplex128(v complex128) error {
-	return errors.New("unexpected complex128")
+	return unexpected("complex128")
 }
 
 func (DefaultVisitor) VisitString(v string) error {
-	return errors.New("unexpected string")
+	return unexpected("string")
 }
 
 func (DefaultVisitor) VisitBytes(v []byte) error {
-	return errors.New("unexpected bytes")
+	return unexpected("bytes")
+}
+
+func (DefaultVisitor) VisitElem(d ElemDecoder) error {
+	return unexpected("elem")
 }
 
 func (DefaultVisitor) VisitSeq(d SeqDecoder) error {
-	return errors.New("unexpected sequence")
+	return unexpected("sequence")
 }
 
 func (DefaultVisitor) VisitMap(d MapDecoder) error {
-	return errors.New("unexpected map")
+	return unexpected("map")
+}
+
+func (DefaultVisitor) VisitVariant(d VariantDecoder) error {
+	return unexpected("variant")
 }
@@ -1,6 +1,9 @@
 package codec
 
-import "io"
+import (
+	"io"
+	"reflect"
+)
 
 type Encoder interface {
 	EncodeNil() error
@@ -22,28 +25,67 @@ type Encoder interface {
 	EncodeComplex128(v complex128) error
 	EncodeString(v string) error
 	EncodeBytes(v []byte) error
+	EncodeElem(v any, s Serializer) error
 	EncodeSeq(len int) (SeqEncoder, error)
 	EncodeMap(len int) (MapEncoder, error)
 	EncodeStruct(name string) (StructEncoder, error)
+	EncodeVariant(enum, variant string, index int) (VariantEncoder, error)
 }
 
 type SeqEncoder interface {
 	io.Closer
 
-	EncodeElement(s Serializer) error
+	EncodeElement(v any, s Serializer) error
 }
 
 type MapEncoder interface {
 	io.Closer
 
-	EncodeKey(s Serializer) error
-	EncodeValue(s Serializer) error
+	EncodeKey(k any, s Serializer) error
+	EncodeValue(v any, s Serializer) error
 }
 
 type StructEncoder interface {
 	io.Closer
 
-	EncodeField(key string, s Serializer) error
+	EncodeField(key string, v any, s Serializer) error
+}
+
+// A StructField describes a field of a struct that is encoded or decoded by the reflection-based struct codec or by a
+// struct codec generated by codecgen.
+type StructField struct {
+	Name string            // the field's key, e.g. as given by its codec tag
+	Type reflect.Type      // the field's type
+	Tag  reflect.StructTag // the field's complete tag, which may hold format-specific options
+}
+
+// A FieldStructEncoder is a StructEncoder that accepts a description of each field it encodes. Formats that need more
+// than a field's key, e.g. formats that identify fields by number, should return FieldStructEncoders from their
+// EncodeStruct methods. The reflection-based struct codec and the struct codecs generated by codecgen call
+// EncodeStructField instead of EncodeField if a struct's encoder implements FieldStructEncoder.
+type FieldStructEncoder interface {
+	StructEncoder
+
+	EncodeStructField(f *StructField, v any, s Serializer) error
+}
+
+// EncodeStructField encodes the described field using the given struct encoder. If the encoder is a
+// FieldStructEncoder, the field is encoded using its EncodeStructField method; otherwise, the field is encoded using
+// EncodeField with the field's name. EncodeStructField is used by the struct codecs generated by codecgen.
+func EncodeStructField(e StructEncoder, f *StructField, v any, s Serializer) error {
+	if e, ok := e.(FieldStructEncoder); ok {
+		return e.EncodeStructField(f, v, s)
+	}
+	return e.EncodeField(f.Name, v, s)
+}
+
+// A VariantEncoder encodes a variant of a sum type (an enum). The representation of variants is chosen by the format,
+// e.g. externally tagged ({"variant": value}), internally tagged, or adjacently tagged. EncodeValue is called at most
+// once to encode the variant's value, and is not called for unit variants, which have no value.
+type VariantEncoder interface {
+	io.Closer
+
+	EncodeValue(v any, s Serializer) error
 }
 
 type Serializer interface {
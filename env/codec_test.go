@@ -0,0 +1,120 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/pgavlin/codec/internal/codectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type database struct {
+	Host     string  `codec:"host"`
+	Port     uint16  `codec:"port"`
+	MaxConns int32   `codec:"maxConns"`
+	Timeout  float64 `codec:"timeout"`
+}
+
+type config struct {
+	Name      string            `codec:"name"`
+	Debug     bool              `codec:"debug"`
+	Ratio     float32           `codec:"ratio"`
+	Port      int               `codec:"port"`
+	PortRange string            `codec:"port_range"`
+	Hosts     []string          `codec:"hosts"`
+	Ports     []int             `codec:"ports"`
+	DB        database          `codec:"db"`
+	Replica   *database         `codec:"replica"`
+	Labels    map[string]string `codec:"labels"`
+	Token     *string           `codec:"token"`
+	Key       []byte            `codec:"key"`
+}
+
+func TestDecode(t *testing.T) {
+	vars := map[string]string{
+		"APP_NAME":         "svc",
+		"APP_DEBUG":        "true",
+		"APP_RATIO":        "0.5",
+		"APP_PORT_RANGE":   "8000-9000",
+		"APP_HOSTS":        "a.example.com, b.example.com",
+		"APP_PORTS":        "80,443",
+		"APP_DB_HOST":      "db",
+		"APP_DB_PORT":      "5432",
+		"APP_DB_MAX_CONNS": "10",
+		"APP_DB_TIMEOUT":   "1.5",
+		"APP_LABELS_TEAM":  "infra",
+		"APP_LABELS_COST":  "42",
+		"APP_TOKEN":        "",
+		"APP_KEY":          "aGk=",
+		"APP_UNKNOWN":      "x",
+		"OTHER_NAME":       "other",
+	}
+
+	d := NewDecoder(vars)
+	d.SetPrefix("APP_")
+	var c config
+	require.NoError(t, d.Decode(&c))
+	assert.Equal(t, config{
+		Name:      "svc",
+		Debug:     true,
+		Ratio:     0.5,
+		PortRange: "8000-9000",
+		Hosts:     []string{"a.example.com", "b.example.com"},
+		Ports:     []int{80, 443},
+		DB:        database{Host: "db", Port: 5432, MaxConns: 10, Timeout: 1.5},
+		Labels:    map[string]string{"TEAM": "infra", "COST": "42"},
+		Key:       []byte("hi"),
+	}, c)
+
+	// Fields may also be named by their keys in upper case.
+	d = NewDecoder(map[string]string{"APP_REPLICA_MAXCONNS": "3", "APP_PORTS": "1;2"})
+	d.SetPrefix("APP")
+	d.SetDelimiter(";")
+	c = config{}
+	require.NoError(t, d.Decode(&c))
+	assert.Equal(t, config{Ports: []int{1, 2}, Replica: &database{MaxConns: 3}}, c)
+
+	var v any
+	d = NewDecoder(vars)
+	d.SetPrefix("APP_DB")
+	require.NoError(t, d.Decode(&v))
+	assert.Equal(t, map[string]any{"HOST": "db", "PORT": "5432", "MAX_CONNS": "10", "TIMEOUT": "1.5"}, v)
+
+	d = NewDecoder(vars)
+	d.SetPrefix("APP")
+	d.DisallowUnknownFields()
+	assert.EqualError(t, d.Decode(&c), `codec: unknown field "UNKNOWN" in Go struct config`)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	decode := func(vars map[string]string) error {
+		var db database
+		return NewDecoder(vars).Decode(&db)
+	}
+
+	assert.EqualError(t, decode(map[string]string{"PORT": "http"}),
+		"codec: cannot unmarshal string into Go struct field database.port of type uint16")
+	assert.EqualError(t, decode(map[string]string{"PORT": "65536"}),
+		"codec: cannot unmarshal number 65536 into Go struct field database.port of type uint16")
+	assert.EqualError(t, decode(map[string]string{"MAX_CONNS": "1.5"}),
+		"codec: cannot unmarshal string into Go struct field database.maxConns of type int32")
+}
+
+func TestEnviron(t *testing.T) {
+	t.Setenv("CODEC_ENV_TEST_DB_HOST", "h=1")
+	t.Setenv("CODEC_ENV_TEST_DB_PORT", "1")
+
+	var c config
+	require.NoError(t, Unmarshal("CODEC_ENV_TEST", &c))
+	assert.Equal(t, config{DB: database{Host: "h=1", Port: 1}}, c)
+}
+
+func TestVariant(t *testing.T) {
+	type options struct {
+		Options []codectest.Option `codec:"options"`
+	}
+
+	var actual options
+	require.NoError(t, NewDecoder(map[string]string{"OPTIONS": "None,Some"}).Decode(&actual))
+	assert.Equal(t, options{Options: []codectest.Option{{}, {Valid: true}}}, actual)
+}
@@ -0,0 +1,460 @@
+package env
+
+import (
+	"encoding/base64"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pgavlin/codec"
+)
+
+// A node is a value to decode. Variables are named by their full names, and are decoded from their values or, for
+// maps and structs, from the variables that they prefix.
+type node struct {
+	name    string // the name of a variable or, for the root, the prefix
+	text    string // the text of a map key or sequence item
+	literal bool   // true if the node is a map key or sequence item
+}
+
+// A Decoder decodes values from a set of environment variables.
+type Decoder struct {
+	vars    map[string]string
+	delim   string
+	options codec.DecodeOptions
+	n       node
+}
+
+// NewDecoder returns a new decoder that reads from vars, which maps variable names to values. Use Environ to decode
+// the environment of the current process.
+func NewDecoder(vars map[string]string) *Decoder {
+	return &Decoder{vars: vars, delim: ","}
+}
+
+// SetPrefix sets the prefix of the variables to decode. The prefix is separated from the names of fields by an
+// underscore, which may be omitted from the prefix.
+func (d *Decoder) SetPrefix(prefix string) {
+	d.n.name = strings.TrimSuffix(prefix, "_")
+}
+
+// SetDelimiter sets the delimiter that separates the items of sequences, which defaults to a comma.
+func (d *Decoder) SetDelimiter(delim string) {
+	d.delim = delim
+}
+
+// DisallowUnknownFields causes the Decoder to return an error when the destination is a struct and there are
+// variables with the struct's prefix which do not match any of the struct's fields.
+func (d *Decoder) DisallowUnknownFields() {
+	d.options.DisallowUnknownFields = true
+}
+
+// Decode decodes the variables into the value pointed to by v.
+func (d *Decoder) Decode(v any) error {
+	return codec.GetDeserializer(v, Format).Deserialize(*d)
+}
+
+func (d Decoder) with(n node) Decoder {
+	d.n = n
+	return d
+}
+
+// text returns the value of a variable, map key, or sequence item.
+func (d Decoder) text() (string, bool) {
+	if d.n.literal {
+		return d.n.text, true
+	}
+	if d.n.name == "" {
+		return "", false
+	}
+	s, ok := d.vars[d.n.name]
+	return s, ok
+}
+
+// join returns the name of the variable with the given suffix.
+func (d Decoder) join(suffix string) string {
+	if d.n.name == "" {
+		return suffix
+	}
+	return d.n.name + "_" + suffix
+}
+
+// suffixes returns the sorted suffixes of the names of the variables with the node's prefix.
+func (d Decoder) suffixes() []string {
+	if d.n.literal {
+		return nil
+	}
+	prefix := d.join("")
+	var suffixes []string
+	for name := range d.vars {
+		if suffix, ok := strings.CutPrefix(name, prefix); ok && suffix != "" {
+			suffixes = append(suffixes, suffix)
+		}
+	}
+	sort.Strings(suffixes)
+	return suffixes
+}
+
+// fieldNames returns the possible names of the variable that holds a field: the field's key in upper case, followed
+// by the key in upper snake case if that differs.
+func fieldNames(key string) []string {
+	var snake strings.Builder
+	prev := rune(0)
+	for _, r := range key {
+		if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)) {
+			snake.WriteByte('_')
+		}
+		snake.WriteRune(unicode.ToUpper(r))
+		prev = r
+	}
+
+	upper := strings.ToUpper(key)
+	if s := snake.String(); s != upper {
+		return []string{upper, s}
+	}
+	return []string{upper}
+}
+
+// isNested returns true if values of type t may be decoded from the variables that their names prefix.
+func isNested(t reflect.Type) bool {
+	if t == nil {
+		return true
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Interface:
+		return true
+	default:
+		return false
+	}
+}
+
+// mapEntries returns the entries of a map, which are keyed by the suffixes of the variables with the node's prefix.
+func (d Decoder) mapEntries() []mapEntry {
+	suffixes := d.suffixes()
+	entries := make([]mapEntry, len(suffixes))
+	for i, s := range suffixes {
+		entries[i] = mapEntry{key: s, n: node{name: d.join(s)}}
+	}
+	return entries
+}
+
+// structEntries returns the entries of a struct with the given fields. Each field is keyed by its key and named by the
+// first of its possible names that names a variable or, if the field is a struct or map, prefixes a variable.
+// Variables that do not match any field are keyed by their suffixes so that they are reported as unknown fields if
+// unknown fields are disallowed.
+func (d Decoder) structEntries(fields []codec.StructField) []mapEntry {
+	suffixes := d.suffixes()
+	matched := make([]bool, len(suffixes))
+
+	var entries []mapEntry
+	for _, f := range fields {
+		nested := isNested(f.Type)
+		for _, name := range fieldNames(f.Name) {
+			found := false
+			for i, s := range suffixes {
+				if s == name || nested && strings.HasPrefix(s, name+"_") {
+					found, matched[i] = true, true
+				}
+			}
+			if found {
+				entries = append(entries, mapEntry{key: f.Name, n: node{name: d.join(name)}})
+				break
+			}
+		}
+	}
+	for i, s := range suffixes {
+		if !matched[i] {
+			entries = append(entries, mapEntry{key: s, n: node{name: d.join(s)}})
+		}
+	}
+	return entries
+}
+
+// groupEntries returns the entries of a struct whose fields are unknown. The variables with the node's prefix are
+// grouped by the first components of their suffixes.
+func (d Decoder) groupEntries() []mapEntry {
+	var entries []mapEntry
+	seen := map[string]bool{}
+	for _, s := range d.suffixes() {
+		name, _, _ := strings.Cut(s, "_")
+		if !seen[name] {
+			seen[name] = true
+			entries = append(entries, mapEntry{key: name, n: node{name: d.join(name)}})
+		}
+	}
+	return entries
+}
+
+// Format returns the environment variable format.
+func (d Decoder) Format() *codec.Format {
+	return Format
+}
+
+// DecodeAny decodes the value of a variable as a string. The variables prefixed by the names of unset variables are
+// decoded as maps.
+func (d Decoder) DecodeAny(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		return v.VisitString(s)
+	}
+	return v.VisitMap(&MapDecoder{d: d, entries: d.mapEntries()})
+}
+
+func (d Decoder) DecodeComplex64(v codec.Visitor) error  { return d.DecodeAny(v) }
+func (d Decoder) DecodeComplex128(v codec.Visitor) error { return d.DecodeAny(v) }
+
+func (d Decoder) DecodeInt(v codec.Visitor) error {
+	return d.decodeInt(strconv.IntSize, v, func(i int64) error { return v.VisitInt(int(i)) })
+}
+
+func (d Decoder) DecodeInt8(v codec.Visitor) error {
+	return d.decodeInt(8, v, func(i int64) error { return v.VisitInt8(int8(i)) })
+}
+
+func (d Decoder) DecodeInt16(v codec.Visitor) error {
+	return d.decodeInt(16, v, func(i int64) error { return v.VisitInt16(int16(i)) })
+}
+
+func (d Decoder) DecodeInt32(v codec.Visitor) error {
+	return d.decodeInt(32, v, func(i int64) error { return v.VisitInt32(int32(i)) })
+}
+
+func (d Decoder) DecodeInt64(v codec.Visitor) error {
+	return d.decodeInt(64, v, v.VisitInt64)
+}
+
+func (d Decoder) DecodeUint(v codec.Visitor) error {
+	return d.decodeUint(strconv.IntSize, v, func(u uint64) error { return v.VisitUint(uint(u)) })
+}
+
+func (d Decoder) DecodeUint8(v codec.Visitor) error {
+	return d.decodeUint(8, v, func(u uint64) error { return v.VisitUint8(uint8(u)) })
+}
+
+func (d Decoder) DecodeUint16(v codec.Visitor) error {
+	return d.decodeUint(16, v, func(u uint64) error { return v.VisitUint16(uint16(u)) })
+}
+
+func (d Decoder) DecodeUint32(v codec.Visitor) error {
+	return d.decodeUint(32, v, func(u uint64) error { return v.VisitUint32(uint32(u)) })
+}
+
+func (d Decoder) DecodeUint64(v codec.Visitor) error {
+	return d.decodeUint(64, v, v.VisitUint64)
+}
+
+func (d Decoder) DecodeUintptr(v codec.Visitor) error {
+	return d.decodeUint(strconv.IntSize, v, func(u uint64) error { return v.VisitUintptr(uintptr(u)) })
+}
+
+func (d Decoder) DecodeFloat32(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 32); err == nil {
+			return v.VisitFloat32(float32(f))
+		}
+	}
+	return d.DecodeFloat64(v)
+}
+
+func (d Decoder) DecodeFloat64(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return v.VisitFloat64(f)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// decodeInt decodes an integer of the given size from text and visits it using visit. Integers that do not fit in the
+// given size are visited as 64-bit integers so that the visitor can report the overflow, and text that is not an
+// integer is visited as a string.
+func (d Decoder) decodeInt(bits int, v codec.Visitor, visit func(i int64) error) error {
+	if s, ok := d.text(); ok {
+		if i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+			if _, err := strconv.ParseInt(strings.TrimSpace(s), 10, bits); err != nil {
+				return v.VisitInt64(i)
+			}
+			return visit(i)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// decodeUint decodes an unsigned integer of the given size from text and visits it using visit. Integers that do not
+// fit in the given size are visited as 64-bit integers so that the visitor can report the overflow, and text that is
+// not an unsigned integer is visited as a string.
+func (d Decoder) decodeUint(bits int, v codec.Visitor, visit func(u uint64) error) error {
+	if s, ok := d.text(); ok {
+		if u, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64); err == nil {
+			if _, err := strconv.ParseUint(strings.TrimSpace(s), 10, bits); err != nil {
+				return v.VisitUint64(u)
+			}
+			return visit(u)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeNil decodes an empty value as nil.
+func (d Decoder) DecodeNil(v codec.Visitor) error {
+	if s, ok := d.text(); ok && s == "" {
+		return v.VisitNil()
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeBool decodes a boolean from text. Text that is not a boolean is visited as a string.
+func (d Decoder) DecodeBool(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+			return v.VisitBool(b)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+func (d Decoder) DecodeString(v codec.Visitor) error {
+	return d.DecodeAny(v)
+}
+
+// DecodeBytes decodes a byte slice from base64-encoded text.
+func (d Decoder) DecodeBytes(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		return v.VisitBytes(b)
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeSeq decodes a sequence from text. The items of the sequence are separated by the decoder's delimiter, and
+// leading and trailing white space is removed from each item. Empty text is decoded as an empty sequence.
+func (d Decoder) DecodeSeq(v codec.Visitor) error {
+	s, ok := d.text()
+	if !ok {
+		return d.DecodeAny(v)
+	}
+
+	var items []string
+	if strings.TrimSpace(s) != "" {
+		items = strings.Split(s, d.delim)
+		for i, item := range items {
+			items[i] = strings.TrimSpace(item)
+		}
+	}
+	return v.VisitSeq(&SeqDecoder{d: d, items: items})
+}
+
+// DecodeMap decodes the variables with the node's prefix as a map.
+func (d Decoder) DecodeMap(v codec.Visitor) error {
+	if d.n.literal {
+		return d.DecodeAny(v)
+	}
+	return v.VisitMap(&MapDecoder{d: d, entries: d.mapEntries()})
+}
+
+// DecodeStruct decodes the variables with the node's prefix as a struct. If v describes the struct's fields, the
+// fields are matched with the variables as described in the package documentation; otherwise, the variables are
+// grouped by the first components of their suffixes.
+func (d Decoder) DecodeStruct(name string, v codec.Visitor) error {
+	if d.n.literal {
+		return d.DecodeAny(v)
+	}
+	if sv, ok := v.(codec.StructVisitor); ok {
+		return v.VisitMap(&MapDecoder{d: d, entries: d.structEntries(sv.StructFields())})
+	}
+	return v.VisitMap(&MapDecoder{d: d, entries: d.groupEntries()})
+}
+
+// DecodeVariant decodes a unit variant from its name.
+func (d Decoder) DecodeVariant(enum string, variants []string, v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		return v.VisitVariant(VariantDecoder{d: d, name: strings.TrimSpace(s)})
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodePtr decodes a pointer. Empty values are decoded as nil.
+func (d Decoder) DecodePtr(v codec.Visitor) error {
+	if s, ok := d.text(); ok && s == "" {
+		return v.VisitNil()
+	}
+	return v.VisitElem(ElemDecoder{d})
+}
+
+type ElemDecoder struct {
+	d Decoder
+}
+
+func (d ElemDecoder) Element(v any, ds codec.Deserializer) error {
+	return ds.Deserialize(d.d)
+}
+
+// A SeqDecoder decodes the items of a delimited value.
+type SeqDecoder struct {
+	d     Decoder
+	items []string
+}
+
+func (d *SeqDecoder) Size() (int, bool) {
+	return len(d.items), true
+}
+
+func (d *SeqDecoder) NextElement(x any, ds codec.Deserializer) (bool, error) {
+	if len(d.items) == 0 {
+		return false, nil
+	}
+	item := d.items[0]
+	d.items = d.items[1:]
+	return true, ds.Deserialize(d.d.with(node{text: item, literal: true}))
+}
+
+type mapEntry struct {
+	key string
+	n   node
+}
+
+type MapDecoder struct {
+	d       Decoder
+	entries []mapEntry
+}
+
+func (d *MapDecoder) Size() (int, bool) {
+	return len(d.entries), true
+}
+
+func (d *MapDecoder) Options() codec.DecodeOptions {
+	return d.d.options
+}
+
+func (d *MapDecoder) NextKey(k any, ds codec.Deserializer) (bool, error) {
+	if len(d.entries) == 0 {
+		return false, nil
+	}
+	return true, ds.Deserialize(d.d.with(node{text: d.entries[0].key, literal: true}))
+}
+
+func (d *MapDecoder) NextValue(v any, ds codec.Deserializer) error {
+	n := d.entries[0].n
+	d.entries = d.entries[1:]
+	return ds.Deserialize(d.d.with(n))
+}
+
+type VariantDecoder struct {
+	d    Decoder
+	name string
+}
+
+func (d VariantDecoder) Variant() string {
+	return d.name
+}
+
+// Value decodes the value of a unit variant.
+func (d VariantDecoder) Value(v any, ds codec.Deserializer) error {
+	return ds.Deserialize(d.d.with(node{literal: true}))
+}
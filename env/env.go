@@ -0,0 +1,43 @@
+// Package env implements the decoding of values from environment variables.
+//
+// The variables that share a prefix are decoded as a map or struct. The fields of a struct are named by the prefix and
+// the fields' keys in upper case, separated by underscores: with the prefix "APP", the field "port" is read from
+// APP_PORT, and the field "host" of the struct field "db" is read from APP_DB_HOST. Keys in camel case may also be
+// written in snake case, so the field "maxConns" is read from either APP_MAXCONNS or APP_MAX_CONNS. The keys of maps
+// are the remainders of the names of the variables that share the map's prefix.
+//
+// Values are parsed from text according to the types of their destinations, so that int32, bool, and float64 fields,
+// for example, are all decoded from their text representations. Sequences are decoded from values separated by a
+// delimiter, which defaults to a comma. Empty values decode as nil pointers, and byte slices are decoded from
+// base64-encoded text.
+package env
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pgavlin/codec"
+)
+
+// Format is the codec format for environment variables.
+var Format = codec.NewFormat("env")
+
+// Environ returns the environment of the current process as a map from variable names to values.
+func Environ() map[string]string {
+	environ := os.Environ()
+	vars := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok && name != "" {
+			vars[name] = value
+		}
+	}
+	return vars
+}
+
+// Unmarshal decodes the environment variables of the current process that begin with prefix into the value pointed to
+// by x.
+func Unmarshal(prefix string, x any) error {
+	d := NewDecoder(Environ())
+	d.SetPrefix(prefix)
+	return d.Decode(x)
+}
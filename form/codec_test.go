@@ -0,0 +1,151 @@
+package form
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/pgavlin/codec/internal/codectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type filter struct {
+	Name  string `codec:"name"`
+	Owner *int   `codec:"owner"`
+}
+
+type order struct {
+	Field string `codec:"field"`
+	Desc  bool   `codec:"desc,omitempty"`
+}
+
+type request struct {
+	Limit  int               `codec:"limit"`
+	Offset uint              `codec:"offset,omitempty"`
+	Ratio  float64           `codec:"ratio,omitempty"`
+	Tags   []string          `codec:"tags"`
+	Filter filter            `codec:"filter"`
+	Sort   []order           `codec:"sort"`
+	Labels map[string]string `codec:"labels,omitempty"`
+	Cursor []byte            `codec:"cursor,omitempty"`
+	Next   *string           `codec:"next"`
+}
+
+func TestMarshal(t *testing.T) {
+	owner := 7
+	r := request{
+		Limit:  10,
+		Ratio:  0.5,
+		Tags:   []string{"a", "b"},
+		Filter: filter{Name: "x y", Owner: &owner},
+		Sort:   []order{{Field: "name"}, {Field: "age", Desc: true}},
+		Labels: map[string]string{"env": "prod"},
+		Cursor: []byte("hi"),
+	}
+
+	values, err := Marshal(r)
+	require.NoError(t, err)
+	assert.Equal(t, url.Values{
+		"limit":        {"10"},
+		"ratio":        {"0.5"},
+		"tags":         {"a", "b"},
+		"filter.name":  {"x y"},
+		"filter.owner": {"7"},
+		"sort.0.field": {"name"},
+		"sort.1.field": {"age"},
+		"sort.1.desc":  {"true"},
+		"labels.env":   {"prod"},
+		"cursor":       {"aGk="},
+	}, values)
+
+	values = url.Values{}
+	e := NewEncoder(values)
+	e.SetNotation(BracketNotation)
+	require.NoError(t, e.Encode(r))
+	assert.Equal(t,
+		"cursor=aGk%3D&filter%5Bname%5D=x+y&filter%5Bowner%5D=7&labels%5Benv%5D=prod&limit=10&ratio=0.5&"+
+			"sort%5B0%5D%5Bfield%5D=name&sort%5B1%5D%5Bdesc%5D=true&sort%5B1%5D%5Bfield%5D=age&tags=a&tags=b",
+		values.Encode())
+
+	values, err = Marshal(map[string][][]int{"m": {{1, 2}, {3}}})
+	require.NoError(t, err)
+	assert.Equal(t, url.Values{"m.0": {"1", "2"}, "m.1": {"3"}}, values)
+
+	_, err = Marshal([]int{1})
+	assert.EqualError(t, err, "form: values must be structs or maps")
+}
+
+func TestUnmarshal(t *testing.T) {
+	values, err := url.ParseQuery("limit=10&tags[]=a&tags[]=b&filter[name]=x+y&filter.owner=7&" +
+		"sort[1][field]=age&sort[1][desc]=on&sort.0.field=name&labels[env]=prod&cursor=aGk%3D&next=&unknown=1")
+	require.NoError(t, err)
+
+	var r request
+	require.NoError(t, Unmarshal(values, &r))
+	owner := 7
+	assert.Equal(t, request{
+		Limit:  10,
+		Tags:   []string{"a", "b"},
+		Filter: filter{Name: "x y", Owner: &owner},
+		Sort:   []order{{Field: "name"}, {Field: "age", Desc: true}},
+		Labels: map[string]string{"env": "prod"},
+		Cursor: []byte("hi"),
+	}, r)
+
+	// A single value decodes as a single-element sequence.
+	var single request
+	require.NoError(t, Unmarshal(url.Values{"tags": {"a"}, "sort.field": {"name"}}, &single))
+	assert.Equal(t, request{Tags: []string{"a"}, Sort: []order{{Field: "name"}}}, single)
+
+	// Keys are decoded as maps, repeated keys as sequences, and other keys as text.
+	var v any
+	require.NoError(t, Unmarshal(url.Values{"a": {"1", "2"}, "b[c]": {"3"}}, &v))
+	assert.Equal(t, map[string]any{"a": []any{"1", "2"}, "b": map[string]any{"c": "3"}}, v)
+
+	err = Unmarshal(url.Values{"limit": {"ten"}}, &r)
+	assert.EqualError(t, err, "codec: cannot unmarshal string into Go struct field request.limit of type int")
+
+	d := NewDecoder(url.Values{"name": {"x"}, "color": {"red"}})
+	d.DisallowUnknownFields()
+	var f filter
+	assert.EqualError(t, d.Decode(&f), `codec: unknown field "color" in Go struct filter`)
+}
+
+func TestRoundTrip(t *testing.T) {
+	next := "abc"
+	expected := request{
+		Limit:  1,
+		Offset: 2,
+		Tags:   []string{"x"},
+		Filter: filter{Name: "a.b[c]"},
+		Sort:   []order{{Field: "f"}},
+		Next:   &next,
+	}
+
+	for _, n := range []Notation{DotNotation, BracketNotation} {
+		values := url.Values{}
+		e := NewEncoder(values)
+		e.SetNotation(n)
+		require.NoError(t, e.Encode(expected))
+
+		var actual request
+		require.NoError(t, Unmarshal(values, &actual))
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func TestVariant(t *testing.T) {
+	type options struct {
+		A codectest.Option `codec:"a"`
+		B codectest.Option `codec:"b"`
+	}
+
+	expected := options{A: codectest.Option{Valid: true, Value: "x"}}
+	values, err := Marshal(expected)
+	require.NoError(t, err)
+	assert.Equal(t, url.Values{"a.Some": {"x"}, "b": {"None"}}, values)
+
+	var actual options
+	require.NoError(t, Unmarshal(values, &actual))
+	assert.Equal(t, expected, actual)
+}
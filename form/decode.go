@@ -0,0 +1,369 @@
+package form
+
+import (
+	"encoding/base64"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pgavlin/codec"
+)
+
+// A node is a key of the decoded values. Each node holds the values of its key and the nodes of the keys that it
+// prefixes.
+type node struct {
+	values   []string
+	children []entry          // the children in the order they were added
+	index    map[string]*node // the children by key
+}
+
+type entry struct {
+	key string
+	n   *node
+}
+
+// child returns the child with the given key, adding it if necessary.
+func (n *node) child(key string) *node {
+	if c, ok := n.index[key]; ok {
+		return c
+	}
+	if n.index == nil {
+		n.index = map[string]*node{}
+	}
+	c := &node{}
+	n.children = append(n.children, entry{key: key, n: c})
+	n.index[key] = c
+	return c
+}
+
+// parseValues returns the tree of nodes that is named by the keys of values.
+func parseValues(values url.Values) *node {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	root := &node{}
+	for _, k := range keys {
+		n := root
+		for _, name := range splitKey(k) {
+			n = n.child(name)
+		}
+		n.values = append(n.values, values[k]...)
+	}
+	return root
+}
+
+// splitKey splits a key in dot or bracket notation into the names of its components. Empty brackets are ignored.
+func splitKey(key string) []string {
+	var names []string
+	for len(key) != 0 {
+		var name string
+		if key[0] == '[' {
+			end := strings.IndexByte(key, ']')
+			if end < 0 {
+				return append(names, key)
+			}
+			name, key = key[1:end], key[end+1:]
+		} else {
+			key = strings.TrimPrefix(key, ".")
+			end := strings.IndexAny(key, ".[")
+			if end < 0 {
+				end = len(key)
+			}
+			name, key = key[:end], key[end:]
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// indexes returns the children of n ordered by index if all of the children are named by indexes.
+func (n *node) indexes() ([]*node, bool) {
+	type item struct {
+		index int
+		n     *node
+	}
+	items := make([]item, len(n.children))
+	for i, e := range n.children {
+		index, err := strconv.Atoi(e.key)
+		if err != nil || index < 0 {
+			return nil, false
+		}
+		items[i] = item{index: index, n: e.n}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].index < items[j].index })
+
+	nodes := make([]*node, len(items))
+	for i, item := range items {
+		nodes[i] = item.n
+	}
+	return nodes, true
+}
+
+// A Decoder decodes values from url.Values. Keys that have nested keys are decoded as maps, repeated keys as
+// sequences, and other keys as text.
+type Decoder struct {
+	n       *node
+	options codec.DecodeOptions
+	values  url.Values
+}
+
+// NewDecoder returns a new decoder that reads from values.
+func NewDecoder(values url.Values) *Decoder {
+	return &Decoder{values: values}
+}
+
+// DisallowUnknownFields causes the Decoder to return an error when the destination is a struct and the values contain
+// keys which do not match any of the struct's fields.
+func (d *Decoder) DisallowUnknownFields() {
+	d.options.DisallowUnknownFields = true
+}
+
+// Decode decodes the decoder's values into the value pointed to by v.
+func (d *Decoder) Decode(v any) error {
+	return codec.GetDeserializer(v, Format).Deserialize(Decoder{n: parseValues(d.values), options: d.options})
+}
+
+func (d Decoder) with(n *node) Decoder {
+	return Decoder{n: n, options: d.options}
+}
+
+// text returns the first value of a key that has no nested keys.
+func (d Decoder) text() (string, bool) {
+	if len(d.n.children) != 0 || len(d.n.values) == 0 {
+		return "", false
+	}
+	return d.n.values[0], true
+}
+
+// Format returns the URL-encoded form format.
+func (d Decoder) Format() *codec.Format {
+	return Format
+}
+
+func (d Decoder) DecodeAny(v codec.Visitor) error {
+	switch {
+	case len(d.n.children) != 0 || len(d.n.values) == 0:
+		return v.VisitMap(&MapDecoder{d: d, entries: d.n.children})
+	case len(d.n.values) == 1:
+		return v.VisitString(d.n.values[0])
+	default:
+		return v.VisitSeq(&SeqDecoder{d: d, values: d.n.values})
+	}
+}
+
+func (d Decoder) DecodeComplex64(v codec.Visitor) error  { return d.DecodeAny(v) }
+func (d Decoder) DecodeComplex128(v codec.Visitor) error { return d.DecodeAny(v) }
+func (d Decoder) DecodeInt(v codec.Visitor) error        { return d.decodeInt(v) }
+func (d Decoder) DecodeInt8(v codec.Visitor) error       { return d.decodeInt(v) }
+func (d Decoder) DecodeInt16(v codec.Visitor) error      { return d.decodeInt(v) }
+func (d Decoder) DecodeInt32(v codec.Visitor) error      { return d.decodeInt(v) }
+func (d Decoder) DecodeInt64(v codec.Visitor) error      { return d.decodeInt(v) }
+func (d Decoder) DecodeUint(v codec.Visitor) error       { return d.decodeUint(v) }
+func (d Decoder) DecodeUint8(v codec.Visitor) error      { return d.decodeUint(v) }
+func (d Decoder) DecodeUint16(v codec.Visitor) error     { return d.decodeUint(v) }
+func (d Decoder) DecodeUint32(v codec.Visitor) error     { return d.decodeUint(v) }
+func (d Decoder) DecodeUint64(v codec.Visitor) error     { return d.decodeUint(v) }
+func (d Decoder) DecodeUintptr(v codec.Visitor) error    { return d.decodeUint(v) }
+func (d Decoder) DecodeFloat32(v codec.Visitor) error    { return d.decodeFloat(v) }
+func (d Decoder) DecodeFloat64(v codec.Visitor) error    { return d.decodeFloat(v) }
+
+// DecodeNil decodes an empty value as nil.
+func (d Decoder) DecodeNil(v codec.Visitor) error {
+	if s, ok := d.text(); ok && s == "" {
+		return v.VisitNil()
+	}
+	return d.DecodeAny(v)
+}
+
+// decodeInt decodes an integer from text. Text that is not an integer is visited as a string.
+func (d Decoder) decodeInt(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+			return v.VisitInt64(i)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// decodeUint decodes an unsigned integer from text. Text that is not an unsigned integer is visited as a string.
+func (d Decoder) decodeUint(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if u, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64); err == nil {
+			return v.VisitUint64(u)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// decodeFloat decodes a float from text. Text that is not a float is visited as a string.
+func (d Decoder) decodeFloat(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return v.VisitFloat64(f)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeBool decodes a boolean from text. In addition to the values accepted by strconv.ParseBool, "on" is decoded
+// as true. Text that is not a boolean is visited as a string.
+func (d Decoder) DecodeBool(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		s = strings.TrimSpace(s)
+		if s == "on" {
+			return v.VisitBool(true)
+		}
+		if b, err := strconv.ParseBool(s); err == nil {
+			return v.VisitBool(b)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeString decodes the first value of a key as a string.
+func (d Decoder) DecodeString(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		return v.VisitString(s)
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeBytes decodes a byte slice from base64-encoded text.
+func (d Decoder) DecodeBytes(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		return v.VisitBytes(b)
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeSeq decodes a sequence. The values of a key are decoded as a sequence of text, and keys whose nested keys are
+// all indexes are decoded as sequences of the nested keys in index order. Other keys are decoded as single-element
+// sequences.
+func (d Decoder) DecodeSeq(v codec.Visitor) error {
+	if len(d.n.children) == 0 {
+		return v.VisitSeq(&SeqDecoder{d: d, values: d.n.values})
+	}
+	if nodes, ok := d.n.indexes(); ok {
+		return v.VisitSeq(&SeqDecoder{d: d, nodes: nodes})
+	}
+	return v.VisitSeq(&SeqDecoder{d: d, nodes: []*node{d.n}})
+}
+
+// DecodeMap decodes a key's nested keys as a map.
+func (d Decoder) DecodeMap(v codec.Visitor) error {
+	if len(d.n.children) == 0 && len(d.n.values) != 0 {
+		return d.DecodeAny(v)
+	}
+	return v.VisitMap(&MapDecoder{d: d, entries: d.n.children})
+}
+
+func (d Decoder) DecodeStruct(name string, v codec.Visitor) error {
+	return d.DecodeMap(v)
+}
+
+// DecodeVariant decodes the externally tagged representation produced by Encoder.EncodeVariant: unit variants are
+// represented by their names, and other variants by a single nested key that is named by the variant and holds its
+// value. Other values are decoded as-is.
+func (d Decoder) DecodeVariant(enum string, variants []string, v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		return v.VisitVariant(VariantDecoder{d: d, name: strings.TrimSpace(s)})
+	}
+	if len(d.n.children) == 1 {
+		c := d.n.children[0]
+		return v.VisitVariant(VariantDecoder{d: d.with(c.n), name: c.key, value: true})
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodePtr decodes a pointer. Empty values are decoded as nil.
+func (d Decoder) DecodePtr(v codec.Visitor) error {
+	if s, ok := d.text(); ok && s == "" {
+		return v.VisitNil()
+	}
+	return v.VisitElem(ElemDecoder{d})
+}
+
+type ElemDecoder struct {
+	d Decoder
+}
+
+func (d ElemDecoder) Element(v any, ds codec.Deserializer) error {
+	return ds.Deserialize(d.d)
+}
+
+// A SeqDecoder decodes the values of a key or a sequence of keys.
+type SeqDecoder struct {
+	d      Decoder
+	values []string
+	nodes  []*node
+}
+
+func (d *SeqDecoder) Size() (int, bool) {
+	return len(d.values) + len(d.nodes), true
+}
+
+func (d *SeqDecoder) NextElement(x any, ds codec.Deserializer) (bool, error) {
+	var n *node
+	switch {
+	case len(d.values) != 0:
+		n, d.values = &node{values: d.values[:1]}, d.values[1:]
+	case len(d.nodes) != 0:
+		n, d.nodes = d.nodes[0], d.nodes[1:]
+	default:
+		return false, nil
+	}
+	return true, ds.Deserialize(d.d.with(n))
+}
+
+type MapDecoder struct {
+	d       Decoder
+	entries []entry
+}
+
+func (d *MapDecoder) Size() (int, bool) {
+	return len(d.entries), true
+}
+
+func (d *MapDecoder) Options() codec.DecodeOptions {
+	return d.d.options
+}
+
+func (d *MapDecoder) NextKey(k any, ds codec.Deserializer) (bool, error) {
+	if len(d.entries) == 0 {
+		return false, nil
+	}
+	return true, ds.Deserialize(d.d.with(&node{values: []string{d.entries[0].key}}))
+}
+
+func (d *MapDecoder) NextValue(v any, ds codec.Deserializer) error {
+	n := d.entries[0].n
+	d.entries = d.entries[1:]
+	return ds.Deserialize(d.d.with(n))
+}
+
+type VariantDecoder struct {
+	d     Decoder
+	name  string
+	value bool // true if the variant has a value
+}
+
+func (d VariantDecoder) Variant() string {
+	return d.name
+}
+
+func (d VariantDecoder) Value(v any, ds codec.Deserializer) error {
+	if !d.value {
+		return ds.Deserialize(d.d.with(&node{values: []string{""}}))
+	}
+	return ds.Deserialize(d.d)
+}
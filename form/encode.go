@@ -0,0 +1,259 @@
+package form
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"reflect"
+	"strconv"
+
+	"github.com/pgavlin/codec"
+)
+
+var errNotForm = errors.New("form: values must be structs or maps")
+
+// An Encoder encodes values into url.Values. Each scalar is added to the values under the key that names its
+// position within the top-level value.
+type Encoder struct {
+	values   url.Values
+	notation Notation
+	key      string // the key of the value being encoded; empty for the top-level value
+	elem     bool   // true if the value is an item of a sequence
+	index    int    // the index of a sequence item
+}
+
+// NewEncoder returns a new encoder that adds to values.
+func NewEncoder(values url.Values) *Encoder {
+	return &Encoder{values: values}
+}
+
+// SetNotation sets the notation used to name the fields of nested values, which defaults to DotNotation.
+func (e *Encoder) SetNotation(n Notation) {
+	e.notation = n
+}
+
+// Encode adds the form encoding of v to the encoder's values.
+func (e *Encoder) Encode(v any) error {
+	return codec.GetSerializer(v, Format).Serialize(Encoder{values: e.values, notation: e.notation})
+}
+
+// join returns the key of the field of a struct or map with the given name.
+func (e Encoder) join(key, name string) string {
+	switch {
+	case key == "":
+		return name
+	case e.notation == BracketNotation:
+		return key + "[" + name + "]"
+	default:
+		return key + "." + name
+	}
+}
+
+// container returns the prefix for the keys of a struct or map. Structs and maps within sequences are named by their
+// indexes.
+func (e Encoder) container() string {
+	if e.elem {
+		return e.join(e.key, strconv.Itoa(e.index))
+	}
+	return e.key
+}
+
+// with returns an encoder for the value with the given key.
+func (e Encoder) with(key string) Encoder {
+	return Encoder{values: e.values, notation: e.notation, key: key}
+}
+
+func (e Encoder) text(s string) error {
+	if e.key == "" {
+		return errNotForm
+	}
+	e.values.Add(e.key, s)
+	return nil
+}
+
+// Format returns the URL-encoded form format.
+func (e Encoder) Format() *codec.Format {
+	return Format
+}
+
+// EncodeNil omits nil values.
+func (e Encoder) EncodeNil() error {
+	if e.key == "" {
+		return errNotForm
+	}
+	return nil
+}
+
+func (e Encoder) EncodeBool(v bool) error {
+	return e.text(strconv.FormatBool(v))
+}
+
+func (e Encoder) EncodeInt(v int) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt8(v int8) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt16(v int16) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt32(v int32) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt64(v int64) error {
+	return e.text(strconv.FormatInt(v, 10))
+}
+
+func (e Encoder) EncodeUint(v uint) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint8(v uint8) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint16(v uint16) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint32(v uint32) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint64(v uint64) error {
+	return e.text(strconv.FormatUint(v, 10))
+}
+
+func (e Encoder) EncodeUintptr(v uintptr) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeFloat32(v float32) error {
+	return e.text(strconv.FormatFloat(float64(v), 'g', -1, 32))
+}
+
+func (e Encoder) EncodeFloat64(v float64) error {
+	return e.text(strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+func (e Encoder) EncodeComplex64(v complex64) error {
+	return &codec.UnsupportedTypeError{Type: reflect.TypeOf(v)}
+}
+
+func (e Encoder) EncodeComplex128(v complex128) error {
+	return &codec.UnsupportedTypeError{Type: reflect.TypeOf(v)}
+}
+
+func (e Encoder) EncodeString(v string) error {
+	return e.text(v)
+}
+
+// EncodeBytes encodes a byte slice as base64-encoded text.
+func (e Encoder) EncodeBytes(v []byte) error {
+	return e.text(base64.StdEncoding.EncodeToString(v))
+}
+
+func (e Encoder) EncodeElem(v any, s codec.Serializer) error {
+	return s.Serialize(e)
+}
+
+func (e Encoder) EncodeSeq(len int) (codec.SeqEncoder, error) {
+	if e.key == "" {
+		return nil, errNotForm
+	}
+	return &SeqEncoder{e: e.with(e.container())}, nil
+}
+
+func (e Encoder) EncodeMap(len int) (codec.MapEncoder, error) {
+	return &MapEncoder{e: e.with(e.container())}, nil
+}
+
+func (e Encoder) EncodeStruct(name string) (codec.StructEncoder, error) {
+	return &StructEncoder{e: e.with(e.container())}, nil
+}
+
+// EncodeVariant encodes a variant of a sum type using the externally tagged representation: unit variants are encoded
+// as their names, and the values of other variants are encoded as fields that are named by the variants.
+func (e Encoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	if e.key == "" {
+		return nil, errNotForm
+	}
+	return &VariantEncoder{e: e, name: variant}, nil
+}
+
+// A SeqEncoder encodes the items of a sequence. Scalars are encoded as repeated keys, and other items are named by
+// their indexes.
+type SeqEncoder struct {
+	e     Encoder
+	index int
+}
+
+func (e *SeqEncoder) Close() error {
+	return nil
+}
+
+func (e *SeqEncoder) EncodeElement(x any, ser codec.Serializer) error {
+	elem := e.e
+	elem.elem, elem.index = true, e.index
+	e.index++
+	return ser.Serialize(elem)
+}
+
+type MapEncoder struct {
+	e   Encoder
+	key string
+}
+
+func (e *MapEncoder) Close() error {
+	return nil
+}
+
+func (e *MapEncoder) EncodeKey(x any, ser codec.Serializer) error {
+	k := url.Values{}
+	if err := ser.Serialize(Encoder{values: k, key: "key"}); err != nil {
+		return err
+	}
+	if len(k) != 1 || len(k["key"]) != 1 {
+		return errors.New("form: map keys must be scalars")
+	}
+	e.key = k["key"][0]
+	return nil
+}
+
+func (e *MapEncoder) EncodeValue(x any, ser codec.Serializer) error {
+	return ser.Serialize(e.e.with(e.e.join(e.e.key, e.key)))
+}
+
+type StructEncoder struct {
+	e Encoder
+}
+
+func (e *StructEncoder) Close() error {
+	return nil
+}
+
+func (e *StructEncoder) EncodeField(key string, x any, ser codec.Serializer) error {
+	return ser.Serialize(e.e.with(e.e.join(e.e.key, key)))
+}
+
+type VariantEncoder struct {
+	e     Encoder
+	name  string
+	value bool
+}
+
+func (e *VariantEncoder) Close() error {
+	if !e.value {
+		return e.e.text(e.name)
+	}
+	return nil
+}
+
+func (e *VariantEncoder) EncodeValue(x any, ser codec.Serializer) error {
+	e.value = true
+	return ser.Serialize(e.e.with(e.e.join(e.e.container(), e.name)))
+}
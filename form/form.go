@@ -0,0 +1,47 @@
+// Package form implements the encoding of values as URL query strings and HTML form data, represented as url.Values.
+//
+// The top-level value must be a struct or map. Its fields are encoded as keys whose values are the fields' values as
+// text. Nested structs and maps are flattened, and their fields are named by their paths in either dot notation (e.g.
+// "filter.name") or bracket notation (e.g. "filter[name]"). Sequences of scalars are encoded as repeated keys, and
+// the items of other sequences are named by their indexes (e.g. "items.0.name" or "items[0][name]"). Nil values are
+// omitted, and byte slices are encoded as base64-encoded text. Because scalar items are not named by their indexes,
+// sequences that mix scalars with other values cannot be decoded in their original order.
+//
+// When decoding, both notations are accepted, as are empty brackets after the names of sequences (e.g. "tags[]").
+// Values are decoded from text according to the types of their destinations, so "?limit=10" decodes into an int
+// field. Empty values decode as nil pointers, and the value "on", which browsers submit for checked checkboxes,
+// decodes as true.
+package form
+
+import (
+	"net/url"
+
+	"github.com/pgavlin/codec"
+)
+
+// Format is the codec format for URL-encoded forms.
+var Format = codec.NewFormat("form")
+
+// Notation identifies the notation used to name the fields of nested values.
+type Notation int
+
+const (
+	// DotNotation separates the names of nested fields with dots, as in "filter.name".
+	DotNotation Notation = iota
+	// BracketNotation encloses the names of nested fields in brackets, as in "filter[name]".
+	BracketNotation
+)
+
+// Marshal returns the form encoding of x, which must be a struct or map, using dot notation.
+func Marshal(x any) (url.Values, error) {
+	values := url.Values{}
+	if err := NewEncoder(values).Encode(x); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Unmarshal decodes values into the value pointed to by x.
+func Unmarshal(values url.Values, x any) error {
+	return NewDecoder(values).Decode(x)
+}
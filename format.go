@@ -0,0 +1,87 @@
+package codec
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"github.com/pgavlin/codec/typecache"
+)
+
+// A Format identifies a serialization format, e.g. JSON. Each format maintains its own cache of reflection-based
+// codecs, and may override the codecs used for particular types.
+//
+// Formats are typically declared once by the package that implements the format's Encoder and Decoder.
+type Format struct {
+	name string
+
+	m         sync.RWMutex
+	overrides map[reflect.Type]codec
+
+	codecs typecache.Cache[codec]
+}
+
+// NewFormat creates a new format with the given name.
+func NewFormat(name string) *Format {
+	return &Format{name: name}
+}
+
+// Name returns the name of the format.
+func (f *Format) Name() string {
+	return f.name
+}
+
+// Override registers c as the codec for values of type T when serializing or deserializing in format f. The codec is
+// used wherever a T appears, including inside structs, slices, maps, and pointers.
+//
+// Overrides must be registered before f is used to serialize or deserialize values that contain a T, and are
+// typically registered from an init function.
+func Override[T any](f *Format, c Codec[T]) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.overrides == nil {
+		f.overrides = map[reflect.Type]codec{}
+	}
+	f.overrides[reflect.TypeOf((*T)(nil)).Elem()] = typedCodec[T]{Codec: c}
+}
+
+// FormatOf returns the format of the given Encoder or Decoder. Encoders and Decoders report their formats by
+// implementing a Format method that returns a *Format. If x does not implement such a method, FormatOf returns nil.
+//
+// Codecs that are not constructed for a particular format, e.g. those returned by NewReflect and NewUnion, use the
+// format of the Encoder or Decoder they are given so that the format's overrides are applied.
+func FormatOf(x any) *Format {
+	if x, ok := x.(interface{ Format() *Format }); ok {
+		return x.Format()
+	}
+	return nil
+}
+
+func (f *Format) override(t reflect.Type) (codec, bool) {
+	if f == nil {
+		return nil, false
+	}
+
+	f.m.RLock()
+	defer f.m.RUnlock()
+
+	c, ok := f.overrides[t]
+	return c, ok
+}
+
+func (f *Format) cache() *typecache.Cache[codec] {
+	if f == nil {
+		return &codecs
+	}
+	return &f.codecs
+}
+
+// typedCodec adapts a Codec[T] for use by the reflection-based codecs.
+type typedCodec[T any] struct {
+	Codec[T]
+}
+
+func (c typedCodec[T]) new(v unsafe.Pointer) codec {
+	return typedCodec[T]{Codec: c.Codec.New((*T)(v))}
+}
@@ -0,0 +1,114 @@
+// Package codectest provides fixtures that are shared by the tests of the format packages.
+package codectest
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/pgavlin/codec"
+	"github.com/stretchr/testify/require"
+)
+
+// BoolStruct is a struct with a single boolean field.
+type BoolStruct struct {
+	Field bool `codec:"field"`
+}
+
+// Option is an optional string that is encoded as a variant: None if the option is not valid, and Some with the
+// option's value otherwise.
+type Option struct {
+	Valid bool
+	Value string
+}
+
+func (o Option) Serialize(e codec.Encoder) error {
+	if !o.Valid {
+		v, err := e.EncodeVariant("Option", "None", 0)
+		if err != nil {
+			return err
+		}
+		return v.Close()
+	}
+	v, err := e.EncodeVariant("Option", "Some", 1)
+	if err != nil {
+		return err
+	}
+	if err := v.EncodeValue(o.Value, codec.NewString(&o.Value)); err != nil {
+		return err
+	}
+	return v.Close()
+}
+
+func (o *Option) Deserialize(d codec.Decoder) error {
+	return d.DecodeVariant("Option", []string{"None", "Some"}, optionVisitor{o: o})
+}
+
+type optionVisitor struct {
+	codec.DefaultVisitor
+	o *Option
+}
+
+func (v optionVisitor) VisitVariant(d codec.VariantDecoder) error {
+	switch d.Variant() {
+	case "None":
+		*v.o = Option{}
+		return nil
+	case "Some":
+		v.o.Valid = true
+		return d.Value(&v.o.Value, codec.NewString(&v.o.Value))
+	default:
+		return fmt.Errorf("unknown variant %q", d.Variant())
+	}
+}
+
+// SizeVisitor records the sizes reported by sequence and map decoders. Scalars other than unsigned integers are
+// rejected.
+type SizeVisitor struct {
+	codec.DefaultVisitor
+	Sizes *[]string // each size is recorded as "size/ok"
+}
+
+func (v SizeVisitor) record(size int, ok bool) {
+	*v.Sizes = append(*v.Sizes, fmt.Sprintf("%v/%v", size, ok))
+}
+
+func (v SizeVisitor) VisitUint64(uint64) error { return nil }
+
+func (v SizeVisitor) VisitSeq(d codec.SeqDecoder) error {
+	v.record(d.Size())
+	for {
+		ok, err := d.NextElement(nil, v)
+		if !ok || err != nil {
+			return err
+		}
+	}
+}
+
+func (v SizeVisitor) VisitMap(d codec.MapDecoder) error {
+	v.record(d.Size())
+	for {
+		ok, err := d.NextKey(nil, v)
+		if !ok || err != nil {
+			return err
+		}
+		if err = d.NextValue(nil, v); err != nil {
+			return err
+		}
+	}
+}
+
+func (v SizeVisitor) Deserialize(d codec.Decoder) error {
+	return d.DecodeAny(v)
+}
+
+// DecodeHex decodes a value of type T from the hex-encoded input s using unmarshal. The test fails if s cannot be
+// decoded.
+func DecodeHex[T any](t *testing.T, unmarshal func(b []byte, v any) error, s string) T {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+
+	var v T
+	require.NoError(t, unmarshal(b, &v))
+	return v
+}
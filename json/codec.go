@@ -8,9 +8,14 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/pgavlin/codec"
 	"github.com/pgavlin/codec/typecache"
 )
 
+// Format is the codec format for JSON. Types that implement Marshaler, Unmarshaler, encoding.TextMarshaler, or
+// encoding.TextUnmarshaler (e.g. time.Time) are encoded and decoded using those methods.
+var Format = codec.NewFormat("json")
+
 const (
 	// 1000 is the value used by the standard encoding/json package.
 	//
@@ -31,6 +36,7 @@ type encoder struct {
 
 type decoder struct {
 	flags ParseFlags
+	s     *stream // non-nil if the input is read from a stream
 }
 
 type jsonCodec struct {
@@ -46,14 +52,14 @@ func getCodec(v any) jsonCodec {
 			return jsonCodec{}
 		}
 
-		// TOOD:
-		// - json.Number
-		// - json.RawMessage
-
 		// TODO: pointer cases?
 
 		var c jsonCodec
 		switch {
+		case t == numberType:
+			c.encode = encoder.encodeJSONNumber
+		case t == rawMessageType:
+			c.encode = encoder.encodeRawMessage
 		case t.Implements(jsonMarshalerType):
 			c.encode = encoder.encodeJSONMarshaler
 		case t.Implements(textMarshalerType):
@@ -65,6 +71,10 @@ func getCodec(v any) jsonCodec {
 			p = reflect.PtrTo(t)
 		}
 		switch {
+		case p == numberPtrType:
+			c.decode = decoder.decodeJSONNumber
+		case p == rawMessagePtrType:
+			c.decode = decoder.decodeRawMessage
 		case p.Implements(jsonUnmarshalerType):
 			c.decode = decoder.decodeJSONUnmarshaler
 		case p.Implements(textUnmarshalerType):
@@ -169,8 +179,11 @@ var (
 	mapType    = reflect.TypeOf((*map[string]any)(nil)).Elem()
 	structType = reflect.TypeOf((*struct{})(nil)).Elem()
 
-	bytesType  = reflect.TypeOf((*[]byte)(nil)).Elem()
-	numberType = reflect.TypeOf((*Number)(nil)).Elem()
+	bytesType         = reflect.TypeOf((*[]byte)(nil)).Elem()
+	numberType        = reflect.TypeOf((*Number)(nil)).Elem()
+	numberPtrType     = reflect.PtrTo(numberType)
+	rawMessageType    = reflect.TypeOf((*RawMessage)(nil)).Elem()
+	rawMessagePtrType = reflect.PtrTo(rawMessageType)
 
 	jsonMarshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
 	jsonUnmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
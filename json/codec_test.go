@@ -1,10 +1,17 @@
 package json
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/pgavlin/codec"
+	"github.com/pgavlin/codec/internal/codectest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -129,42 +136,602 @@ func TestCodec(t *testing.T) {
 
 func TestCodecReflect(t *testing.T) {
 	var b bool
-	_, err := Parse([]byte("true"), &b, codec.GetDeserializer(&b), 0)
+	_, err := Parse([]byte("true"), &b, codec.GetDeserializer(&b, Format), 0)
 	require.NoError(t, err)
 	assert.Equal(t, true, b)
 
 	var bp *bool
-	_, err = Parse([]byte("true"), &bp, codec.GetDeserializer(&bp), 0)
+	_, err = Parse([]byte("true"), &bp, codec.GetDeserializer(&bp, Format), 0)
 	require.NoError(t, err)
 	require.NotNil(t, bp)
 	assert.Equal(t, true, *bp)
 
-	_, err = Parse([]byte("null"), &bp, codec.GetDeserializer(&bp), 0)
+	_, err = Parse([]byte("null"), &bp, codec.GetDeserializer(&bp, Format), 0)
 	require.NoError(t, err)
 	require.Nil(t, bp)
 
 	var bools []bool
-	_, err = Parse([]byte("[true, false]"), &bools, codec.GetDeserializer(&bools), 0)
+	_, err = Parse([]byte("[true, false]"), &bools, codec.GetDeserializer(&bools, Format), 0)
 	require.NoError(t, err)
 	assert.Equal(t, []bool{true, false}, bools)
 
 	var boolMap map[string]bool
-	_, err = Parse([]byte(`{"42": true}`), &boolMap, codec.GetDeserializer(&boolMap), 0)
+	_, err = Parse([]byte(`{"42": true}`), &boolMap, codec.GetDeserializer(&boolMap, Format), 0)
 	require.NoError(t, err)
 	assert.Equal(t, map[string]bool{"42": true}, boolMap)
 
 	var struct_ boolStruct
-	_, err = Parse([]byte(`{"field": true}`), &struct_, codec.GetDeserializer(&struct_), 0)
+	_, err = Parse([]byte(`{"field": true}`), &struct_, codec.GetDeserializer(&struct_, Format), 0)
 	require.NoError(t, err)
 	assert.Equal(t, boolStruct{Field: true}, struct_)
 
 	var any_ any
-	_, err = Parse([]byte(`{"field": true}`), &any_, codec.GetDeserializer(&any_), 0)
+	_, err = Parse([]byte(`{"field": true}`), &any_, codec.GetDeserializer(&any_, Format), 0)
 	require.NoError(t, err)
 	assert.Equal(t, map[string]any{"field": true}, any_)
 
 	var secret SecretValue
-	_, err = Parse([]byte(`"plaintext"`), &secret, codec.GetDeserializer(&secret), 0)
+	_, err = Parse([]byte(`"plaintext"`), &secret, codec.GetDeserializer(&secret, Format), 0)
 	require.NoError(t, err)
 	assert.Equal(t, SecretValue{Value: "plaintext"}, secret)
 }
+
+func TestDecodeOptions(t *testing.T) {
+	var struct_ boolStruct
+	_, err := Parse([]byte(`{"field": true, "extra": true}`), &struct_, codec.GetDeserializer(&struct_, Format), DisallowUnknownFields)
+	var unknown *codec.UnknownFieldError
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, "boolStruct", unknown.Struct)
+	assert.Equal(t, "extra", unknown.Field)
+
+	struct_ = boolStruct{}
+	_, err = Parse([]byte(`{"FIELD": true}`), &struct_, codec.GetDeserializer(&struct_, Format), 0)
+	require.NoError(t, err)
+	assert.Equal(t, boolStruct{Field: true}, struct_)
+
+	struct_ = boolStruct{}
+	_, err = Parse([]byte(`{"FIELD": true}`), &struct_, codec.GetDeserializer(&struct_, Format), DontMatchCaseInsensitiveStructFields)
+	require.NoError(t, err)
+	assert.Equal(t, boolStruct{}, struct_)
+
+	_, err = Parse([]byte(`{"FIELD": true}`), &struct_, codec.GetDeserializer(&struct_, Format), DisallowUnknownFields|DontMatchCaseInsensitiveStructFields)
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, "FIELD", unknown.Field)
+}
+
+type defaultsStruct struct {
+	Name    string `codec:"name,required"`
+	Mode    string `codec:"mode,default=auto"`
+	Enabled *bool  `codec:"enabled,default=true"`
+}
+
+func TestDefaults(t *testing.T) {
+	var struct_ defaultsStruct
+	require.NoError(t, Unmarshal([]byte(`{"name": "test"}`), &struct_))
+	enabled := true
+	assert.Equal(t, defaultsStruct{Name: "test", Mode: "auto", Enabled: &enabled}, struct_)
+
+	struct_ = defaultsStruct{}
+	require.NoError(t, Unmarshal([]byte(`{"name": "test", "mode": "", "enabled": null}`), &struct_))
+	assert.Equal(t, defaultsStruct{Name: "test"}, struct_)
+
+	err := Unmarshal([]byte(`{"mode": "manual"}`), &struct_)
+	var missing *codec.MissingFieldError
+	require.ErrorAs(t, err, &missing)
+	assert.Equal(t, "defaultsStruct", missing.Struct)
+	assert.Equal(t, "name", missing.Field)
+}
+
+type pathStruct struct {
+	Items  []boolStruct `codec:"items"`
+	Counts []Number     `codec:"counts"`
+}
+
+func TestErrorPaths(t *testing.T) {
+	var struct_ pathStruct
+	err := Unmarshal([]byte(`{"items": [{"field": true}, {"field": "yes"}]}`), &struct_)
+	var typeErr *codec.UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, "boolStruct", typeErr.Struct)
+	assert.Equal(t, "items[1].field", typeErr.Field)
+	assert.EqualError(t, err, "codec: cannot unmarshal string into Go struct field boolStruct.items[1].field of type bool")
+
+	// Type errors reported by the fast-path decoders carry paths as well.
+	err = Unmarshal([]byte(`{"counts": [1, "two"]}`), &struct_)
+	require.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, "pathStruct", typeErr.Struct)
+	assert.Equal(t, "counts[1]", typeErr.Field)
+	assert.Equal(t, reflect.TypeOf(Number("")), typeErr.Type)
+
+	var structs []boolStruct
+	err = Unmarshal([]byte(`[{"field": true}, {"field": "yes"}]`), &structs)
+	assert.EqualError(t, err, "codec: cannot unmarshal string into Go struct field boolStruct[1].field of type bool")
+
+	// Type errors have the same type at every depth, including the top level and map keys.
+	var number Number
+	require.ErrorAs(t, Unmarshal([]byte(`"two"`), &number), &typeErr)
+	var numbers map[int]Number
+	require.ErrorAs(t, Unmarshal([]byte(`{"one": 1}`), &numbers), &typeErr)
+	require.ErrorAs(t, NewDecoder(strings.NewReader(`"two"`)).Decode(&number), &typeErr)
+
+	err = Unmarshal([]byte(`{"items": [{"field": "yes"}}`), &struct_)
+	var syntaxErr *SyntaxError
+	require.ErrorAs(t, err, &syntaxErr)
+}
+
+type testShape interface {
+	Sides() int
+}
+
+type testCircle struct {
+	Kind   string  `codec:"kind,omitempty"`
+	Radius float64 `codec:"radius"`
+}
+
+func (testCircle) Sides() int { return 0 }
+
+type testTriangle struct {
+	Base  float64   `codec:"base"`
+	Label testLabel `codec:"label"`
+}
+
+func (*testTriangle) Sides() int { return 3 }
+
+type testLabel struct {
+	Text string `codec:"text,omitempty"`
+}
+
+func TestUnion(t *testing.T) {
+	codec.RegisterUnion[testShape]("kind", map[string]reflect.Type{
+		"circle":   reflect.TypeOf(testCircle{}),
+		"triangle": reflect.TypeOf((*testTriangle)(nil)),
+	})
+
+	shapes := []testShape{testCircle{Radius: 1.5}, &testTriangle{Base: 2.5}, nil}
+	b, err := Marshal(shapes)
+	require.NoError(t, err)
+	assert.Equal(t, `[{"kind":"circle","radius":1.5},{"kind":"triangle","base":2.5,"label":{}},null]`, string(b))
+
+	var decoded []testShape
+	require.NoError(t, Unmarshal([]byte(`[{"radius":1.5,"kind":"circle"},{"kind":"triangle","base":2.5},null]`), &decoded))
+	assert.Equal(t, shapes, decoded)
+
+	b, err = Marshal([]testShape{testCircle{Kind: "ignored", Radius: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, `[{"kind":"circle","radius":1}]`, string(b))
+
+	err = Unmarshal([]byte(`[{"kind":"square"}]`), &decoded)
+	assert.EqualError(t, err, `codec: unknown variant "square" of union json.testShape`)
+
+	// Keys that precede the discriminator are replayed with the flags of the decoder.
+	var shape testShape
+	input := `{"base":2.5,"label":{"text":"t","extra":true},"kind":"triangle"}`
+	_, err = Parse([]byte(input), &shape, codec.NewUnion(&shape), UseNumber)
+	require.NoError(t, err)
+	assert.Equal(t, &testTriangle{Base: 2.5, Label: testLabel{Text: "t"}}, shape)
+
+	_, err = Parse([]byte(input), &shape, codec.NewUnion(&shape), DisallowUnknownFields)
+	assert.EqualError(t, err, `codec: unknown field "extra" in Go struct testLabel`)
+}
+
+func TestVariant(t *testing.T) {
+	options := []codectest.Option{{}, {Valid: true, Value: "x"}}
+	b, err := Marshal(options)
+	require.NoError(t, err)
+	assert.Equal(t, `["None",{"Some":"x"}]`, string(b))
+
+	var decoded []codectest.Option
+	require.NoError(t, Unmarshal(b, &decoded))
+	assert.Equal(t, options, decoded)
+
+	_, err = Marshal(map[codectest.Option]bool{{Valid: true}: true})
+	assert.Error(t, err)
+
+	err = Unmarshal([]byte(`[{"Some":"x","None":null}]`), &decoded)
+	assert.Error(t, err)
+
+	err = Unmarshal([]byte(`[{}]`), &decoded)
+	assert.Error(t, err)
+}
+
+func TestNumbers(t *testing.T) {
+	var ports []uint16
+	require.NoError(t, Unmarshal([]byte(`[80, 443, 8080.0]`), &ports))
+	assert.Equal(t, []uint16{80, 443, 8080}, ports)
+
+	var f float32
+	require.NoError(t, Unmarshal([]byte(`-3`), &f))
+	assert.Equal(t, float32(-3), f)
+
+	var overflow []uint16
+	err := Unmarshal([]byte(`[80, 70000]`), &overflow)
+	assert.EqualError(t, err, "codec: cannot unmarshal number 70000 into Go value at [1] of type uint16")
+
+	var i int
+	err = Unmarshal([]byte(`1.5`), &i)
+	assert.EqualError(t, err, "codec: cannot unmarshal number 1.5 into Go value of type int")
+}
+
+func TestDecoder(t *testing.T) {
+	d := NewDecoder(iotest.OneByteReader(strings.NewReader(` {"field": true} [1, 2]"x"3.5 null {"ptr": {"field": false}} `)))
+
+	var s boolStruct
+	require.NoError(t, d.Decode(&s))
+	assert.Equal(t, boolStruct{Field: true}, s)
+
+	var ints []int
+	require.NoError(t, d.Decode(&ints))
+	assert.Equal(t, []int{1, 2}, ints)
+
+	var str string
+	require.NoError(t, d.Decode(&str))
+	assert.Equal(t, "x", str)
+
+	var f float64
+	require.NoError(t, d.Decode(&f))
+	assert.Equal(t, 3.5, f)
+
+	var p *boolStruct
+	require.NoError(t, d.Decode(&p))
+	assert.Nil(t, p)
+
+	var ptr struct {
+		Ptr *boolStruct `codec:"ptr"`
+	}
+	require.NoError(t, d.Decode(&ptr))
+	assert.Equal(t, &boolStruct{Field: false}, ptr.Ptr)
+
+	assert.Equal(t, io.EOF, d.Decode(&s))
+
+	d = NewDecoder(strings.NewReader(`[1, 2 3]`))
+	err := d.Decode(&ints)
+	assert.IsType(t, &SyntaxError{}, err)
+	assert.Equal(t, err, d.Decode(&ints))
+}
+
+func TestDecoderToken(t *testing.T) {
+	d := NewDecoder(iotest.OneByteReader(strings.NewReader(`{"a": [1, "b", {"c": null}], "d": true}`)))
+
+	var tokens []Token
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		tokens = append(tokens, tok)
+	}
+	assert.Equal(t, []Token{
+		Delim('{'), "a", Delim('['), float64(1), "b", Delim('{'), "c", nil, Delim('}'), Delim(']'), "d", true, Delim('}'),
+	}, tokens)
+
+	// Numbers are returned with the same types as encoding/json.
+	input := `[-1, 2.5, 18446744073709551616, 1e400]`
+	for _, useNumber := range []bool{false, true} {
+		expected := json.NewDecoder(strings.NewReader(input))
+		d = NewDecoder(strings.NewReader(input))
+		if useNumber {
+			expected.UseNumber()
+			d.UseNumber()
+		}
+		for {
+			want, wantErr := expected.Token()
+			got, err := d.Token()
+			if wantErr != nil {
+				assert.EqualError(t, err, wantErr.Error())
+				break
+			}
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		}
+	}
+
+	d = NewDecoder(strings.NewReader(`[}`))
+	_, err := d.Token()
+	require.NoError(t, err)
+	_, err = d.Token()
+	assert.IsType(t, &SyntaxError{}, err)
+}
+
+func TestDecoderMore(t *testing.T) {
+	d := NewDecoder(strings.NewReader(`[{"field": true}, {"field": false}, {"field": true}]`))
+
+	tok, err := d.Token()
+	require.NoError(t, err)
+	assert.Equal(t, Delim('['), tok)
+
+	var fields []bool
+	for d.More() {
+		var s boolStruct
+		require.NoError(t, d.Decode(&s))
+		fields = append(fields, s.Field)
+	}
+	assert.Equal(t, []bool{true, false, true}, fields)
+
+	tok, err = d.Token()
+	require.NoError(t, err)
+	assert.Equal(t, Delim(']'), tok)
+}
+
+func TestDecoderZero(t *testing.T) {
+	// Decoders that were not returned by NewDecoder have no input to stream.
+	var d Decoder
+	var s boolStruct
+	assert.EqualError(t, d.Decode(&s), "json: Decode and Token require a decoder returned by NewDecoder")
+	assert.False(t, d.More())
+	_, err := d.Token()
+	assert.EqualError(t, err, "json: Decode and Token require a decoder returned by NewDecoder")
+}
+
+// arrayReader reads a JSON array with n copies of elem.
+type arrayReader struct {
+	elem string
+	n, i int
+	buf  []byte
+}
+
+func (r *arrayReader) Read(b []byte) (int, error) {
+	for len(r.buf) < len(b) && r.i <= r.n+1 {
+		switch {
+		case r.i == 0:
+			r.buf = append(r.buf, '[')
+		case r.i == r.n+1:
+			r.buf = append(r.buf, ']')
+		default:
+			r.buf = append(r.buf, r.elem...)
+			if r.i < r.n {
+				r.buf = append(r.buf, ',')
+			}
+		}
+		r.i++
+	}
+	if len(r.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+type countVisitor struct {
+	codec.DefaultVisitor
+	count, fields int
+}
+
+func (v *countVisitor) VisitSeq(d codec.SeqDecoder) error {
+	for {
+		var s boolStruct
+		ok, err := d.NextElement(&s, codec.GetDeserializer(&s, Format))
+		if err != nil || !ok {
+			return err
+		}
+		v.count++
+		if s.Field {
+			v.fields++
+		}
+	}
+}
+
+func (v *countVisitor) Deserialize(d codec.Decoder) error {
+	return d.DecodeSeq(v)
+}
+
+func TestDecoderLargeArray(t *testing.T) {
+	const n = 100000
+
+	d := NewDecoder(&arrayReader{elem: `{"field": true, "ignored": [1, 2, 3]}`, n: n})
+	var v countVisitor
+	require.NoError(t, d.Decode(&v))
+	assert.Equal(t, n, v.count)
+	assert.Equal(t, n, v.fields)
+	assert.Less(t, cap(d.s.buf), 64*1024)
+
+	assert.Equal(t, io.EOF, d.Decode(&v))
+}
+
+func TestEncoder(t *testing.T) {
+	var buf strings.Builder
+	e := NewEncoder(&buf)
+	require.NoError(t, e.Encode(boolStruct{Field: true}))
+	require.NoError(t, e.Encode([]int{1, 2}))
+	require.NoError(t, e.Encode("<a>"))
+	e.SetEscapeHTML(false)
+	require.NoError(t, e.Encode("<a>"))
+	assert.Equal(t, "{\"field\":true}\n[1,2]\n\"\\u003ca\\u003e\"\n\"<a>\"\n", buf.String())
+
+	value := map[string]any{
+		"array":  []any{1, "x", map[string]any{}, []any{}},
+		"empty":  []int{},
+		"nested": map[string]any{"a": nil, "b": []any{[]any{true}}},
+		"secret": SecretValue{Value: "s", Secret: true},
+	}
+	for _, indent := range [][2]string{{"", "  "}, {">", "\t"}, {"", ""}} {
+		var expected strings.Builder
+		std := json.NewEncoder(&expected)
+		std.SetIndent(indent[0], indent[1])
+		require.NoError(t, std.Encode(value))
+
+		buf.Reset()
+		e := NewEncoder(&buf)
+		e.SetIndent(indent[0], indent[1])
+		require.NoError(t, e.Encode(value))
+		assert.Equal(t, expected.String(), buf.String())
+	}
+}
+
+type countingWriter struct {
+	strings.Builder
+	writes int
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.writes++
+	return w.Builder.Write(b)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(b []byte) (int, error) {
+	return 0, errors.New("failed")
+}
+
+func TestEncoderFlush(t *testing.T) {
+	ints := make([]int, 100000)
+	for i := range ints {
+		ints[i] = i
+	}
+	expected, err := Marshal(ints)
+	require.NoError(t, err)
+
+	var w countingWriter
+	e := NewEncoder(&w)
+	require.NoError(t, e.Encode(ints))
+	assert.Equal(t, string(expected)+"\n", w.String())
+	assert.Greater(t, w.writes, 1)
+	assert.Less(t, cap(e.out), 2*encoderFlushSize)
+
+	e = NewEncoder(failingWriter{})
+	assert.EqualError(t, e.Encode(ints), "failed")
+	assert.EqualError(t, e.Encode(true), "failed")
+}
+
+func TestMarshalIndent(t *testing.T) {
+	value := map[string]any{
+		"array":  []any{1, "x", map[string]any{}, []any{}},
+		"nested": map[string]any{"a": nil, "b": []any{[]any{true}}},
+		"secret": SecretValue{Value: "s", Secret: true},
+	}
+	expected, err := json.MarshalIndent(value, "#", "  ")
+	require.NoError(t, err)
+	actual, err := MarshalIndent(value, "#", "  ")
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(actual))
+}
+
+func TestIndentCompactValid(t *testing.T) {
+	inputs := []string{
+		`null`,
+		` {"a" : [1, 2, {"b": "c, d: [e]"}], "f": {}, "g": [ ], "h": "\"{"}  `,
+		"\n[\"\\u00e9\", -1.5e3, true, false]\t\n",
+		`[1 2]`,
+		`{"a": 1,}`,
+		`1 2`,
+		`"unterminated`,
+		``,
+	}
+	for _, input := range inputs {
+		src := []byte(input)
+		assert.Equal(t, json.Valid(src), Valid(src), input)
+
+		var expected, actual bytes.Buffer
+		expectedErr := json.Indent(&expected, src, ">", "\t")
+		actualErr := Indent(&actual, src, ">", "\t")
+		if expectedErr != nil {
+			assert.Error(t, actualErr, input)
+			assert.Zero(t, actual.Len(), input)
+		} else if assert.NoError(t, actualErr, input) {
+			assert.Equal(t, expected.String(), actual.String(), input)
+		}
+
+		expected.Reset()
+		actual.Reset()
+		expectedErr = json.Compact(&expected, src)
+		actualErr = Compact(&actual, src)
+		if expectedErr != nil {
+			assert.Error(t, actualErr, input)
+			assert.Zero(t, actual.Len(), input)
+		} else if assert.NoError(t, actualErr, input) {
+			assert.Equal(t, expected.String(), actual.String(), input)
+		}
+	}
+}
+
+func TestNumberRawMessage(t *testing.T) {
+	type message struct {
+		N   Number     `json:"n" codec:"n"`
+		Raw RawMessage `json:"raw" codec:"raw"`
+	}
+
+	input := []byte(`{"n": 12345678901234567890.5, "raw": {"a": [1, true, "b"]}}`)
+
+	var m message
+	require.NoError(t, Unmarshal(input, &m))
+	assert.Equal(t, Number("12345678901234567890.5"), m.N)
+	assert.Equal(t, RawMessage(`{"a": [1, true, "b"]}`), m.Raw)
+
+	// Without DontCopyRawMessage the message must not alias the input.
+	input[len(input)-5] = 'x'
+	assert.Equal(t, RawMessage(`{"a": [1, true, "b"]}`), m.Raw)
+
+	var zeroCopy message
+	_, err := Parse(input, &zeroCopy, codec.GetDeserializer(&zeroCopy, Format), ZeroCopy)
+	require.NoError(t, err)
+	assert.Equal(t, RawMessage(`{"a": [1, true, "x"]}`), zeroCopy.Raw)
+
+	actual, err := Marshal(m)
+	require.NoError(t, err)
+	expected, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(actual))
+
+	actual, err = Marshal(message{})
+	require.NoError(t, err)
+	assert.Equal(t, `{"n":0,"raw":null}`, string(actual))
+
+	_, err = Marshal(Number("1x"))
+	assert.EqualError(t, err, `json: invalid number literal "1x"`)
+	_, err = Marshal(RawMessage(`{`))
+	assert.Error(t, err)
+
+	var n Number
+	require.NoError(t, Unmarshal([]byte(`"-1e10"`), &n))
+	assert.Equal(t, Number("-1e10"), n)
+	assert.Error(t, Unmarshal([]byte(`"abc"`), &n))
+	assert.Error(t, Unmarshal([]byte(`true`), &n))
+}
+
+func TestUseNumber(t *testing.T) {
+	input := `{"a": 1, "b": [-2.5, 18446744073709551616]}`
+
+	var v any
+	_, err := Parse([]byte(input), &v, codec.NewAny(&v), UseNumber)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": Number("1"), "b": []any{Number("-2.5"), Number("18446744073709551616")}}, v)
+
+	var expected any
+	d := json.NewDecoder(strings.NewReader(input))
+	d.UseNumber()
+	require.NoError(t, d.Decode(&expected))
+
+	v = nil
+	dec := NewDecoder(strings.NewReader(input))
+	dec.UseNumber()
+	require.NoError(t, dec.Decode(&v))
+	assert.Equal(t, expected, v)
+}
+
+// numberText is a visitor that records the text of a number literal.
+type numberText struct {
+	codec.DefaultVisitor
+	text *string
+}
+
+func (v numberText) VisitNumber(n codec.Number) error {
+	*v.text = n.String()
+	return nil
+}
+
+func (v numberText) Deserialize(d codec.Decoder) error {
+	return d.DecodeAny(v)
+}
+
+func TestNumberVisitor(t *testing.T) {
+	var text string
+	_, err := Parse([]byte(`1.50`), &text, numberText{text: &text}, UseNumber)
+	require.NoError(t, err)
+	assert.Equal(t, "1.50", text)
+
+	// Without UseNumber, numbers are visited as Go numeric values.
+	_, err = Parse([]byte(`1.50`), &text, numberText{text: &text}, 0)
+	assert.Error(t, err)
+}
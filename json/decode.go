@@ -2,6 +2,7 @@ package json
 
 import (
 	"encoding"
+	"errors"
 	"math"
 	"reflect"
 	"strconv"
@@ -109,6 +110,18 @@ func (d decoder) decodeNumber(b []byte, cv codec.Visitor) ([]byte, error) {
 		return r, err
 	}
 
+	if (d.flags & UseNumber) != 0 {
+		if nv, ok := cv.(codec.NumberVisitor); ok {
+			var n string
+			if (d.flags & DontCopyNumber) != 0 {
+				n = *(*string)(unsafe.Pointer(&v))
+			} else {
+				n = string(v)
+			}
+			return r, nv.VisitNumber(Number(n))
+		}
+	}
+
 	switch kind {
 	case Uint:
 		u, err := convUint(v)
@@ -153,7 +166,7 @@ func (d decoder) decodePtr(b []byte, cv codec.Visitor) ([]byte, error) {
 	if hasNullPrefix(b) {
 		return b[4:], cv.VisitNil()
 	}
-	dec := ElemDecoder{rest: b, flags: d.flags}
+	dec := ElemDecoder{rest: b, flags: d.flags, s: d.s}
 	err := cv.VisitElem(&dec)
 	return dec.rest, err
 }
@@ -167,7 +180,7 @@ func (d decoder) decodeObject(b []byte, cv codec.Visitor) ([]byte, error) {
 		return b, syntaxError(b, "expected '{' at the beginning of an object value")
 	}
 
-	dec := MapDecoder{first: true, rest: b[1:], flags: d.flags}
+	dec := MapDecoder{first: true, rest: b[1:], flags: d.flags, s: d.s}
 	err := cv.VisitMap(&dec)
 	return dec.rest, err
 }
@@ -181,11 +194,59 @@ func (d decoder) decodeArray(b []byte, cv codec.Visitor) ([]byte, error) {
 		return b, syntaxError(b, "expected '[' at the beginning of array value")
 	}
 
-	dec := SeqDecoder{first: true, rest: b[1:], flags: d.flags}
+	dec := SeqDecoder{first: true, rest: b[1:], flags: d.flags, s: d.s}
 	err := cv.VisitSeq(&dec)
 	return dec.rest, err
 }
 
+// decodeVariant decodes an externally tagged variant: either a string that names a unit variant or a single-key object
+// that maps a variant's name to its value. Other values are decoded as-is.
+func (d decoder) decodeVariant(b []byte, cv codec.Visitor) ([]byte, error) {
+	if len(b) == 0 {
+		return b, unexpectedEOF(b)
+	}
+
+	switch b[0] {
+	case '"':
+		s, r, _, err := d.parseStringUnquote(b, nil)
+		if err != nil {
+			return r, err
+		}
+		return r, cv.VisitVariant(&VariantDecoder{name: string(s), flags: d.flags})
+	case '{':
+		dec := MapDecoder{first: true, rest: b[1:], flags: d.flags, s: d.s}
+		var name string
+		ok, err := dec.NextKey(&name, codec.NewString(&name))
+		if err != nil {
+			return dec.rest, err
+		}
+		if !ok {
+			return dec.rest, errors.New("json: cannot unmarshal empty object into variant")
+		}
+
+		variant := VariantDecoder{name: name, value: &dec, flags: d.flags}
+		if err := cv.VisitVariant(&variant); err != nil {
+			return dec.rest, err
+		}
+		if !variant.done {
+			if err := dec.NextValue(nil, codec.SkipCodec{}); err != nil {
+				return dec.rest, err
+			}
+		}
+
+		ok, err = dec.NextKey(nil, codec.SkipCodec{})
+		if err != nil {
+			return dec.rest, err
+		}
+		if ok {
+			return dec.rest, errors.New("json: cannot unmarshal object with multiple keys into variant")
+		}
+		return dec.rest, nil
+	default:
+		return d.decodeValue(b, cv)
+	}
+}
+
 func (d decoder) decodeValue(b []byte, cv codec.Visitor) ([]byte, error) {
 	if len(b) == 0 {
 		return b, syntaxError(b, "unexpected end of JSON input")
@@ -252,6 +313,54 @@ func (d decoder) decodeBytes(b []byte, cv codec.Visitor) ([]byte, error) {
 	return r, cv.VisitBytes(dst[:n])
 }
 
+func (d decoder) decodeJSONNumber(b []byte, v any) ([]byte, error) {
+	if hasNullPrefix(b) {
+		return b[4:], nil
+	}
+
+	j, r, k, err := d.parseValue(b)
+	if err != nil {
+		return r, err
+	}
+
+	// Like encoding/json, a string that contains a valid number literal may be decoded into a Number.
+	new := false
+	switch k.Class() {
+	case Num:
+	case String:
+		if j, _, new, err = d.parseStringUnquote(j, nil); err != nil {
+			return r, err
+		}
+		if _, rest, _, err := d.parseNumber(j); err != nil || len(rest) != 0 {
+			return r, &UnmarshalTypeError{Value: "string " + strconv.Quote(string(j)), Type: numberType}
+		}
+	default:
+		return r, unmarshalTypeError(b, numberType)
+	}
+
+	var n string
+	if new || (d.flags&DontCopyNumber) != 0 {
+		n = *(*string)(unsafe.Pointer(&j))
+	} else {
+		n = string(j)
+	}
+	*v.(*Number) = Number(n)
+	return r, nil
+}
+
+func (d decoder) decodeRawMessage(b []byte, v any) ([]byte, error) {
+	j, r, _, err := d.parseValue(b)
+	if err != nil {
+		return r, err
+	}
+
+	if (d.flags & DontCopyRawMessage) == 0 {
+		j = append(make([]byte, 0, len(j)), j...)
+	}
+	*v.(*RawMessage) = j
+	return r, nil
+}
+
 func (d decoder) decodeJSONUnmarshaler(b []byte, v any) ([]byte, error) {
 	j, b, _, err := d.parseValue(b)
 	if err != nil {
@@ -314,10 +423,11 @@ func (d decoder) decodeTextUnmarshaler(b []byte, v any) ([]byte, error) {
 type ElemDecoder struct {
 	rest  []byte
 	flags ParseFlags
+	s     *stream
 }
 
 func (d *ElemDecoder) Element(v any, ds codec.Deserializer) error {
-	dec := Decoder{rest: d.rest, flags: d.flags}
+	dec := Decoder{rest: d.rest, flags: d.flags, s: d.s}
 	err := dec.decode(v, ds)
 	d.rest = dec.rest
 	return err
@@ -327,6 +437,7 @@ type SeqDecoder struct {
 	first bool
 	rest  []byte
 	flags ParseFlags
+	s     *stream
 }
 
 func (d *SeqDecoder) Size() (int, bool) {
@@ -334,7 +445,11 @@ func (d *SeqDecoder) Size() (int, bool) {
 }
 
 func (d *SeqDecoder) NextElement(v any, ds codec.Deserializer) (bool, error) {
-	b := skipSpaces(d.rest)
+	b, err := d.s.skipSpaces(d.rest)
+	if err != nil {
+		d.rest = b
+		return false, err
+	}
 
 	if len(b) == 0 {
 		d.rest = b
@@ -355,7 +470,10 @@ func (d *SeqDecoder) NextElement(v any, ds codec.Deserializer) (bool, error) {
 			d.rest = b
 			return false, syntaxError(b, "expected ',' after array element but found '%c'", b[0])
 		}
-		b = skipSpaces(b[1:])
+		if b, err = d.s.skipSpaces(b[1:]); err != nil {
+			d.rest = b
+			return false, err
+		}
 		if len(b) == 0 {
 			d.rest = b
 			return false, unexpectedEOF(b)
@@ -368,8 +486,8 @@ func (d *SeqDecoder) NextElement(v any, ds codec.Deserializer) (bool, error) {
 		d.first = false
 	}
 
-	dec := Decoder{rest: b, flags: d.flags}
-	err := dec.decode(v, ds)
+	dec := Decoder{rest: b, flags: d.flags, s: d.s}
+	err = dec.decode(v, ds)
 	d.rest = dec.rest
 	return err == nil, err
 }
@@ -378,14 +496,26 @@ type MapDecoder struct {
 	first bool
 	rest  []byte
 	flags ParseFlags
+	s     *stream
 }
 
 func (d *MapDecoder) Size() (int, bool) {
 	return 0, false
 }
 
+func (d *MapDecoder) Options() codec.DecodeOptions {
+	return codec.DecodeOptions{
+		DisallowUnknownFields: d.flags.has(DisallowUnknownFields),
+		CaseSensitive:         d.flags.has(DontMatchCaseInsensitiveStructFields),
+	}
+}
+
 func (d *MapDecoder) NextKey(k any, ds codec.Deserializer) (bool, error) {
-	b := skipSpaces(d.rest)
+	b, err := d.s.skipSpaces(d.rest)
+	if err != nil {
+		d.rest = b
+		return false, err
+	}
 
 	if len(b) == 0 {
 		d.rest = b
@@ -406,7 +536,10 @@ func (d *MapDecoder) NextKey(k any, ds codec.Deserializer) (bool, error) {
 			d.rest = b
 			return false, syntaxError(b, "expected ',' after object field value but found '%c'", b[0])
 		}
-		b = skipSpaces(b[1:])
+		if b, err = d.s.skipSpaces(b[1:]); err != nil {
+			d.rest = b
+			return false, err
+		}
 		if len(b) == 0 {
 			d.rest = b
 			return false, unexpectedEOF(b)
@@ -419,14 +552,24 @@ func (d *MapDecoder) NextKey(k any, ds codec.Deserializer) (bool, error) {
 		d.first = false
 	}
 
+	// Keys are decoded by mapKeyDecoder, which requires the complete key.
+	if b, err = d.s.fill(b, false); err != nil {
+		d.rest = b
+		return false, err
+	}
+
 	dec := mapKeyDecoder{dec: &Decoder{rest: b, flags: d.flags}}
-	err := dec.decode(k, ds)
+	err = dec.decode(k, ds)
 	d.rest = dec.dec.rest
 	return err == nil, err
 }
 
 func (d *MapDecoder) NextValue(v any, ds codec.Deserializer) error {
-	b := d.rest
+	b, err := d.s.skipSpaces(d.rest)
+	if err != nil {
+		d.rest = b
+		return err
+	}
 	if len(b) == 0 {
 		d.rest = b
 		return syntaxError(b, "unexpected EOF after object field key")
@@ -435,10 +578,13 @@ func (d *MapDecoder) NextValue(v any, ds codec.Deserializer) error {
 		d.rest = b
 		return syntaxError(b, "expected ':' after object field key but found '%c'", b[0])
 	}
-	b = skipSpaces(b[1:])
+	if b, err = d.s.skipSpaces(b[1:]); err != nil {
+		d.rest = b
+		return err
+	}
 
-	dec := Decoder{rest: b, flags: d.flags}
-	err := dec.decode(v, ds)
+	dec := Decoder{rest: b, flags: d.flags, s: d.s}
+	err = dec.decode(v, ds)
 	d.rest = dec.rest
 	return err
 }
@@ -446,19 +592,42 @@ func (d *MapDecoder) NextValue(v any, ds codec.Deserializer) error {
 type Decoder struct {
 	rest  []byte
 	flags ParseFlags
+	s     *stream // non-nil if the decoder was returned by NewDecoder or reads from its input
 }
 
+// decode decodes a value using its fast-path decoder, if it has one, or its deserializer otherwise. Type errors returned
+// by the fast-path decoders are converted to codec.UnmarshalTypeErrors so that type errors have the same type at every
+// depth, and so that the codecs of enclosing values can add path information to them.
 func (d *Decoder) decode(v any, ds codec.Deserializer) (err error) {
 	codec := getCodec(v)
 	if codec.decode != nil {
+		if d.rest, err = d.s.fill(d.rest, false); err != nil {
+			return err
+		}
 		d.rest, err = codec.decode(decoder{flags: d.flags}, d.rest, v)
-		return
+		return typeError(err)
 	}
 	return ds.Deserialize(d)
 }
 
+// typeError converts an UnmarshalTypeError returned by a fast-path decoder into a codec.UnmarshalTypeError.
+func typeError(err error) error {
+	if e, ok := err.(*UnmarshalTypeError); ok {
+		return &codec.UnmarshalTypeError{Value: e.Value, Type: e.Type, Offset: e.Offset, Struct: e.Struct, Field: e.Field}
+	}
+	return err
+}
+
+// Format returns the JSON format.
+func (d *Decoder) Format() *codec.Format {
+	return Format
+}
+
 func (d *Decoder) DecodeAny(v codec.Visitor) (err error) {
-	dec := decoder{flags: d.flags}
+	if d.rest, err = d.s.fill(d.rest, true); err != nil {
+		return err
+	}
+	dec := decoder{flags: d.flags, s: d.s}
 	d.rest, err = dec.decodeValue(d.rest, v)
 	return
 }
@@ -536,13 +705,19 @@ func (d *Decoder) DecodeString(v codec.Visitor) error {
 }
 
 func (d *Decoder) DecodeBytes(v codec.Visitor) (err error) {
+	if d.rest, err = d.s.fill(d.rest, false); err != nil {
+		return err
+	}
 	dec := decoder{flags: d.flags}
 	d.rest, err = dec.decodeBytes(d.rest, v)
 	return
 }
 
 func (d *Decoder) DecodePtr(v codec.Visitor) (err error) {
-	dec := decoder{flags: d.flags}
+	if d.rest, err = d.s.fill(d.rest, true); err != nil {
+		return err
+	}
+	dec := decoder{flags: d.flags, s: d.s}
 	d.rest, err = dec.decodePtr(d.rest, v)
 	return
 }
@@ -558,3 +733,36 @@ func (d *Decoder) DecodeMap(v codec.Visitor) error {
 func (d *Decoder) DecodeStruct(name string, v codec.Visitor) error {
 	return d.DecodeAny(v)
 }
+
+func (d *Decoder) DecodeVariant(enum string, variants []string, v codec.Visitor) (err error) {
+	if d.rest, err = d.s.fill(d.rest, true); err != nil {
+		return err
+	}
+	dec := decoder{flags: d.flags, s: d.s}
+	d.rest, err = dec.decodeVariant(d.rest, v)
+	return
+}
+
+type VariantDecoder struct {
+	name  string
+	value *MapDecoder // nil for unit variants
+	flags ParseFlags
+	done  bool
+}
+
+func (d *VariantDecoder) Variant() string {
+	return d.name
+}
+
+func (d *VariantDecoder) Value(v any, ds codec.Deserializer) error {
+	if d.done {
+		return errors.New("json: variant value has already been decoded")
+	}
+	d.done = true
+
+	if d.value == nil {
+		dec := Decoder{rest: []byte("null"), flags: d.flags}
+		return dec.decode(v, ds)
+	}
+	return d.value.NextValue(v, ds)
+}
@@ -2,6 +2,8 @@ package json
 
 import (
 	"encoding"
+	"fmt"
+	"io"
 	"math"
 	"reflect"
 	"strconv"
@@ -16,17 +18,85 @@ const hex = "0123456789abcdef"
 type Encoder struct {
 	enc encoder
 	out []byte
+
+	w   io.Writer // if non-nil, out is flushed to w as values are encoded
+	err error     // the first error returned by w, if any
+
+	prefix, indent string // see SetIndent
+	depth          int    // the number of unclosed arrays and objects
 }
 
 func (e *Encoder) encode(v any, s codec.Serializer) (err error) {
 	codec := getCodec(v)
 	if codec.encode != nil {
+		start := len(e.out)
 		e.out, err = codec.encode(e.enc, e.out, v)
+		if err == nil && e.indenting() {
+			// Marshalers produce compact JSON that must be indented to match the enclosing output.
+			value := append([]byte(nil), e.out[start:]...)
+			e.out = appendIndent(e.out[:start], value, e.prefix, e.indent, e.depth)
+		}
 		return
 	}
 	return s.Serialize(e)
 }
 
+func (e *Encoder) indenting() bool {
+	return e.prefix != "" || e.indent != ""
+}
+
+// open begins an array or object with the delimiter c.
+func (e *Encoder) open(c byte) {
+	e.out = append(e.out, c)
+	e.depth++
+}
+
+// element begins an element of an array or object.
+func (e *Encoder) element(first bool) {
+	if !first {
+		e.out = append(e.out, ',')
+	}
+	if e.indenting() {
+		e.out = appendLineBreak(e.out, e.prefix, e.indent, e.depth)
+	}
+}
+
+// colon separates an object key from its value.
+func (e *Encoder) colon() {
+	e.out = append(e.out, ':')
+	if e.indenting() {
+		e.out = append(e.out, ' ')
+	}
+}
+
+// close ends an array or object with the delimiter c.
+func (e *Encoder) close(c byte, empty bool) error {
+	e.depth--
+	if !empty && e.indenting() {
+		e.out = appendLineBreak(e.out, e.prefix, e.indent, e.depth)
+	}
+	e.out = append(e.out, c)
+	return e.flush(false)
+}
+
+// flush writes the encoded output to the encoder's writer, if any. Unless force is true, the output is written only
+// once enough of it has been buffered.
+func (e *Encoder) flush(force bool) error {
+	if e.w == nil || !force && len(e.out) < encoderFlushSize {
+		return e.err
+	}
+	if e.err == nil && len(e.out) != 0 {
+		_, e.err = e.w.Write(e.out)
+	}
+	e.out = e.out[:0]
+	return e.err
+}
+
+// Format returns the JSON format.
+func (e *Encoder) Format() *codec.Format {
+	return Format
+}
+
 func (e *Encoder) EncodeNil() (err error) {
 	e.out, err = e.enc.encodeNull(e.out)
 	return
@@ -125,37 +195,42 @@ func (e *Encoder) EncodeElem(v any, s codec.Serializer) error {
 }
 
 func (e *Encoder) EncodeSeq(count int) (codec.SeqEncoder, error) {
-	e.out = append(e.out, '[')
+	e.open('[')
 	return &SeqEncoder{enc: e, first: true}, nil
 }
 
 func (e *Encoder) EncodeMap(len int) (codec.MapEncoder, error) {
-	e.out = append(e.out, '{')
+	e.open('{')
 	return &MapEncoder{enc: e, first: true}, nil
 }
 
 func (e *Encoder) EncodeStruct(name string) (codec.StructEncoder, error) {
-	e.out = append(e.out, '{')
+	e.open('{')
 	return &StructEncoder{enc: e, first: true}, nil
 }
 
+// EncodeVariant encodes a variant using the externally tagged representation: unit variants are encoded as their names,
+// and other variants are encoded as single-key objects that map their names to their values.
+func (e *Encoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	return &VariantEncoder{enc: e, name: variant}, nil
+}
+
 type SeqEncoder struct {
 	enc   *Encoder
 	first bool
 }
 
 func (e *SeqEncoder) Close() error {
-	e.enc.out = append(e.enc.out, ']')
-	return nil
+	return e.enc.close(']', e.first)
 }
 
 func (e *SeqEncoder) EncodeElement(v any, s codec.Serializer) error {
-	if !e.first {
-		e.enc.out = append(e.enc.out, ',')
-	} else {
-		e.first = false
+	e.enc.element(e.first)
+	e.first = false
+	if err := e.enc.encode(v, s); err != nil {
+		return err
 	}
-	return e.enc.encode(v, s)
+	return e.enc.flush(false)
 }
 
 type MapEncoder struct {
@@ -164,22 +239,21 @@ type MapEncoder struct {
 }
 
 func (e *MapEncoder) Close() error {
-	e.enc.out = append(e.enc.out, '}')
-	return nil
+	return e.enc.close('}', e.first)
 }
 
 func (e *MapEncoder) EncodeKey(k any, s codec.Serializer) error {
-	if !e.first {
-		e.enc.out = append(e.enc.out, ',')
-	} else {
-		e.first = false
-	}
+	e.enc.element(e.first)
+	e.first = false
 	return mapKeyEncoder{enc: e.enc}.encode(k, s)
 }
 
 func (e *MapEncoder) EncodeValue(v any, s codec.Serializer) error {
-	e.enc.out = append(e.enc.out, ':')
-	return e.enc.encode(v, s)
+	e.enc.colon()
+	if err := e.enc.encode(v, s); err != nil {
+		return err
+	}
+	return e.enc.flush(false)
 }
 
 type StructEncoder struct {
@@ -188,21 +262,44 @@ type StructEncoder struct {
 }
 
 func (e *StructEncoder) Close() error {
-	e.enc.out = append(e.enc.out, '}')
-	return nil
+	return e.enc.close('}', e.first)
 }
 
 func (e *StructEncoder) EncodeField(key string, v any, s codec.Serializer) error {
-	if !e.first {
-		e.enc.out = append(e.enc.out, ',')
-	} else {
-		e.first = false
-	}
+	e.enc.element(e.first)
+	e.first = false
 
 	if err := e.enc.EncodeString(key); err != nil {
 		return err
 	}
-	e.enc.out = append(e.enc.out, ':')
+	e.enc.colon()
+	if err := e.enc.encode(v, s); err != nil {
+		return err
+	}
+	return e.enc.flush(false)
+}
+
+type VariantEncoder struct {
+	enc   *Encoder
+	name  string
+	value bool
+}
+
+func (e *VariantEncoder) Close() error {
+	if !e.value {
+		return e.enc.EncodeString(e.name)
+	}
+	return e.enc.close('}', false)
+}
+
+func (e *VariantEncoder) EncodeValue(v any, s codec.Serializer) error {
+	e.value = true
+	e.enc.open('{')
+	e.enc.element(true)
+	if err := e.enc.EncodeString(e.name); err != nil {
+		return err
+	}
+	e.enc.colon()
 	return e.enc.encode(v, s)
 }
 
@@ -437,6 +534,39 @@ func (e encoder) encodeBytes(b, v []byte) ([]byte, error) {
 	return b, nil
 }
 
+func (e encoder) encodeJSONNumber(b []byte, v any) ([]byte, error) {
+	n := v.(Number)
+	if n == "" {
+		return append(b, '0'), nil
+	}
+
+	d := decoder{}
+	_, r, _, err := d.parseNumber(stringToBytes(string(n)))
+	if err != nil || len(r) != 0 {
+		return b, fmt.Errorf("json: invalid number literal %q", n)
+	}
+	return append(b, n...), nil
+}
+
+func (e encoder) encodeRawMessage(b []byte, v any) ([]byte, error) {
+	m := v.(RawMessage)
+	if m == nil {
+		return append(b, "null"...), nil
+	}
+
+	if (e.flags & TrustRawMessage) == 0 {
+		if err := validate(m); err != nil {
+			return b, &MarshalerError{Type: rawMessageType, Err: err}
+		}
+	}
+
+	// Raw messages are compacted so that they do not disturb the layout of the enclosing output.
+	for t := NewTokenizer(m); t.Next(); {
+		b = append(b, t.Value...)
+	}
+	return b, nil
+}
+
 func (e encoder) encodeJSONMarshaler(b []byte, v any) ([]byte, error) {
 	t := reflect.TypeOf(v)
 	switch t.Kind() {
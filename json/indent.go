@@ -0,0 +1,104 @@
+package json
+
+import (
+	"bytes"
+
+	"github.com/pgavlin/codec"
+)
+
+// MarshalIndent is documented at https://golang.org/pkg/encoding/json/#MarshalIndent
+func MarshalIndent(x any, prefix, indent string) ([]byte, error) {
+	e := Encoder{enc: encoder{flags: EscapeHTML | SortMapKeys}, prefix: prefix, indent: indent}
+	if err := e.encode(x, codec.GetSerializer(x, Format)); err != nil {
+		return nil, err
+	}
+	return e.out, nil
+}
+
+// Valid is documented at https://golang.org/pkg/encoding/json/#Valid
+func Valid(data []byte) bool {
+	return validate(data) == nil
+}
+
+// validate returns a SyntaxError if data is not a single JSON value surrounded by optional whitespace.
+func validate(data []byte) error {
+	data = skipSpaces(data)
+	_, r, _, err := decoder{flags: internalParseFlags(data)}.parseValue(data)
+	if err != nil {
+		return err
+	}
+	if r = skipSpaces(r); len(r) != 0 {
+		return syntaxError(r, "invalid character '%c' after top-level value", r[0])
+	}
+	return nil
+}
+
+// Compact is documented at https://golang.org/pkg/encoding/json/#Compact
+func Compact(dst *bytes.Buffer, src []byte) error {
+	if err := validate(src); err != nil {
+		return err
+	}
+
+	b := make([]byte, 0, len(src))
+	for t := NewTokenizer(src); t.Next(); {
+		b = append(b, t.Value...)
+	}
+	dst.Write(b)
+	return nil
+}
+
+// Indent is documented at https://golang.org/pkg/encoding/json/#Indent
+func Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	if err := validate(src); err != nil {
+		return err
+	}
+
+	b := appendIndent(make([]byte, 0, len(src)), src, prefix, indent, 0)
+	b = append(b, src[len(trimTrailingSpaces(src)):]...)
+	dst.Write(b)
+	return nil
+}
+
+// appendIndent appends an indented form of the JSON value src to dst. Each element of an array or object begins on a
+// new line that starts with prefix followed by depth+1 copies of indent, where depth is the nesting depth of src in
+// the enclosing output. The appended data does not begin with the prefix or any indentation. src must be valid JSON.
+func appendIndent(dst, src []byte, prefix, indent string, depth int) []byte {
+	open := false
+	for t := NewTokenizer(src); t.Next(); {
+		if open && t.Delim != ']' && t.Delim != '}' {
+			// The first element of a non-empty array or object.
+			dst = appendLineBreak(dst, prefix, indent, depth)
+		}
+
+		switch t.Delim {
+		case '[', '{':
+			depth++
+			dst = append(dst, t.Value...)
+		case ']', '}':
+			depth--
+			if !open {
+				dst = appendLineBreak(dst, prefix, indent, depth)
+			}
+			dst = append(dst, t.Value...)
+		case ',':
+			dst = append(dst, ',')
+			dst = appendLineBreak(dst, prefix, indent, depth)
+		case ':':
+			dst = append(dst, ':', ' ')
+		default:
+			dst = append(dst, t.Value...)
+		}
+		open = t.Delim == '[' || t.Delim == '{'
+	}
+	return dst
+}
+
+// appendLineBreak appends a newline followed by prefix and depth copies of indent to b.
+func appendLineBreak(b []byte, prefix, indent string, depth int) []byte {
+	b = append(b, '\n')
+	b = append(b, prefix...)
+	for i := 0; i < depth; i++ {
+		b = append(b, indent...)
+	}
+	return b
+}
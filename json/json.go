@@ -191,7 +191,7 @@ func Marshal(x any) ([]byte, error) {
 	var err error
 	var buf = encoderBufferPool.Get().(*encoderBuffer)
 
-	if buf.data, err = Append(buf.data[:0], x, codec.GetSerializer(x), EscapeHTML|SortMapKeys); err != nil {
+	if buf.data, err = Append(buf.data[:0], x, codec.GetSerializer(x, Format), EscapeHTML|SortMapKeys); err != nil {
 		return nil, err
 	}
 
@@ -203,14 +203,26 @@ func Marshal(x any) ([]byte, error) {
 
 // Unmarshal is documented at https://golang.org/pkg/encoding/json/#Unmarshal
 func Unmarshal(b []byte, x any) error {
-	r, err := Parse(b, x, codec.GetDeserializer(x), 0)
-	if len(r) != 0 {
-		if _, ok := err.(*SyntaxError); !ok {
-			// The encoding/json package prioritizes reporting errors caused by
-			// unexpected trailing bytes over other issues; here we emulate this
-			// behavior by overriding the error.
-			err = syntaxError(r, "invalid character '%c' after top-level value", r[0])
+	r, err := Parse(b, x, codec.GetDeserializer(x, Format), 0)
+	if _, ok := err.(*SyntaxError); ok {
+		return err
+	}
+	if err != nil {
+		// Decoding stops at the first error, so the rest of the input is
+		// validated separately: syntax errors take priority over other
+		// errors, as they do in the encoding/json package.
+		b = skipSpaces(b)
+		_, rest, _, serr := decoder{flags: internalParseFlags(b)}.parseValue(b)
+		if serr != nil {
+			return serr
 		}
+		r = skipSpaces(rest)
+	}
+	if len(r) != 0 {
+		// The encoding/json package prioritizes reporting errors caused by
+		// unexpected trailing bytes over other issues; here we emulate this
+		// behavior by overriding the error.
+		err = syntaxError(r, "invalid character '%c' after top-level value", r[0])
 	}
 	return err
 }
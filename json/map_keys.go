@@ -1,6 +1,7 @@
 package json
 
 import (
+	"errors"
 	"reflect"
 	"strconv"
 	"unsafe"
@@ -14,11 +15,18 @@ var mapKeyCodecs typecache.Cache[jsonCodec]
 func getMapKeyCodec(v any) jsonCodec {
 	return mapKeyCodecs.GetOrCreate(reflect.TypeOf(v), func(t reflect.Type) jsonCodec {
 		var c jsonCodec
+		if t == nil {
+			return c
+		}
+
 		if t.Implements(textMarshalerType) {
 			c.encode = encoder.encodeTextMarshaler
 		}
 
-		p := reflect.PtrTo(t)
+		p := t
+		if p.Kind() != reflect.Pointer {
+			p = reflect.PtrTo(t)
+		}
 		if p.Implements(textUnmarshalerType) {
 			c.decode = decoder.decodeTextUnmarshaler
 		}
@@ -132,6 +140,24 @@ func (e mapKeyEncoder) EncodeStruct(name string) (codec.StructEncoder, error) {
 	return nil, &UnsupportedTypeError{Type: structType}
 }
 
+// EncodeVariant encodes a unit variant as its name. Variants with values cannot be used as map keys.
+func (e mapKeyEncoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	return &mapKeyVariantEncoder{enc: e.enc, name: variant}, nil
+}
+
+type mapKeyVariantEncoder struct {
+	enc  *Encoder
+	name string
+}
+
+func (e *mapKeyVariantEncoder) Close() error {
+	return e.enc.EncodeString(e.name)
+}
+
+func (e *mapKeyVariantEncoder) EncodeValue(v any, s codec.Serializer) error {
+	return errors.New("json: only unit variants can be used as map keys")
+}
+
 type mapKeyDecoder struct {
 	dec *Decoder
 }
@@ -140,7 +166,7 @@ func (d mapKeyDecoder) decode(v any, ds codec.Deserializer) (err error) {
 	codec := getMapKeyCodec(v)
 	if codec.decode != nil {
 		d.dec.rest, err = codec.decode(decoder{flags: d.dec.flags}, d.dec.rest, v)
-		return
+		return typeError(err)
 	}
 	return ds.Deserialize(d)
 }
@@ -165,7 +191,7 @@ func (d mapKeyDecoder) decodeInt(v codec.Visitor, t reflect.Type) (n int64, err
 	}
 	n, err = strconv.ParseInt(s, 10, 64)
 	if err != nil || reflect.Zero(t).OverflowInt(n) {
-		return 0, &UnmarshalTypeError{Value: "number " + s, Type: t}
+		return 0, &codec.UnmarshalTypeError{Value: "number " + s, Type: t}
 	}
 	return n, nil
 }
@@ -177,13 +203,13 @@ func (d mapKeyDecoder) decodeUint(v codec.Visitor, t reflect.Type) (n uint64, er
 	}
 	n, err = strconv.ParseUint(s, 10, 64)
 	if err != nil || reflect.Zero(t).OverflowUint(n) {
-		return 0, &UnmarshalTypeError{Value: "number " + s, Type: t}
+		return 0, &codec.UnmarshalTypeError{Value: "number " + s, Type: t}
 	}
 	return n, nil
 }
 
 func (d mapKeyDecoder) DecodeAny(v codec.Visitor) (err error) {
-	return &UnsupportedTypeError{Type: nilType}
+	return d.DecodeString(v)
 }
 
 func (d mapKeyDecoder) DecodeNil(v codec.Visitor) error {
@@ -321,3 +347,7 @@ func (d mapKeyDecoder) DecodeMap(v codec.Visitor) error {
 func (d mapKeyDecoder) DecodeStruct(name string, v codec.Visitor) error {
 	return &UnsupportedTypeError{Type: structType}
 }
+
+func (d mapKeyDecoder) DecodeVariant(enum string, variants []string, v codec.Visitor) error {
+	return d.dec.DecodeVariant(enum, variants, v)
+}
@@ -0,0 +1,479 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/pgavlin/codec"
+)
+
+const (
+	// The initial size of a stream's buffer.
+	streamBufferSize = 4096
+
+	// The minimum amount of free space in a stream's buffer before a read.
+	minStreamRead = 512
+
+	// The amount of output an Encoder returned by NewEncoder buffers before writing it.
+	encoderFlushSize = 4096
+)
+
+// stream is the input of a Decoder returned by NewDecoder. The decoders that read from a stream hold slices of its
+// buffer; these slices are replaced by the slices returned from the stream's methods, which may move the unread input
+// to the front of the buffer before reading more of it.
+//
+// The methods of a nil *stream operate on their argument as-is.
+type stream struct {
+	r   io.Reader
+	buf []byte
+	err error // the error returned by the last read, if any
+
+	failed error // the error returned by Decode, if any
+
+	// The tokenizer state. See Decoder.Token.
+	tokenState int
+	tokenStack []int
+}
+
+// refill moves the unread input b to the front of the buffer and reads more input. It returns the unread input, which
+// is never shorter than b. If no input could be read, refill returns the reader's error.
+func (s *stream) refill(b []byte) ([]byte, error) {
+	if s.err != nil {
+		return b, s.err
+	}
+
+	n := len(b)
+	if cap(s.buf)-n < minStreamRead {
+		buf := make([]byte, n, 2*cap(s.buf)+streamBufferSize)
+		copy(buf, b)
+		s.buf = buf
+	} else {
+		s.buf = s.buf[:copy(s.buf[:cap(s.buf)], b)]
+	}
+
+	m, err := s.r.Read(s.buf[n:cap(s.buf)])
+	s.buf = s.buf[:n+m]
+	if err != nil {
+		s.err = err
+		if m == 0 {
+			return s.buf, err
+		}
+	}
+	return s.buf, nil
+}
+
+// skipSpaces skips whitespace at the start of b, reading more input as necessary. The returned slice is empty only at
+// the end of the input.
+func (s *stream) skipSpaces(b []byte) ([]byte, error) {
+	if s == nil {
+		return skipSpaces(b), nil
+	}
+
+	for {
+		if b = skipSpaces(b); len(b) != 0 {
+			return b, nil
+		}
+
+		var err error
+		if b, err = s.refill(b); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return b, err
+		}
+	}
+}
+
+// fill skips whitespace at the start of b and reads input until b begins with a complete JSON value or the end of the
+// input is reached. If partial is true, only the opening delimiter of an array or object is read, as their contents
+// are read as they are decoded.
+//
+// The value is not validated: it is only scanned for the quotes and brackets that delimit it. Decoding the value
+// reports any syntax errors, including those caused by the end of the input.
+func (s *stream) fill(b []byte, partial bool) ([]byte, error) {
+	if s == nil {
+		return b, nil
+	}
+
+	b, err := s.skipSpaces(b)
+	if err != nil || len(b) == 0 {
+		return b, err
+	}
+
+	var sc scanner
+	for {
+		if partial && (b[0] == '[' || b[0] == '{') {
+			// decodeArray and decodeObject require the byte that follows the delimiter.
+			if len(b) >= 2 {
+				return b, nil
+			}
+		} else if sc.scan(b) {
+			return b, nil
+		}
+
+		if b, err = s.refill(b); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return b, err
+		}
+	}
+}
+
+// scanner finds the end of the JSON value at the start of its input. Scanning can be resumed after more input has been
+// read.
+type scanner struct {
+	n     int  // the number of bytes scanned
+	depth int  // the number of unclosed arrays and objects
+	str   bool // true if the scanner is inside a string
+	esc   bool // true if the previous byte was a backslash inside a string
+}
+
+// scan continues scanning b, and returns true if b begins with a complete value.
+func (sc *scanner) scan(b []byte) bool {
+	for ; sc.n < len(b); sc.n++ {
+		c := b[sc.n]
+		switch {
+		case sc.esc:
+			sc.esc = false
+		case sc.str:
+			switch c {
+			case '\\':
+				sc.esc = true
+			case '"':
+				sc.str = false
+				if sc.depth == 0 {
+					return true
+				}
+			}
+		case c == '"':
+			sc.str = true
+		case c == '[' || c == '{':
+			sc.depth++
+		case c == ']' || c == '}':
+			if sc.depth--; sc.depth <= 0 {
+				return true
+			}
+		case sc.depth == 0:
+			// Literals and numbers end at the first byte that cannot be part of them.
+			switch c {
+			case sp, ht, nl, cr, ',', ':', '"', '[', ']', '{', '}':
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// errNoStream is returned by the streaming methods of decoders that were not returned by NewDecoder.
+var errNoStream = errors.New("json: Decode and Token require a decoder returned by NewDecoder")
+
+// NewDecoder returns a new decoder that reads from r. Arrays and objects are read from r as their elements are
+// decoded rather than all at once, so a SeqDecoder or MapDecoder can process inputs that are much larger than memory.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{s: &stream{r: r}}
+}
+
+// Buffered returns a reader of the data remaining in the Decoder's buffer.
+func (d *Decoder) Buffered() io.Reader {
+	return bytes.NewReader(d.rest)
+}
+
+// DisallowUnknownFields causes the Decoder to return an error when the destination is a struct and the input contains
+// object keys which do not match any non-ignored, exported fields in the destination.
+func (d *Decoder) DisallowUnknownFields() {
+	d.flags |= DisallowUnknownFields
+}
+
+// UseNumber causes the Decoder to unmarshal a number into an interface value as a Number instead of as a numeric type.
+func (d *Decoder) UseNumber() {
+	d.flags |= UseNumber
+}
+
+// Decode reads the next JSON-encoded value from its input and stores it in the value pointed to by v. At the end of
+// the input, Decode returns io.EOF. Any other error is sticky: the decoder's position in its input is unknown after
+// a failed decode, so subsequent calls return the same error.
+//
+// Decode, More and Token may only be called on decoders returned by NewDecoder. Other decoders return an error from
+// Decode and Token, and false from More.
+func (d *Decoder) Decode(v any) error {
+	if d.s == nil {
+		return errNoStream
+	}
+	if d.s.failed != nil {
+		return d.s.failed
+	}
+
+	if err := d.tokenPrepareForDecode(); err != nil {
+		return err
+	}
+	if !d.tokenValueAllowed() {
+		return syntaxError(d.rest, "not at beginning of value")
+	}
+
+	b, err := d.s.skipSpaces(d.rest)
+	d.rest = b
+	if err != nil {
+		d.s.failed = err
+		return err
+	}
+	if len(b) == 0 {
+		return io.EOF
+	}
+
+	if err := d.decode(v, codec.GetDeserializer(v, Format)); err != nil {
+		d.s.failed = err
+		return err
+	}
+	d.tokenValueEnd()
+	return nil
+}
+
+// More reports whether there is another element in the current array or object being parsed.
+func (d *Decoder) More() bool {
+	if d.s == nil {
+		return false
+	}
+	b, err := d.s.skipSpaces(d.rest)
+	d.rest = b
+	return err == nil && len(b) != 0 && b[0] != ']' && b[0] != '}'
+}
+
+const (
+	tokenTopValue = iota
+	tokenArrayStart
+	tokenArrayValue
+	tokenArrayComma
+	tokenObjectStart
+	tokenObjectKey
+	tokenObjectColon
+	tokenObjectValue
+	tokenObjectComma
+)
+
+// tokenPrepareForDecode advances the tokenizer past the comma or colon that precedes a value decoded after a call to
+// Token.
+func (d *Decoder) tokenPrepareForDecode() error {
+	switch d.s.tokenState {
+	case tokenArrayComma:
+		b, err := d.s.skipSpaces(d.rest)
+		d.rest = b
+		if err != nil {
+			return err
+		}
+		if len(b) == 0 || b[0] != ',' {
+			return syntaxError(b, "expected comma after array element")
+		}
+		d.rest = b[1:]
+		d.s.tokenState = tokenArrayValue
+	case tokenObjectColon:
+		b, err := d.s.skipSpaces(d.rest)
+		d.rest = b
+		if err != nil {
+			return err
+		}
+		if len(b) == 0 || b[0] != ':' {
+			return syntaxError(b, "expected colon after object key")
+		}
+		d.rest = b[1:]
+		d.s.tokenState = tokenObjectValue
+	}
+	return nil
+}
+
+func (d *Decoder) tokenValueAllowed() bool {
+	switch d.s.tokenState {
+	case tokenTopValue, tokenArrayStart, tokenArrayValue, tokenObjectValue:
+		return true
+	}
+	return false
+}
+
+func (d *Decoder) tokenValueEnd() {
+	switch d.s.tokenState {
+	case tokenArrayStart, tokenArrayValue:
+		d.s.tokenState = tokenArrayComma
+	case tokenObjectValue:
+		d.s.tokenState = tokenObjectComma
+	}
+}
+
+func (d *Decoder) tokenPush(state int) {
+	d.s.tokenStack = append(d.s.tokenStack, d.s.tokenState)
+	d.s.tokenState = state
+}
+
+func (d *Decoder) tokenPop() {
+	n := len(d.s.tokenStack) - 1
+	d.s.tokenState, d.s.tokenStack = d.s.tokenStack[n], d.s.tokenStack[:n]
+	d.tokenValueEnd()
+}
+
+func (d *Decoder) tokenError(b []byte) (Token, error) {
+	var context string
+	switch d.s.tokenState {
+	case tokenTopValue, tokenArrayStart, tokenArrayValue, tokenObjectValue:
+		context = "looking for beginning of value"
+	case tokenArrayComma:
+		context = "after array element"
+	case tokenObjectKey:
+		context = "looking for beginning of object key string"
+	case tokenObjectColon:
+		context = "after object key"
+	case tokenObjectComma:
+		context = "after object key:value pair"
+	}
+	return nil, syntaxError(b, "invalid character '%c' %s", b[0], context)
+}
+
+// Token returns the next JSON token in the input stream. At the end of the input stream, Token returns nil, io.EOF.
+// As in encoding/json, numbers are returned as float64 values, or as Numbers if UseNumber has been called.
+//
+// Token is documented at https://golang.org/pkg/encoding/json/#Decoder.Token
+func (d *Decoder) Token() (Token, error) {
+	if d.s == nil {
+		return nil, errNoStream
+	}
+	for {
+		b, err := d.s.skipSpaces(d.rest)
+		d.rest = b
+		if err != nil {
+			return nil, err
+		}
+		if len(b) == 0 {
+			return nil, io.EOF
+		}
+
+		switch c := b[0]; c {
+		case '[':
+			if !d.tokenValueAllowed() {
+				return d.tokenError(b)
+			}
+			d.rest = b[1:]
+			d.tokenPush(tokenArrayStart)
+			return Delim('['), nil
+		case ']':
+			if d.s.tokenState != tokenArrayStart && d.s.tokenState != tokenArrayComma {
+				return d.tokenError(b)
+			}
+			d.rest = b[1:]
+			d.tokenPop()
+			return Delim(']'), nil
+		case '{':
+			if !d.tokenValueAllowed() {
+				return d.tokenError(b)
+			}
+			d.rest = b[1:]
+			d.tokenPush(tokenObjectStart)
+			return Delim('{'), nil
+		case '}':
+			if d.s.tokenState != tokenObjectStart && d.s.tokenState != tokenObjectComma {
+				return d.tokenError(b)
+			}
+			d.rest = b[1:]
+			d.tokenPop()
+			return Delim('}'), nil
+		case ':':
+			if d.s.tokenState != tokenObjectColon {
+				return d.tokenError(b)
+			}
+			d.rest = b[1:]
+			d.s.tokenState = tokenObjectValue
+		case ',':
+			switch d.s.tokenState {
+			case tokenArrayComma:
+				d.s.tokenState = tokenArrayValue
+			case tokenObjectComma:
+				d.s.tokenState = tokenObjectKey
+			default:
+				return d.tokenError(b)
+			}
+			d.rest = b[1:]
+		case '"':
+			if d.s.tokenState == tokenObjectStart || d.s.tokenState == tokenObjectKey {
+				var key string
+				if err := d.decode(&key, codec.NewString(&key)); err != nil {
+					return nil, err
+				}
+				d.s.tokenState = tokenObjectColon
+				return key, nil
+			}
+			fallthrough
+		default:
+			if !d.tokenValueAllowed() {
+				return d.tokenError(b)
+			}
+			if c == '-' || '0' <= c && c <= '9' {
+				return d.numberToken()
+			}
+			var x any
+			if err := d.Decode(&x); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	}
+}
+
+// numberToken returns the number at the start of the input as a Number if UseNumber has been called, and as a float64
+// otherwise.
+func (d *Decoder) numberToken() (Token, error) {
+	var n Number
+	if err := d.Decode(&n); err != nil {
+		return nil, err
+	}
+	if d.flags&UseNumber != 0 {
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return nil, &UnmarshalTypeError{Value: "number " + string(n), Type: float64Type}
+	}
+	return f, nil
+}
+
+// NewEncoder returns a new encoder that writes to w. The output of Encode is written to w in chunks as the elements of
+// arrays and objects are encoded rather than all at once, so values that are much larger than memory can be encoded
+// by a codec.Serializer that produces their elements one at a time.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{enc: encoder{flags: EscapeHTML | SortMapKeys | appendNewline}, w: w}
+}
+
+// Encode writes the JSON encoding of v to the stream, followed by a newline character.
+//
+// Unlike the encoding/json package, Encode may have written part of the encoding of v to the stream when it returns
+// an error.
+func (e *Encoder) Encode(v any) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	if err := e.encode(v, codec.GetSerializer(v, Format)); err != nil {
+		e.out, e.depth = e.out[:0], 0
+		return err
+	}
+	if (e.enc.flags & appendNewline) != 0 {
+		e.out = append(e.out, '\n')
+	}
+	return e.flush(true)
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters should be escaped inside JSON quoted strings. The
+// default behavior is to escape &, <, and > to \u0026, \u003c, and \u003e to avoid certain safety problems that can
+// arise when embedding JSON in HTML.
+func (e *Encoder) SetEscapeHTML(on bool) {
+	if on {
+		e.enc.flags |= EscapeHTML
+	} else {
+		e.enc.flags &^= EscapeHTML
+	}
+}
+
+// SetIndent instructs the encoder to indent each subsequent encoded value: each element of an array or object begins on
+// a new line that starts with prefix followed by one copy of indent per level of nesting. Calling SetIndent("", "")
+// disables indentation.
+func (e *Encoder) SetIndent(prefix, indent string) {
+	e.prefix, e.indent = prefix, indent
+}
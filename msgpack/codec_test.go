@@ -0,0 +1,250 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pgavlin/codec/internal/codectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		value    any
+		expected string
+	}{
+		{value: 0, expected: "00"},
+		{value: 127, expected: "7f"},
+		{value: 128, expected: "cc80"},
+		{value: 256, expected: "cd0100"},
+		{value: 65536, expected: "ce00010000"},
+		{value: int64(1) << 32, expected: "cf0000000100000000"},
+		{value: uint64(math.MaxUint64), expected: "cfffffffffffffffff"},
+		{value: -1, expected: "ff"},
+		{value: -32, expected: "e0"},
+		{value: -33, expected: "d0df"},
+		{value: -129, expected: "d1ff7f"},
+		{value: -32769, expected: "d2ffff7fff"},
+		{value: int64(math.MinInt64), expected: "d38000000000000000"},
+		{value: float32(1.5), expected: "ca3fc00000"},
+		{value: 1.5, expected: "cb3ff8000000000000"},
+		{value: false, expected: "c2"},
+		{value: true, expected: "c3"},
+		{value: nil, expected: "c0"},
+		{value: "", expected: "a0"},
+		{value: "a", expected: "a161"},
+		{value: strings.Repeat("a", 32), expected: "d920" + strings.Repeat("61", 32)},
+		{value: []byte{}, expected: "c400"},
+		{value: []byte{1, 2, 3}, expected: "c403010203"},
+		{value: []int{}, expected: "90"},
+		{value: []int{1, 2, 3}, expected: "93010203"},
+		{value: make([]int, 16), expected: "dc0010" + strings.Repeat("00", 16)},
+		{value: map[string]int{"a": 1}, expected: "81a16101"},
+		{value: time.Unix(1, 0), expected: "d6ff00000001"},
+		{value: time.Unix(1, 1), expected: "d7ff0000000400000001"},
+		{value: time.Unix(-1, 0), expected: "c70cff00000000ffffffffffffffff"},
+		{value: Ext{Type: 5, Data: []byte{1, 2, 3}}, expected: "c70305010203"},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v", c.value), func(t *testing.T) {
+			actual, err := Marshal(c.value)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, hex.EncodeToString(actual))
+		})
+	}
+
+	_, err := Marshal(complex(1, 2))
+	assert.EqualError(t, err, "codec: unsupported type: complex128")
+}
+
+func decodeHex[T any](t *testing.T, s string) T {
+	return codectest.DecodeHex[T](t, Unmarshal, s)
+}
+
+func TestDecode(t *testing.T) {
+	assert.Equal(t, uint64(127), decodeHex[any](t, "7f"))
+	assert.Equal(t, int64(-32), decodeHex[any](t, "e0"))
+	assert.Equal(t, int64(-129), decodeHex[any](t, "d1ff7f"))
+	assert.Equal(t, int64(math.MinInt64), decodeHex[any](t, "d38000000000000000"))
+	assert.Equal(t, uint64(math.MaxUint64), decodeHex[any](t, "cfffffffffffffffff"))
+	assert.Equal(t, int8(5), decodeHex[int8](t, "d005"))
+	assert.Equal(t, float32(1.5), decodeHex[any](t, "ca3fc00000"))
+	assert.Equal(t, 1.5, decodeHex[float64](t, "cb3ff8000000000000"))
+	assert.Nil(t, decodeHex[any](t, "c0"))
+	assert.Equal(t, "a", decodeHex[any](t, "a161"))
+	assert.Equal(t, []byte{1, 2, 3}, decodeHex[any](t, "c403010203"))
+	assert.Equal(t, []any{uint64(1), "a"}, decodeHex[any](t, "9201a161"))
+	assert.Equal(t, map[string]any{"a": []any{}}, decodeHex[any](t, "81a16190"))
+
+	// Strings and byte strings are interchangeable.
+	assert.Equal(t, "abc", decodeHex[string](t, "c403616263"))
+	assert.Equal(t, []byte("abc"), decodeHex[[]byte](t, "a3616263"))
+
+	// Timestamps in each format.
+	assert.True(t, time.Unix(1, 0).Equal(decodeHex[time.Time](t, "d6ff00000001")))
+	assert.True(t, time.Unix(1, 1).Equal(decodeHex[time.Time](t, "d7ff0000000400000001")))
+	assert.True(t, time.Unix(-1, 0).Equal(decodeHex[time.Time](t, "c70cff00000000ffffffffffffffff")))
+	assert.Equal(t, time.Unix(1, 0).UTC(), decodeHex[any](t, "d6ff00000001"))
+}
+
+func TestSize(t *testing.T) {
+	b, err := hex.DecodeString("9301820102dc0000de000106079100")
+	require.NoError(t, err)
+
+	var sizes []string
+	require.NoError(t, codectest.SizeVisitor{Sizes: &sizes}.Deserialize(NewDecoder(bytes.NewReader(b))))
+	assert.Equal(t, []string{"3/true", "2/true", "0/true", "1/true", "1/true"}, sizes)
+}
+
+type testStruct struct {
+	Name   string            `codec:"name"`
+	Count  int16             `codec:"count"`
+	Ratio  float32           `codec:"ratio"`
+	Data   []byte            `codec:"data"`
+	Tags   []string          `codec:"tags"`
+	Labels map[string]string `codec:"labels"`
+	Time   time.Time         `codec:"time"`
+	Next   *testStruct       `codec:"next"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	expected := testStruct{
+		Name:   "name",
+		Count:  -300,
+		Ratio:  0.25,
+		Data:   []byte("data"),
+		Tags:   []string{"a", "b"},
+		Labels: map[string]string{"z": "1", "a": "2"},
+		Time:   time.Date(2023, 6, 1, 12, 30, 0, 123456789, time.UTC),
+		Next:   &testStruct{Name: "next", Data: []byte{}, Tags: []string{}, Labels: map[string]string{}},
+	}
+	expected.Next.Time = time.Unix(0, 0).UTC()
+
+	b, err := Marshal(expected)
+	require.NoError(t, err)
+
+	var actual testStruct
+	require.NoError(t, Unmarshal(b, &actual))
+	assert.Equal(t, expected, actual)
+}
+
+// point is encoded as an extension value whose data holds its coordinates.
+type point struct {
+	X, Y int8
+}
+
+type pointExtension struct{}
+
+func (pointExtension) MarshalExt(v *point) ([]byte, error) {
+	return []byte{byte(v.X), byte(v.Y)}, nil
+}
+
+func (pointExtension) UnmarshalExt(data []byte, v *point) error {
+	if len(data) != 2 {
+		return fmt.Errorf("invalid point")
+	}
+	*v = point{X: int8(data[0]), Y: int8(data[1])}
+	return nil
+}
+
+func init() {
+	RegisterExtension[point](1, pointExtension{})
+}
+
+func TestExtension(t *testing.T) {
+	b, err := Marshal([]point{{X: 1, Y: -1}})
+	require.NoError(t, err)
+	assert.Equal(t, "91d50101ff", hex.EncodeToString(b))
+
+	var points []point
+	require.NoError(t, Unmarshal(b, &points))
+	assert.Equal(t, []point{{X: 1, Y: -1}}, points)
+
+	var v any
+	require.NoError(t, Unmarshal(b, &v))
+	assert.Equal(t, []any{point{X: 1, Y: -1}}, v)
+
+	// Unregistered extension types are decoded as Ext values.
+	assert.Equal(t, Ext{Type: 5, Data: []byte{1, 2, 3}}, decodeHex[any](t, "c70305010203"))
+	assert.Equal(t, Ext{Type: 5, Data: []byte{1, 2, 3}}, decodeHex[Ext](t, "c70305010203"))
+
+	var p point
+	assert.EqualError(t, Unmarshal([]byte{0xd5, 0x02, 0x01, 0xff}, &p),
+		"codec: cannot unmarshal extension type 2 into Go value of type msgpack.point")
+	assert.EqualError(t, Unmarshal([]byte{0xa1, 0x61}, &p),
+		"codec: cannot unmarshal string into Go value of type msgpack.point")
+
+	assert.Panics(t, func() { RegisterExtension[point](-2, pointExtension{}) })
+}
+
+func TestStream(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	require.NoError(t, e.Encode(1))
+	require.NoError(t, e.Encode("a"))
+	require.NoError(t, e.Encode([]int{2}))
+	assert.Equal(t, "01a1619102", hex.EncodeToString(buf.Bytes()))
+
+	// Read through a reader that does not implement io.ByteScanner.
+	d := NewDecoder(io.MultiReader(&buf))
+	var values []any
+	for {
+		var v any
+		err := d.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		values = append(values, v)
+	}
+	assert.Equal(t, []any{uint64(1), "a", []any{uint64(2)}}, values)
+	assert.Equal(t, int64(5), d.InputOffset())
+}
+
+func TestErrors(t *testing.T) {
+	var v any
+	err := Unmarshal([]byte{0x93, 0x01, 0x02}, &v)
+	assert.EqualError(t, err, "msgpack: unexpected end of input")
+	assert.Equal(t, int64(1), err.(*SyntaxError).Offset)
+
+	err = Unmarshal([]byte{0x01, 0x02}, &v)
+	assert.EqualError(t, err, "msgpack: unexpected data after top-level value")
+
+	err = Unmarshal([]byte{0xc1}, &v)
+	assert.EqualError(t, err, "msgpack: invalid format byte 0xc1")
+
+	err = Unmarshal([]byte{0xdb, 0xff, 0xff, 0xff, 0xff}, &v)
+	assert.EqualError(t, err, "msgpack: unexpected end of input")
+
+	var i8 int8
+	err = Unmarshal([]byte{0xcd, 0x01, 0x2c}, &i8)
+	assert.EqualError(t, err, "codec: cannot unmarshal number 300 into Go value of type int8")
+
+	b, err := Marshal(map[string]bool{"field": true, "extra": true})
+	require.NoError(t, err)
+	d := NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	var s codectest.BoolStruct
+	assert.EqualError(t, d.Decode(&s), `codec: unknown field "extra" in Go struct BoolStruct`)
+}
+
+func TestVariant(t *testing.T) {
+	b, err := Marshal([]codectest.Option{{Valid: true, Value: "x"}, {}})
+	require.NoError(t, err)
+	assert.Equal(t, "9281a4536f6d65a178a44e6f6e65", hex.EncodeToString(b))
+
+	var options []codectest.Option
+	require.NoError(t, Unmarshal(b, &options))
+	assert.Equal(t, []codectest.Option{{Valid: true, Value: "x"}, {}}, options)
+
+	var v any
+	require.NoError(t, Unmarshal(b, &v))
+	assert.Equal(t, []any{map[string]any{"Some": "x"}, "None"}, v)
+}
@@ -0,0 +1,541 @@
+package msgpack
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+
+	"github.com/pgavlin/codec"
+)
+
+// A SyntaxError describes malformed MessagePack input.
+type SyntaxError struct {
+	msg    string
+	Offset int64 // the offset in the input at which the error was detected
+}
+
+func (e *SyntaxError) Error() string {
+	return "msgpack: " + e.msg
+}
+
+// reader is the interface required of the input to a Decoder.
+type reader interface {
+	io.Reader
+	io.ByteScanner
+}
+
+// A Decoder reads and decodes MessagePack values from an input stream.
+type Decoder struct {
+	r       reader
+	offset  int64 // the number of bytes read from r
+	options codec.DecodeOptions
+}
+
+// NewDecoder returns a new decoder that reads from r. If r does not also implement io.ByteScanner, the decoder
+// introduces its own buffering and may read data from r beyond the values requested.
+func NewDecoder(r io.Reader) *Decoder {
+	rr, ok := r.(reader)
+	if !ok {
+		rr = bufio.NewReader(r)
+	}
+	return &Decoder{r: rr}
+}
+
+// DisallowUnknownFields causes the Decoder to return an error when the destination is a struct and the input contains
+// map keys which do not match any of the struct's fields.
+func (d *Decoder) DisallowUnknownFields() {
+	d.options.DisallowUnknownFields = true
+}
+
+// InputOffset returns the input stream byte offset of the current decoder position.
+func (d *Decoder) InputOffset() int64 {
+	return d.offset
+}
+
+// Decode reads the next MessagePack value from its input and stores it in the value pointed to by v. At the end of the
+// input, Decode returns io.EOF.
+func (d *Decoder) Decode(v any) error {
+	if _, err := d.r.ReadByte(); err != nil {
+		return err
+	}
+	d.r.UnreadByte()
+	return codec.GetDeserializer(v, Format).Deserialize(d)
+}
+
+func (d *Decoder) syntaxError(format string, args ...any) error {
+	return &SyntaxError{msg: fmt.Sprintf(format, args...), Offset: d.offset}
+}
+
+// readError converts an error returned by the underlying reader. The end of the input is unexpected when reading a
+// value.
+func (d *Decoder) readError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return d.syntaxError("unexpected end of input")
+	}
+	return err
+}
+
+func (d *Decoder) readByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return 0, d.readError(err)
+	}
+	d.offset++
+	return b, nil
+}
+
+func (d *Decoder) peekByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return 0, d.readError(err)
+	}
+	d.r.UnreadByte()
+	return b, nil
+}
+
+// checkLength returns an error if the input is known to hold fewer than n bytes.
+func (d *Decoder) checkLength(n uint64) error {
+	if r, ok := d.r.(interface{ Len() int }); ok && n > uint64(r.Len()) {
+		return d.syntaxError("unexpected end of input")
+	}
+	return nil
+}
+
+// readChunk is the largest buffer allocated at once by read. Larger values are read in chunks so that a corrupt length
+// cannot cause a large allocation.
+const readChunk = 64 << 10
+
+// read reads the next n bytes of input into a new buffer.
+func (d *Decoder) read(n uint32) ([]byte, error) {
+	if err := d.checkLength(uint64(n)); err != nil {
+		return nil, err
+	}
+
+	size := n
+	if size > readChunk {
+		size = readChunk
+	}
+	b := make([]byte, 0, size)
+	for uint32(len(b)) < n {
+		c := n - uint32(len(b))
+		if c > readChunk {
+			c = readChunk
+		}
+		start := len(b)
+		b = append(b, make([]byte, c)...)
+		k, err := io.ReadFull(d.r, b[start:])
+		d.offset += int64(k)
+		if err != nil {
+			return nil, d.readError(err)
+		}
+	}
+	return b, nil
+}
+
+// readUint reads a big-endian unsigned integer of the given size.
+func (d *Decoder) readUint(size int) (uint64, error) {
+	var buf [8]byte
+	k, err := io.ReadFull(d.r, buf[:size])
+	d.offset += int64(k)
+	if err != nil {
+		return 0, d.readError(err)
+	}
+	switch size {
+	case 1:
+		return uint64(buf[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(buf[:])), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(buf[:])), nil
+	default:
+		return binary.BigEndian.Uint64(buf[:]), nil
+	}
+}
+
+// readLength reads a length of the given size and returns it.
+func (d *Decoder) readLength(size int) (uint32, error) {
+	n, err := d.readUint(size)
+	return uint32(n), err
+}
+
+// extLength returns the size of the length of an extension value with the given format byte, or the length itself for
+// fixext formats. The boolean result is false if the format byte is not an extension format.
+func extLength(b byte) (size int, length uint32, ok bool) {
+	switch b {
+	case fixext1, fixext2, fixext4, fixext8, fixext16:
+		return 0, 1 << (b - fixext1), true
+	case ext8:
+		return 1, 0, true
+	case ext16:
+		return 2, 0, true
+	case ext32:
+		return 4, 0, true
+	default:
+		return 0, 0, false
+	}
+}
+
+// readExtData reads the type and data of an extension value whose format byte has been read.
+func (d *Decoder) readExtData(size int, length uint32) (int8, []byte, error) {
+	if size != 0 {
+		n, err := d.readLength(size)
+		if err != nil {
+			return 0, nil, err
+		}
+		length = n
+	}
+	typ, err := d.readByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	data, err := d.read(length)
+	if err != nil {
+		return 0, nil, err
+	}
+	return int8(typ), data, nil
+}
+
+// decodeExt decodes an extension value and passes its type and data to f. Other values are decoded using v.
+func (d *Decoder) decodeExt(v codec.Visitor, f func(typ int8, data []byte) error) error {
+	b, err := d.peekByte()
+	if err != nil {
+		return err
+	}
+	size, length, ok := extLength(b)
+	if !ok {
+		return d.DecodeAny(v)
+	}
+	d.readByte()
+
+	typ, data, err := d.readExtData(size, length)
+	if err != nil {
+		return err
+	}
+	return f(typ, data)
+}
+
+// extElem stores a decoded extension value in the interface value passed to Element. It allows an AnyCodec to decode
+// extension values as values of their registered types.
+type extElem struct {
+	value any
+}
+
+func (e extElem) Element(v any, _ codec.Deserializer) error {
+	*v.(*any) = e.value
+	return nil
+}
+
+// visitExt visits an extension value. Extension values can only be decoded into interface values, which receive values
+// of the extension's registered type or Ext values if the extension type is not registered.
+func (d *Decoder) visitExt(v codec.Visitor, typ int8, data []byte) error {
+	if _, ok := v.(codec.AnyCodec); !ok {
+		return &codec.UnmarshalTypeError{Value: fmt.Sprintf("extension type %d", typ)}
+	}
+	if decode, ok := lookupExtension(typ); ok {
+		x, err := decode(data)
+		if err != nil {
+			return err
+		}
+		return v.VisitElem(extElem{x})
+	}
+	return v.VisitElem(extElem{Ext{Type: typ, Data: data}})
+}
+
+// decodeValue decodes the next value. If kind is String, bin values are visited as strings; if kind is Slice, str
+// values are visited as bytes.
+func (d *Decoder) decodeValue(v codec.Visitor, kind reflect.Kind) error {
+	b, err := d.readByte()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case b <= positiveFixintMax:
+		return v.VisitUint64(uint64(b))
+	case b >= negFixintMin:
+		return v.VisitInt64(int64(int8(b)))
+	case b&0xf0 == fixmapMask:
+		return d.visitMap(v, uint32(b&0x0f))
+	case b&0xf0 == fixarrayMask:
+		return d.visitSeq(v, uint32(b&0x0f))
+	case b&0xe0 == fixstrMask:
+		return d.visitString(v, uint32(b&0x1f), kind)
+	}
+
+	switch b {
+	case nilByte:
+		return v.VisitNil()
+	case falseByte:
+		return v.VisitBool(false)
+	case trueByte:
+		return v.VisitBool(true)
+	case bin8, bin16, bin32:
+		n, err := d.readLength(1 << (b - bin8))
+		if err != nil {
+			return err
+		}
+		data, err := d.read(n)
+		if err != nil {
+			return err
+		}
+		if kind == reflect.String {
+			return v.VisitString(string(data))
+		}
+		return v.VisitBytes(data)
+	case float32Byte:
+		bits, err := d.readUint(4)
+		if err != nil {
+			return err
+		}
+		return v.VisitFloat32(math.Float32frombits(uint32(bits)))
+	case float64Byte:
+		bits, err := d.readUint(8)
+		if err != nil {
+			return err
+		}
+		return v.VisitFloat64(math.Float64frombits(bits))
+	case uint8Byte, uint16Byte, uint32Byte, uint64Byte:
+		u, err := d.readUint(1 << (b - uint8Byte))
+		if err != nil {
+			return err
+		}
+		return v.VisitUint64(u)
+	case int8Byte, int16Byte, int32Byte, int64Byte:
+		size := 1 << (b - int8Byte)
+		u, err := d.readUint(size)
+		if err != nil {
+			return err
+		}
+		// Sign-extend the value.
+		shift := 64 - 8*size
+		return v.VisitInt64(int64(u<<shift) >> shift)
+	case str8, str16, str32:
+		n, err := d.readLength(1 << (b - str8))
+		if err != nil {
+			return err
+		}
+		return d.visitString(v, n, kind)
+	case array16, array32:
+		n, err := d.readLength(2 << (b - array16))
+		if err != nil {
+			return err
+		}
+		return d.visitSeq(v, n)
+	case map16, map32:
+		n, err := d.readLength(2 << (b - map16))
+		if err != nil {
+			return err
+		}
+		return d.visitMap(v, n)
+	}
+
+	if size, length, ok := extLength(b); ok {
+		typ, data, err := d.readExtData(size, length)
+		if err != nil {
+			return err
+		}
+		return d.visitExt(v, typ, data)
+	}
+	return d.syntaxError("invalid format byte 0x%02x", b)
+}
+
+func (d *Decoder) visitString(v codec.Visitor, n uint32, kind reflect.Kind) error {
+	data, err := d.read(n)
+	if err != nil {
+		return err
+	}
+	if kind == reflect.Slice {
+		return v.VisitBytes(data)
+	}
+	return v.VisitString(string(data))
+}
+
+func (d *Decoder) visitSeq(v codec.Visitor, n uint32) error {
+	// Each element occupies at least one byte.
+	if err := d.checkLength(uint64(n)); err != nil {
+		return err
+	}
+	return v.VisitSeq(&SeqDecoder{d: d, remaining: int(n)})
+}
+
+func (d *Decoder) visitMap(v codec.Visitor, n uint32) error {
+	// Each entry occupies at least two bytes.
+	if err := d.checkLength(2 * uint64(n)); err != nil {
+		return err
+	}
+	return v.VisitMap(&MapDecoder{d: d, remaining: int(n)})
+}
+
+// Format returns the MessagePack format.
+func (d *Decoder) Format() *codec.Format {
+	return Format
+}
+
+func (d *Decoder) DecodeAny(v codec.Visitor) error {
+	return d.decodeValue(v, reflect.Invalid)
+}
+
+func (d *Decoder) DecodeNil(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d *Decoder) DecodeBool(v codec.Visitor) error                { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt8(v codec.Visitor) error                { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt16(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt32(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d *Decoder) DecodeInt64(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint(v codec.Visitor) error                { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint8(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint16(v codec.Visitor) error              { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint32(v codec.Visitor) error              { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUint64(v codec.Visitor) error              { return d.DecodeAny(v) }
+func (d *Decoder) DecodeUintptr(v codec.Visitor) error             { return d.DecodeAny(v) }
+func (d *Decoder) DecodeFloat32(v codec.Visitor) error             { return d.DecodeAny(v) }
+func (d *Decoder) DecodeFloat64(v codec.Visitor) error             { return d.DecodeAny(v) }
+func (d *Decoder) DecodeComplex64(v codec.Visitor) error           { return d.DecodeAny(v) }
+func (d *Decoder) DecodeComplex128(v codec.Visitor) error          { return d.DecodeAny(v) }
+func (d *Decoder) DecodeSeq(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d *Decoder) DecodeMap(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d *Decoder) DecodeStruct(name string, v codec.Visitor) error { return d.DecodeAny(v) }
+
+// DecodeString decodes a str value. For compatibility with encoders that do not distinguish between strings and byte
+// strings, bin values are also accepted.
+func (d *Decoder) DecodeString(v codec.Visitor) error {
+	return d.decodeValue(v, reflect.String)
+}
+
+// DecodeBytes decodes a bin value. For compatibility with encoders that do not distinguish between strings and byte
+// strings, str values are also accepted.
+func (d *Decoder) DecodeBytes(v codec.Visitor) error {
+	return d.decodeValue(v, reflect.Slice)
+}
+
+func (d *Decoder) DecodePtr(v codec.Visitor) error {
+	b, err := d.peekByte()
+	if err != nil {
+		return err
+	}
+	if b == nilByte {
+		d.readByte()
+		return v.VisitNil()
+	}
+	return v.VisitElem(ElemDecoder{d})
+}
+
+// nilValue is the encoding of nil. It is decoded as the value of unit variants.
+var nilValue = []byte{nilByte}
+
+// DecodeVariant decodes the externally tagged representation produced by Encoder.EncodeVariant: unit variants are
+// represented by their names, and other variants by single-entry maps from their names to their values. Other values
+// are decoded as-is.
+func (d *Decoder) DecodeVariant(enum string, variants []string, v codec.Visitor) error {
+	b, err := d.peekByte()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case b&0xe0 == fixstrMask || b == str8 || b == str16 || b == str32:
+		var name string
+		if err := d.DecodeString(codec.NewString(&name)); err != nil {
+			return err
+		}
+		nd := &Decoder{r: bytes.NewReader(nilValue), options: d.options}
+		return v.VisitVariant(&VariantDecoder{d: nd, name: name})
+	case b == fixmapMask|1:
+		d.readByte()
+
+		// If the key is not a string, this is an ordinary map.
+		k, err := d.peekByte()
+		if err != nil {
+			return err
+		}
+		if k&0xe0 != fixstrMask && k != str8 && k != str16 && k != str32 {
+			return v.VisitMap(&MapDecoder{d: d, remaining: 1})
+		}
+
+		var name string
+		if err := d.DecodeString(codec.NewString(&name)); err != nil {
+			return err
+		}
+		vd := &VariantDecoder{d: d, name: name}
+		if err := v.VisitVariant(vd); err != nil {
+			return err
+		}
+		if !vd.decoded {
+			return (codec.SkipCodec{}).Deserialize(d)
+		}
+		return nil
+	default:
+		return d.DecodeAny(v)
+	}
+}
+
+type ElemDecoder struct {
+	d *Decoder
+}
+
+func (d ElemDecoder) Element(_ any, ds codec.Deserializer) error {
+	return ds.Deserialize(d.d)
+}
+
+type SeqDecoder struct {
+	d         *Decoder
+	remaining int
+}
+
+// Size returns the number of remaining elements.
+func (d *SeqDecoder) Size() (int, bool) {
+	return d.remaining, true
+}
+
+func (d *SeqDecoder) NextElement(_ any, ds codec.Deserializer) (bool, error) {
+	if d.remaining == 0 {
+		return false, nil
+	}
+	d.remaining--
+	return true, ds.Deserialize(d.d)
+}
+
+type MapDecoder struct {
+	d         *Decoder
+	remaining int
+}
+
+// Size returns the number of remaining entries.
+func (d *MapDecoder) Size() (int, bool) {
+	return d.remaining, true
+}
+
+func (d *MapDecoder) Options() codec.DecodeOptions {
+	return d.d.options
+}
+
+func (d *MapDecoder) NextKey(_ any, ds codec.Deserializer) (bool, error) {
+	if d.remaining == 0 {
+		return false, nil
+	}
+	d.remaining--
+	return true, ds.Deserialize(d.d)
+}
+
+func (d *MapDecoder) NextValue(_ any, ds codec.Deserializer) error {
+	return ds.Deserialize(d.d)
+}
+
+type VariantDecoder struct {
+	d       *Decoder
+	name    string
+	decoded bool
+}
+
+func (d *VariantDecoder) Variant() string {
+	return d.name
+}
+
+func (d *VariantDecoder) Value(_ any, ds codec.Deserializer) error {
+	d.decoded = true
+	return ds.Deserialize(d.d)
+}
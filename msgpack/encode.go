@@ -0,0 +1,345 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"reflect"
+
+	"github.com/pgavlin/codec"
+)
+
+// An Encoder appends the MessagePack encoding of values to a buffer.
+//
+// Encoders returned by NewEncoder write each value passed to Encode to an underlying writer.
+type Encoder struct {
+	out []byte
+	w   io.Writer // non-nil if the encoder was returned by NewEncoder
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the MessagePack encoding of v to the stream.
+//
+// Encode may only be called on encoders returned by NewEncoder.
+func (e *Encoder) Encode(v any) error {
+	e.out = e.out[:0]
+	if err := codec.GetSerializer(v, Format).Serialize(e); err != nil {
+		return err
+	}
+	_, err := e.w.Write(e.out)
+	return err
+}
+
+// appendUint appends v using the smallest of the positive fixint and unsigned integer formats that can represent it.
+func appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v <= positiveFixintMax:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, uint8Byte, byte(v))
+	case v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, uint16Byte), uint16(v))
+	case v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, uint32Byte), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, uint64Byte), v)
+	}
+}
+
+// appendInt appends v using the smallest integer format that can represent it. Non-negative values are encoded as
+// unsigned integers.
+func appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, int8Byte, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, int16Byte), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, int32Byte), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, int64Byte), uint64(v))
+	}
+}
+
+// appendStringHead appends the head of a str value with the given length.
+func appendStringHead(b []byte, n int) []byte {
+	switch {
+	case n < 32:
+		return append(b, fixstrMask|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, str8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, str16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, str32), uint32(n))
+	}
+}
+
+// appendBytesHead appends the head of a bin value with the given length.
+func appendBytesHead(b []byte, n int) []byte {
+	switch {
+	case n <= math.MaxUint8:
+		return append(b, bin8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, bin16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, bin32), uint32(n))
+	}
+}
+
+// appendContainerHead appends the head of an array or map with the given length. fix is the fixarray or fixmap mask,
+// and the 16- and 32-bit formats follow the given 16-bit format byte.
+func appendContainerHead(b []byte, fix, format16 byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, format16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, format16+1), uint32(n))
+	}
+}
+
+// appendExt appends an extension value, using the fixext formats where possible.
+func appendExt(b []byte, typ int8, data []byte) []byte {
+	switch n := len(data); n {
+	case 1:
+		b = append(b, fixext1)
+	case 2:
+		b = append(b, fixext2)
+	case 4:
+		b = append(b, fixext4)
+	case 8:
+		b = append(b, fixext8)
+	case 16:
+		b = append(b, fixext16)
+	default:
+		switch {
+		case n <= math.MaxUint8:
+			b = append(b, ext8, byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, ext16), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, ext32), uint32(n))
+		}
+	}
+	return append(append(b, byte(typ)), data...)
+}
+
+// Format returns the MessagePack format.
+func (e *Encoder) Format() *codec.Format {
+	return Format
+}
+
+func (e *Encoder) EncodeNil() error {
+	e.out = append(e.out, nilByte)
+	return nil
+}
+
+func (e *Encoder) EncodeBool(v bool) error {
+	if v {
+		e.out = append(e.out, trueByte)
+	} else {
+		e.out = append(e.out, falseByte)
+	}
+	return nil
+}
+
+func (e *Encoder) EncodeInt(v int) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e *Encoder) EncodeInt8(v int8) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e *Encoder) EncodeInt16(v int16) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e *Encoder) EncodeInt32(v int32) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e *Encoder) EncodeInt64(v int64) error {
+	e.out = appendInt(e.out, v)
+	return nil
+}
+
+func (e *Encoder) EncodeUint(v uint) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e *Encoder) EncodeUint8(v uint8) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e *Encoder) EncodeUint16(v uint16) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e *Encoder) EncodeUint32(v uint32) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e *Encoder) EncodeUint64(v uint64) error {
+	e.out = appendUint(e.out, v)
+	return nil
+}
+
+func (e *Encoder) EncodeUintptr(v uintptr) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e *Encoder) EncodeFloat32(v float32) error {
+	e.out = binary.BigEndian.AppendUint32(append(e.out, float32Byte), math.Float32bits(v))
+	return nil
+}
+
+func (e *Encoder) EncodeFloat64(v float64) error {
+	e.out = binary.BigEndian.AppendUint64(append(e.out, float64Byte), math.Float64bits(v))
+	return nil
+}
+
+// EncodeComplex64 returns an error: MessagePack has no representation for complex numbers.
+func (e *Encoder) EncodeComplex64(v complex64) error {
+	return &codec.UnsupportedTypeError{Type: reflect.TypeOf(v)}
+}
+
+// EncodeComplex128 returns an error: MessagePack has no representation for complex numbers.
+func (e *Encoder) EncodeComplex128(v complex128) error {
+	return &codec.UnsupportedTypeError{Type: reflect.TypeOf(v)}
+}
+
+// EncodeString encodes v as a str value.
+func (e *Encoder) EncodeString(v string) error {
+	e.out = append(appendStringHead(e.out, len(v)), v...)
+	return nil
+}
+
+// EncodeBytes encodes v as a bin value.
+func (e *Encoder) EncodeBytes(v []byte) error {
+	e.out = append(appendBytesHead(e.out, len(v)), v...)
+	return nil
+}
+
+func (e *Encoder) EncodeElem(_ any, s codec.Serializer) error {
+	return s.Serialize(e)
+}
+
+func (e *Encoder) EncodeSeq(len int) (codec.SeqEncoder, error) {
+	return &SeqEncoder{e.open(fixarrayMask, array16, len)}, nil
+}
+
+func (e *Encoder) EncodeMap(len int) (codec.MapEncoder, error) {
+	return &MapEncoder{e.open(fixmapMask, map16, len)}, nil
+}
+
+func (e *Encoder) EncodeStruct(name string) (codec.StructEncoder, error) {
+	return &StructEncoder{MapEncoder{e.open(fixmapMask, map16, 0)}}, nil
+}
+
+// EncodeVariant encodes a variant of a sum type using the externally tagged representation: unit variants are encoded
+// as their names, and other variants are encoded as single-entry maps from their names to their values.
+func (e *Encoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	return &VariantEncoder{e: e, name: variant}, nil
+}
+
+// A container is an array or map whose head is written before its contents. The length of a container is not known
+// until it is closed, so its head is written using the length hint passed to EncodeSeq or EncodeMap and patched when
+// the container is closed.
+type container struct {
+	e        *Encoder
+	fix      byte // the fixarray or fixmap mask
+	format16 byte // the array 16 or map 16 format byte
+	start    int  // the offset of the container's head
+	size     int  // the size of the container's head
+	count    int  // the number of elements or entries written
+}
+
+func (e *Encoder) open(fix, format16 byte, n int) container {
+	start := len(e.out)
+	e.out = appendContainerHead(e.out, fix, format16, n)
+	return container{e: e, fix: fix, format16: format16, start: start, size: len(e.out) - start}
+}
+
+func (c *container) close() {
+	var buf [5]byte
+	head := appendContainerHead(buf[:0], c.fix, c.format16, c.count)
+	if len(head) == c.size {
+		copy(c.e.out[c.start:], head)
+		return
+	}
+	c.e.out = append(c.e.out[:c.start], append(head, c.e.out[c.start+c.size:]...)...)
+}
+
+type SeqEncoder struct {
+	container
+}
+
+func (e *SeqEncoder) Close() error {
+	e.close()
+	return nil
+}
+
+func (e *SeqEncoder) EncodeElement(_ any, s codec.Serializer) error {
+	e.count++
+	return s.Serialize(e.e)
+}
+
+type MapEncoder struct {
+	container
+}
+
+func (e *MapEncoder) Close() error {
+	e.close()
+	return nil
+}
+
+func (e *MapEncoder) EncodeKey(_ any, s codec.Serializer) error {
+	e.count++
+	return s.Serialize(e.e)
+}
+
+func (e *MapEncoder) EncodeValue(_ any, s codec.Serializer) error {
+	return s.Serialize(e.e)
+}
+
+type StructEncoder struct {
+	MapEncoder
+}
+
+func (e *StructEncoder) EncodeField(key string, v any, s codec.Serializer) error {
+	if err := e.EncodeKey(key, codec.NewString(&key)); err != nil {
+		return err
+	}
+	return e.EncodeValue(v, s)
+}
+
+type VariantEncoder struct {
+	e        *Encoder
+	name     string
+	hasValue bool
+}
+
+func (e *VariantEncoder) Close() error {
+	if !e.hasValue {
+		return e.e.EncodeString(e.name)
+	}
+	return nil
+}
+
+func (e *VariantEncoder) EncodeValue(_ any, s codec.Serializer) error {
+	e.hasValue = true
+	e.e.out = append(e.e.out, fixmapMask|1)
+	e.e.EncodeString(e.name)
+	return s.Serialize(e.e)
+}
@@ -0,0 +1,269 @@
+// Package msgpack implements the MessagePack serialization format as described by
+// https://github.com/msgpack/msgpack/blob/master/spec.md.
+package msgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pgavlin/codec"
+)
+
+// Format is the codec format for MessagePack. Values of types registered using RegisterExtension are encoded as
+// extension values. Values of type time.Time are encoded using the timestamp extension type.
+var Format = codec.NewFormat("msgpack")
+
+func init() {
+	codec.Override[Ext](Format, extValueCodec{})
+	registerExtension[time.Time](extTimestamp, timestampExtension{})
+}
+
+// Format bytes.
+const (
+	positiveFixintMax = 0x7f
+
+	fixmapMask   = 0x80
+	fixarrayMask = 0x90
+	fixstrMask   = 0xa0
+	negFixintMin = 0xe0
+
+	nilByte   = 0xc0
+	falseByte = 0xc2
+	trueByte  = 0xc3
+
+	bin8  = 0xc4
+	bin16 = 0xc5
+	bin32 = 0xc6
+
+	ext8  = 0xc7
+	ext16 = 0xc8
+	ext32 = 0xc9
+
+	float32Byte = 0xca
+	float64Byte = 0xcb
+
+	uint8Byte  = 0xcc
+	uint16Byte = 0xcd
+	uint32Byte = 0xce
+	uint64Byte = 0xcf
+
+	int8Byte  = 0xd0
+	int16Byte = 0xd1
+	int32Byte = 0xd2
+	int64Byte = 0xd3
+
+	fixext1  = 0xd4
+	fixext2  = 0xd5
+	fixext4  = 0xd6
+	fixext8  = 0xd7
+	fixext16 = 0xd8
+
+	str8  = 0xd9
+	str16 = 0xda
+	str32 = 0xdb
+
+	array16 = 0xdc
+	array32 = 0xdd
+	map16   = 0xde
+	map32   = 0xdf
+)
+
+// extTimestamp is the extension type of timestamps.
+const extTimestamp = -1
+
+// Marshal returns the MessagePack encoding of x.
+func Marshal(x any) ([]byte, error) {
+	return Append(nil, x, codec.GetSerializer(x, Format))
+}
+
+// Append appends the MessagePack encoding of x to b, using s to serialize x, and returns the extended buffer.
+func Append(b []byte, x any, s codec.Serializer) ([]byte, error) {
+	e := Encoder{out: b}
+	err := s.Serialize(&e)
+	return e.out, err
+}
+
+// Unmarshal decodes the single MessagePack value in b into the value pointed to by x. It is an error for b to contain
+// any data after the value.
+func Unmarshal(b []byte, x any) error {
+	d := NewDecoder(bytes.NewReader(b))
+	if err := codec.GetDeserializer(x, Format).Deserialize(d); err != nil {
+		return err
+	}
+	if d.offset != int64(len(b)) {
+		return d.syntaxError("unexpected data after top-level value")
+	}
+	return nil
+}
+
+// An Ext is an extension value. Extension values whose types have not been registered are decoded into interface
+// values as Ext values.
+type Ext struct {
+	Type int8
+	Data []byte
+}
+
+// An Extension converts values of type T to and from the data of an extension value.
+type Extension[T any] interface {
+	MarshalExt(v *T) ([]byte, error)
+	UnmarshalExt(data []byte, v *T) error
+}
+
+// extensions maps extension types to functions that decode extension data into new values of the registered type.
+var extensions struct {
+	m      sync.RWMutex
+	decode map[int8]func(data []byte) (any, error)
+}
+
+// RegisterExtension registers ext as the representation of values of type T as extension values of type typ. Values of
+// type T are encoded as extension values, and extension values of type typ are decoded into values of type T. When
+// decoding into an interface value, extension values of type typ are decoded as values of type T.
+//
+// Negative extension types are reserved by the MessagePack specification, so typ must be non-negative. The timestamp
+// extension type is registered for time.Time. Registering another extension for time.Time replaces the timestamp
+// representation.
+//
+// Extensions must be registered before Format is used to encode or decode values that contain a T, and are typically
+// registered from an init function.
+func RegisterExtension[T any](typ int8, ext Extension[T]) {
+	if typ < 0 {
+		panic(fmt.Sprintf("msgpack: extension type %d is reserved", typ))
+	}
+	registerExtension[T](typ, ext)
+}
+
+func registerExtension[T any](typ int8, ext Extension[T]) {
+	extensions.m.Lock()
+	defer extensions.m.Unlock()
+
+	if extensions.decode == nil {
+		extensions.decode = map[int8]func([]byte) (any, error){}
+	}
+	extensions.decode[typ] = func(data []byte) (any, error) {
+		var v T
+		if err := ext.UnmarshalExt(data, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	codec.Override[T](Format, extCodec[T]{typ: typ, ext: ext})
+}
+
+// lookupExtension returns the function that decodes extension values of type typ, if any.
+func lookupExtension(typ int8) (func([]byte) (any, error), bool) {
+	extensions.m.RLock()
+	defer extensions.m.RUnlock()
+
+	decode, ok := extensions.decode[typ]
+	return decode, ok
+}
+
+// extCodec encodes and decodes values of type T as extension values using a registered extension.
+type extCodec[T any] struct {
+	codec.DefaultVisitor
+	typ   int8
+	ext   Extension[T]
+	value *T
+}
+
+func (c extCodec[T]) New(v *T) codec.Codec[T] {
+	return extCodec[T]{typ: c.typ, ext: c.ext, value: v}
+}
+
+func (c extCodec[T]) VisitBytes(v []byte) error {
+	return c.ext.UnmarshalExt(v, c.value)
+}
+
+func (c extCodec[T]) Deserialize(d codec.Decoder) error {
+	if d, ok := d.(*Decoder); ok {
+		return d.decodeExt(c, func(typ int8, data []byte) error {
+			if typ != c.typ {
+				return &codec.UnmarshalTypeError{Value: fmt.Sprintf("extension type %d", typ)}
+			}
+			return c.ext.UnmarshalExt(data, c.value)
+		})
+	}
+	return d.DecodeBytes(c)
+}
+
+func (c extCodec[T]) Serialize(e codec.Encoder) error {
+	data, err := c.ext.MarshalExt(c.value)
+	if err != nil {
+		return err
+	}
+	if e, ok := e.(*Encoder); ok {
+		e.out = appendExt(e.out, c.typ, data)
+		return nil
+	}
+	return e.EncodeBytes(data)
+}
+
+// extValueCodec encodes and decodes Ext values.
+type extValueCodec struct {
+	codec.DefaultVisitor
+	value *Ext
+}
+
+func (extValueCodec) New(v *Ext) codec.Codec[Ext] {
+	return extValueCodec{value: v}
+}
+
+func (c extValueCodec) Deserialize(d codec.Decoder) error {
+	if d, ok := d.(*Decoder); ok {
+		return d.decodeExt(c, func(typ int8, data []byte) error {
+			*c.value = Ext{Type: typ, Data: data}
+			return nil
+		})
+	}
+	return d.DecodeAny(c)
+}
+
+func (c extValueCodec) Serialize(e codec.Encoder) error {
+	if e, ok := e.(*Encoder); ok {
+		e.out = appendExt(e.out, c.value.Type, c.value.Data)
+		return nil
+	}
+	return e.EncodeBytes(c.value.Data)
+}
+
+// timestampExtension represents time.Time values using the timestamp extension type. Times are encoded using the
+// smallest of the 32-, 64-, and 96-bit timestamp formats that can represent them, and are decoded as UTC times.
+type timestampExtension struct{}
+
+func (timestampExtension) MarshalExt(v *time.Time) ([]byte, error) {
+	sec, nsec := v.Unix(), uint32(v.Nanosecond())
+	switch {
+	case nsec == 0 && sec >= 0 && sec <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(nil, uint32(sec)), nil
+	case sec>>34 == 0:
+		return binary.BigEndian.AppendUint64(nil, uint64(nsec)<<34|uint64(sec)), nil
+	default:
+		b := binary.BigEndian.AppendUint32(make([]byte, 0, 12), nsec)
+		return binary.BigEndian.AppendUint64(b, uint64(sec)), nil
+	}
+}
+
+func (timestampExtension) UnmarshalExt(data []byte, v *time.Time) error {
+	var sec int64
+	var nsec uint32
+	switch len(data) {
+	case 4:
+		sec = int64(binary.BigEndian.Uint32(data))
+	case 8:
+		x := binary.BigEndian.Uint64(data)
+		sec, nsec = int64(x&(1<<34-1)), uint32(x>>34)
+	case 12:
+		nsec, sec = binary.BigEndian.Uint32(data), int64(binary.BigEndian.Uint64(data[4:]))
+	default:
+		return fmt.Errorf("msgpack: invalid timestamp length %d", len(data))
+	}
+	if nsec >= 1e9 {
+		return fmt.Errorf("msgpack: invalid timestamp nanoseconds %d", nsec)
+	}
+	*v = time.Unix(sec, int64(nsec)).UTC()
+	return nil
+}
@@ -0,0 +1,112 @@
+package codec
+
+import (
+	"math"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/exp/constraints"
+)
+
+// number is the set of types that the numeric codecs decode.
+type number interface {
+	constraints.Integer | constraints.Float
+}
+
+// numberError returns an UnmarshalTypeError for a number that cannot be represented by the type of the enclosing codec
+// without overflow or loss of a fractional part.
+func numberError(v string) error {
+	return &UnmarshalTypeError{Value: "number " + v}
+}
+
+// formatFloat formats v for an error message in the same manner as encoding/json.
+func formatFloat(v float64) string {
+	format, abs := byte('f'), math.Abs(v)
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	return strconv.FormatFloat(v, format, -1, 64)
+}
+
+func isFloat[T number]() bool {
+	one := T(1)
+	return one/2 != 0
+}
+
+// setInt stores v in *p. The conversion must be lossless: integers are range-checked, and floats must represent v
+// exactly, e.g. 1<<24 + 1 cannot be stored in a float32.
+func setInt[T number](p *T, v int64) error {
+	t := T(v)
+	if isFloat[T]() {
+		// The float is compared in float64, which represents every float32 exactly. Values of 1<<63 or more are
+		// checked explicitly, as their conversion to int64 is implementation-defined.
+		if f := float64(t); f >= 1<<63 || int64(f) != v {
+			return numberError(strconv.FormatInt(v, 10))
+		}
+	} else if int64(t) != v || (t < 0) != (v < 0) {
+		return numberError(strconv.FormatInt(v, 10))
+	}
+	*p = t
+	return nil
+}
+
+// setUint stores v in *p. As with setInt, the conversion must be lossless.
+func setUint[T number](p *T, v uint64) error {
+	t := T(v)
+	if isFloat[T]() {
+		if f := float64(t); f >= 1<<64 || uint64(f) != v {
+			return numberError(strconv.FormatUint(v, 10))
+		}
+	} else if uint64(t) != v || t < 0 {
+		return numberError(strconv.FormatUint(v, 10))
+	}
+	*p = t
+	return nil
+}
+
+// setFloat stores v in *p. Floats are rounded to the nearest representable value, and must not overflow. Integers
+// must be in range and must not have a fractional part.
+func setFloat[T number](p *T, v float64) error {
+	if isFloat[T]() {
+		t := T(v)
+		if math.IsInf(float64(t), 0) && !math.IsInf(v, 0) {
+			return numberError(formatFloat(v))
+		}
+		*p = t
+		return nil
+	}
+
+	var zero T
+	bits := int(unsafe.Sizeof(zero)) * 8
+	lo, hi := 0.0, math.Ldexp(1, bits)
+	if zero-1 < 0 {
+		lo, hi = -math.Ldexp(1, bits-1), math.Ldexp(1, bits-1)
+	}
+	if v != math.Trunc(v) || v < lo || v >= hi {
+		return numberError(formatFloat(v))
+	}
+	*p = T(v)
+	return nil
+}
+
+// numberVisitor implements the numeric visit methods for the generic numeric codecs. Any numeric value is accepted so
+// long as it can be converted to T without overflow or loss of a fractional part. Floats are rounded to the nearest
+// representable value, but integers must be represented exactly.
+type numberVisitor[T number] struct {
+	DefaultVisitor
+	value *T
+}
+
+func (c numberVisitor[T]) VisitInt(v int) error         { return setInt(c.value, int64(v)) }
+func (c numberVisitor[T]) VisitInt8(v int8) error       { return setInt(c.value, int64(v)) }
+func (c numberVisitor[T]) VisitInt16(v int16) error     { return setInt(c.value, int64(v)) }
+func (c numberVisitor[T]) VisitInt32(v int32) error     { return setInt(c.value, int64(v)) }
+func (c numberVisitor[T]) VisitInt64(v int64) error     { return setInt(c.value, v) }
+func (c numberVisitor[T]) VisitUint(v uint) error       { return setUint(c.value, uint64(v)) }
+func (c numberVisitor[T]) VisitUint8(v uint8) error     { return setUint(c.value, uint64(v)) }
+func (c numberVisitor[T]) VisitUint16(v uint16) error   { return setUint(c.value, uint64(v)) }
+func (c numberVisitor[T]) VisitUint32(v uint32) error   { return setUint(c.value, uint64(v)) }
+func (c numberVisitor[T]) VisitUint64(v uint64) error   { return setUint(c.value, v) }
+func (c numberVisitor[T]) VisitUintptr(v uintptr) error { return setUint(c.value, uint64(v)) }
+func (c numberVisitor[T]) VisitFloat32(v float32) error { return setFloat(c.value, float64(v)) }
+func (c numberVisitor[T]) VisitFloat64(v float64) error { return setFloat(c.value, v) }
@@ -0,0 +1,179 @@
+package protowire
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/pgavlin/codec/internal/codectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type varintMessage struct {
+	A int32 `codec:"a" proto:"1"`
+}
+
+type stringMessage struct {
+	B string `codec:"b" proto:"2"`
+}
+
+type nestedMessage struct {
+	C *varintMessage `codec:"c" proto:"3"`
+}
+
+type packedMessage struct {
+	D []int32 `codec:"d" proto:"4,varint,packed"`
+}
+
+type repeatedMessage struct {
+	E []string `codec:"e" proto:"1"`
+}
+
+type zigzagMessage struct {
+	F int64 `codec:"f" proto:"1,zigzag"`
+}
+
+type fixedMessage struct {
+	G float32 `codec:"g" proto:"1"`
+	H float64 `codec:"h" proto:"2"`
+	I int32   `codec:"i" proto:"3,fixed32"`
+}
+
+type mapMessage struct {
+	M map[string]int32 `codec:"m" proto:"1"`
+}
+
+// Examples from the Protocol Buffers encoding documentation.
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		value    any
+		expected string
+	}{
+		{value: varintMessage{A: 150}, expected: "089601"},
+		{value: varintMessage{A: -1}, expected: "08ffffffffffffffffff01"},
+		{value: stringMessage{B: "testing"}, expected: "120774657374696e67"},
+		{value: nestedMessage{C: &varintMessage{A: 150}}, expected: "1a03089601"},
+		{value: nestedMessage{}, expected: ""},
+		{value: packedMessage{D: []int32{3, 270, 86942}}, expected: "2206038e029ea705"},
+		{value: packedMessage{D: []int32{}}, expected: ""},
+		{value: repeatedMessage{E: []string{"a", "b"}}, expected: "0a01610a0162"},
+		{value: zigzagMessage{F: -2}, expected: "0803"},
+		{value: fixedMessage{G: 1, H: 1, I: -2}, expected: "0d0000803f11000000000000f03f1dfeffffff"},
+		{value: mapMessage{M: map[string]int32{"a": 1}}, expected: "0a050a01611001"},
+		{value: &varintMessage{A: 1}, expected: "0801"},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%#v", c.value), func(t *testing.T) {
+			actual, err := Marshal(c.value)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, hex.EncodeToString(actual))
+		})
+	}
+}
+
+func decodeHex[T any](t *testing.T, s string) T {
+	return codectest.DecodeHex[T](t, Unmarshal, s)
+}
+
+func TestDecode(t *testing.T) {
+	assert.Equal(t, varintMessage{A: 150}, decodeHex[varintMessage](t, "089601"))
+	assert.Equal(t, varintMessage{A: -1}, decodeHex[varintMessage](t, "08ffffffffffffffffff01"))
+	assert.Equal(t, nestedMessage{C: &varintMessage{A: 150}}, decodeHex[nestedMessage](t, "1a03089601"))
+	assert.Equal(t, zigzagMessage{F: -2}, decodeHex[zigzagMessage](t, "0803"))
+	assert.Equal(t, fixedMessage{G: 1, H: 1, I: -2}, decodeHex[fixedMessage](t, "0d0000803f11000000000000f03f1dfeffffff"))
+	assert.Equal(t, mapMessage{M: map[string]int32{"a": 1, "": 0}}, decodeHex[mapMessage](t, "0a050a016110010a00"))
+
+	// Packed and unpacked repeated fields are both accepted.
+	assert.Equal(t, packedMessage{D: []int32{3, 270, 86942}}, decodeHex[packedMessage](t, "2206038e029ea705"))
+	assert.Equal(t, packedMessage{D: []int32{3, 270, 1}}, decodeHex[packedMessage](t, "200322038e0201"))
+
+	// The last value of a scalar field wins, and the values of message fields are merged.
+	assert.Equal(t, varintMessage{A: 2}, decodeHex[varintMessage](t, "08010802"))
+	type pair struct {
+		A int32 `codec:"a" proto:"1"`
+		B int32 `codec:"b" proto:"2"`
+	}
+	type pairMessage struct {
+		P pair `codec:"p" proto:"1"`
+	}
+	assert.Equal(t, pairMessage{P: pair{A: 1, B: 2}}, decodeHex[pairMessage](t, "0a0208010a021002"))
+
+	// Unknown fields are skipped.
+	assert.Equal(t, varintMessage{A: 150}, decodeHex[varintMessage](t, "120161089601"))
+
+	// Messages decoded into interfaces are keyed by field number.
+	assert.Equal(t, map[string]any{"1": uint64(150), "2": []byte("a")}, decodeHex[any](t, "089601120161"))
+}
+
+type testMessage struct {
+	Name     string            `codec:"name" proto:"1"`
+	ID       int32             `codec:"id" proto:"2,zigzag"`
+	Score    float64           `codec:"score" proto:"3"`
+	Enabled  bool              `codec:"enabled" proto:"4"`
+	Data     []byte            `codec:"data" proto:"5"`
+	Scores   []uint32          `codec:"scores" proto:"6,fixed32,packed"`
+	Labels   map[string]string `codec:"labels" proto:"7"`
+	Children []*testMessage    `codec:"children" proto:"8"`
+	Parent   *testMessage      `codec:"parent,omitempty" proto:"9"`
+	Ignored  string            `codec:"-"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	expected := testMessage{
+		Name:     "root",
+		ID:       -42,
+		Score:    0.5,
+		Enabled:  true,
+		Data:     []byte{1, 2, 3},
+		Scores:   []uint32{1, 2, 3},
+		Labels:   map[string]string{"a": "1", "b": "2"},
+		Children: []*testMessage{{Name: "a"}, {Name: "b", Labels: map[string]string{"c": "3"}}},
+		Parent:   &testMessage{Name: "parent"},
+	}
+
+	b, err := Marshal(expected)
+	require.NoError(t, err)
+
+	var actual testMessage
+	require.NoError(t, Unmarshal(b, &actual))
+	assert.Equal(t, expected, actual)
+}
+
+func TestErrors(t *testing.T) {
+	_, err := Marshal(struct{ A int }{A: 1})
+	assert.EqualError(t, err, `protowire: field "A" has no proto tag`)
+
+	_, err = Marshal(struct {
+		A int `proto:"0"`
+	}{})
+	assert.EqualError(t, err, `protowire: field "A" has invalid field number "0"`)
+
+	_, err = Marshal(struct {
+		A string `proto:"1,varint"`
+	}{})
+	assert.EqualError(t, err, "protowire: cannot encode string as field 1 with encoding varint")
+
+	_, err = Marshal(struct {
+		A []string `proto:"1,packed"`
+	}{})
+	assert.EqualError(t, err, `protowire: field "A" cannot be packed`)
+
+	_, err = Marshal(1)
+	assert.EqualError(t, err, "protowire: cannot encode int as a message")
+
+	var v varintMessage
+	err = Unmarshal([]byte{0x08}, &v)
+	assert.EqualError(t, err, "protowire: invalid varint")
+	assert.Equal(t, int64(1), err.(*SyntaxError).Offset)
+
+	err = Unmarshal([]byte{0x0a, 0x01, 0x01}, &v)
+	assert.EqualError(t, err, "protowire: wire type 2 does not match encoding varint")
+
+	err = Unmarshal([]byte{0x0b}, &v)
+	assert.EqualError(t, err, "protowire: groups are not supported")
+
+	d := NewDecoder([]byte{0x10, 0x01})
+	d.DisallowUnknownFields()
+	assert.EqualError(t, d.Decode(&v), `codec: unknown field "2" in Go struct varintMessage`)
+}
@@ -0,0 +1,511 @@
+package protowire
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/pgavlin/codec"
+)
+
+// A SyntaxError describes malformed wire-format input.
+type SyntaxError struct {
+	msg    string
+	Offset int64 // the offset in the input at which the error was detected
+}
+
+func (e *SyntaxError) Error() string {
+	return "protowire: " + e.msg
+}
+
+func syntaxError(offset int, format string, args ...any) error {
+	return &SyntaxError{msg: fmt.Sprintf(format, args...), Offset: int64(offset)}
+}
+
+// A value is a single value of a field.
+type value struct {
+	wt     wireType
+	u      uint64 // the value of varint and fixed-width values
+	b      []byte // the contents of length-delimited values
+	offset int    // the offset of the value in the input
+}
+
+// A field holds the values of a field in the order in which they appear in a message.
+type field struct {
+	number uint32
+	values []value
+}
+
+// parseMessage parses the fields of the message in b, which begins at the given offset in the input. The values of
+// fields that appear more than once are grouped together, and fields are ordered by their first appearance.
+func parseMessage(b []byte, offset int) ([]field, error) {
+	var fields []field
+	var index map[uint32]int
+	for i := 0; i < len(b); {
+		start := offset + i
+		tag, n := binary.Uvarint(b[i:])
+		if n <= 0 {
+			return nil, syntaxError(start, "invalid field tag")
+		}
+		i += n
+
+		number, wt := tag>>3, wireType(tag&7)
+		if number == 0 || number > maxFieldNumber {
+			return nil, syntaxError(start, "invalid field number %d", number)
+		}
+
+		v := value{wt: wt, offset: offset + i}
+		switch wt {
+		case wireVarint:
+			v.u, n = binary.Uvarint(b[i:])
+			if n <= 0 {
+				return nil, syntaxError(v.offset, "invalid varint")
+			}
+			i += n
+		case wireFixed32:
+			if len(b)-i < 4 {
+				return nil, syntaxError(v.offset, "unexpected end of input")
+			}
+			v.u, i = uint64(binary.LittleEndian.Uint32(b[i:])), i+4
+		case wireFixed64:
+			if len(b)-i < 8 {
+				return nil, syntaxError(v.offset, "unexpected end of input")
+			}
+			v.u, i = binary.LittleEndian.Uint64(b[i:]), i+8
+		case wireBytes:
+			l, n := binary.Uvarint(b[i:])
+			if n <= 0 {
+				return nil, syntaxError(v.offset, "invalid length")
+			}
+			i += n
+			if l > uint64(len(b)-i) {
+				return nil, syntaxError(v.offset, "unexpected end of input")
+			}
+			v.b, v.offset, i = b[i:i+int(l)], offset+i, i+int(l)
+		case wireStartGroup, wireEndGroup:
+			return nil, syntaxError(start, "groups are not supported")
+		default:
+			return nil, syntaxError(start, "invalid wire type %d", wt)
+		}
+
+		if j, ok := index[uint32(number)]; ok {
+			fields[j].values = append(fields[j].values, v)
+			continue
+		}
+		if index == nil {
+			index = map[uint32]int{}
+		}
+		index[uint32(number)] = len(fields)
+		fields = append(fields, field{number: uint32(number), values: []value{v}})
+	}
+	return fields, nil
+}
+
+// A Decoder decodes a message from a buffer.
+type Decoder struct {
+	b       []byte
+	options codec.DecodeOptions
+}
+
+// NewDecoder returns a new decoder that decodes the message in b.
+func NewDecoder(b []byte) *Decoder {
+	return &Decoder{b: b}
+}
+
+// DisallowUnknownFields causes the Decoder to return an error when the destination is a struct and the input contains
+// fields whose numbers do not match any of the struct's fields.
+func (d *Decoder) DisallowUnknownFields() {
+	d.options.DisallowUnknownFields = true
+}
+
+// Decode decodes the message into the value pointed to by v.
+func (d *Decoder) Decode(v any) error {
+	return codec.GetDeserializer(v, Format).Deserialize(d)
+}
+
+// visitMessage visits the message in b, which begins at the given offset in the input, as a map. If v is a
+// StructVisitor, the message's fields are identified by the names of the struct fields with matching numbers. Other
+// fields are identified by their numbers.
+func (d *Decoder) visitMessage(b []byte, offset int, v codec.Visitor) error {
+	fields, err := parseMessage(b, offset)
+	if err != nil {
+		return err
+	}
+
+	var known map[uint32]knownField
+	if sv, ok := v.(codec.StructVisitor); ok {
+		structFields := sv.StructFields()
+		known = make(map[uint32]knownField, len(structFields))
+		for i := range structFields {
+			f := &structFields[i]
+			if _, ok := f.Tag.Lookup("proto"); !ok {
+				continue
+			}
+			info, err := fieldInfoOf(f)
+			if err != nil {
+				return err
+			}
+			known[info.number] = knownField{name: f.Name, info: info}
+		}
+	}
+	return v.VisitMap(&MapDecoder{d: d, fields: fields, known: known})
+}
+
+func (d *Decoder) notMessage() error {
+	return &codec.UnmarshalTypeError{Value: "message"}
+}
+
+func (d *Decoder) DecodeNil(v codec.Visitor) error        { return d.notMessage() }
+func (d *Decoder) DecodeBool(v codec.Visitor) error       { return d.notMessage() }
+func (d *Decoder) DecodeInt(v codec.Visitor) error        { return d.notMessage() }
+func (d *Decoder) DecodeInt8(v codec.Visitor) error       { return d.notMessage() }
+func (d *Decoder) DecodeInt16(v codec.Visitor) error      { return d.notMessage() }
+func (d *Decoder) DecodeInt32(v codec.Visitor) error      { return d.notMessage() }
+func (d *Decoder) DecodeInt64(v codec.Visitor) error      { return d.notMessage() }
+func (d *Decoder) DecodeUint(v codec.Visitor) error       { return d.notMessage() }
+func (d *Decoder) DecodeUint8(v codec.Visitor) error      { return d.notMessage() }
+func (d *Decoder) DecodeUint16(v codec.Visitor) error     { return d.notMessage() }
+func (d *Decoder) DecodeUint32(v codec.Visitor) error     { return d.notMessage() }
+func (d *Decoder) DecodeUint64(v codec.Visitor) error     { return d.notMessage() }
+func (d *Decoder) DecodeUintptr(v codec.Visitor) error    { return d.notMessage() }
+func (d *Decoder) DecodeFloat32(v codec.Visitor) error    { return d.notMessage() }
+func (d *Decoder) DecodeFloat64(v codec.Visitor) error    { return d.notMessage() }
+func (d *Decoder) DecodeComplex64(v codec.Visitor) error  { return d.notMessage() }
+func (d *Decoder) DecodeComplex128(v codec.Visitor) error { return d.notMessage() }
+func (d *Decoder) DecodeString(v codec.Visitor) error     { return d.notMessage() }
+func (d *Decoder) DecodeBytes(v codec.Visitor) error      { return d.notMessage() }
+func (d *Decoder) DecodeSeq(v codec.Visitor) error        { return d.notMessage() }
+
+func (d *Decoder) DecodeVariant(enum string, variants []string, v codec.Visitor) error {
+	return d.notMessage()
+}
+
+func (d *Decoder) DecodeMap(v codec.Visitor) error                 { return d.visitMessage(d.b, 0, v) }
+func (d *Decoder) DecodeStruct(name string, v codec.Visitor) error { return d.visitMessage(d.b, 0, v) }
+func (d *Decoder) DecodeAny(v codec.Visitor) error                 { return d.visitMessage(d.b, 0, v) }
+
+func (d *Decoder) DecodePtr(v codec.Visitor) error {
+	return v.VisitElem(ElemDecoder{d})
+}
+
+// Format returns the Protocol Buffers wire format.
+func (d *Decoder) Format() *codec.Format {
+	return Format
+}
+
+type ElemDecoder struct {
+	d codec.Decoder
+}
+
+func (d ElemDecoder) Element(_ any, ds codec.Deserializer) error {
+	return ds.Deserialize(d.d)
+}
+
+// A knownField is a field of the struct being decoded.
+type knownField struct {
+	name string
+	info *fieldInfo
+}
+
+// A MapDecoder decodes the fields of a message. Its keys are the names of known fields and the numbers of other fields.
+type MapDecoder struct {
+	d      *Decoder
+	fields []field
+	known  map[uint32]knownField
+	next   int
+}
+
+func (d *MapDecoder) Size() (int, bool) {
+	return len(d.fields) - d.next, true
+}
+
+func (d *MapDecoder) Options() codec.DecodeOptions {
+	return d.d.options
+}
+
+func (d *MapDecoder) NextKey(_ any, ds codec.Deserializer) (bool, error) {
+	if d.next == len(d.fields) {
+		return false, nil
+	}
+	d.next++
+
+	f := &d.fields[d.next-1]
+	name := strconv.FormatUint(uint64(f.number), 10)
+	if known, ok := d.known[f.number]; ok {
+		name = known.name
+	}
+	return true, ds.Deserialize(&fieldDecoder{d: d.d, info: bytesField, values: []value{{wt: wireBytes, b: []byte(name)}}})
+}
+
+func (d *MapDecoder) NextValue(_ any, ds codec.Deserializer) error {
+	f := &d.fields[d.next-1]
+	return ds.Deserialize(&fieldDecoder{d: d.d, info: d.known[f.number].info, values: f.values})
+}
+
+// A fieldDecoder decodes the values of a field. Scalar fields take their last value, the values of message fields are
+// merged, and each value of a repeated field is an element. The info for unknown fields is nil.
+type fieldDecoder struct {
+	d      *Decoder
+	info   *fieldInfo
+	values []value
+}
+
+// A scalarKind identifies the kind of scalar requested by a Decode method.
+type scalarKind int
+
+const (
+	kindAny scalarKind = iota
+	kindBool
+	kindInt
+	kindUint
+	kindFloat
+	kindString
+)
+
+func (d *fieldDecoder) encoding(v value) encoding {
+	if d.info == nil {
+		return wireEncoding(v.wt)
+	}
+	return d.info.encoding
+}
+
+// decodeScalar decodes the last value of the field. Fields without values hold zero values.
+func (d *fieldDecoder) decodeScalar(cv codec.Visitor, kind scalarKind) error {
+	if len(d.values) == 0 {
+		switch kind {
+		case kindBool:
+			return cv.VisitBool(false)
+		case kindInt:
+			return cv.VisitInt64(0)
+		case kindFloat:
+			return cv.VisitFloat64(0)
+		case kindString:
+			return cv.VisitString("")
+		default:
+			return cv.VisitUint64(0)
+		}
+	}
+
+	v := d.values[len(d.values)-1]
+	enc := d.encoding(v)
+	if v.wt != enc.wireType() {
+		return syntaxError(v.offset, "wire type %d does not match encoding %v", v.wt, enc)
+	}
+
+	switch enc {
+	case encVarint:
+		switch kind {
+		case kindBool:
+			return cv.VisitBool(v.u != 0)
+		case kindInt:
+			return cv.VisitInt64(int64(v.u))
+		default:
+			return cv.VisitUint64(v.u)
+		}
+	case encZigzag:
+		return cv.VisitInt64(unzigzag(v.u))
+	case encFixed32:
+		switch kind {
+		case kindFloat:
+			return cv.VisitFloat32(math.Float32frombits(uint32(v.u)))
+		case kindInt:
+			return cv.VisitInt64(int64(int32(v.u)))
+		default:
+			return cv.VisitUint64(v.u)
+		}
+	case encFixed64:
+		switch kind {
+		case kindFloat:
+			return cv.VisitFloat64(math.Float64frombits(v.u))
+		case kindInt:
+			return cv.VisitInt64(int64(v.u))
+		default:
+			return cv.VisitUint64(v.u)
+		}
+	default:
+		if kind == kindString {
+			return cv.VisitString(string(v.b))
+		}
+		return cv.VisitBytes(append([]byte(nil), v.b...))
+	}
+}
+
+func (d *fieldDecoder) DecodeNil(v codec.Visitor) error        { return d.DecodeAny(v) }
+func (d *fieldDecoder) DecodeBool(v codec.Visitor) error       { return d.decodeScalar(v, kindBool) }
+func (d *fieldDecoder) DecodeInt(v codec.Visitor) error        { return d.decodeScalar(v, kindInt) }
+func (d *fieldDecoder) DecodeInt8(v codec.Visitor) error       { return d.decodeScalar(v, kindInt) }
+func (d *fieldDecoder) DecodeInt16(v codec.Visitor) error      { return d.decodeScalar(v, kindInt) }
+func (d *fieldDecoder) DecodeInt32(v codec.Visitor) error      { return d.decodeScalar(v, kindInt) }
+func (d *fieldDecoder) DecodeInt64(v codec.Visitor) error      { return d.decodeScalar(v, kindInt) }
+func (d *fieldDecoder) DecodeUint(v codec.Visitor) error       { return d.decodeScalar(v, kindUint) }
+func (d *fieldDecoder) DecodeUint8(v codec.Visitor) error      { return d.decodeScalar(v, kindUint) }
+func (d *fieldDecoder) DecodeUint16(v codec.Visitor) error     { return d.decodeScalar(v, kindUint) }
+func (d *fieldDecoder) DecodeUint32(v codec.Visitor) error     { return d.decodeScalar(v, kindUint) }
+func (d *fieldDecoder) DecodeUint64(v codec.Visitor) error     { return d.decodeScalar(v, kindUint) }
+func (d *fieldDecoder) DecodeUintptr(v codec.Visitor) error    { return d.decodeScalar(v, kindUint) }
+func (d *fieldDecoder) DecodeFloat32(v codec.Visitor) error    { return d.decodeScalar(v, kindFloat) }
+func (d *fieldDecoder) DecodeFloat64(v codec.Visitor) error    { return d.decodeScalar(v, kindFloat) }
+func (d *fieldDecoder) DecodeComplex64(v codec.Visitor) error  { return d.DecodeAny(v) }
+func (d *fieldDecoder) DecodeComplex128(v codec.Visitor) error { return d.DecodeAny(v) }
+func (d *fieldDecoder) DecodeString(v codec.Visitor) error     { return d.decodeScalar(v, kindString) }
+func (d *fieldDecoder) DecodeBytes(v codec.Visitor) error      { return d.decodeScalar(v, kindAny) }
+
+func (d *fieldDecoder) DecodeVariant(enum string, variants []string, v codec.Visitor) error {
+	return d.DecodeAny(v)
+}
+
+func (d *fieldDecoder) Format() *codec.Format {
+	return Format
+}
+
+// DecodeAny decodes fields with more than one value as repeated fields, and other fields as scalars.
+func (d *fieldDecoder) DecodeAny(v codec.Visitor) error {
+	if len(d.values) > 1 {
+		return d.DecodeSeq(v)
+	}
+	return d.decodeScalar(v, kindAny)
+}
+
+func (d *fieldDecoder) DecodePtr(v codec.Visitor) error {
+	if len(d.values) == 0 {
+		return v.VisitNil()
+	}
+	return v.VisitElem(ElemDecoder{d})
+}
+
+func (d *fieldDecoder) DecodeSeq(v codec.Visitor) error {
+	return v.VisitSeq(&SeqDecoder{d: d.d, info: d.info, values: d.values})
+}
+
+// DecodeMap decodes a map field from its entry messages. Other fields are decoded as-is.
+func (d *fieldDecoder) DecodeMap(v codec.Visitor) error {
+	if d.info == nil || d.info.key == nil {
+		return d.DecodeAny(v)
+	}
+	return v.VisitMap(&entryDecoder{d: d.d, info: d.info, values: d.values})
+}
+
+// DecodeStruct decodes a message field. The values of message fields that appear more than once are merged.
+func (d *fieldDecoder) DecodeStruct(name string, v codec.Visitor) error {
+	var b []byte
+	offset := 0
+	for i, val := range d.values {
+		if val.wt != wireBytes {
+			return syntaxError(val.offset, "wire type %d does not match encoding %v", val.wt, encBytes)
+		}
+		if i == 0 {
+			b, offset = val.b, val.offset
+		} else {
+			b = append(b[:len(b):len(b)], val.b...)
+		}
+	}
+	return d.d.visitMessage(b, offset, v)
+}
+
+// A SeqDecoder decodes the elements of a repeated field. The values of packed fields hold multiple elements.
+type SeqDecoder struct {
+	d      *Decoder
+	info   *fieldInfo
+	values []value
+	packed value // the remaining contents of the current packed value
+}
+
+// Size returns the number of remaining values. The number of elements in packed values is unknown.
+func (d *SeqDecoder) Size() (int, bool) {
+	if d.info != nil && d.info.encoding != encBytes {
+		return 0, false
+	}
+	return len(d.values), true
+}
+
+func (d *SeqDecoder) NextElement(_ any, ds codec.Deserializer) (bool, error) {
+	for len(d.packed.b) == 0 {
+		if len(d.values) == 0 {
+			return false, nil
+		}
+
+		v := d.values[0]
+		d.values = d.values[1:]
+		if v.wt != wireBytes || d.info == nil || d.info.encoding == encBytes {
+			return true, ds.Deserialize(&fieldDecoder{d: d.d, info: d.info, values: []value{v}})
+		}
+		d.packed = v
+	}
+
+	v, err := d.nextPacked()
+	if err != nil {
+		return false, err
+	}
+	return true, ds.Deserialize(&fieldDecoder{d: d.d, info: d.info, values: []value{v}})
+}
+
+// nextPacked reads the next element of the current packed value.
+func (d *SeqDecoder) nextPacked() (value, error) {
+	p := &d.packed
+	v := value{wt: d.info.encoding.wireType(), offset: p.offset}
+	n := 0
+	switch v.wt {
+	case wireVarint:
+		v.u, n = binary.Uvarint(p.b)
+		if n <= 0 {
+			return value{}, syntaxError(p.offset, "invalid varint")
+		}
+	case wireFixed32:
+		if n = 4; len(p.b) < n {
+			return value{}, syntaxError(p.offset, "unexpected end of input")
+		}
+		v.u = uint64(binary.LittleEndian.Uint32(p.b))
+	case wireFixed64:
+		if n = 8; len(p.b) < n {
+			return value{}, syntaxError(p.offset, "unexpected end of input")
+		}
+		v.u = binary.LittleEndian.Uint64(p.b)
+	}
+	p.b, p.offset = p.b[n:], p.offset+n
+	return v, nil
+}
+
+// An entryDecoder decodes a map field from its entry messages.
+type entryDecoder struct {
+	d      *Decoder
+	info   *fieldInfo
+	values []value
+	value  []value // the values of the current entry's value field
+}
+
+func (d *entryDecoder) Size() (int, bool) {
+	return len(d.values), true
+}
+
+func (d *entryDecoder) NextKey(_ any, ds codec.Deserializer) (bool, error) {
+	if len(d.values) == 0 {
+		return false, nil
+	}
+
+	v := d.values[0]
+	d.values = d.values[1:]
+	if v.wt != wireBytes {
+		return false, syntaxError(v.offset, "wire type %d does not match encoding %v", v.wt, encBytes)
+	}
+	fields, err := parseMessage(v.b, v.offset)
+	if err != nil {
+		return false, err
+	}
+
+	var key []value
+	d.value = nil
+	for _, f := range fields {
+		switch f.number {
+		case 1:
+			key = f.values
+		case 2:
+			d.value = f.values
+		}
+	}
+	return true, ds.Deserialize(&fieldDecoder{d: d.d, info: d.info.key, values: key})
+}
+
+func (d *entryDecoder) NextValue(_ any, ds codec.Deserializer) error {
+	return ds.Deserialize(&fieldDecoder{d: d.d, info: d.info.value, values: d.value})
+}
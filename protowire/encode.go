@@ -0,0 +1,320 @@
+package protowire
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/pgavlin/codec"
+)
+
+// An Encoder appends the wire encoding of a message to a buffer. Only structs can be encoded as messages; the encoders
+// for their fields are provided by the StructEncoder returned by EncodeStruct.
+type Encoder struct {
+	out []byte
+}
+
+func (e *Encoder) notMessage(what string) error {
+	return fmt.Errorf("protowire: cannot encode %s as a message", what)
+}
+
+// insertLength inserts the length of the data written since start at start.
+func (e *Encoder) insertLength(start int) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(e.out)-start))
+	e.out = append(e.out, buf[:n]...)
+	copy(e.out[start+n:], e.out[start:len(e.out)-n])
+	copy(e.out[start:], buf[:n])
+}
+
+// Format returns the Protocol Buffers wire format.
+func (e *Encoder) Format() *codec.Format {
+	return Format
+}
+
+// EncodeNil encodes an empty message.
+func (e *Encoder) EncodeNil() error                            { return nil }
+func (e *Encoder) EncodeBool(v bool) error                     { return e.notMessage("bool") }
+func (e *Encoder) EncodeInt(v int) error                       { return e.notMessage("int") }
+func (e *Encoder) EncodeInt8(v int8) error                     { return e.notMessage("int8") }
+func (e *Encoder) EncodeInt16(v int16) error                   { return e.notMessage("int16") }
+func (e *Encoder) EncodeInt32(v int32) error                   { return e.notMessage("int32") }
+func (e *Encoder) EncodeInt64(v int64) error                   { return e.notMessage("int64") }
+func (e *Encoder) EncodeUint(v uint) error                     { return e.notMessage("uint") }
+func (e *Encoder) EncodeUint8(v uint8) error                   { return e.notMessage("uint8") }
+func (e *Encoder) EncodeUint16(v uint16) error                 { return e.notMessage("uint16") }
+func (e *Encoder) EncodeUint32(v uint32) error                 { return e.notMessage("uint32") }
+func (e *Encoder) EncodeUint64(v uint64) error                 { return e.notMessage("uint64") }
+func (e *Encoder) EncodeUintptr(v uintptr) error               { return e.notMessage("uintptr") }
+func (e *Encoder) EncodeFloat32(v float32) error               { return e.notMessage("float32") }
+func (e *Encoder) EncodeFloat64(v float64) error               { return e.notMessage("float64") }
+func (e *Encoder) EncodeComplex64(v complex64) error           { return e.notMessage("complex64") }
+func (e *Encoder) EncodeComplex128(v complex128) error         { return e.notMessage("complex128") }
+func (e *Encoder) EncodeString(v string) error                 { return e.notMessage("string") }
+func (e *Encoder) EncodeBytes(v []byte) error                  { return e.notMessage("bytes") }
+func (e *Encoder) EncodeSeq(len int) (codec.SeqEncoder, error) { return nil, e.notMessage("sequence") }
+func (e *Encoder) EncodeMap(len int) (codec.MapEncoder, error) { return nil, e.notMessage("map") }
+
+func (e *Encoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	return nil, e.notMessage("variant")
+}
+
+func (e *Encoder) EncodeElem(_ any, s codec.Serializer) error {
+	return s.Serialize(e)
+}
+
+func (e *Encoder) EncodeStruct(name string) (codec.StructEncoder, error) {
+	return &StructEncoder{e: e, start: -1}, nil
+}
+
+// A StructEncoder encodes the fields of a message. Fields are described by their proto tags.
+type StructEncoder struct {
+	e     *Encoder
+	start int // the offset of the contents of a nested message, or -1 for the top-level message
+}
+
+func (e *StructEncoder) Close() error {
+	if e.start >= 0 {
+		e.e.insertLength(e.start)
+	}
+	return nil
+}
+
+// EncodeField returns an error: fields must be described by proto tags.
+func (e *StructEncoder) EncodeField(key string, v any, s codec.Serializer) error {
+	return fmt.Errorf("protowire: field %q has no proto tag", key)
+}
+
+func (e *StructEncoder) EncodeStructField(f *codec.StructField, v any, s codec.Serializer) error {
+	info, err := fieldInfoOf(f)
+	if err != nil {
+		return err
+	}
+	return s.Serialize(&fieldEncoder{e: e.e, info: info})
+}
+
+// A fieldEncoder encodes the value of a field.
+type fieldEncoder struct {
+	e    *Encoder
+	info *fieldInfo
+
+	element bool // true if the encoder encodes an element of a repeated field
+	packed  bool // true if the encoder encodes an element of a packed repeated field, which has no tag
+}
+
+func (e *fieldEncoder) mismatch(what string) error {
+	return fmt.Errorf("protowire: cannot encode %s as field %d with encoding %v", what, e.info.number, e.info.encoding)
+}
+
+func (e *fieldEncoder) tag(wt wireType) {
+	if !e.packed {
+		e.e.out = appendTag(e.e.out, e.info.number, wt)
+	}
+}
+
+func (e *fieldEncoder) encodeInt(what string, v int64) error {
+	switch e.info.encoding {
+	case encVarint:
+		e.tag(wireVarint)
+		e.e.out = binary.AppendUvarint(e.e.out, uint64(v))
+	case encZigzag:
+		e.tag(wireVarint)
+		e.e.out = binary.AppendUvarint(e.e.out, zigzag(v))
+	case encFixed32:
+		e.tag(wireFixed32)
+		e.e.out = binary.LittleEndian.AppendUint32(e.e.out, uint32(v))
+	case encFixed64:
+		e.tag(wireFixed64)
+		e.e.out = binary.LittleEndian.AppendUint64(e.e.out, uint64(v))
+	default:
+		return e.mismatch(what)
+	}
+	return nil
+}
+
+func (e *fieldEncoder) encodeUint(what string, v uint64) error {
+	switch e.info.encoding {
+	case encVarint:
+		e.tag(wireVarint)
+		e.e.out = binary.AppendUvarint(e.e.out, v)
+	case encFixed32:
+		e.tag(wireFixed32)
+		e.e.out = binary.LittleEndian.AppendUint32(e.e.out, uint32(v))
+	case encFixed64:
+		e.tag(wireFixed64)
+		e.e.out = binary.LittleEndian.AppendUint64(e.e.out, v)
+	default:
+		return e.mismatch(what)
+	}
+	return nil
+}
+
+func (e *fieldEncoder) encodeFloat(what string, v float64) error {
+	switch e.info.encoding {
+	case encFixed32:
+		e.tag(wireFixed32)
+		e.e.out = binary.LittleEndian.AppendUint32(e.e.out, math.Float32bits(float32(v)))
+	case encFixed64:
+		e.tag(wireFixed64)
+		e.e.out = binary.LittleEndian.AppendUint64(e.e.out, math.Float64bits(v))
+	default:
+		return e.mismatch(what)
+	}
+	return nil
+}
+
+func (e *fieldEncoder) encodeBytes(what string, v []byte) error {
+	if e.info.encoding != encBytes {
+		return e.mismatch(what)
+	}
+	e.tag(wireBytes)
+	e.e.out = binary.AppendUvarint(e.e.out, uint64(len(v)))
+	e.e.out = append(e.e.out, v...)
+	return nil
+}
+
+func (e *fieldEncoder) Format() *codec.Format {
+	return Format
+}
+
+// EncodeNil omits the field.
+func (e *fieldEncoder) EncodeNil() error {
+	return nil
+}
+
+func (e *fieldEncoder) EncodeBool(v bool) error {
+	if e.info.encoding != encVarint {
+		return e.mismatch("bool")
+	}
+	u := uint64(0)
+	if v {
+		u = 1
+	}
+	return e.encodeUint("bool", u)
+}
+
+func (e *fieldEncoder) EncodeInt(v int) error         { return e.encodeInt("int", int64(v)) }
+func (e *fieldEncoder) EncodeInt8(v int8) error       { return e.encodeInt("int8", int64(v)) }
+func (e *fieldEncoder) EncodeInt16(v int16) error     { return e.encodeInt("int16", int64(v)) }
+func (e *fieldEncoder) EncodeInt32(v int32) error     { return e.encodeInt("int32", int64(v)) }
+func (e *fieldEncoder) EncodeInt64(v int64) error     { return e.encodeInt("int64", v) }
+func (e *fieldEncoder) EncodeUint(v uint) error       { return e.encodeUint("uint", uint64(v)) }
+func (e *fieldEncoder) EncodeUint8(v uint8) error     { return e.encodeUint("uint8", uint64(v)) }
+func (e *fieldEncoder) EncodeUint16(v uint16) error   { return e.encodeUint("uint16", uint64(v)) }
+func (e *fieldEncoder) EncodeUint32(v uint32) error   { return e.encodeUint("uint32", uint64(v)) }
+func (e *fieldEncoder) EncodeUint64(v uint64) error   { return e.encodeUint("uint64", v) }
+func (e *fieldEncoder) EncodeUintptr(v uintptr) error { return e.encodeUint("uintptr", uint64(v)) }
+func (e *fieldEncoder) EncodeFloat32(v float32) error { return e.encodeFloat("float32", float64(v)) }
+func (e *fieldEncoder) EncodeFloat64(v float64) error { return e.encodeFloat("float64", v) }
+func (e *fieldEncoder) EncodeString(v string) error   { return e.encodeBytes("string", []byte(v)) }
+func (e *fieldEncoder) EncodeBytes(v []byte) error    { return e.encodeBytes("bytes", v) }
+func (e *fieldEncoder) EncodeComplex64(v complex64) error {
+	return &codec.UnsupportedTypeError{Type: reflect.TypeOf(v)}
+}
+
+func (e *fieldEncoder) EncodeComplex128(v complex128) error {
+	return &codec.UnsupportedTypeError{Type: reflect.TypeOf(v)}
+}
+
+func (e *fieldEncoder) EncodeElem(_ any, s codec.Serializer) error {
+	return s.Serialize(e)
+}
+
+// EncodeSeq encodes a repeated field. The elements of packed fields are encoded without tags in a single
+// length-delimited value; the elements of other fields are encoded as separate values.
+func (e *fieldEncoder) EncodeSeq(_ int) (codec.SeqEncoder, error) {
+	if e.element {
+		return nil, e.mismatch("nested sequence")
+	}
+	if !e.info.packed {
+		return &repeatedEncoder{fieldEncoder{e: e.e, info: e.info, element: true}}, nil
+	}
+
+	tag := len(e.e.out)
+	e.tag(wireBytes)
+	return &packedEncoder{
+		elem:  fieldEncoder{e: e.e, info: e.info, element: true, packed: true},
+		tag:   tag,
+		start: len(e.e.out),
+	}, nil
+}
+
+// EncodeMap encodes a map field as a repeated field of entry messages.
+func (e *fieldEncoder) EncodeMap(_ int) (codec.MapEncoder, error) {
+	if e.info.key == nil || e.element {
+		return nil, e.mismatch("map")
+	}
+	return &mapEncoder{e: e.e, info: e.info}, nil
+}
+
+// EncodeStruct encodes a nested message.
+func (e *fieldEncoder) EncodeStruct(name string) (codec.StructEncoder, error) {
+	if e.info.encoding != encBytes || e.packed {
+		return nil, e.mismatch("message")
+	}
+	e.tag(wireBytes)
+	return &StructEncoder{e: e.e, start: len(e.e.out)}, nil
+}
+
+func (e *fieldEncoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	return nil, e.mismatch("variant")
+}
+
+type repeatedEncoder struct {
+	elem fieldEncoder
+}
+
+func (e *repeatedEncoder) Close() error {
+	return nil
+}
+
+func (e *repeatedEncoder) EncodeElement(_ any, s codec.Serializer) error {
+	return s.Serialize(&e.elem)
+}
+
+type packedEncoder struct {
+	elem  fieldEncoder
+	tag   int // the offset of the field's tag
+	start int // the offset of the field's contents
+	count int // the number of elements written
+}
+
+// Close writes the length of the field's contents. Empty packed fields are omitted.
+func (e *packedEncoder) Close() error {
+	if e.count == 0 {
+		e.elem.e.out = e.elem.e.out[:e.tag]
+		return nil
+	}
+	e.elem.e.insertLength(e.start)
+	return nil
+}
+
+func (e *packedEncoder) EncodeElement(_ any, s codec.Serializer) error {
+	e.count++
+	return s.Serialize(&e.elem)
+}
+
+type mapEncoder struct {
+	e     *Encoder
+	info  *fieldInfo
+	start int // the offset of the contents of the current entry
+}
+
+func (e *mapEncoder) Close() error {
+	return nil
+}
+
+func (e *mapEncoder) EncodeKey(_ any, s codec.Serializer) error {
+	e.e.out = appendTag(e.e.out, e.info.number, wireBytes)
+	e.start = len(e.e.out)
+	return s.Serialize(&fieldEncoder{e: e.e, info: e.info.key, element: true})
+}
+
+func (e *mapEncoder) EncodeValue(_ any, s codec.Serializer) error {
+	if err := s.Serialize(&fieldEncoder{e: e.e, info: e.info.value, element: true}); err != nil {
+		return err
+	}
+	e.e.insertLength(e.start)
+	return nil
+}
@@ -0,0 +1,238 @@
+// Package protowire implements the Protocol Buffers wire format for plain Go structs. Rather than relying on generated
+// code, structs declare the numbers and encodings of their fields using proto tags:
+//
+//	type Person struct {
+//		Name   string           `codec:"name" proto:"1"`
+//		ID     int32            `codec:"id" proto:"2,zigzag"`
+//		Scores []uint32         `codec:"scores" proto:"3,fixed32,packed"`
+//		Labels map[string]int64 `codec:"labels" proto:"4"`
+//		Parent *Person          `codec:"parent" proto:"5"`
+//	}
+//
+// The first element of a proto tag is the field's number. The optional second element is the field's encoding, which is
+// one of varint, zigzag, fixed32, fixed64, or bytes. If the encoding is omitted, booleans and integers use varint,
+// float32 values use fixed32, float64 values use fixed64, and all other values, including nested messages, use bytes.
+// The encoding of a repeated field applies to its elements. Repeated fields whose tags include the packed option are
+// encoded as packed repeated fields; packed and unpacked repeated fields are both accepted when decoding. Maps are
+// encoded as repeated entry messages whose keys and values are fields 1 and 2, respectively.
+//
+// Fields are encoded even if they hold zero values unless their codec tags include omitempty.
+package protowire
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pgavlin/codec"
+)
+
+// Format is the codec format for the Protocol Buffers wire format.
+var Format = codec.NewFormat("protowire")
+
+// Marshal returns the wire encoding of x, which must be a struct or a pointer to a struct.
+func Marshal(x any) ([]byte, error) {
+	return Append(nil, x, codec.GetSerializer(x, Format))
+}
+
+// Append appends the wire encoding of x to b, using s to serialize x, and returns the extended buffer.
+func Append(b []byte, x any, s codec.Serializer) ([]byte, error) {
+	e := Encoder{out: b}
+	err := s.Serialize(&e)
+	return e.out, err
+}
+
+// Unmarshal decodes the message in b into the value pointed to by x.
+func Unmarshal(b []byte, x any) error {
+	return NewDecoder(b).Decode(x)
+}
+
+// A wireType identifies the representation of a field's value.
+type wireType byte
+
+const (
+	wireVarint     wireType = 0
+	wireFixed64    wireType = 1
+	wireBytes      wireType = 2
+	wireStartGroup wireType = 3
+	wireEndGroup   wireType = 4
+	wireFixed32    wireType = 5
+)
+
+// An encoding identifies how a field's value is encoded.
+type encoding byte
+
+const (
+	encVarint encoding = iota
+	encZigzag
+	encFixed32
+	encFixed64
+	encBytes
+)
+
+var encodingNames = [...]string{
+	encVarint:  "varint",
+	encZigzag:  "zigzag",
+	encFixed32: "fixed32",
+	encFixed64: "fixed64",
+	encBytes:   "bytes",
+}
+
+func (enc encoding) String() string {
+	return encodingNames[enc]
+}
+
+func (enc encoding) wireType() wireType {
+	switch enc {
+	case encVarint, encZigzag:
+		return wireVarint
+	case encFixed32:
+		return wireFixed32
+	case encFixed64:
+		return wireFixed64
+	default:
+		return wireBytes
+	}
+}
+
+// wireEncoding returns the encoding of values with the given wire type when a field's encoding is unknown.
+func wireEncoding(wt wireType) encoding {
+	switch wt {
+	case wireVarint:
+		return encVarint
+	case wireFixed32:
+		return encFixed32
+	case wireFixed64:
+		return encFixed64
+	default:
+		return encBytes
+	}
+}
+
+// maxFieldNumber is the largest valid field number.
+const maxFieldNumber = 1<<29 - 1
+
+// A fieldInfo describes the wire representation of a field.
+type fieldInfo struct {
+	number   uint32
+	encoding encoding
+	packed   bool
+
+	key, value *fieldInfo // the fields of map entries
+}
+
+// bytesField describes a field whose value is encoded as bytes. It is used to decode struct keys.
+var bytesField = &fieldInfo{encoding: encBytes}
+
+type fieldKey struct {
+	tag reflect.StructTag
+	typ reflect.Type
+}
+
+// fieldInfos caches the results of fieldInfoOf.
+var fieldInfos sync.Map // map[fieldKey]*fieldInfo
+
+// fieldInfoOf returns the wire representation of the given struct field as described by its proto tag.
+func fieldInfoOf(f *codec.StructField) (*fieldInfo, error) {
+	key := fieldKey{tag: f.Tag, typ: f.Type}
+	if info, ok := fieldInfos.Load(key); ok {
+		return info.(*fieldInfo), nil
+	}
+
+	info, err := parseFieldInfo(f)
+	if err != nil {
+		return nil, err
+	}
+	fieldInfos.Store(key, info)
+	return info, nil
+}
+
+func parseFieldInfo(f *codec.StructField) (*fieldInfo, error) {
+	tag, ok := f.Tag.Lookup("proto")
+	if !ok {
+		return nil, fmt.Errorf("protowire: field %q has no proto tag", f.Name)
+	}
+
+	parts := strings.Split(tag, ",")
+	number, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil || number == 0 || number > maxFieldNumber || number >= 19000 && number <= 19999 {
+		return nil, fmt.Errorf("protowire: field %q has invalid field number %q", f.Name, parts[0])
+	}
+
+	t := indirect(f.Type)
+	repeated := (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8
+	if repeated {
+		t = indirect(t.Elem())
+	}
+
+	info := &fieldInfo{number: uint32(number), encoding: defaultEncoding(t)}
+	explicit := false
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "packed":
+			info.packed = true
+		case "varint", "zigzag", "fixed32", "fixed64", "bytes":
+			for enc, name := range encodingNames {
+				if name == opt {
+					info.encoding = encoding(enc)
+				}
+			}
+			explicit = true
+		default:
+			return nil, fmt.Errorf("protowire: field %q has unknown option %q", f.Name, opt)
+		}
+	}
+
+	if info.packed && (!repeated || info.encoding == encBytes) {
+		return nil, fmt.Errorf("protowire: field %q cannot be packed", f.Name)
+	}
+	if t.Kind() == reflect.Map {
+		if repeated || explicit {
+			return nil, fmt.Errorf("protowire: map field %q cannot be repeated or have an encoding", f.Name)
+		}
+		kt, vt := indirect(t.Key()), indirect(t.Elem())
+		if vt.Kind() == reflect.Map || (vt.Kind() == reflect.Slice || vt.Kind() == reflect.Array) && vt.Elem().Kind() != reflect.Uint8 {
+			return nil, fmt.Errorf("protowire: the values of map field %q cannot be repeated", f.Name)
+		}
+		info.key = &fieldInfo{number: 1, encoding: defaultEncoding(kt)}
+		info.value = &fieldInfo{number: 2, encoding: defaultEncoding(vt)}
+	}
+	return info, nil
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// defaultEncoding returns the encoding of values of type t for fields that do not specify an encoding.
+func defaultEncoding(t reflect.Type) encoding {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return encVarint
+	case reflect.Float32:
+		return encFixed32
+	case reflect.Float64:
+		return encFixed64
+	default:
+		return encBytes
+	}
+}
+
+func appendTag(b []byte, number uint32, wt wireType) []byte {
+	return binary.AppendUvarint(b, uint64(number)<<3|uint64(wt))
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func unzigzag(u uint64) int64 {
+	return int64(u>>1) ^ -int64(u&1)
+}
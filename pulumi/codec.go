@@ -1,21 +1,52 @@
 package pulumi
 
 import (
+	"context"
+	"time"
+
 	"github.com/pgavlin/codec"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 )
 
+// Format is the codec format for Pulumi property values.
+var Format = codec.NewFormat("pulumi")
+
+func init() {
+	codec.Override[time.Time](Format, timeCodec{})
+}
+
+// An Encrypter encrypts the values of secrets. The crypters in the Pulumi SDK's config package implement Encrypter.
+type Encrypter interface {
+	EncryptValue(ctx context.Context, plaintext string) (string, error)
+}
+
+// A Decrypter decrypts the values of secrets. The crypters in the Pulumi SDK's config package implement Decrypter.
+type Decrypter interface {
+	DecryptValue(ctx context.Context, ciphertext string) (string, error)
+}
+
 type Unmarshaler interface {
 	UnmarshalPropertyValue(pv resource.PropertyValue) error
 }
 
+// An OptionsUnmarshaler is an Unmarshaler that accepts decode options. The Decoder calls
+// UnmarshalPropertyValueWithOptions instead of UnmarshalPropertyValue if a value implements OptionsUnmarshaler.
+type OptionsUnmarshaler interface {
+	Unmarshaler
+
+	UnmarshalPropertyValueWithOptions(pv resource.PropertyValue, options codec.DecodeOptions) error
+}
+
 type Marshaler interface {
 	MarshalPropertyValue() (resource.PropertyValue, error)
 }
 
+// A Value is a value of type T that may be unknown or secret, and that may depend on resources. Values are unmarshaled
+// from and marshaled to Pulumi output values.
 type Value[T any] struct {
 	unknown bool
 	secret  bool
+	deps    []resource.URN
 	t       T
 }
 
@@ -35,6 +66,13 @@ func NewValue[T any](v T) Value[T] {
 	return Value[T]{t: v}
 }
 
+// WithDependencies returns a copy of v that depends on the resources with the given URNs in addition to v's
+// dependencies.
+func (v Value[T]) WithDependencies(deps ...resource.URN) Value[T] {
+	v.deps = append(v.deps[:len(v.deps):len(v.deps)], deps...)
+	return v
+}
+
 func (v Value[T]) IsUnknown() bool {
 	return v.unknown
 }
@@ -43,30 +81,95 @@ func (v Value[T]) IsSecret() bool {
 	return v.secret
 }
 
+// Dependencies returns the URNs of the resources that v depends on.
+func (v Value[T]) Dependencies() []resource.URN {
+	return v.deps
+}
+
 func (v Value[T]) Value() T {
 	return v.t
 }
 
+// UnmarshalPropertyValue unmarshals a Value from a property value. Secrets and output values may be nested: the Value
+// is secret if any of them is secret, and it depends on the dependencies of all of the output values.
 func (v *Value[T]) UnmarshalPropertyValue(pv resource.PropertyValue) error {
-	for pv.IsSecret() {
-		v.secret = true
-		pv = pv.SecretValue().Element
-	}
-	if pv.IsComputed() || pv.IsOutput() {
-		v.unknown = true
-		return nil
+	return v.UnmarshalPropertyValueWithOptions(pv, codec.DecodeOptions{})
+}
+
+// UnmarshalPropertyValueWithOptions unmarshals a Value from a property value using the given decode options. Any
+// previous contents of v are discarded.
+func (v *Value[T]) UnmarshalPropertyValueWithOptions(pv resource.PropertyValue, options codec.DecodeOptions) error {
+	*v = Value[T]{}
+	for {
+		switch {
+		case pv.IsSecret():
+			v.secret = true
+			pv = pv.SecretValue().Element
+			continue
+		case pv.IsOutput():
+			o := pv.OutputValue()
+			v.secret = v.secret || o.Secret
+			v.deps = append(v.deps, o.Dependencies...)
+			if !o.Known {
+				v.unknown = true
+				return nil
+			}
+			pv = o.Element
+			continue
+		case pv.IsComputed():
+			v.unknown = true
+			return nil
+		}
+		return codec.GetDeserializer(&v.t, Format).Deserialize(NewDecoder(pv).WithOptions(options))
 	}
-	return codec.GetDeserializer(&v.t).Deserialize(NewDecoder(pv))
 }
 
+// MarshalPropertyValue marshals a Value to a property value. Values that are unknown or that have dependencies are
+// marshaled as output values; other secret values are marshaled as secrets.
 func (v Value[T]) MarshalPropertyValue() (pv resource.PropertyValue, err error) {
 	if v.unknown {
-		pv = resource.MakeComputed(resource.NewStringProperty(""))
-	} else if err = codec.GetSerializer(v.t).Serialize(NewEncoder(&pv)); err != nil {
+		pv = resource.NewNullProperty()
+	} else if err = codec.GetSerializer(v.t, Format).Serialize(NewEncoder(&pv)); err != nil {
 		return
 	}
-	if v.secret {
+
+	switch {
+	case v.unknown || len(v.deps) != 0:
+		pv = resource.NewOutputProperty(resource.Output{
+			Element:      pv,
+			Known:        !v.unknown,
+			Secret:       v.secret,
+			Dependencies: v.deps,
+		})
+	case v.secret:
 		pv = resource.MakeSecret(pv)
 	}
 	return
 }
+
+// timeCodec represents time.Time values as RFC 3339 strings.
+type timeCodec struct {
+	codec.DefaultVisitor
+	value *time.Time
+}
+
+func (timeCodec) New(v *time.Time) codec.Codec[time.Time] {
+	return timeCodec{value: v}
+}
+
+func (c timeCodec) VisitString(v string) error {
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return err
+	}
+	*c.value = t
+	return nil
+}
+
+func (c timeCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeString(c)
+}
+
+func (c timeCodec) Serialize(e codec.Encoder) error {
+	return e.EncodeString(c.value.Format(time.RFC3339Nano))
+}
@@ -1,10 +1,14 @@
 package pulumi
 
 import (
+	"context"
+	"encoding/base64"
 	"testing"
+	"time"
 
 	"github.com/pgavlin/codec"
 	any_codec "github.com/pgavlin/codec/any"
+	"github.com/pgavlin/codec/internal/codectest"
 	"github.com/pgavlin/codec/json"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/stretchr/testify/assert"
@@ -16,9 +20,9 @@ type boolStruct struct {
 }
 
 type valueStruct struct {
-	Bool   Value[bool]            `codec:bool`
-	Array  Value[[]Value[string]] `codec:array`
-	Struct Value[*valueStruct]    `codec:struct`
+	Bool   Value[bool]            `codec:"bool"`
+	Array  Value[[]Value[string]] `codec:"array"`
+	Struct Value[*valueStruct]    `codec:"struct"`
 }
 
 func TestDecode(t *testing.T) {
@@ -133,7 +137,7 @@ func TestDeserialize(t *testing.T) {
 
 func TestDeserializeJSON(t *testing.T) {
 	deserialize := func(v any) (pv resource.PropertyValue, err error) {
-		bytes, err := json.Append(nil, v, codec.GetSerializer(v), 0)
+		bytes, err := json.Append(nil, v, codec.GetSerializer(v, nil), 0)
 		require.NoError(t, err)
 		_, err = json.Parse(bytes, nil, NewDeserializer(&pv), 0)
 		return
@@ -203,7 +207,7 @@ func TestSerializeJSON(t *testing.T) {
 	serialize := func(v resource.PropertyValue) (res any, err error) {
 		bytes, err := json.Append(nil, nil, NewSerializer(v), 0)
 		require.NoError(t, err)
-		_, err = json.Parse(bytes, nil, codec.GetDeserializer(&res), 0)
+		_, err = json.Parse(bytes, nil, codec.GetDeserializer(&res, nil), 0)
 		return
 	}
 
@@ -234,3 +238,299 @@ func TestSerializeJSON(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, asset.Serialize(), v)
 }
+
+func TestTime(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 30, 0, 0, time.UTC)
+
+	v, err := Encode(now)
+	require.NoError(t, err)
+	assert.Equal(t, resource.NewStringProperty("2023-06-01T12:30:00Z"), v)
+
+	tv, err := Decode[time.Time](v)
+	require.NoError(t, err)
+	assert.Equal(t, now, tv)
+}
+
+func TestDecodeOptions(t *testing.T) {
+	_, err := DecodeWithOptions[boolStruct](resource.NewObjectProperty(resource.PropertyMap{
+		"field": resource.NewBoolProperty(true),
+		"extra": resource.NewBoolProperty(true),
+	}), codec.DecodeOptions{DisallowUnknownFields: true})
+	assert.EqualError(t, err, `codec: unknown field "extra" in Go struct boolStruct`)
+
+	struct_, err := DecodeWithOptions[boolStruct](resource.NewObjectProperty(resource.PropertyMap{
+		"Field": resource.NewBoolProperty(true),
+	}), codec.DecodeOptions{CaseSensitive: true})
+	require.NoError(t, err)
+	assert.Equal(t, boolStruct{}, struct_)
+
+	// Options apply to the values of Values.
+	_, err = DecodeWithOptions[valueStruct](resource.NewObjectProperty(resource.PropertyMap{
+		"struct": resource.MakeSecret(resource.NewObjectProperty(resource.PropertyMap{
+			"extra": resource.NewBoolProperty(true),
+		})),
+	}), codec.DecodeOptions{DisallowUnknownFields: true})
+	assert.EqualError(t, err, `codec: unknown field "extra" in Go struct valueStruct`)
+
+	values, err := DecodeWithOptions[valueStruct](resource.NewObjectProperty(resource.PropertyMap{
+		"struct": resource.NewObjectProperty(resource.PropertyMap{"Bool": resource.NewBoolProperty(true)}),
+	}), codec.DecodeOptions{CaseSensitive: true})
+	require.NoError(t, err)
+	assert.Equal(t, valueStruct{Struct: NewValue(&valueStruct{})}, values)
+}
+
+type defaultsStruct struct {
+	Name    string `codec:"name,required"`
+	Mode    string `codec:"mode,default=auto"`
+	Enabled bool   `codec:"enabled,default=true"`
+}
+
+func TestDefaults(t *testing.T) {
+	struct_, err := Decode[defaultsStruct](resource.NewObjectProperty(resource.PropertyMap{
+		"name": resource.NewStringProperty("test"),
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, defaultsStruct{Name: "test", Mode: "auto", Enabled: true}, struct_)
+
+	_, err = Decode[defaultsStruct](resource.NewObjectProperty(resource.PropertyMap{
+		"mode": resource.NewStringProperty("manual"),
+	}))
+	assert.EqualError(t, err, `codec: missing required field "name" in Go struct defaultsStruct`)
+}
+
+type pathStruct struct {
+	Items []boolStruct `codec:"items"`
+}
+
+func TestErrorPaths(t *testing.T) {
+	_, err := Decode[pathStruct](resource.NewObjectProperty(resource.PropertyMap{
+		"items": resource.NewArrayProperty([]resource.PropertyValue{
+			resource.NewObjectProperty(resource.PropertyMap{"field": resource.NewStringProperty("yes")}),
+		}),
+	}))
+	var typeErr *codec.UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, "boolStruct", typeErr.Struct)
+	assert.Equal(t, "items[0].field", typeErr.Field)
+}
+
+func TestVariant(t *testing.T) {
+	v, err := Encode(codectest.Option{Valid: true, Value: "x"})
+	require.NoError(t, err)
+	assert.Equal(t, resource.NewObjectProperty(resource.PropertyMap{
+		"variant": resource.NewStringProperty("Some"),
+		"value":   resource.NewStringProperty("x"),
+	}), v)
+
+	o, err := Decode[codectest.Option](v)
+	require.NoError(t, err)
+	assert.Equal(t, codectest.Option{Valid: true, Value: "x"}, o)
+
+	v, err = Encode(codectest.Option{})
+	require.NoError(t, err)
+	assert.Equal(t, resource.NewObjectProperty(resource.PropertyMap{"variant": resource.NewStringProperty("None")}), v)
+
+	o, err = Decode[codectest.Option](resource.NewStringProperty("None"))
+	require.NoError(t, err)
+	assert.Equal(t, codectest.Option{}, o)
+
+	v, err = Deserialize(any_codec.NewDecoder(any_codec.Variant{Name: "Some", Value: "x"}))
+	require.NoError(t, err)
+	assert.Equal(t, resource.NewObjectProperty(resource.PropertyMap{
+		"variant": resource.NewStringProperty("Some"),
+		"value":   resource.NewStringProperty("x"),
+	}), v)
+}
+
+func TestNumbers(t *testing.T) {
+	i, err := Decode[int](resource.NewNumberProperty(42))
+	require.NoError(t, err)
+	assert.Equal(t, 42, i)
+
+	ports, err := Decode[[]uint16](resource.NewArrayProperty([]resource.PropertyValue{resource.NewNumberProperty(443)}))
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{443}, ports)
+
+	_, err = Decode[int](resource.NewNumberProperty(0.5))
+	assert.EqualError(t, err, "codec: cannot unmarshal number 0.5 into Go value of type int")
+
+	_, err = Decode[int32](resource.NewNumberProperty(1 << 40))
+	assert.EqualError(t, err, "codec: cannot unmarshal number 1099511627776 into Go value of type int32")
+}
+
+func TestResourceReference(t *testing.T) {
+	const urn = "urn:pulumi:stack::project::pkg:index:Type::name"
+	custom := resource.MakeCustomResourceReference(urn, "id-1", "1.2.3")
+	unknownID := resource.MakeCustomResourceReference(urn, "", "")
+	component := resource.MakeComponentResourceReference(urn, "")
+
+	var v any
+	require.NoError(t, Serialize(custom, any_codec.NewEncoder(&v)))
+	assert.Equal(t, map[string]any{
+		resource.SigKey:  resource.ResourceReferenceSig,
+		"urn":            urn,
+		"id":             "id-1",
+		"packageVersion": "1.2.3",
+	}, v)
+
+	for _, expected := range []resource.PropertyValue{custom, unknownID, component} {
+		require.NoError(t, Serialize(expected, any_codec.NewEncoder(&v)))
+		pv, err := Deserialize(any_codec.NewDecoder(v))
+		require.NoError(t, err)
+		assert.Equal(t, expected, pv)
+
+		bytes, err := json.Append(nil, nil, NewSerializer(expected), 0)
+		require.NoError(t, err)
+		_, err = json.Parse(bytes, nil, NewDeserializer(&pv), 0)
+		require.NoError(t, err)
+		assert.Equal(t, expected, pv)
+	}
+
+	_, err := Deserialize(any_codec.NewDecoder(map[string]any{resource.SigKey: resource.ResourceReferenceSig}))
+	assert.EqualError(t, err, "malformed resource reference: missing urn")
+}
+
+// base64Crypter "encrypts" values by encoding them as base64.
+type base64Crypter struct{}
+
+func (base64Crypter) EncryptValue(ctx context.Context, plaintext string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(plaintext)), nil
+}
+
+func (base64Crypter) DecryptValue(ctx context.Context, ciphertext string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(ciphertext)
+	return string(b), err
+}
+
+func TestSecret(t *testing.T) {
+	var v any
+	require.NoError(t, Serialize(resource.MakeSecret(resource.NewStringProperty("hunter2")), any_codec.NewEncoder(&v)))
+	assert.Equal(t, map[string]any{resource.SigKey: resource.SecretSig, "value": "hunter2"}, v)
+
+	expected := resource.NewObjectProperty(resource.PropertyMap{
+		"password": resource.MakeSecret(resource.NewStringProperty("hunter2")),
+		"list": resource.NewArrayProperty([]resource.PropertyValue{
+			resource.NewNumberProperty(1),
+			resource.MakeSecret(resource.NewObjectProperty(resource.PropertyMap{
+				"key": resource.MakeSecret(resource.NewBoolProperty(true)),
+			})),
+		}),
+	})
+
+	require.NoError(t, Serialize(expected, any_codec.NewEncoder(&v)))
+	pv, err := Deserialize(any_codec.NewDecoder(v))
+	require.NoError(t, err)
+	assert.Equal(t, expected, pv)
+
+	bytes, err := json.Append(nil, nil, NewSerializer(expected), 0)
+	require.NoError(t, err)
+	_, err = json.Parse(bytes, nil, NewDeserializer(&pv), 0)
+	require.NoError(t, err)
+	assert.Equal(t, expected, pv)
+
+	// Secrets may be encrypted.
+	bytes, err = json.Append(nil, nil, NewSerializer(expected).WithEncrypter(base64Crypter{}), 0)
+	require.NoError(t, err)
+	assert.NotContains(t, string(bytes), "hunter2")
+	_, err = json.Parse(bytes, nil, NewDeserializer(&pv).WithDecrypter(base64Crypter{}), 0)
+	require.NoError(t, err)
+	assert.Equal(t, expected, pv)
+
+	_, err = json.Parse(bytes, nil, NewDeserializer(&pv), 0)
+	assert.EqualError(t, err, "cannot decrypt secret: no decrypter")
+
+	_, err = Deserialize(any_codec.NewDecoder(map[string]any{resource.SigKey: resource.SecretSig}))
+	assert.EqualError(t, err, "malformed secret: missing value, plaintext, or ciphertext")
+
+	// Secrets in exported state hold the JSON representation of their values as plaintext.
+	_, err = json.Parse([]byte(`{"4dabf18193072939515e22adb298388d":"1b47061264138c4ac30d75fd1eb44270",`+
+		`"plaintext":"{\"password\":\"hunter2\",\"port\":8080}"}`), nil, NewDeserializer(&pv), 0)
+	require.NoError(t, err)
+	assert.Equal(t, resource.MakeSecret(resource.NewObjectProperty(resource.PropertyMap{
+		"password": resource.NewStringProperty("hunter2"),
+		"port":     resource.NewNumberProperty(8080),
+	})), pv)
+
+	_, err = json.Parse([]byte(`{"4dabf18193072939515e22adb298388d":"1b47061264138c4ac30d75fd1eb44270",`+
+		`"plaintext":"\"secret\""}`), nil, NewDeserializer(&pv), 0)
+	require.NoError(t, err)
+	assert.Equal(t, resource.MakeSecret(resource.NewStringProperty("secret")), pv)
+}
+
+func TestOutputValue(t *testing.T) {
+	const urn1, urn2 = resource.URN("urn:pulumi:stack::project::pkg:index:Type::a"), resource.URN("urn:pulumi:stack::project::pkg:index:Type::b")
+
+	vs, err := Decode[valueStruct](resource.NewObjectProperty(resource.PropertyMap{
+		"bool": resource.NewOutputProperty(resource.Output{
+			Element:      resource.NewBoolProperty(true),
+			Known:        true,
+			Dependencies: []resource.URN{urn1},
+		}),
+		"array": resource.MakeSecret(resource.NewOutputProperty(resource.Output{
+			Element:      resource.NewNullProperty(),
+			Dependencies: []resource.URN{urn2},
+		})),
+		"struct": resource.NewOutputProperty(resource.Output{
+			Element: resource.NewObjectProperty(resource.PropertyMap{"bool": resource.NewBoolProperty(false)}),
+			Known:   true,
+			Secret:  true,
+		}),
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, valueStruct{
+		Bool:   NewValue(true).WithDependencies(urn1),
+		Array:  NewSecretUnknown[[]Value[string]]().WithDependencies(urn2),
+		Struct: NewSecret(&valueStruct{Bool: NewValue(false)}),
+	}, vs)
+
+	v, err := NewValue(true).WithDependencies(urn1, urn2).MarshalPropertyValue()
+	require.NoError(t, err)
+	assert.Equal(t, resource.NewOutputProperty(resource.Output{
+		Element:      resource.NewBoolProperty(true),
+		Known:        true,
+		Dependencies: []resource.URN{urn1, urn2},
+	}), v)
+
+	v, err = NewSecretUnknown[bool]().MarshalPropertyValue()
+	require.NoError(t, err)
+	assert.Equal(t, resource.NewOutputProperty(resource.Output{Element: resource.NewNullProperty(), Secret: true}), v)
+
+	v, err = NewSecret(true).MarshalPropertyValue()
+	require.NoError(t, err)
+	assert.Equal(t, resource.MakeSecret(resource.NewBoolProperty(true)), v)
+
+	// Output values round-trip through other formats.
+	expected := valueStruct{
+		Bool:   NewSecret(true).WithDependencies(urn1),
+		Array:  NewUnknown[[]Value[string]]().WithDependencies(urn1, urn2),
+		Struct: NewValue[*valueStruct](nil),
+	}
+	v, err = Encode(expected)
+	require.NoError(t, err)
+
+	bytes, err := json.Append(nil, nil, NewSerializer(v), 0)
+	require.NoError(t, err)
+	var pv resource.PropertyValue
+	_, err = json.Parse(bytes, nil, NewDeserializer(&pv), 0)
+	require.NoError(t, err)
+	assert.Equal(t, v, pv)
+
+	actual, err := Decode[valueStruct](pv)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	// Unmarshaling into a Value discards its previous contents.
+	var reused Value[string]
+	output := resource.NewOutputProperty(resource.Output{Element: resource.NewStringProperty("x"), Known: true, Dependencies: []resource.URN{urn1}})
+	require.NoError(t, reused.UnmarshalPropertyValue(output))
+	require.NoError(t, reused.UnmarshalPropertyValue(output))
+	assert.Equal(t, NewValue("x").WithDependencies(urn1), reused)
+	require.NoError(t, reused.UnmarshalPropertyValue(resource.MakeSecret(resource.NewStringProperty("y"))))
+	assert.Equal(t, NewSecret("y"), reused)
+	require.NoError(t, reused.UnmarshalPropertyValue(resource.MakeComputed(resource.NewStringProperty(""))))
+	assert.Equal(t, NewUnknown[string](), reused)
+
+	var obj any
+	require.NoError(t, Serialize(resource.NewOutputProperty(resource.Output{Element: resource.NewStringProperty("x"), Known: true, Dependencies: []resource.URN{urn1}}), any_codec.NewEncoder(&obj)))
+	assert.Equal(t, map[string]any{resource.SigKey: resource.OutputValueSig, "value": "x", "dependencies": []any{string(urn1)}}, obj)
+}
@@ -9,11 +9,17 @@ import (
 )
 
 type Decoder struct {
-	v resource.PropertyValue
+	v       resource.PropertyValue
+	options codec.DecodeOptions
 }
 
 func Decode[T any](v resource.PropertyValue) (t T, err error) {
-	err = codec.GetDeserializer(&t).Deserialize(NewDecoder(v))
+	return DecodeWithOptions[T](v, codec.DecodeOptions{})
+}
+
+// DecodeWithOptions decodes a T from v using the given decode options.
+func DecodeWithOptions[T any](v resource.PropertyValue, options codec.DecodeOptions) (t T, err error) {
+	err = codec.GetDeserializer(&t, Format).Deserialize(NewDecoder(v).WithOptions(options))
 	return
 }
 
@@ -21,6 +27,17 @@ func NewDecoder(v resource.PropertyValue) Decoder {
 	return Decoder{v: v}
 }
 
+// WithOptions returns a copy of d that uses the given decode options.
+func (d Decoder) WithOptions(options codec.DecodeOptions) Decoder {
+	d.options = options
+	return d
+}
+
+// Format returns the format for Pulumi property values.
+func (d Decoder) Format() *codec.Format {
+	return Format
+}
+
 func (d Decoder) DecodeAny(v codec.Visitor) error {
 	switch {
 	case d.v.IsNull():
@@ -32,10 +49,10 @@ func (d Decoder) DecodeAny(v codec.Visitor) error {
 	case d.v.IsString():
 		return v.VisitString(d.v.StringValue())
 	case d.v.IsArray():
-		return v.VisitSeq(&SeqDecoder{v: d.v.ArrayValue()})
+		return v.VisitSeq(&SeqDecoder{v: d.v.ArrayValue(), options: d.options})
 	case d.v.IsObject():
 		dv := d.v.ObjectValue()
-		return v.VisitMap(&MapDecoder{size: len(dv), iter: reflect.ValueOf(dv).MapRange()})
+		return v.VisitMap(&MapDecoder{size: len(dv), iter: reflect.ValueOf(dv).MapRange(), options: d.options})
 	default:
 		return fmt.Errorf("cannot decode %v", d.v.TypeString())
 	}
@@ -64,6 +81,25 @@ func (d Decoder) DecodeSeq(v codec.Visitor) error                 { return d.Dec
 func (d Decoder) DecodeMap(v codec.Visitor) error                 { return d.DecodeAny(v) }
 func (d Decoder) DecodeStruct(name string, v codec.Visitor) error { return d.DecodeAny(v) }
 
+// DecodeVariant decodes the adjacently tagged representation produced by Encoder.EncodeVariant. Strings are decoded as
+// unit variants. Other values are decoded as-is.
+func (d Decoder) DecodeVariant(enum string, variants []string, v codec.Visitor) error {
+	switch {
+	case d.v.IsString():
+		return v.VisitVariant(VariantDecoder{name: d.v.StringValue(), value: resource.NewNullProperty(), options: d.options})
+	case d.v.IsObject():
+		obj := d.v.ObjectValue()
+		if name, ok := obj[variantKey]; ok && name.IsString() {
+			value, ok := obj[valueKey]
+			if !ok {
+				value = resource.NewNullProperty()
+			}
+			return v.VisitVariant(VariantDecoder{name: name.StringValue(), value: value, options: d.options})
+		}
+	}
+	return d.DecodeAny(v)
+}
+
 func (d Decoder) DecodePtr(v codec.Visitor) error {
 	if d.v.IsNull() {
 		return v.VisitNil()
@@ -94,6 +130,8 @@ func (d Decoder) decode(v any, ds codec.Deserializer) error {
 		}
 		*v = d.v.ResourceReferenceValue()
 		return nil
+	case OptionsUnmarshaler:
+		return v.UnmarshalPropertyValueWithOptions(d.v, d.options)
 	case Unmarshaler:
 		return v.UnmarshalPropertyValue(d.v)
 	default:
@@ -110,7 +148,8 @@ func (d ElemDecoder) Element(v any, ds codec.Deserializer) error {
 }
 
 type SeqDecoder struct {
-	v []resource.PropertyValue
+	v       []resource.PropertyValue
+	options codec.DecodeOptions
 }
 
 func (d *SeqDecoder) Size() (int, bool) {
@@ -123,25 +162,44 @@ func (d *SeqDecoder) NextElement(x any, ds codec.Deserializer) (bool, error) {
 	}
 	v := d.v[0]
 	d.v = d.v[1:]
-	return true, Decoder{v}.decode(x, ds)
+	return true, Decoder{v, d.options}.decode(x, ds)
 }
 
 type MapDecoder struct {
-	size int
-	iter *reflect.MapIter
+	size    int
+	iter    *reflect.MapIter
+	options codec.DecodeOptions
 }
 
 func (d *MapDecoder) Size() (int, bool) {
 	return d.size, true
 }
 
+func (d *MapDecoder) Options() codec.DecodeOptions {
+	return d.options
+}
+
 func (d *MapDecoder) NextKey(k any, ds codec.Deserializer) (bool, error) {
 	if !d.iter.Next() {
 		return false, nil
 	}
-	return true, Decoder{resource.NewStringProperty(d.iter.Key().String())}.decode(k, ds)
+	return true, Decoder{resource.NewStringProperty(d.iter.Key().String()), d.options}.decode(k, ds)
 }
 
 func (d *MapDecoder) NextValue(v any, ds codec.Deserializer) error {
-	return Decoder{d.iter.Value().Interface().(resource.PropertyValue)}.decode(v, ds)
+	return Decoder{d.iter.Value().Interface().(resource.PropertyValue), d.options}.decode(v, ds)
+}
+
+type VariantDecoder struct {
+	name    string
+	value   resource.PropertyValue
+	options codec.DecodeOptions
+}
+
+func (d VariantDecoder) Variant() string {
+	return d.name
+}
+
+func (d VariantDecoder) Value(v any, ds codec.Deserializer) error {
+	return Decoder{d.value, d.options}.decode(v, ds)
 }
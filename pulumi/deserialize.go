@@ -1,14 +1,18 @@
 package pulumi
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/pgavlin/codec"
+	"github.com/pgavlin/codec/json"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 )
 
 type Deserializer struct {
-	v *resource.PropertyValue
+	v         *resource.PropertyValue
+	decrypter Decrypter
 }
 
 func Deserialize(dec codec.Decoder) (v resource.PropertyValue, err error) {
@@ -20,6 +24,18 @@ func NewDeserializer(v *resource.PropertyValue) Deserializer {
 	return Deserializer{v: v}
 }
 
+// WithDecrypter returns a copy of d that decrypts the ciphertext of secrets using dec. Secrets that hold ciphertext
+// cannot be deserialized without a decrypter.
+func (d Deserializer) WithDecrypter(dec Decrypter) Deserializer {
+	d.decrypter = dec
+	return d
+}
+
+func (d Deserializer) with(v *resource.PropertyValue) Deserializer {
+	d.v = v
+	return d
+}
+
 func (d Deserializer) VisitNil() error                    { return NewEncoder(d.v).EncodeNil() }
 func (d Deserializer) VisitBool(v bool) error             { return NewEncoder(d.v).EncodeBool(v) }
 func (d Deserializer) VisitInt(v int) error               { return NewEncoder(d.v).EncodeInt(v) }
@@ -59,7 +75,7 @@ func (d Deserializer) VisitSeq(seq codec.SeqDecoder) error {
 	}
 	for {
 		var v resource.PropertyValue
-		ok, err := seq.NextElement(&v, NewDeserializer(&v))
+		ok, err := seq.NextElement(&v, d.with(&v))
 		if err != nil {
 			return err
 		}
@@ -89,13 +105,30 @@ func (d Deserializer) VisitMap(map_ codec.MapDecoder) error {
 		}
 
 		var v resource.PropertyValue
-		if err := map_.NextValue(&v, NewDeserializer(&v)); err != nil {
+		if err := map_.NextValue(&v, d.with(&v)); err != nil {
 			return err
 		}
 		m[k] = v
 	}
 }
 
+func (d Deserializer) VisitVariant(variant codec.VariantDecoder) error {
+	enc, err := NewEncoder(d.v).EncodeVariant("", variant.Variant(), 0)
+	if err != nil {
+		return err
+	}
+	var v resource.PropertyValue
+	if err := variant.Value(&v, d.with(&v)); err != nil {
+		return err
+	}
+	if !v.IsNull() {
+		if err := enc.EncodeValue(v, nil); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
 func (d Deserializer) visitSig(m resource.PropertyMap) error {
 	sig, ok := m[resource.PropertyKey(resource.SigKey)]
 	if !ok {
@@ -110,7 +143,7 @@ func (d Deserializer) visitSig(m resource.PropertyMap) error {
 	switch sig.StringValue() {
 	case resource.ArchiveSig:
 		var obj map[string]any
-		if err := codec.GetDeserializer(&obj).Deserialize(NewDecoder(resource.NewObjectProperty(m))); err != nil {
+		if err := codec.GetDeserializer(&obj, Format).Deserialize(NewDecoder(resource.NewObjectProperty(m))); err != nil {
 			return err
 		}
 		archive, _, err := resource.DeserializeArchive(obj)
@@ -121,7 +154,7 @@ func (d Deserializer) visitSig(m resource.PropertyMap) error {
 		return nil
 	case resource.AssetSig:
 		var obj map[string]any
-		if err := codec.GetDeserializer(&obj).Deserialize(NewDecoder(resource.NewObjectProperty(m))); err != nil {
+		if err := codec.GetDeserializer(&obj, Format).Deserialize(NewDecoder(resource.NewObjectProperty(m))); err != nil {
 			return err
 		}
 		asset, _, err := resource.DeserializeAsset(obj)
@@ -131,14 +164,126 @@ func (d Deserializer) visitSig(m resource.PropertyMap) error {
 		*d.v = resource.NewAssetProperty(asset)
 		return nil
 	case resource.ResourceReferenceSig:
-		panic("todo")
+		ref, err := resourceReference(m)
+		if err != nil {
+			return err
+		}
+		*d.v = ref
+		return nil
 	case resource.SecretSig:
-		panic("todo")
+		return d.visitSecret(m)
+	case resource.OutputValueSig:
+		output, err := outputValue(m)
+		if err != nil {
+			return err
+		}
+		*d.v = output
+		return nil
 	default:
 		return fmt.Errorf("unrecognized signature %q", sig.StringValue())
 	}
 }
 
+// visitSecret decodes a secret from its signature object, which holds the secret's value, the JSON representation of
+// its value (as in the secrets of exported state), or the ciphertext of its JSON representation.
+func (d Deserializer) visitSecret(m resource.PropertyMap) error {
+	if v, ok := m["value"]; ok {
+		*d.v = resource.MakeSecret(v)
+		return nil
+	}
+
+	var plaintext string
+	if p, ok := m["plaintext"]; ok {
+		if !p.IsString() {
+			return errors.New("malformed secret: plaintext must be a string")
+		}
+		plaintext = p.StringValue()
+	} else {
+		ciphertext, ok := m["ciphertext"]
+		if !ok || !ciphertext.IsString() {
+			return errors.New("malformed secret: missing value, plaintext, or ciphertext")
+		}
+		if d.decrypter == nil {
+			return errors.New("cannot decrypt secret: no decrypter")
+		}
+		decrypted, err := d.decrypter.DecryptValue(context.Background(), ciphertext.StringValue())
+		if err != nil {
+			return err
+		}
+		plaintext = decrypted
+	}
+
+	var v resource.PropertyValue
+	if _, err := json.Parse([]byte(plaintext), nil, d.with(&v), 0); err != nil {
+		return err
+	}
+	*d.v = resource.MakeSecret(v)
+	return nil
+}
+
+// resourceReference returns the resource reference represented by a signature object. References that have IDs refer
+// to custom resources; an empty ID refers to a custom resource whose ID is unknown.
+func resourceReference(m resource.PropertyMap) (resource.PropertyValue, error) {
+	urn, ok := m["urn"]
+	if !ok || !urn.IsString() {
+		return resource.PropertyValue{}, errors.New("malformed resource reference: missing urn")
+	}
+
+	var packageVersion string
+	if v, ok := m["packageVersion"]; ok {
+		if !v.IsString() {
+			return resource.PropertyValue{}, errors.New("malformed resource reference: packageVersion must be a string")
+		}
+		packageVersion = v.StringValue()
+	}
+
+	id, ok := m["id"]
+	switch {
+	case !ok:
+		return resource.MakeComponentResourceReference(resource.URN(urn.StringValue()), packageVersion), nil
+	case id.IsComputed():
+		return resource.MakeCustomResourceReference(resource.URN(urn.StringValue()), "", packageVersion), nil
+	case id.IsString():
+		return resource.MakeCustomResourceReference(resource.URN(urn.StringValue()), resource.ID(id.StringValue()), packageVersion), nil
+	default:
+		return resource.PropertyValue{}, errors.New("malformed resource reference: id must be a string")
+	}
+}
+
+// outputValue returns the output value represented by a signature object. Outputs whose objects have no values are
+// unknown.
+func outputValue(m resource.PropertyMap) (resource.PropertyValue, error) {
+	var o resource.Output
+	if v, ok := m["value"]; ok {
+		o.Element, o.Known = v, true
+	} else {
+		o.Element = resource.NewNullProperty()
+	}
+
+	if v, ok := m["secret"]; ok {
+		if !v.IsBool() {
+			return resource.PropertyValue{}, errors.New("malformed output value: secret must be a bool")
+		}
+		o.Secret = v.BoolValue()
+	}
+
+	if v, ok := m["dependencies"]; ok {
+		if !v.IsArray() {
+			return resource.PropertyValue{}, errors.New("malformed output value: dependencies must be an array")
+		}
+		deps := v.ArrayValue()
+		o.Dependencies = make([]resource.URN, len(deps))
+		for i, dep := range deps {
+			if !dep.IsString() {
+				return resource.PropertyValue{}, errors.New("malformed output value: dependencies must be strings")
+			}
+			o.Dependencies[i] = resource.URN(dep.StringValue())
+		}
+	}
+
+	return resource.NewOutputProperty(o), nil
+}
+
 func (d Deserializer) Deserialize(dec codec.Decoder) error {
 	return dec.DecodeAny(d)
 }
@@ -12,7 +12,7 @@ type Encoder struct {
 }
 
 func Encode[T any](v T) (res resource.PropertyValue, err error) {
-	err = codec.GetSerializer(v).Serialize(NewEncoder(&res))
+	err = codec.GetSerializer(v, Format).Serialize(NewEncoder(&res))
 	return
 }
 
@@ -43,6 +43,11 @@ func (e Encoder) encode(v any, s codec.Serializer) error {
 	}
 }
 
+// Format returns the format for Pulumi property values.
+func (e Encoder) Format() *codec.Format {
+	return Format
+}
+
 func (e Encoder) EncodeNil() error {
 	*e.v = resource.NewNullProperty()
 	return nil
@@ -161,6 +166,17 @@ func (e Encoder) EncodeStruct(name string) (codec.StructEncoder, error) {
 	return &StructEncoder{v: e.v, m: make(resource.PropertyMap)}, nil
 }
 
+// Variants are encoded using the adjacently tagged representation: an object whose "variant" property names the
+// variant and whose "value" property, if present, holds the variant's value. Unit variants have no "value" property.
+const (
+	variantKey resource.PropertyKey = "variant"
+	valueKey   resource.PropertyKey = "value"
+)
+
+func (e Encoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	return &VariantEncoder{v: e.v, m: resource.PropertyMap{variantKey: resource.NewStringProperty(variant)}}, nil
+}
+
 type SeqEncoder struct {
 	v  *resource.PropertyValue
 	vs []resource.PropertyValue
@@ -223,6 +239,25 @@ func (e *StructEncoder) EncodeField(key string, x any, ser codec.Serializer) err
 	return nil
 }
 
+type VariantEncoder struct {
+	v *resource.PropertyValue
+	m resource.PropertyMap
+}
+
+func (e *VariantEncoder) Close() error {
+	*e.v = resource.NewObjectProperty(e.m)
+	return nil
+}
+
+func (e *VariantEncoder) EncodeValue(x any, ser codec.Serializer) error {
+	var v resource.PropertyValue
+	if err := NewEncoder(&v).encode(x, ser); err != nil {
+		return err
+	}
+	e.m[valueKey] = v
+	return nil
+}
+
 type mapKeyEncoder struct {
 	key *resource.PropertyKey
 }
@@ -266,3 +301,6 @@ func (e mapKeyEncoder) EncodeMap(len int) (codec.MapEncoder, error) {
 func (e mapKeyEncoder) EncodeStruct(name string) (codec.StructEncoder, error) {
 	return nil, errors.New("map key must be a string")
 }
+func (e mapKeyEncoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	return nil, errors.New("map key must be a string")
+}
@@ -1,7 +1,10 @@
 package pulumi
 
 import (
+	"context"
+
 	"github.com/pgavlin/codec"
+	"github.com/pgavlin/codec/json"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
@@ -10,7 +13,8 @@ import (
 const unknownRepr = "04da6b54-80e4-46f7-96ec-b56ff0331ba9"
 
 type Serializer struct {
-	v resource.PropertyValue
+	v         resource.PropertyValue
+	encrypter Encrypter
 }
 
 func Serialize(v resource.PropertyValue, enc codec.Encoder) error {
@@ -21,12 +25,26 @@ func NewSerializer(v resource.PropertyValue) Serializer {
 	return Serializer{v: v}
 }
 
+// WithEncrypter returns a copy of s that encrypts the values of secrets using e. The ciphertext of each secret is the
+// encryption of the JSON representation of its value.
+func (s Serializer) WithEncrypter(e Encrypter) Serializer {
+	s.encrypter = e
+	return s
+}
+
+func (s Serializer) with(v resource.PropertyValue) Serializer {
+	s.v = v
+	return s
+}
+
 func (s Serializer) Serialize(enc codec.Encoder) error {
 	switch {
 	case s.v.IsNull():
 		return enc.EncodeNil()
-	case s.v.IsComputed(), s.v.IsOutput():
+	case s.v.IsComputed():
 		return enc.EncodeString(unknownRepr)
+	case s.v.IsOutput():
+		return s.with(resource.NewObjectProperty(outputObject(s.v.OutputValue()))).Serialize(enc)
 	case s.v.IsBool():
 		return enc.EncodeBool(s.v.BoolValue())
 	case s.v.IsNumber():
@@ -34,13 +52,17 @@ func (s Serializer) Serialize(enc codec.Encoder) error {
 	case s.v.IsString():
 		return enc.EncodeString(s.v.StringValue())
 	case s.v.IsArchive():
-		return codec.GetSerializer(s.v.ArchiveValue().Serialize()).Serialize(enc)
+		return codec.GetSerializer(s.v.ArchiveValue().Serialize(), nil).Serialize(enc)
 	case s.v.IsAsset():
-		return codec.GetSerializer(s.v.AssetValue().Serialize()).Serialize(enc)
+		return codec.GetSerializer(s.v.AssetValue().Serialize(), nil).Serialize(enc)
 	case s.v.IsResourceReference():
-		panic("todo")
+		return s.with(resource.NewObjectProperty(resourceReferenceObject(s.v.ResourceReferenceValue()))).Serialize(enc)
 	case s.v.IsSecret():
-		panic("todo")
+		obj, err := s.secretObject(s.v.SecretValue().Element)
+		if err != nil {
+			return err
+		}
+		return s.with(resource.NewObjectProperty(obj)).Serialize(enc)
 	case s.v.IsArray():
 		vals := s.v.ArrayValue()
 		seq, err := enc.EncodeSeq(len(vals))
@@ -48,7 +70,7 @@ func (s Serializer) Serialize(enc codec.Encoder) error {
 			return err
 		}
 		for _, v := range vals {
-			if err := seq.EncodeElement(v, NewSerializer(v)); err != nil {
+			if err := seq.EncodeElement(v, s.with(v)); err != nil {
 				return err
 			}
 		}
@@ -66,7 +88,7 @@ func (s Serializer) Serialize(enc codec.Encoder) error {
 			if err := map_.EncodeKey(k, codec.NewString(&k)); err != nil {
 				return err
 			}
-			if err := map_.EncodeValue(obj[k], NewSerializer(obj[k])); err != nil {
+			if err := map_.EncodeValue(obj[k], s.with(obj[k])); err != nil {
 				return err
 			}
 		}
@@ -76,3 +98,66 @@ func (s Serializer) Serialize(enc codec.Encoder) error {
 	}
 
 }
+
+// resourceReferenceObject returns the signature object that represents a resource reference. The object has the same
+// shape as the resource references that are exchanged with providers: the reference's URN, its ID if it refers to a
+// custom resource, and its package version if it has one.
+func resourceReferenceObject(ref resource.ResourceReference) resource.PropertyMap {
+	m := resource.PropertyMap{
+		resource.SigKey: resource.NewStringProperty(resource.ResourceReferenceSig),
+		"urn":           resource.NewStringProperty(string(ref.URN)),
+	}
+	if id, hasID := ref.IDString(); hasID {
+		m["id"] = resource.NewStringProperty(id)
+	}
+	if ref.PackageVersion != "" {
+		m["packageVersion"] = resource.NewStringProperty(ref.PackageVersion)
+	}
+	return m
+}
+
+// outputObject returns the signature object that represents an output value: the output's value if it is known, its
+// secretness if it is secret, and its dependencies if it has any.
+func outputObject(o resource.Output) resource.PropertyMap {
+	m := resource.PropertyMap{
+		resource.SigKey: resource.NewStringProperty(resource.OutputValueSig),
+	}
+	if o.Known {
+		m["value"] = o.Element
+	}
+	if o.Secret {
+		m["secret"] = resource.NewBoolProperty(true)
+	}
+	if len(o.Dependencies) != 0 {
+		deps := make([]resource.PropertyValue, len(o.Dependencies))
+		for i, urn := range o.Dependencies {
+			deps[i] = resource.NewStringProperty(string(urn))
+		}
+		m["dependencies"] = resource.NewArrayProperty(deps)
+	}
+	return m
+}
+
+// secretObject returns the signature object that represents a secret with the given value. The object holds either
+// the value itself or, if s has an encrypter, the ciphertext of the value.
+func (s Serializer) secretObject(v resource.PropertyValue) (resource.PropertyMap, error) {
+	if s.encrypter == nil {
+		return resource.PropertyMap{
+			resource.SigKey: resource.NewStringProperty(resource.SecretSig),
+			"value":         v,
+		}, nil
+	}
+
+	plaintext, err := json.Append(nil, nil, s.with(v), 0)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := s.encrypter.EncryptValue(context.Background(), string(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	return resource.PropertyMap{
+		resource.SigKey: resource.NewStringProperty(resource.SecretSig),
+		"ciphertext":    resource.NewStringProperty(ciphertext),
+	}, nil
+}
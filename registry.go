@@ -0,0 +1,47 @@
+package codec
+
+import (
+	"reflect"
+	"sync"
+)
+
+var registry struct {
+	m      sync.RWMutex
+	codecs map[reflect.Type]codec
+	unions map[reflect.Type]*unionType
+}
+
+// Register registers c as the codec for values of type T. This allows types that do not implement Serializer and
+// Deserializer themselves--e.g. types from third-party packages--to participate in serialization. The codec is used
+// wherever a T appears, including inside structs, slices, maps, and pointers.
+//
+// If formats are given, the codec is only registered for those formats (see Override). Otherwise, it is registered
+// for all formats. Codecs registered for a specific format take precedence over codecs registered for all formats,
+// and registered codecs take precedence over a type's own Serialize and Deserialize methods.
+//
+// Codecs must be registered before any values that contain a T are serialized or deserialized, and are typically
+// registered from an init function.
+func Register[T any](c Codec[T], formats ...*Format) {
+	if len(formats) != 0 {
+		for _, f := range formats {
+			Override(f, c)
+		}
+		return
+	}
+
+	registry.m.Lock()
+	defer registry.m.Unlock()
+
+	if registry.codecs == nil {
+		registry.codecs = map[reflect.Type]codec{}
+	}
+	registry.codecs[reflect.TypeOf((*T)(nil)).Elem()] = typedCodec[T]{Codec: c}
+}
+
+func registered(t reflect.Type) (codec, bool) {
+	registry.m.RLock()
+	defer registry.m.RUnlock()
+
+	c, ok := registry.codecs[t]
+	return c, ok
+}
@@ -0,0 +1,343 @@
+package toml
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pgavlin/codec/internal/codectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type server struct {
+	Host  string   `codec:"host"`
+	Ports []int    `codec:"ports"`
+	Tags  []string `codec:"tags,omitempty"`
+}
+
+type owner struct {
+	Name string    `codec:"name"`
+	DOB  time.Time `codec:"dob"`
+}
+
+type config struct {
+	Title    string            `codec:"title"`
+	Ratio    float64           `codec:"ratio"`
+	Enabled  bool              `codec:"enabled"`
+	Owner    owner             `codec:"owner"`
+	Servers  map[string]server `codec:"servers"`
+	Products []product         `codec:"products"`
+	Points   []map[string]any  `codec:"points,omitempty"`
+	Optional *string           `codec:"optional"`
+}
+
+type product struct {
+	Name string `codec:"name"`
+	SKU  int64  `codec:"sku,omitempty"`
+}
+
+func TestMarshal(t *testing.T) {
+	dob := time.Date(1979, 5, 27, 7, 32, 0, 0, time.FixedZone("", -8*60*60))
+	b, err := Marshal(config{
+		Title:   "TOML \"Example\"",
+		Ratio:   1,
+		Enabled: true,
+		Owner:   owner{Name: "Tom", DOB: dob},
+		Servers: map[string]server{
+			"beta":  {Host: "10.0.0.2", Ports: []int{8001}},
+			"alpha": {Host: "10.0.0.1", Ports: []int{8000, 8001}, Tags: []string{"a"}},
+		},
+		Products: []product{{Name: "Hammer", SKU: 738594937}, {Name: "Nail"}},
+		Points:   []map[string]any{{"x": 1}, {"y": 2}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `title = "TOML \"Example\""
+ratio = 1.0
+enabled = true
+
+[owner]
+name = "Tom"
+dob = 1979-05-27T07:32:00-08:00
+
+[servers.alpha]
+host = "10.0.0.1"
+ports = [8000, 8001]
+tags = ["a"]
+
+[servers.beta]
+host = "10.0.0.2"
+ports = [8001]
+
+[[products]]
+name = "Hammer"
+sku = 738594937
+
+[[products]]
+name = "Nail"
+
+[[points]]
+x = 1
+
+[[points]]
+y = 2
+`, string(b))
+
+	var c config
+	require.NoError(t, Unmarshal(b, &c))
+	assert.Equal(t, "TOML \"Example\"", c.Title)
+	assert.True(t, dob.Equal(c.Owner.DOB))
+	assert.Equal(t, server{Host: "10.0.0.1", Ports: []int{8000, 8001}, Tags: []string{"a"}}, c.Servers["alpha"])
+	assert.Equal(t, []product{{Name: "Hammer", SKU: 738594937}, {Name: "Nail"}}, c.Products)
+	assert.Equal(t, []map[string]any{{"x": int64(1)}, {"y": int64(2)}}, c.Points)
+	assert.Nil(t, c.Optional)
+
+	// Tables in arrays that contain other values are written as inline tables, and keys are quoted if necessary.
+	b, err = Marshal(map[string]any{
+		"mixed":     []any{1, map[string]any{"a b": "c", "d": []int{}}, map[string]any{}},
+		"empty":     map[string]any{},
+		"nested":    map[string]any{"inner": map[string]any{"x": math.Inf(-1)}},
+		"omitted":   nil,
+		"float32":   float32(0.1),
+		"character": "tab\there\u0001",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `character = "tab\there\u0001"
+float32 = 0.1
+mixed = [1, { "a b" = "c", d = [] }, {}]
+
+[empty]
+
+[nested.inner]
+x = -inf
+`, string(b))
+
+	_, err = Marshal([]int{1})
+	assert.EqualError(t, err, "toml: top-level value must be a table")
+
+	_, err = Marshal(map[string]any{"a": []any{nil}})
+	assert.EqualError(t, err, "toml: cannot encode nil array element")
+
+	_, err = Marshal(map[string]uint64{"a": math.MaxUint64})
+	assert.EqualError(t, err, "toml: integer 18446744073709551615 overflows int64")
+}
+
+func TestMapKeys(t *testing.T) {
+	type keys struct {
+		Ints  map[int]string   `codec:"ints"`
+		Uints map[uint8]string `codec:"uints"`
+	}
+
+	// Integer keys are written as their decimal representations and may be decoded from them.
+	expected := keys{Ints: map[int]string{1: "one", -2: "two"}, Uints: map[uint8]string{255: "max"}}
+	b, err := Marshal(expected)
+	require.NoError(t, err)
+	assert.Equal(t, "[ints]\n-2 = \"two\"\n1 = \"one\"\n\n[uints]\n255 = \"max\"\n", string(b))
+
+	var actual keys
+	require.NoError(t, Unmarshal(b, &actual))
+	assert.Equal(t, expected, actual)
+
+	err = Unmarshal([]byte("[uints]\n256 = \"x\""), &actual)
+	assert.EqualError(t, err, "toml: line 2, column 1: codec: cannot unmarshal number 256 into Go struct field keys.uints of type map[uint8]string")
+	err = Unmarshal([]byte("[ints]\na = \"x\""), &actual)
+	assert.EqualError(t, err, "toml: line 2, column 1: codec: cannot unmarshal string into Go struct field keys.ints of type map[int]string")
+}
+
+func TestDecode(t *testing.T) {
+	const doc = `# This is a TOML document
+title = "TOML Example" # trailing comment
+"quoted key" = 'C:\Users\nodejs'
+site."google.com" = true
+fruit.apple.color = "red"
+
+[owner]
+name = """
+Tom \
+    Preston-Werner"""
+bio = '''
+line one
+line two'''
+
+[database]
+enabled = true
+ports = [ 8000, 8001, 8002, ]
+data = [ ["delta", "phi"], [3.14] ]
+temp_targets = { cpu = 79.5, case = 72.0 }
+
+[servers]
+
+  [servers.alpha]
+  ip = "10.0.0.1"
+
+[[fruits]]
+name = "apple"
+
+[fruits.physical]
+shape = "round"
+
+[[fruits.varieties]]
+name = "red delicious"
+
+[[fruits]]
+name = "banana"
+
+[numbers]
+int = +99
+hex = 0xDEAD_BEEF
+oct = 0o755
+bin = 0b1101
+neg = -17
+big = 1_000_000
+float = 6.626e-34
+exp = 5e+22
+frac = -0.01
+inf = -inf
+escape = "\u00e9\U0001F600\t\"\\"
+
+[dates]
+odt = 1979-05-27T00:32:00.999999-07:00
+odt2 = 1979-05-27 07:32:00Z
+ldt = 1979-05-27T07:32:00
+ld = 1979-05-27
+lt = 07:32:00.5
+`
+
+	var v map[string]any
+	require.NoError(t, Unmarshal([]byte(doc), &v))
+	assert.Equal(t, "TOML Example", v["title"])
+	assert.Equal(t, `C:\Users\nodejs`, v["quoted key"])
+	assert.Equal(t, map[string]any{"google.com": true}, v["site"])
+	assert.Equal(t, map[string]any{"apple": map[string]any{"color": "red"}}, v["fruit"])
+	assert.Equal(t, map[string]any{"name": "Tom Preston-Werner", "bio": "line one\nline two"}, v["owner"])
+	assert.Equal(t, map[string]any{
+		"enabled":      true,
+		"ports":        []any{int64(8000), int64(8001), int64(8002)},
+		"data":         []any{[]any{"delta", "phi"}, []any{3.14}},
+		"temp_targets": map[string]any{"cpu": 79.5, "case": 72.0},
+	}, v["database"])
+	assert.Equal(t, map[string]any{"alpha": map[string]any{"ip": "10.0.0.1"}}, v["servers"])
+	assert.Equal(t, []any{
+		map[string]any{
+			"name":      "apple",
+			"physical":  map[string]any{"shape": "round"},
+			"varieties": []any{map[string]any{"name": "red delicious"}},
+		},
+		map[string]any{"name": "banana"},
+	}, v["fruits"])
+	assert.Equal(t, map[string]any{
+		"int":    int64(99),
+		"hex":    int64(0xdeadbeef),
+		"oct":    int64(0o755),
+		"bin":    int64(13),
+		"neg":    int64(-17),
+		"big":    int64(1000000),
+		"float":  6.626e-34,
+		"exp":    5e+22,
+		"frac":   -0.01,
+		"inf":    math.Inf(-1),
+		"escape": "é😀\t\"\\",
+	}, v["numbers"])
+
+	dates := v["dates"].(map[string]any)
+	assert.Equal(t, time.Date(1979, 5, 27, 0, 32, 0, 999999000, time.FixedZone("", -7*60*60)).String(),
+		dates["odt"].(time.Time).String())
+	assert.Equal(t, time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC), dates["odt2"])
+	assert.Equal(t, time.Date(1979, 5, 27, 7, 32, 0, 0, time.Local), dates["ldt"])
+	assert.Equal(t, time.Date(1979, 5, 27, 0, 0, 0, 0, time.Local), dates["ld"])
+	assert.Equal(t, time.Date(0, 1, 1, 7, 32, 0, 500000000, time.Local), dates["lt"])
+
+	// Keys are matched to struct fields using the struct codec.
+	var s struct {
+		Title string
+		Dates struct {
+			LD time.Time `codec:"ld"`
+		}
+		Numbers struct {
+			Hex uint32
+			Inf float32
+		}
+	}
+	require.NoError(t, Unmarshal([]byte(doc), &s))
+	assert.Equal(t, "TOML Example", s.Title)
+	assert.Equal(t, time.Date(1979, 5, 27, 0, 0, 0, 0, time.Local), s.Dates.LD)
+	assert.Equal(t, uint32(0xdeadbeef), s.Numbers.Hex)
+	assert.True(t, math.IsInf(float64(s.Numbers.Inf), -1))
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	cases := []struct {
+		doc      string
+		expected string
+	}{
+		{"a = ", "toml: line 1, column 5: expected value, found end of input"},
+		{"a = 1 b = 2", `toml: line 1, column 7: expected newline, found 'b'`},
+		{"a = 1\na = 2", "toml: line 2, column 1: key a is already defined"},
+		{"a = \"x\ny\"", "toml: line 1, column 5: unterminated string"},
+		{"é = 1", `toml: line 1, column 1: expected key, found 'é'`},
+		{"a = 'é' x", `toml: line 1, column 9: expected newline, found 'x'`},
+		{"a = 01", `toml: line 1, column 5: invalid integer "01"`},
+		{"a = 1__0", `toml: line 1, column 5: invalid integer "1__0"`},
+		{"a = 1.", `toml: line 1, column 5: invalid float "1."`},
+		{"a = 9223372036854775808", "toml: line 1, column 5: integer 9223372036854775808 is out of range"},
+		{"a = 1979-05-27T25:00:00Z", `toml: line 1, column 5: invalid date-time "1979-05-27T25:00:00Z"`},
+		{`a = "\q"`, "toml: line 1, column 6: invalid escape sequence"},
+		{`a = "\`, "toml: line 1, column 6: invalid escape sequence"},
+		{`a = """\`, "toml: line 1, column 8: invalid escape sequence"},
+		{"a = [1 2]", `toml: line 1, column 8: expected "," or "]", found '2'`},
+		{"a = {b = 1,}", `toml: line 1, column 12: expected key, found '}'`},
+		{"a = {b = 1\n}", `toml: line 1, column 11: expected "," or "}", found '\n'`},
+		{"[a]\n[a]", "toml: line 2, column 1: table a is already defined"},
+		{"a = {}\n[a.b]", "toml: line 2, column 2: key a is not a table"},
+		{"a = []\n[[a]]", "toml: line 2, column 1: key a is already defined and is not an array of tables"},
+		{"a.b = 1\n[a]", "toml: line 2, column 1: table a is already defined"},
+		{"[a.b]\n[a]\nb.c = 1", "toml: line 3, column 1: cannot extend key b with dotted keys"},
+		{"a = 1\r\rb = 2", `toml: line 1, column 6: expected newline, found '\r'`},
+		{"a = \"\x00\"", "toml: line 1, column 6: invalid control character U+0000 in string"},
+		{"a = \"\xff\"", "toml: line 1, column 6: invalid UTF-8"},
+	}
+	for _, c := range cases {
+		t.Run(c.doc, func(t *testing.T) {
+			var v any
+			err := Unmarshal([]byte(c.doc), &v)
+			assert.EqualError(t, err, c.expected)
+		})
+	}
+
+	// Sub-tables may be defined within tables created by dotted keys, and implicitly created tables may be defined later.
+	var v any
+	require.NoError(t, Unmarshal([]byte("a.b = 1\n[a.c]\n[x.y]\n[x]"), &v))
+	assert.Equal(t, map[string]any{"a": map[string]any{"b": int64(1), "c": map[string]any{}}, "x": map[string]any{"y": map[string]any{}}}, v)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	var c config
+	err := Unmarshal([]byte("title = \"x\"\n\n[owner]\n  name = 42\n"), &c)
+	assert.EqualError(t, err,
+		"toml: line 4, column 10: codec: cannot unmarshal int64 into Go struct field owner.owner.name of type string")
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, 4, decodeErr.Line)
+	assert.Equal(t, 10, decodeErr.Column)
+
+	d := NewDecoder(strings.NewReader("title = \"x\"\n[owner]\nage = 1\n"))
+	d.DisallowUnknownFields()
+	err = d.Decode(&c)
+	assert.EqualError(t, err, `toml: line 2, column 1: codec: unknown field "age" in Go struct owner`)
+}
+
+func TestVariant(t *testing.T) {
+	type options struct {
+		Options []codectest.Option `codec:"options"`
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewEncoder(&buf).Encode(options{Options: []codectest.Option{{Valid: true, Value: "x"}, {}}}))
+	assert.Equal(t, "options = [{ Some = \"x\" }, \"None\"]\n", buf.String())
+
+	var actual options
+	require.NoError(t, NewDecoder(&buf).Decode(&actual))
+	assert.Equal(t, options{Options: []codectest.Option{{Valid: true, Value: "x"}, {}}}, actual)
+}
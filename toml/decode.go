@@ -0,0 +1,284 @@
+package toml
+
+import (
+	"encoding/base64"
+	"io"
+	"strconv"
+
+	"github.com/pgavlin/codec"
+)
+
+// A Decoder decodes values from a parsed TOML document. Tables are decoded as maps, so structs are decoded using the
+// field matching of the struct codec. Errors that occur while decoding a value are returned as DecodeErrors that
+// carry the position of the value.
+//
+// Decoders returned by NewDecoder read a document from an io.Reader.
+type Decoder struct {
+	v       *value
+	options codec.DecodeOptions
+	state   *decodeState
+	r       io.Reader // non-nil if the decoder was returned by NewDecoder
+}
+
+// decodeState is shared by the decoders of a single document.
+type decodeState struct {
+	failed *value // the innermost value that could not be decoded, if any
+}
+
+// NewDecoder returns a new decoder that reads a TOML document from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// DisallowUnknownFields causes the Decoder to return an error when the destination is a struct and the input contains
+// keys which do not match any of the struct's fields.
+func (d *Decoder) DisallowUnknownFields() {
+	d.options.DisallowUnknownFields = true
+}
+
+// Decode reads the remainder of its input as a TOML document and stores it in the value pointed to by v.
+//
+// Decode may only be called on decoders returned by NewDecoder.
+func (d *Decoder) Decode(v any) error {
+	b, err := io.ReadAll(d.r)
+	if err != nil {
+		return err
+	}
+	root, err := parse(b)
+	if err != nil {
+		return err
+	}
+	return decodeDocument(root, d.options, v)
+}
+
+// decodeDocument decodes a parsed document into v. Errors are annotated with the position of the innermost value that
+// could not be decoded.
+func decodeDocument(root *value, options codec.DecodeOptions, v any) error {
+	d := Decoder{v: root, options: options, state: &decodeState{}}
+	if err := d.decode(v, codec.GetDeserializer(v, Format)); err != nil {
+		return &DecodeError{Line: d.state.failed.line, Column: d.state.failed.col, Err: err}
+	}
+	return nil
+}
+
+// decode decodes the decoder's value into v using ds. If the value cannot be decoded and no value nested within it
+// has failed, the value is recorded as the position of the error. Errors are not wrapped until they reach the root so
+// that the codecs of enclosing values can add path information to them.
+func (d Decoder) decode(v any, ds codec.Deserializer) error {
+	err := ds.Deserialize(d)
+	if err != nil && d.state.failed == nil {
+		d.state.failed = d.v
+	}
+	return err
+}
+
+// datetimeElem is an element decoder that stores a date-time in an interface value.
+type datetimeElem struct {
+	v *value
+}
+
+func (e datetimeElem) Element(v any, _ codec.Deserializer) error {
+	*v.(*any) = e.v.t
+	return nil
+}
+
+// Format returns the TOML format.
+func (d Decoder) Format() *codec.Format {
+	return Format
+}
+
+// DecodeAny visits the decoder's value. Date-times are decoded into interface values as time.Time values, and are
+// otherwise visited as strings.
+func (d Decoder) DecodeAny(v codec.Visitor) error {
+	switch d.v.kind {
+	case kindString:
+		return v.VisitString(d.v.str)
+	case kindInteger:
+		return v.VisitInt64(d.v.i)
+	case kindFloat:
+		return v.VisitFloat64(d.v.f)
+	case kindBool:
+		return v.VisitBool(d.v.b)
+	case kindDatetime:
+		if _, ok := v.(codec.AnyCodec); ok {
+			return v.VisitElem(datetimeElem{d.v})
+		}
+		return v.VisitString(d.v.str)
+	case kindArray:
+		return v.VisitSeq(&SeqDecoder{v: d.v.elems, options: d.options, state: d.state})
+	case kindTable:
+		return v.VisitMap(&MapDecoder{entries: d.v.entries, options: d.options, state: d.state})
+	default:
+		return v.VisitNil()
+	}
+}
+
+func (d Decoder) DecodeNil(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d Decoder) DecodeBool(v codec.Visitor) error                { return d.DecodeAny(v) }
+func (d Decoder) DecodeInt(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d Decoder) DecodeInt8(v codec.Visitor) error                { return d.DecodeAny(v) }
+func (d Decoder) DecodeInt16(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d Decoder) DecodeInt32(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d Decoder) DecodeInt64(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d Decoder) DecodeUint(v codec.Visitor) error                { return d.DecodeAny(v) }
+func (d Decoder) DecodeUint8(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d Decoder) DecodeUint16(v codec.Visitor) error              { return d.DecodeAny(v) }
+func (d Decoder) DecodeUint32(v codec.Visitor) error              { return d.DecodeAny(v) }
+func (d Decoder) DecodeUint64(v codec.Visitor) error              { return d.DecodeAny(v) }
+func (d Decoder) DecodeUintptr(v codec.Visitor) error             { return d.DecodeAny(v) }
+func (d Decoder) DecodeFloat32(v codec.Visitor) error             { return d.DecodeAny(v) }
+func (d Decoder) DecodeFloat64(v codec.Visitor) error             { return d.DecodeAny(v) }
+func (d Decoder) DecodeComplex64(v codec.Visitor) error           { return d.DecodeAny(v) }
+func (d Decoder) DecodeComplex128(v codec.Visitor) error          { return d.DecodeAny(v) }
+func (d Decoder) DecodeString(v codec.Visitor) error              { return d.DecodeAny(v) }
+func (d Decoder) DecodeSeq(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d Decoder) DecodeMap(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d Decoder) DecodeStruct(name string, v codec.Visitor) error { return d.DecodeAny(v) }
+
+// DecodeBytes decodes a byte slice from a base64-encoded string.
+func (d Decoder) DecodeBytes(v codec.Visitor) error {
+	if d.v.kind == kindString {
+		b, err := base64.StdEncoding.DecodeString(d.v.str)
+		if err != nil {
+			return err
+		}
+		return v.VisitBytes(b)
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeVariant decodes the externally tagged representation produced by Encoder.EncodeVariant: unit variants are
+// represented by their names, and other variants by single-entry tables from their names to their values. Other
+// values are decoded as-is.
+func (d Decoder) DecodeVariant(enum string, variants []string, v codec.Visitor) error {
+	switch {
+	case d.v.kind == kindString:
+		unit := value{kind: kindNil, line: d.v.line, col: d.v.col}
+		return v.VisitVariant(VariantDecoder{name: d.v.str, v: &unit, options: d.options, state: d.state})
+	case d.v.kind == kindTable && len(d.v.entries) == 1:
+		e := d.v.entries[0]
+		return v.VisitVariant(VariantDecoder{name: e.key, v: e.value, options: d.options, state: d.state})
+	default:
+		return d.DecodeAny(v)
+	}
+}
+
+// DecodePtr decodes a pointer. TOML has no null value, so the pointer's element is always decoded.
+func (d Decoder) DecodePtr(v codec.Visitor) error {
+	return v.VisitElem(ElemDecoder{d})
+}
+
+type ElemDecoder struct {
+	d Decoder
+}
+
+func (d ElemDecoder) Element(v any, ds codec.Deserializer) error {
+	return d.d.decode(v, ds)
+}
+
+type SeqDecoder struct {
+	v       []*value
+	options codec.DecodeOptions
+	state   *decodeState
+}
+
+func (d *SeqDecoder) Size() (int, bool) {
+	return len(d.v), true
+}
+
+func (d *SeqDecoder) NextElement(x any, ds codec.Deserializer) (bool, error) {
+	if len(d.v) == 0 {
+		return false, nil
+	}
+	v := d.v[0]
+	d.v = d.v[1:]
+	return true, Decoder{v: v, options: d.options, state: d.state}.decode(x, ds)
+}
+
+type MapDecoder struct {
+	entries []*entry
+	options codec.DecodeOptions
+	state   *decodeState
+}
+
+func (d *MapDecoder) Size() (int, bool) {
+	return len(d.entries), true
+}
+
+func (d *MapDecoder) Options() codec.DecodeOptions {
+	return d.options
+}
+
+func (d *MapDecoder) NextKey(k any, ds codec.Deserializer) (bool, error) {
+	if len(d.entries) == 0 {
+		return false, nil
+	}
+	e := d.entries[0]
+	key := value{kind: kindString, line: e.line, col: e.col, str: e.key}
+	return true, keyDecoder{Decoder{v: &key, options: d.options, state: d.state}}.decode(k, ds)
+}
+
+func (d *MapDecoder) NextValue(v any, ds codec.Deserializer) error {
+	e := d.entries[0]
+	d.entries = d.entries[1:]
+	return Decoder{v: e.value, options: d.options, state: d.state}.decode(v, ds)
+}
+
+// A keyDecoder decodes a table key. Keys are strings, but may be decoded into integers, as the keys of JSON objects may
+// be: Encoder writes integer map keys using their decimal representations.
+type keyDecoder struct {
+	Decoder
+}
+
+func (d keyDecoder) decode(v any, ds codec.Deserializer) error {
+	err := ds.Deserialize(d)
+	if err != nil && d.state.failed == nil {
+		d.state.failed = d.v
+	}
+	return err
+}
+
+// decodeInt visits the key as an integer. Keys that are not integers are visited as strings.
+func (d keyDecoder) decodeInt(v codec.Visitor) error {
+	n, err := strconv.ParseInt(d.v.str, 10, 64)
+	if err != nil {
+		return d.DecodeAny(v)
+	}
+	return v.VisitInt64(n)
+}
+
+// decodeUint visits the key as an unsigned integer. Keys that are not unsigned integers are visited as strings.
+func (d keyDecoder) decodeUint(v codec.Visitor) error {
+	n, err := strconv.ParseUint(d.v.str, 10, 64)
+	if err != nil {
+		return d.DecodeAny(v)
+	}
+	return v.VisitUint64(n)
+}
+
+func (d keyDecoder) DecodeInt(v codec.Visitor) error     { return d.decodeInt(v) }
+func (d keyDecoder) DecodeInt8(v codec.Visitor) error    { return d.decodeInt(v) }
+func (d keyDecoder) DecodeInt16(v codec.Visitor) error   { return d.decodeInt(v) }
+func (d keyDecoder) DecodeInt32(v codec.Visitor) error   { return d.decodeInt(v) }
+func (d keyDecoder) DecodeInt64(v codec.Visitor) error   { return d.decodeInt(v) }
+func (d keyDecoder) DecodeUint(v codec.Visitor) error    { return d.decodeUint(v) }
+func (d keyDecoder) DecodeUint8(v codec.Visitor) error   { return d.decodeUint(v) }
+func (d keyDecoder) DecodeUint16(v codec.Visitor) error  { return d.decodeUint(v) }
+func (d keyDecoder) DecodeUint32(v codec.Visitor) error  { return d.decodeUint(v) }
+func (d keyDecoder) DecodeUint64(v codec.Visitor) error  { return d.decodeUint(v) }
+func (d keyDecoder) DecodeUintptr(v codec.Visitor) error { return d.decodeUint(v) }
+
+type VariantDecoder struct {
+	name    string
+	v       *value
+	options codec.DecodeOptions
+	state   *decodeState
+}
+
+func (d VariantDecoder) Variant() string {
+	return d.name
+}
+
+func (d VariantDecoder) Value(v any, ds codec.Deserializer) error {
+	return Decoder{v: d.v, options: d.options, state: d.state}.decode(v, ds)
+}
@@ -0,0 +1,474 @@
+package toml
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pgavlin/codec"
+)
+
+// An Encoder encodes values into TOML. Values are encoded into an in-memory document, which is written once the
+// top-level value has been encoded so that the key/value pairs of each table can be written before its sub-tables.
+//
+// Encoders returned by NewEncoder write documents to an io.Writer; each call to Encode writes a new document.
+type Encoder struct {
+	v *value
+	w io.Writer // non-nil if the encoder was returned by NewEncoder
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the TOML encoding of v, which must encode as a table, to the stream.
+//
+// Encode may only be called on encoders returned by NewEncoder.
+func (e *Encoder) Encode(v any) error {
+	var root value
+	if err := codec.GetSerializer(v, Format).Serialize(Encoder{v: &root}); err != nil {
+		return err
+	}
+	if root.kind != kindTable {
+		return errors.New("toml: top-level value must be a table")
+	}
+
+	var w writer
+	if err := w.table(nil, &root, false); err != nil {
+		return err
+	}
+	_, err := e.w.Write(w.buf)
+	return err
+}
+
+// Format returns the TOML format.
+func (e Encoder) Format() *codec.Format {
+	return Format
+}
+
+func (e Encoder) EncodeNil() error {
+	*e.v = value{kind: kindNil}
+	return nil
+}
+
+func (e Encoder) EncodeBool(v bool) error {
+	*e.v = value{kind: kindBool, b: v}
+	return nil
+}
+
+func (e Encoder) EncodeInt(v int) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt8(v int8) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt16(v int16) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt32(v int32) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt64(v int64) error {
+	*e.v = value{kind: kindInteger, i: v}
+	return nil
+}
+
+func (e Encoder) EncodeUint(v uint) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint8(v uint8) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint16(v uint16) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint32(v uint32) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+// EncodeUint64 encodes an integer. TOML integers are 64-bit signed integers, so values greater than math.MaxInt64
+// cannot be encoded.
+func (e Encoder) EncodeUint64(v uint64) error {
+	if v > math.MaxInt64 {
+		return fmt.Errorf("toml: integer %d overflows int64", v)
+	}
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeUintptr(v uintptr) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeFloat32(v float32) error {
+	*e.v = value{kind: kindFloat, str: formatFloat(float64(v), 32)}
+	return nil
+}
+
+func (e Encoder) EncodeFloat64(v float64) error {
+	*e.v = value{kind: kindFloat, str: formatFloat(v, 64)}
+	return nil
+}
+
+// formatFloat formats v so that it parses as a float: infinities and NaN use the TOML spellings, and integral values
+// have a trailing ".0".
+func formatFloat(v float64, bits int) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "inf"
+	case math.IsInf(v, -1):
+		return "-inf"
+	case math.IsNaN(v):
+		return "nan"
+	}
+	s := strconv.FormatFloat(v, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func (e Encoder) EncodeComplex64(v complex64) error {
+	return &codec.UnsupportedTypeError{Type: reflect.TypeOf(v)}
+}
+
+func (e Encoder) EncodeComplex128(v complex128) error {
+	return &codec.UnsupportedTypeError{Type: reflect.TypeOf(v)}
+}
+
+func (e Encoder) EncodeString(v string) error {
+	*e.v = value{kind: kindString, str: v}
+	return nil
+}
+
+// EncodeBytes encodes a byte slice as a base64-encoded string.
+func (e Encoder) EncodeBytes(v []byte) error {
+	return e.EncodeString(base64.StdEncoding.EncodeToString(v))
+}
+
+func (e Encoder) EncodeElem(v any, s codec.Serializer) error {
+	return s.Serialize(e)
+}
+
+func (e Encoder) EncodeSeq(len int) (codec.SeqEncoder, error) {
+	*e.v = value{kind: kindArray}
+	if len != 0 {
+		e.v.elems = make([]*value, 0, len)
+	}
+	return &SeqEncoder{v: e.v}, nil
+}
+
+func (e Encoder) EncodeMap(len int) (codec.MapEncoder, error) {
+	*e.v = value{kind: kindTable}
+	if len != 0 {
+		e.v.entries = make([]*entry, 0, len)
+	}
+	return &MapEncoder{v: e.v}, nil
+}
+
+func (e Encoder) EncodeStruct(name string) (codec.StructEncoder, error) {
+	*e.v = value{kind: kindTable}
+	return &StructEncoder{v: e.v}, nil
+}
+
+// EncodeVariant encodes a variant of a sum type using the externally tagged representation: unit variants are encoded
+// as their names, and other variants are encoded as single-entry tables from their names to their values.
+func (e Encoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	return &VariantEncoder{v: e.v, name: variant}, nil
+}
+
+func encodeValue(ser codec.Serializer) (*value, error) {
+	var v value
+	if err := ser.Serialize(Encoder{v: &v}); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+type SeqEncoder struct {
+	v *value
+}
+
+func (e *SeqEncoder) Close() error {
+	return nil
+}
+
+// EncodeElement encodes an element of an array. TOML has no null value, so nil elements cannot be encoded.
+func (e *SeqEncoder) EncodeElement(x any, ser codec.Serializer) error {
+	v, err := encodeValue(ser)
+	if err != nil {
+		return err
+	}
+	if v.kind == kindNil {
+		return errors.New("toml: cannot encode nil array element")
+	}
+	e.v.elems = append(e.v.elems, v)
+	return nil
+}
+
+// A MapEncoder encodes a table. The table's entries are sorted by key when the encoder is closed so that the output
+// does not depend on the iteration order of the map being encoded. Keys must encode as strings or integers.
+type MapEncoder struct {
+	v   *value
+	key string
+}
+
+func (e *MapEncoder) Close() error {
+	sort.SliceStable(e.v.entries, func(i, j int) bool { return e.v.entries[i].key < e.v.entries[j].key })
+	return nil
+}
+
+func (e *MapEncoder) EncodeKey(x any, ser codec.Serializer) error {
+	k, err := encodeValue(ser)
+	if err != nil {
+		return err
+	}
+	switch k.kind {
+	case kindString:
+		e.key = k.str
+	case kindInteger:
+		e.key = strconv.FormatInt(k.i, 10)
+	default:
+		return errors.New("toml: map keys must be strings or integers")
+	}
+	return nil
+}
+
+func (e *MapEncoder) EncodeValue(x any, ser codec.Serializer) error {
+	v, err := encodeValue(ser)
+	if err != nil {
+		return err
+	}
+	e.v.entries = append(e.v.entries, &entry{key: e.key, value: v})
+	return nil
+}
+
+type StructEncoder struct {
+	v *value
+}
+
+func (e *StructEncoder) Close() error {
+	return nil
+}
+
+func (e *StructEncoder) EncodeField(key string, x any, ser codec.Serializer) error {
+	v, err := encodeValue(ser)
+	if err != nil {
+		return err
+	}
+	e.v.entries = append(e.v.entries, &entry{key: key, value: v})
+	return nil
+}
+
+type VariantEncoder struct {
+	v     *value
+	name  string
+	value *value
+}
+
+func (e *VariantEncoder) Close() error {
+	if e.value == nil || e.value.kind == kindNil {
+		*e.v = value{kind: kindString, str: e.name}
+	} else {
+		*e.v = value{kind: kindTable, entries: []*entry{{key: e.name, value: e.value}}}
+	}
+	return nil
+}
+
+func (e *VariantEncoder) EncodeValue(x any, ser codec.Serializer) error {
+	v, err := encodeValue(ser)
+	if err != nil {
+		return err
+	}
+	e.value = v
+	return nil
+}
+
+// writer writes an encoded document.
+type writer struct {
+	buf []byte
+}
+
+// isTableArray returns true if v is a non-empty array of tables, which is written as a sequence of [[array]] sections.
+func isTableArray(v *value) bool {
+	if v.kind != kindArray || len(v.elems) == 0 {
+		return false
+	}
+	for _, e := range v.elems {
+		if e.kind != kindTable {
+			return false
+		}
+	}
+	return true
+}
+
+// table writes the table t with the given path. The table's key/value pairs are written first, followed by its
+// sub-tables and arrays of tables. The header of a table is omitted if the table contains only sub-tables.
+func (w *writer) table(path []string, t *value, arrayElem bool) error {
+	var pairs, tables []*entry
+	for _, e := range t.entries {
+		switch {
+		case e.value.kind == kindNil:
+			// TOML has no null value, so nil values are omitted.
+		case e.value.kind == kindTable || isTableArray(e.value):
+			tables = append(tables, e)
+		default:
+			pairs = append(pairs, e)
+		}
+	}
+
+	if arrayElem || len(path) != 0 && (len(pairs) != 0 || len(tables) == 0) {
+		if len(w.buf) != 0 {
+			w.buf = append(w.buf, '\n')
+		}
+		open, close := "[", "]"
+		if arrayElem {
+			open, close = "[[", "]]"
+		}
+		w.buf = append(w.buf, open...)
+		w.path(path)
+		w.buf = append(w.buf, close...)
+		w.buf = append(w.buf, '\n')
+	}
+
+	for _, e := range pairs {
+		w.buf = append(w.buf, formatSimpleKey(e.key)...)
+		w.buf = append(w.buf, " = "...)
+		if err := w.inline(e.value); err != nil {
+			return err
+		}
+		w.buf = append(w.buf, '\n')
+	}
+
+	for _, e := range tables {
+		path := append(path[:len(path):len(path)], e.key)
+		if e.value.kind == kindTable {
+			if err := w.table(path, e.value, false); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, elem := range e.value.elems {
+			if err := w.table(path, elem, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *writer) path(path []string) {
+	for i, k := range path {
+		if i > 0 {
+			w.buf = append(w.buf, '.')
+		}
+		w.buf = append(w.buf, formatSimpleKey(k)...)
+	}
+}
+
+// inline writes a value on a single line. Tables are written as inline tables.
+func (w *writer) inline(v *value) error {
+	switch v.kind {
+	case kindString:
+		w.buf = appendString(w.buf, v.str)
+	case kindInteger:
+		w.buf = strconv.AppendInt(w.buf, v.i, 10)
+	case kindFloat, kindDatetime:
+		w.buf = append(w.buf, v.str...)
+	case kindBool:
+		w.buf = strconv.AppendBool(w.buf, v.b)
+	case kindArray:
+		w.buf = append(w.buf, '[')
+		for i, e := range v.elems {
+			if i > 0 {
+				w.buf = append(w.buf, ", "...)
+			}
+			if err := w.inline(e); err != nil {
+				return err
+			}
+		}
+		w.buf = append(w.buf, ']')
+	case kindTable:
+		w.buf = append(w.buf, '{')
+		first := true
+		for _, e := range v.entries {
+			if e.value.kind == kindNil {
+				continue
+			}
+			if !first {
+				w.buf = append(w.buf, ',')
+			}
+			first = false
+			w.buf = append(w.buf, ' ')
+			w.buf = append(w.buf, formatSimpleKey(e.key)...)
+			w.buf = append(w.buf, " = "...)
+			if err := w.inline(e.value); err != nil {
+				return err
+			}
+		}
+		if !first {
+			w.buf = append(w.buf, ' ')
+		}
+		w.buf = append(w.buf, '}')
+	default:
+		return errors.New("toml: cannot encode nil value")
+	}
+	return nil
+}
+
+// formatSimpleKey returns key as a bare key if possible, or as a basic string otherwise.
+func formatSimpleKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for i := 0; i < len(key); i++ {
+		if !isBareKeyChar(key[i]) {
+			return string(appendString(nil, key))
+		}
+	}
+	return key
+}
+
+// appendString appends s to b as a basic string. Invalid UTF-8 is replaced with U+FFFD.
+func appendString(b []byte, s string) []byte {
+	b = append(b, '"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b = append(b, `\"`...)
+		case '\\':
+			b = append(b, `\\`...)
+		case '\b':
+			b = append(b, `\b`...)
+		case '\t':
+			b = append(b, `\t`...)
+		case '\n':
+			b = append(b, `\n`...)
+		case '\f':
+			b = append(b, `\f`...)
+		case '\r':
+			b = append(b, `\r`...)
+		default:
+			if r < 0x20 || r == 0x7f {
+				b = append(b, fmt.Sprintf(`\u%04X`, r)...)
+			} else {
+				b = utf8.AppendRune(b, r)
+			}
+		}
+	}
+	return append(b, '"')
+}
@@ -0,0 +1,823 @@
+package toml
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// A kind identifies the type of a TOML value.
+type kind int
+
+const (
+	kindNil kind = iota // TOML has no null value; nil values are only produced by the encoder
+	kindString
+	kindInteger
+	kindFloat
+	kindBool
+	kindDatetime
+	kindArray
+	kindTable
+)
+
+// A value is a node in a parsed or encoded TOML document.
+type value struct {
+	kind      kind
+	line, col int // the position of the value in its document, if it was parsed
+
+	str     string    // a string, or the text of a float or date-time
+	i       int64     // an integer
+	f       float64   // a float
+	b       bool      // a boolean
+	t       time.Time // a date-time
+	elems   []*value  // the elements of an array
+	entries []*entry  // the entries of a table in document order
+
+	index   map[string]*entry // the entries of a table by key
+	defined bool              // true if a table has been defined by a header or is an inline table
+	dotted  bool              // true if a table was created by a dotted key
+	frozen  bool              // true for inline tables and static arrays, which cannot be extended
+	tables  bool              // true for arrays of tables
+}
+
+// An entry is a key/value pair in a table.
+type entry struct {
+	key       string
+	line, col int // the position of the key
+	value     *value
+}
+
+func (t *value) lookup(key string) *value {
+	if e, ok := t.index[key]; ok {
+		return e.value
+	}
+	return nil
+}
+
+func (t *value) add(key string, line, col int, v *value) {
+	if t.index == nil {
+		t.index = map[string]*entry{}
+	}
+	e := &entry{key: key, line: line, col: col, value: v}
+	t.entries = append(t.entries, e)
+	t.index[key] = e
+}
+
+// A keyPart is a simple key that is part of a possibly-dotted key.
+type keyPart struct {
+	name      string
+	line, col int
+}
+
+// formatKey returns the TOML representation of a dotted key.
+func formatKey(key []keyPart) string {
+	var b strings.Builder
+	for i, k := range key {
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(formatSimpleKey(k.name))
+	}
+	return b.String()
+}
+
+// parser parses a TOML document. Positions are tracked as the parser advances so that values and errors can be
+// annotated with their lines and columns.
+type parser struct {
+	data      []byte
+	pos       int
+	line, col int
+
+	root    *value
+	current *value // the table defined by the most recent header
+}
+
+func parse(data []byte) (*value, error) {
+	p := &parser{data: data, line: 1, col: 1}
+	if !utf8.Valid(data) {
+		for {
+			r, size := utf8.DecodeRune(p.data[p.pos:])
+			if r == utf8.RuneError && size <= 1 {
+				return nil, p.errorf("invalid UTF-8")
+			}
+			p.advance(size)
+		}
+	}
+	if strings.HasPrefix(string(data), bom) {
+		p.pos = len(bom)
+	}
+
+	p.root = &value{kind: kindTable, line: 1, col: 1, defined: true}
+	p.current = p.root
+	if err := p.parseDocument(); err != nil {
+		return nil, err
+	}
+	return p.root, nil
+}
+
+// bom is the UTF-8 byte order mark, which is ignored at the start of a document.
+const bom = "\ufeff"
+
+func (p *parser) errorAt(line, col int, format string, args ...any) error {
+	return &SyntaxError{msg: fmt.Sprintf(format, args...), Line: line, Column: col}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return p.errorAt(p.line, p.col, format, args...)
+}
+
+// expected returns an error that describes the expected input and the input that was found instead.
+func (p *parser) expected(what string) error {
+	if p.eof() {
+		return p.errorf("expected %s, found end of input", what)
+	}
+	r, _ := utf8.DecodeRune(p.data[p.pos:])
+	return p.errorf("expected %s, found %s", what, strconv.QuoteRune(r))
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+// peek returns the byte at offset i from the current position, or 0 at the end of the input.
+func (p *parser) peek(i int) byte {
+	if p.pos+i < len(p.data) {
+		return p.data[p.pos+i]
+	}
+	return 0
+}
+
+// advance moves past the next n bytes. Columns are only advanced at the start of each UTF-8 sequence.
+func (p *parser) advance(n int) {
+	for ; n > 0; n-- {
+		switch b := p.data[p.pos]; {
+		case b == '\n':
+			p.line, p.col = p.line+1, 1
+		case b&0xc0 != 0x80:
+			p.col++
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) hasPrefix(s string) bool {
+	return strings.HasPrefix(string(p.data[p.pos:]), s)
+}
+
+func (p *parser) parseDocument() error {
+	for {
+		if err := p.skipBlank(); err != nil {
+			return err
+		}
+		if p.eof() {
+			return nil
+		}
+
+		var err error
+		if p.peek(0) == '[' {
+			err = p.parseTableHeader()
+		} else {
+			err = p.parseKeyValue(p.current)
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.endLine(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) skipSpace() {
+	for c := p.peek(0); c == ' ' || c == '\t'; c = p.peek(0) {
+		p.advance(1)
+	}
+}
+
+// skipBlank skips whitespace, comments, and newlines.
+func (p *parser) skipBlank() error {
+	for {
+		p.skipSpace()
+		switch p.peek(0) {
+		case '#':
+			if err := p.skipComment(); err != nil {
+				return err
+			}
+		case '\n', '\r':
+			if err := p.newline(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *parser) skipComment() error {
+	p.advance(1)
+	for !p.eof() {
+		c := p.peek(0)
+		if c == '\n' || c == '\r' && p.peek(1) == '\n' {
+			return nil
+		}
+		if isControl(c) {
+			return p.errorf("invalid control character %U in comment", c)
+		}
+		p.advance(1)
+	}
+	return nil
+}
+
+func (p *parser) newline() error {
+	switch {
+	case p.peek(0) == '\n':
+		p.advance(1)
+	case p.peek(0) == '\r' && p.peek(1) == '\n':
+		p.advance(2)
+	default:
+		return p.expected("newline")
+	}
+	return nil
+}
+
+// endLine consumes the remainder of a line that contains a key/value pair or table header.
+func (p *parser) endLine() error {
+	p.skipSpace()
+	if p.peek(0) == '#' {
+		if err := p.skipComment(); err != nil {
+			return err
+		}
+	}
+	if p.eof() {
+		return nil
+	}
+	return p.newline()
+}
+
+func isControl(c byte) bool {
+	return c < 0x20 && c != '\t' || c == 0x7f
+}
+
+func isBareKeyChar(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '_' || c == '-'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func (p *parser) parseKey() ([]keyPart, error) {
+	var key []keyPart
+	for {
+		p.skipSpace()
+
+		part := keyPart{line: p.line, col: p.col}
+		switch c := p.peek(0); {
+		case c == '"' || c == '\'':
+			if p.hasPrefix(`"""`) || p.hasPrefix("'''") {
+				return nil, p.errorf("multi-line strings cannot be used as keys")
+			}
+			name, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			part.name = name
+		case isBareKeyChar(c):
+			start := p.pos
+			for isBareKeyChar(p.peek(0)) {
+				p.advance(1)
+			}
+			part.name = string(p.data[start:p.pos])
+		default:
+			return nil, p.expected("key")
+		}
+		key = append(key, part)
+
+		p.skipSpace()
+		if p.peek(0) != '.' {
+			return key, nil
+		}
+		p.advance(1)
+	}
+}
+
+func (p *parser) parseKeyValue(t *value) error {
+	key, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.peek(0) != '=' {
+		return p.expected(`"=" after key`)
+	}
+	p.advance(1)
+	p.skipSpace()
+
+	v, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	// Dotted keys may only extend tables that were created by other dotted keys.
+	for i, k := range key[:len(key)-1] {
+		switch x := t.lookup(k.name); {
+		case x == nil:
+			x = &value{kind: kindTable, line: k.line, col: k.col, dotted: true}
+			t.add(k.name, k.line, k.col, x)
+			t = x
+		case x.kind == kindTable && x.dotted:
+			t = x
+		default:
+			return p.errorAt(k.line, k.col, "cannot extend key %s with dotted keys", formatKey(key[:i+1]))
+		}
+	}
+	last := key[len(key)-1]
+	if t.lookup(last.name) != nil {
+		return p.errorAt(last.line, last.col, "key %s is already defined", formatKey(key))
+	}
+	t.add(last.name, last.line, last.col, v)
+	return nil
+}
+
+func (p *parser) parseTableHeader() error {
+	line, col := p.line, p.col
+	p.advance(1)
+	array := p.peek(0) == '['
+	if array {
+		p.advance(1)
+	}
+
+	key, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if array && !p.hasPrefix("]]") {
+		return p.expected(`"]]"`)
+	}
+	if !array && p.peek(0) != ']' {
+		return p.expected(`"]"`)
+	}
+	if array {
+		p.advance(2)
+	} else {
+		p.advance(1)
+	}
+
+	t := p.root
+	for i, k := range key[:len(key)-1] {
+		switch x := t.lookup(k.name); {
+		case x == nil:
+			x = &value{kind: kindTable, line: k.line, col: k.col}
+			t.add(k.name, k.line, k.col, x)
+			t = x
+		case x.kind == kindTable && !x.frozen:
+			t = x
+		case x.kind == kindArray && x.tables:
+			t = x.elems[len(x.elems)-1]
+		default:
+			return p.errorAt(k.line, k.col, "key %s is not a table", formatKey(key[:i+1]))
+		}
+	}
+
+	last := key[len(key)-1]
+	x := t.lookup(last.name)
+	if array {
+		switch {
+		case x == nil:
+			x = &value{kind: kindArray, line: line, col: col, tables: true}
+			t.add(last.name, last.line, last.col, x)
+		case x.kind != kindArray || !x.tables:
+			return p.errorAt(line, col, "key %s is already defined and is not an array of tables", formatKey(key))
+		}
+		p.current = &value{kind: kindTable, line: line, col: col, defined: true}
+		x.elems = append(x.elems, p.current)
+		return nil
+	}
+
+	switch {
+	case x == nil:
+		x = &value{kind: kindTable, line: line, col: col, defined: true}
+		t.add(last.name, last.line, last.col, x)
+	case x.kind == kindTable && !x.defined && !x.dotted:
+		// The table was created implicitly by a previous header.
+		x.line, x.col, x.defined = line, col, true
+	default:
+		return p.errorAt(line, col, "table %s is already defined", formatKey(key))
+	}
+	p.current = x
+	return nil
+}
+
+func (p *parser) parseValue() (*value, error) {
+	line, col := p.line, p.col
+
+	var v *value
+	var err error
+	switch c := p.peek(0); {
+	case p.eof():
+		return nil, p.expected("value")
+	case c == '"' || c == '\'':
+		var s string
+		s, err = p.parseString()
+		v = &value{kind: kindString, str: s}
+	case c == '[':
+		v, err = p.parseArray()
+	case c == '{':
+		v, err = p.parseInlineTable()
+	case c == 't' || c == 'f':
+		v, err = p.parseBool()
+	default:
+		v, err = p.parseScalar()
+	}
+	if err != nil {
+		return nil, err
+	}
+	v.line, v.col = line, col
+	return v, nil
+}
+
+func (p *parser) parseBool() (*value, error) {
+	start := p.pos
+	for isBareKeyChar(p.peek(0)) {
+		p.advance(1)
+	}
+	switch s := string(p.data[start:p.pos]); s {
+	case "true":
+		return &value{kind: kindBool, b: true}, nil
+	case "false":
+		return &value{kind: kindBool, b: false}, nil
+	default:
+		return nil, p.errorf("invalid value %q", s)
+	}
+}
+
+func isScalarChar(c byte) bool {
+	return isBareKeyChar(c) || c == '+' || c == '.' || c == ':'
+}
+
+// parseScalar parses a number or date-time.
+func (p *parser) parseScalar() (*value, error) {
+	line, col := p.line, p.col
+
+	start := p.pos
+	for isScalarChar(p.peek(0)) {
+		p.advance(1)
+	}
+	// A date may be separated from a time by a space.
+	if p.pos-start == 10 && p.data[start+4] == '-' && p.peek(0) == ' ' && isDigit(p.peek(1)) && isDigit(p.peek(2)) &&
+		p.peek(3) == ':' {
+		p.advance(1)
+		for isScalarChar(p.peek(0)) {
+			p.advance(1)
+		}
+	}
+
+	s := string(p.data[start:p.pos])
+	if s == "" {
+		return nil, p.expected("value")
+	}
+
+	if strings.Contains(s, ":") || len(s) >= 10 && s[4] == '-' && s[7] == '-' {
+		t, err := parseDatetime(s)
+		if err != nil {
+			return nil, p.errorAt(line, col, "%v", err)
+		}
+		return &value{kind: kindDatetime, str: s, t: t}, nil
+	}
+
+	v, err := parseNumber(s)
+	if err != nil {
+		return nil, p.errorAt(line, col, "%v", err)
+	}
+	return v, nil
+}
+
+// parseNumber parses an integer or float.
+func parseNumber(s string) (*value, error) {
+	switch s {
+	case "inf", "+inf":
+		return &value{kind: kindFloat, f: math.Inf(1)}, nil
+	case "-inf":
+		return &value{kind: kindFloat, f: math.Inf(-1)}, nil
+	case "nan", "+nan", "-nan":
+		return &value{kind: kindFloat, f: math.NaN()}, nil
+	}
+
+	if len(s) > 2 && s[0] == '0' {
+		base := 0
+		switch s[1] {
+		case 'x':
+			base = 16
+		case 'o':
+			base = 8
+		case 'b':
+			base = 2
+		}
+		if base != 0 {
+			if !validDigits(s[2:], base) {
+				return nil, fmt.Errorf("invalid integer %q", s)
+			}
+			i, err := strconv.ParseInt(strings.ReplaceAll(s[2:], "_", ""), base, 64)
+			if err != nil {
+				return nil, fmt.Errorf("integer %s is out of range", s)
+			}
+			return &value{kind: kindInteger, i: i}, nil
+		}
+	}
+
+	if strings.ContainsAny(s, ".eE") {
+		if !validFloat(s) {
+			return nil, fmt.Errorf("invalid float %q", s)
+		}
+		f, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64)
+		if err != nil {
+			return nil, fmt.Errorf("float %s is out of range", s)
+		}
+		return &value{kind: kindFloat, f: f}, nil
+	}
+
+	digits := s
+	if s[0] == '+' || s[0] == '-' {
+		digits = s[1:]
+	}
+	if !validDecimal(digits) {
+		return nil, fmt.Errorf("invalid integer %q", s)
+	}
+	i, err := strconv.ParseInt(strings.ReplaceAll(s, "_", ""), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("integer %s is out of range", s)
+	}
+	return &value{kind: kindInteger, i: i}, nil
+}
+
+// validDigits returns true if s is a non-empty sequence of digits in the given base in which each underscore is
+// surrounded by digits.
+func validDigits(s string, base int) bool {
+	if s == "" || s[0] == '_' || s[len(s)-1] == '_' || strings.Contains(s, "__") {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c != '_' && digitValue(c) >= base {
+			return false
+		}
+	}
+	return true
+}
+
+// digitValue returns the value of the hexadecimal digit c, or 16 if c is not a hexadecimal digit.
+func digitValue(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c-'a') + 10
+	case 'A' <= c && c <= 'F':
+		return int(c-'A') + 10
+	default:
+		return 16
+	}
+}
+
+// validDecimal returns true if s is a valid unsigned decimal integer, which may not have leading zeros.
+func validDecimal(s string) bool {
+	return validDigits(s, 10) && (len(s) == 1 || s[0] != '0')
+}
+
+// validFloat returns true if s is a valid float with a fractional part, an exponent part, or both.
+func validFloat(s string) bool {
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+
+	i := strings.IndexAny(s, ".eE")
+	if !validDecimal(s[:i]) {
+		return false
+	}
+	s = s[i:]
+
+	if s[0] == '.' {
+		s = s[1:]
+		frac := s
+		if i := strings.IndexAny(s, "eE"); i >= 0 {
+			frac, s = s[:i], s[i:]
+		} else {
+			s = ""
+		}
+		if !validDigits(frac, 10) {
+			return false
+		}
+	}
+	if s == "" {
+		return true
+	}
+	if s[0] != 'e' && s[0] != 'E' {
+		return false
+	}
+	s = s[1:]
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+	return validDigits(s, 10)
+}
+
+// parseString parses a basic, literal, multi-line basic, or multi-line literal string.
+func (p *parser) parseString() (string, error) {
+	q := p.peek(0)
+	if p.hasPrefix(strings.Repeat(string(q), 3)) {
+		return p.parseMultilineString(q)
+	}
+
+	line, col := p.line, p.col
+	p.advance(1)
+
+	var b strings.Builder
+	for {
+		switch c := p.peek(0); {
+		case p.eof() || c == '\n' || c == '\r':
+			return "", p.errorAt(line, col, "unterminated string")
+		case c == q:
+			p.advance(1)
+			return b.String(), nil
+		case c == '\\' && q == '"':
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+		case isControl(c):
+			return "", p.errorf("invalid control character %U in string", c)
+		default:
+			b.WriteByte(c)
+			p.advance(1)
+		}
+	}
+}
+
+func (p *parser) parseMultilineString(q byte) (string, error) {
+	line, col := p.line, p.col
+	p.advance(3)
+
+	// A newline immediately following the opening delimiter is trimmed.
+	if p.peek(0) == '\n' || p.peek(0) == '\r' && p.peek(1) == '\n' {
+		p.newline()
+	}
+
+	var b strings.Builder
+	for {
+		switch c := p.peek(0); {
+		case p.eof():
+			return "", p.errorAt(line, col, "unterminated string")
+		case c == q:
+			// Up to two quotes may appear immediately before the closing delimiter.
+			n := 1
+			for p.peek(n) == q {
+				n++
+			}
+			if n > 5 {
+				return "", p.errorf("too many quotes in multi-line string")
+			}
+			if n >= 3 {
+				b.WriteString(strings.Repeat(string(q), n-3))
+				p.advance(n)
+				return b.String(), nil
+			}
+			b.WriteString(strings.Repeat(string(q), n))
+			p.advance(n)
+		case c == '\\' && q == '"':
+			// A line ending backslash trims all whitespace and newlines up to the next non-whitespace character.
+			n := 1
+			for p.peek(n) == ' ' || p.peek(n) == '\t' {
+				n++
+			}
+			if p.peek(n) != '\n' && !(p.peek(n) == '\r' && p.peek(n+1) == '\n') {
+				if err := p.parseEscape(&b); err != nil {
+					return "", err
+				}
+				continue
+			}
+			p.advance(n)
+			for {
+				p.skipSpace()
+				if p.peek(0) != '\n' && !(p.peek(0) == '\r' && p.peek(1) == '\n') {
+					break
+				}
+				p.newline()
+			}
+		case c == '\n' || c == '\r':
+			if err := p.newline(); err != nil {
+				return "", err
+			}
+			b.WriteByte('\n')
+		case isControl(c):
+			return "", p.errorf("invalid control character %U in string", c)
+		default:
+			b.WriteByte(c)
+			p.advance(1)
+		}
+	}
+}
+
+func (p *parser) parseEscape(b *strings.Builder) error {
+	line, col := p.line, p.col
+	if p.pos+1 >= len(p.data) {
+		return p.errorAt(line, col, "invalid escape sequence")
+	}
+
+	c := p.data[p.pos+1]
+	p.advance(2)
+
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.data) {
+			return p.errorAt(line, col, "invalid escape sequence")
+		}
+		code, err := strconv.ParseUint(string(p.data[p.pos:p.pos+n]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorAt(line, col, "invalid escape sequence \\%c%s", c, p.data[p.pos:p.pos+n])
+		}
+		p.advance(n)
+		b.WriteRune(rune(code))
+	default:
+		return p.errorAt(line, col, "invalid escape sequence")
+	}
+	return nil
+}
+
+func (p *parser) parseArray() (*value, error) {
+	p.advance(1)
+
+	v := &value{kind: kindArray, frozen: true}
+	for {
+		if err := p.skipBlank(); err != nil {
+			return nil, err
+		}
+		if p.peek(0) == ']' {
+			p.advance(1)
+			return v, nil
+		}
+
+		elem, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		v.elems = append(v.elems, elem)
+
+		if err := p.skipBlank(); err != nil {
+			return nil, err
+		}
+		switch p.peek(0) {
+		case ',':
+			p.advance(1)
+		case ']':
+			p.advance(1)
+			return v, nil
+		default:
+			return nil, p.expected(`"," or "]"`)
+		}
+	}
+}
+
+func (p *parser) parseInlineTable() (*value, error) {
+	p.advance(1)
+
+	t := &value{kind: kindTable, defined: true, frozen: true}
+	p.skipSpace()
+	if p.peek(0) == '}' {
+		p.advance(1)
+		return t, nil
+	}
+	for {
+		if err := p.parseKeyValue(t); err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		switch p.peek(0) {
+		case ',':
+			p.advance(1)
+		case '}':
+			p.advance(1)
+			return t, nil
+		default:
+			return nil, p.expected(`"," or "}"`)
+		}
+	}
+}
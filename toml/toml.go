@@ -0,0 +1,146 @@
+// Package toml implements TOML v1.0.0 as described at https://toml.io/en/v1.0.0.
+//
+// Structs and maps are encoded as tables. Tables that are nested within other tables are written as [table] sections,
+// and sequences of tables are written as [[array]] sections; tables that are nested within other arrays are written as
+// inline tables. TOML has no null value, so nil values are omitted from tables and cannot be encoded as array elements.
+//
+// All four TOML date/time types decode to time.Time values. Local date-times, local dates, and local times are
+// interpreted in time.Local; local times have the date 0000-01-01. Values of type time.Time are encoded as offset
+// date-times.
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pgavlin/codec"
+)
+
+// Format is the codec format for TOML. Values of type time.Time are encoded as TOML date-times.
+var Format = codec.NewFormat("toml")
+
+func init() {
+	codec.Override[time.Time](Format, timeCodec{})
+}
+
+// Marshal returns the TOML encoding of x, which must encode as a table.
+func Marshal(x any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(x); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the TOML document in b into the value pointed to by x.
+func Unmarshal(b []byte, x any) error {
+	root, err := parse(b)
+	if err != nil {
+		return err
+	}
+	return decodeDocument(root, codec.DecodeOptions{}, x)
+}
+
+// A SyntaxError is a description of a TOML syntax error, including the line and column at which it occurred. Columns
+// count characters rather than bytes. Keys and tables that are defined more than once are also reported as
+// SyntaxErrors.
+type SyntaxError struct {
+	msg    string // description of error
+	Line   int    // line of the error, starting at 1
+	Column int    // column of the error, starting at 1
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("toml: line %d, column %d: %s", e.Line, e.Column, e.msg)
+}
+
+// A DecodeError describes an error that occurred while decoding a TOML value, such as an UnmarshalTypeError, along
+// with the line and column at which the value begins.
+type DecodeError struct {
+	Line   int   // line of the value, starting at 1
+	Column int   // column of the value, starting at 1
+	Err    error // the underlying error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("toml: line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// datetimeLayouts are the layouts of the TOML date/time types. When parsing, Go accepts fractional seconds after the
+// seconds field even if the layout omits them. A nil location indicates that the layout includes an offset.
+var datetimeLayouts = []struct {
+	layout string
+	loc    *time.Location
+}{
+	{time.RFC3339, nil},                 // offset date-time
+	{"2006-01-02T15:04:05", time.Local}, // local date-time
+	{"2006-01-02", time.Local},          // local date
+	{"15:04:05", time.Local},            // local time
+}
+
+// parseDatetime parses any of the TOML date/time types.
+func parseDatetime(s string) (time.Time, error) {
+	// TOML allows a lowercase "t" or a space between the date and time, and a lowercase "z" for UTC.
+	if len(s) > 10 && (s[10] == 't' || s[10] == ' ') {
+		s = s[:10] + "T" + s[11:]
+	}
+	if strings.HasSuffix(s, "z") {
+		s = s[:len(s)-1] + "Z"
+	}
+
+	for _, l := range datetimeLayouts {
+		var t time.Time
+		var err error
+		if l.loc == nil {
+			t, err = time.Parse(l.layout, s)
+		} else {
+			t, err = time.ParseInLocation(l.layout, s, l.loc)
+		}
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date-time %q", s)
+}
+
+// timeCodec represents time.Time values as TOML date-times. Times may also be decoded from strings that hold
+// date-times.
+type timeCodec struct {
+	codec.DefaultVisitor
+	value *time.Time
+}
+
+func (timeCodec) New(v *time.Time) codec.Codec[time.Time] {
+	return timeCodec{value: v}
+}
+
+func (c timeCodec) VisitString(v string) error {
+	t, err := parseDatetime(v)
+	if err != nil {
+		return err
+	}
+	*c.value = t
+	return nil
+}
+
+func (c timeCodec) Deserialize(d codec.Decoder) error {
+	if d, ok := d.(Decoder); ok && d.v.kind == kindDatetime {
+		*c.value = d.v.t
+		return nil
+	}
+	return d.DecodeString(c)
+}
+
+func (c timeCodec) Serialize(e codec.Encoder) error {
+	if e, ok := e.(Encoder); ok {
+		*e.v = value{kind: kindDatetime, str: c.value.Format(time.RFC3339Nano)}
+		return nil
+	}
+	return e.EncodeString(c.value.Format(time.RFC3339Nano))
+}
@@ -14,12 +14,12 @@ type Cache[T any] struct {
 	cache unsafe.Pointer // map[unsafe.Pointer]codec
 }
 
-func (c Cache[T]) load() map[unsafe.Pointer]T {
+func (c *Cache[T]) load() map[unsafe.Pointer]T {
 	p := atomic.LoadPointer(&c.cache)
 	return *(*map[unsafe.Pointer]T)(unsafe.Pointer(&p))
 }
 
-func (c Cache[T]) set(type_ reflect.Type, val T, oldCache map[unsafe.Pointer]T) {
+func (c *Cache[T]) set(type_ reflect.Type, val T, oldCache map[unsafe.Pointer]T) {
 	newCache := make(map[unsafe.Pointer]T, len(oldCache)+1)
 	newCache[typeid(type_)] = val
 
@@ -32,7 +32,7 @@ func (c Cache[T]) set(type_ reflect.Type, val T, oldCache map[unsafe.Pointer]T)
 
 // Gets the value associated with the given type. If the type is not in the cache,
 // calls create to create a new value and records it in the cache.
-func (c Cache[T]) GetOrCreate(type_ reflect.Type, create func(reflect.Type) T) T {
+func (c *Cache[T]) GetOrCreate(type_ reflect.Type, create func(reflect.Type) T) T {
 	cache := c.load()
 	v, found := cache[typeid(type_)]
 	if !found {
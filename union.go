@@ -0,0 +1,571 @@
+package codec
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"unsafe"
+)
+
+// unionType describes a registered union: an interface type whose values are encoded as structs or maps that carry a
+// discriminator key naming the concrete type of the value.
+type unionType struct {
+	typ      reflect.Type
+	key      string
+	variants map[string]reflect.Type
+	tags     map[reflect.Type]string
+}
+
+// RegisterUnion registers a union for the interface type I. Values of type I are encoded as structs or maps that
+// carry an additional field, key, whose value names the value's concrete type. When decoding, the value of the
+// discriminator key selects the concrete type from variants. For example,
+//
+//	codec.RegisterUnion[Shape]("kind", map[string]reflect.Type{
+//		"circle": reflect.TypeOf((*Circle)(nil)),
+//		"square": reflect.TypeOf((*Square)(nil)),
+//	})
+//
+// allows a struct field of type Shape to be decoded into a *Circle or a *Square based on the value of its "kind" key.
+//
+// Each variant must implement I, and must be encoded as a struct or map. The discriminator belongs to the union: it is
+// not passed to the variant when decoding, and any struct field of the variant with the same key is omitted when
+// encoding. The discriminator may appear anywhere in the encoded map. Keys that precede it are buffered as generic
+// values (see AnyCodec) and replayed to the variant once its type is known, along with the decode options of the map.
+//
+// As with Register, unions must be registered before any values that contain an I are serialized or deserialized,
+// and are typically registered from an init function. RegisterUnion panics if I is not an interface type or if a
+// variant does not implement I.
+func RegisterUnion[I any](key string, variants map[string]reflect.Type) {
+	t := reflect.TypeOf((*I)(nil)).Elem()
+	if t.Kind() != reflect.Interface {
+		panic(fmt.Errorf("codec: union type %v is not an interface", t))
+	}
+
+	u := &unionType{
+		typ:      t,
+		key:      key,
+		variants: make(map[string]reflect.Type, len(variants)),
+		tags:     make(map[reflect.Type]string, len(variants)),
+	}
+	for tag, vt := range variants {
+		if !vt.Implements(t) {
+			panic(fmt.Errorf("codec: union variant %v does not implement %v", vt, t))
+		}
+		u.variants[tag] = vt
+		u.tags[vt] = tag
+	}
+
+	registry.m.Lock()
+	defer registry.m.Unlock()
+
+	if registry.unions == nil {
+		registry.unions = map[reflect.Type]*unionType{}
+	}
+	registry.unions[t] = u
+}
+
+func registeredUnion(t reflect.Type) (*unionType, bool) {
+	registry.m.RLock()
+	defer registry.m.RUnlock()
+
+	u, ok := registry.unions[t]
+	return u, ok
+}
+
+// An UnknownVariantError is returned when decoding a union whose discriminator does not name a registered variant.
+type UnknownVariantError struct {
+	Type    reflect.Type // the union's interface type
+	Variant string       // the value of the discriminator
+}
+
+func (e *UnknownVariantError) Error() string {
+	return "codec: unknown variant " + fmt.Sprintf("%q", e.Variant) + " of union " + e.Type.String()
+}
+
+// interfaceCodec is the codec for non-empty interface types. Values are encoded using the codecs for their dynamic
+// types. If the interface type is a registered union, the dynamic type's discriminator is added to the encoded value,
+// and values are decoded into the variant selected by the discriminator. Otherwise, only nil values can be decoded.
+type interfaceCodec struct {
+	unsafeCodec
+	typ    reflect.Type
+	union  *unionType
+	format *Format
+}
+
+func (c interfaceCodec) new(v unsafe.Pointer) codec {
+	return interfaceCodec{unsafeCodec: unsafeCodec{value: v}, typ: c.typ, union: c.union, format: c.format}
+}
+
+func (c interfaceCodec) iface() reflect.Value {
+	return reflect.NewAt(c.typ, c.value).Elem()
+}
+
+func (c interfaceCodec) VisitNil() error {
+	c.iface().Set(reflect.Zero(c.typ))
+	return nil
+}
+
+func (c interfaceCodec) VisitMap(map_ MapDecoder) error {
+	if c.union == nil {
+		return &UnmarshalTypeError{Value: "map", Type: c.typ}
+	}
+
+	options := GetDecodeOptions(map_)
+	var buffered []bufferedField
+	for {
+		var k string
+		ok, err := map_.NextKey(&k, NewString(&k))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return &MissingFieldError{Struct: c.typ.String(), Field: c.union.key}
+		}
+
+		if k == c.union.key {
+			var tag string
+			if err := map_.NextValue(&tag, NewString(&tag)); err != nil {
+				return annotateError(err, "", k, stringType)
+			}
+			vt, ok := c.union.variants[tag]
+			if !ok {
+				return &UnknownVariantError{Type: c.typ, Variant: tag}
+			}
+			return c.decodeVariant(vt, &replayMapDecoder{buffered: buffered, rest: map_, options: options, format: c.format})
+		}
+
+		var v any
+		if err := map_.NextValue(&v, AnyCodec{value: &v, format: c.format}); err != nil {
+			return err
+		}
+		buffered = append(buffered, bufferedField{key: k, value: v})
+	}
+}
+
+// decodeVariant decodes a value of type vt from the given map and stores it in the interface.
+func (c interfaceCodec) decodeVariant(vt reflect.Type, map_ MapDecoder) error {
+	var v, p reflect.Value
+	if vt.Kind() == reflect.Pointer {
+		p = reflect.New(vt.Elem())
+		v = p
+	} else {
+		p = reflect.New(vt)
+		v = p.Elem()
+	}
+	if err := GetDeserializer(p.Interface(), c.format).Deserialize(mapDecoder{m: map_, format: c.format}); err != nil {
+		return err
+	}
+	c.iface().Set(v)
+	return nil
+}
+
+func (c interfaceCodec) Deserialize(d Decoder) error {
+	return d.DecodeAny(c)
+}
+
+func (c interfaceCodec) Serialize(e Encoder) error {
+	v := c.iface()
+	if v.IsNil() {
+		return e.EncodeNil()
+	}
+	v = v.Elem()
+
+	if c.union != nil {
+		tag, ok := c.union.tags[v.Type()]
+		if !ok {
+			return fmt.Errorf("codec: %v is not a variant of union %v", v.Type(), c.typ)
+		}
+		e = unionEncoder{Encoder: e, key: c.union.key, tag: tag}
+
+		// Pointer variants are encoded via their elements so that the discriminator is added to the pointed-to
+		// struct or map rather than passed through the encoder's EncodeElem.
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return e.EncodeNil()
+			}
+			return getCodec(v.Type().Elem(), c.format).new(v.UnsafePointer()).Serialize(e)
+		}
+	}
+	return GetSerializer(v.Interface(), c.format).Serialize(e)
+}
+
+// UnionCodec is a Codec[I] for an interface type I. If I is registered using RegisterUnion, values are encoded and
+// decoded as described there. Otherwise, values are encoded using the codecs for their dynamic types, and only nil
+// values can be decoded. As with ReflectCodec, the overrides of the format reported by the Encoder or Decoder passed
+// to Serialize or Deserialize are applied to variants.
+type UnionCodec[I any] struct {
+	codec
+	value *I
+}
+
+func NewUnion[I any](v *I) UnionCodec[I] {
+	return UnionCodec[I]{codec: getCodec(reflect.TypeOf(v).Elem(), nil).new(unsafe.Pointer(v)), value: v}
+}
+
+// formatCodec returns the codec for the value in the given format.
+func (c UnionCodec[I]) formatCodec(f *Format) codec {
+	if f == nil {
+		return c.codec
+	}
+	return getCodec(reflect.TypeOf(c.value).Elem(), f).new(unsafe.Pointer(c.value))
+}
+
+func (c UnionCodec[I]) Serialize(e Encoder) error {
+	return c.formatCodec(FormatOf(e)).Serialize(e)
+}
+
+func (c UnionCodec[I]) Deserialize(d Decoder) error {
+	return c.formatCodec(FormatOf(d)).Deserialize(d)
+}
+
+func (UnionCodec[I]) New(v *I) Codec[I] {
+	return NewUnion(v)
+}
+
+// unionEncoder adds a union's discriminator to the struct or map encoded for a variant.
+type unionEncoder struct {
+	Encoder
+
+	key string
+	tag string
+}
+
+// Format returns the format of the underlying encoder.
+func (e unionEncoder) Format() *Format {
+	return FormatOf(e.Encoder)
+}
+
+func (e unionEncoder) EncodeMap(len int) (MapEncoder, error) {
+	if len >= 0 {
+		len++
+	}
+	enc, err := e.Encoder.EncodeMap(len)
+	if err != nil {
+		return nil, err
+	}
+	if err := enc.EncodeKey(e.key, NewString(&e.key)); err != nil {
+		return nil, err
+	}
+	if err := enc.EncodeValue(e.tag, NewString(&e.tag)); err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+func (e unionEncoder) EncodeStruct(name string) (StructEncoder, error) {
+	enc, err := e.Encoder.EncodeStruct(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := enc.EncodeField(e.key, e.tag, NewString(&e.tag)); err != nil {
+		return nil, err
+	}
+	return unionStructEncoder{StructEncoder: enc, key: e.key}, nil
+}
+
+// unionStructEncoder omits a variant's field named by the discriminator key.
+type unionStructEncoder struct {
+	StructEncoder
+
+	key string
+}
+
+func (e unionStructEncoder) EncodeField(key string, v any, s Serializer) error {
+	if key == e.key {
+		return nil
+	}
+	return e.StructEncoder.EncodeField(key, v, s)
+}
+
+func (e unionStructEncoder) EncodeStructField(f *StructField, v any, s Serializer) error {
+	if f.Name == e.key {
+		return nil
+	}
+	if enc, ok := e.StructEncoder.(FieldStructEncoder); ok {
+		return enc.EncodeStructField(f, v, s)
+	}
+	return e.StructEncoder.EncodeField(f.Name, v, s)
+}
+
+type bufferedField struct {
+	key   string
+	value any
+}
+
+// replayMapDecoder is a MapDecoder that replays buffered fields before continuing with the rest of a map. Buffered
+// values are replayed with the options and format of the original map.
+type replayMapDecoder struct {
+	buffered []bufferedField
+	rest     MapDecoder
+	options  DecodeOptions
+	format   *Format
+}
+
+func (d *replayMapDecoder) Size() (int, bool) {
+	return 0, false
+}
+
+func (d *replayMapDecoder) Options() DecodeOptions {
+	return d.options
+}
+
+func (d *replayMapDecoder) NextKey(k any, de Deserializer) (bool, error) {
+	if len(d.buffered) == 0 {
+		return d.rest.NextKey(k, de)
+	}
+	return true, de.Deserialize(d.value(d.buffered[0].key))
+}
+
+func (d *replayMapDecoder) NextValue(v any, de Deserializer) error {
+	if len(d.buffered) == 0 {
+		return d.rest.NextValue(v, de)
+	}
+	value := d.buffered[0].value
+	d.buffered = d.buffered[1:]
+	return de.Deserialize(d.value(value))
+}
+
+func (d *replayMapDecoder) value(v any) valueDecoder {
+	return valueDecoder{v: v, options: d.options, format: d.format}
+}
+
+// mapDecoder is a Decoder for a map whose entries are supplied by a MapDecoder.
+type mapDecoder struct {
+	m      MapDecoder
+	format *Format
+}
+
+func (d mapDecoder) Format() *Format {
+	return d.format
+}
+
+func (d mapDecoder) DecodeNil(v Visitor) error                 { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeBool(v Visitor) error                { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeInt(v Visitor) error                 { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeInt8(v Visitor) error                { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeInt16(v Visitor) error               { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeInt32(v Visitor) error               { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeInt64(v Visitor) error               { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeUint(v Visitor) error                { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeUint8(v Visitor) error               { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeUint16(v Visitor) error              { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeUint32(v Visitor) error              { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeUint64(v Visitor) error              { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeUintptr(v Visitor) error             { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeFloat32(v Visitor) error             { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeFloat64(v Visitor) error             { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeComplex64(v Visitor) error           { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeComplex128(v Visitor) error          { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeString(v Visitor) error              { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeBytes(v Visitor) error               { return d.DecodeAny(v) }
+func (d mapDecoder) DecodePtr(v Visitor) error                 { return v.VisitElem(mapElemDecoder(d)) }
+func (d mapDecoder) DecodeSeq(v Visitor) error                 { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeMap(v Visitor) error                 { return d.DecodeAny(v) }
+func (d mapDecoder) DecodeStruct(name string, v Visitor) error { return d.DecodeAny(v) }
+
+func (d mapDecoder) DecodeVariant(enum string, variants []string, v Visitor) error {
+	return d.DecodeAny(v)
+}
+
+func (d mapDecoder) DecodeAny(v Visitor) error {
+	return v.VisitMap(d.m)
+}
+
+type mapElemDecoder mapDecoder
+
+func (d mapElemDecoder) Element(v any, de Deserializer) error {
+	return de.Deserialize(mapDecoder(d))
+}
+
+// valueDecoder is a Decoder for the generic values produced by AnyCodec. Structs are decoded from maps using the
+// decoder's options, and the decoder reports the format of the input from which the values were produced.
+type valueDecoder struct {
+	v       any
+	options DecodeOptions
+	format  *Format
+}
+
+func (d valueDecoder) Format() *Format {
+	return d.format
+}
+
+// value returns a decoder for a value nested within the decoder's value.
+func (d valueDecoder) value(v any) valueDecoder {
+	return valueDecoder{v: v, options: d.options, format: d.format}
+}
+
+func (d valueDecoder) DecodeNil(v Visitor) error                 { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeBool(v Visitor) error                { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeInt(v Visitor) error                 { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeInt8(v Visitor) error                { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeInt16(v Visitor) error               { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeInt32(v Visitor) error               { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeInt64(v Visitor) error               { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeUint(v Visitor) error                { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeUint8(v Visitor) error               { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeUint16(v Visitor) error              { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeUint32(v Visitor) error              { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeUint64(v Visitor) error              { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeUintptr(v Visitor) error             { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeFloat32(v Visitor) error             { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeFloat64(v Visitor) error             { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeComplex64(v Visitor) error           { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeComplex128(v Visitor) error          { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeString(v Visitor) error              { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeBytes(v Visitor) error               { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeSeq(v Visitor) error                 { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeMap(v Visitor) error                 { return d.DecodeAny(v) }
+func (d valueDecoder) DecodeStruct(name string, v Visitor) error { return d.DecodeAny(v) }
+
+// DecodeVariant decodes the externally tagged representation produced by AnyCodec.VisitVariant.
+func (d valueDecoder) DecodeVariant(enum string, variants []string, v Visitor) error {
+	switch dv := d.v.(type) {
+	case string:
+		return v.VisitVariant(valueVariantDecoder{variant: dv, d: d.value(nil)})
+	case map[string]any:
+		if len(dv) == 1 {
+			for k, value := range dv {
+				return v.VisitVariant(valueVariantDecoder{variant: k, d: d.value(value)})
+			}
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+func (d valueDecoder) DecodePtr(v Visitor) error {
+	if d.v == nil {
+		return v.VisitNil()
+	}
+	return v.VisitElem(valueElemDecoder(d))
+}
+
+func (d valueDecoder) DecodeAny(v Visitor) error {
+	switch dv := d.v.(type) {
+	case nil:
+		return v.VisitNil()
+	case bool:
+		return v.VisitBool(dv)
+	case int:
+		return v.VisitInt(dv)
+	case int8:
+		return v.VisitInt8(dv)
+	case int16:
+		return v.VisitInt16(dv)
+	case int32:
+		return v.VisitInt32(dv)
+	case int64:
+		return v.VisitInt64(dv)
+	case uint:
+		return v.VisitUint(dv)
+	case uint8:
+		return v.VisitUint8(dv)
+	case uint16:
+		return v.VisitUint16(dv)
+	case uint32:
+		return v.VisitUint32(dv)
+	case uint64:
+		return v.VisitUint64(dv)
+	case uintptr:
+		return v.VisitUintptr(dv)
+	case float32:
+		return v.VisitFloat32(dv)
+	case float64:
+		return v.VisitFloat64(dv)
+	case complex64:
+		return v.VisitComplex64(dv)
+	case complex128:
+		return v.VisitComplex128(dv)
+	case string:
+		return v.VisitString(dv)
+	case []byte:
+		return v.VisitBytes(dv)
+	case Number:
+		if nv, ok := v.(NumberVisitor); ok {
+			return nv.VisitNumber(dv)
+		}
+		return visitNumber(dv.String(), v)
+	case []any:
+		return v.VisitSeq(&valueSeqDecoder{vals: dv, d: d})
+	case map[string]any:
+		return v.VisitMap(&valueMapDecoder{m: dv, iter: reflect.ValueOf(dv).MapRange(), d: d})
+	default:
+		return fmt.Errorf("codec: cannot decode value of type %T", dv)
+	}
+}
+
+// visitNumber visits a number literal as an int64 or uint64 if it is an integer that fits, and as a float64 otherwise.
+func visitNumber(s string, v Visitor) error {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return v.VisitInt64(i)
+	}
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return v.VisitUint64(u)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	return v.VisitFloat64(f)
+}
+
+type valueVariantDecoder struct {
+	variant string
+	d       valueDecoder
+}
+
+func (d valueVariantDecoder) Variant() string {
+	return d.variant
+}
+
+func (d valueVariantDecoder) Value(v any, de Deserializer) error {
+	return de.Deserialize(d.d)
+}
+
+type valueElemDecoder valueDecoder
+
+func (d valueElemDecoder) Element(v any, de Deserializer) error {
+	return de.Deserialize(valueDecoder(d))
+}
+
+type valueSeqDecoder struct {
+	vals []any
+	d    valueDecoder
+}
+
+func (d *valueSeqDecoder) Size() (int, bool) {
+	return len(d.vals), true
+}
+
+func (d *valueSeqDecoder) NextElement(v any, de Deserializer) (bool, error) {
+	if len(d.vals) == 0 {
+		return false, nil
+	}
+	elem := d.vals[0]
+	d.vals = d.vals[1:]
+	return true, de.Deserialize(d.d.value(elem))
+}
+
+type valueMapDecoder struct {
+	m    map[string]any
+	iter *reflect.MapIter
+	d    valueDecoder
+}
+
+func (d *valueMapDecoder) Size() (int, bool) {
+	return len(d.m), true
+}
+
+func (d *valueMapDecoder) Options() DecodeOptions {
+	return d.d.options
+}
+
+func (d *valueMapDecoder) NextKey(k any, de Deserializer) (bool, error) {
+	if !d.iter.Next() {
+		return false, nil
+	}
+	return true, de.Deserialize(d.d.value(d.iter.Key().Interface()))
+}
+
+func (d *valueMapDecoder) NextValue(v any, de Deserializer) error {
+	return de.Deserialize(d.d.value(d.iter.Value().Interface()))
+}
@@ -0,0 +1,182 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pgavlin/codec/internal/codectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	SKU   string  `codec:"sku" xml:",attr"`
+	Price float64 `codec:"price" xml:"price,attr,omitempty"`
+	Name  string  `codec:"name" xml:",chardata"`
+}
+
+type order struct {
+	XMLName  xml.Name          `xml:"urn:orders Order"`
+	ID       int               `codec:"id" xml:",attr"`
+	Lang     string            `codec:"lang" xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+	Customer string            `codec:"customer"`
+	Items    []item            `codec:"items" xml:"Item"`
+	Notes    []string          `codec:"notes" xml:"Note,omitempty"`
+	Express  *bool             `codec:"express"`
+	Data     []byte            `codec:"data,omitempty"`
+	Labels   map[string]string `codec:"labels,omitempty"`
+	Internal string            `codec:"internal" xml:"-"`
+}
+
+func TestMarshal(t *testing.T) {
+	yes := true
+	b, err := MarshalIndent(order{
+		ID:       7,
+		Customer: "Ann & Bob",
+		Items:    []item{{SKU: "a-1", Price: 1.5, Name: "Widget"}, {SKU: "b-2", Name: "Gadget"}},
+		Express:  &yes,
+		Data:     []byte("hi"),
+		Labels:   map[string]string{"z": "1", "a": "2"},
+		Internal: "secret",
+	}, "", "  ")
+	require.NoError(t, err)
+	assert.Equal(t, `<Order xmlns="urn:orders" id="7">
+  <customer>Ann &amp; Bob</customer>
+  <Item sku="a-1" price="1.5">Widget</Item>
+  <Item sku="b-2">Gadget</Item>
+  <express>true</express>
+  <data>aGk=</data>
+  <labels>
+    <a>2</a>
+    <z>1</z>
+  </labels>
+</Order>`, string(b))
+
+	// Attributes in other namespaces are prefixed.
+	b, err = Marshal(order{ID: 1, Lang: "en"})
+	require.NoError(t, err)
+	assert.Equal(t, `<Order xmlns="urn:orders" id="1" xml:lang="en"><customer></customer></Order>`, string(b))
+
+	b, err = Marshal(struct {
+		XMLName xml.Name `xml:"r"`
+		Kind    string   `xml:"http://example.com/schema kind,attr"`
+	}{Kind: "k"})
+	require.NoError(t, err)
+	assert.Equal(t, `<r xmlns:schema="http://example.com/schema" schema:kind="k"></r>`, string(b))
+
+	// Without an XMLName field, structs are named by their types.
+	b, err = Marshal(item{SKU: "x"})
+	require.NoError(t, err)
+	assert.Equal(t, `<item sku="x"></item>`, string(b))
+
+	// Other values must be named explicitly.
+	_, err = Marshal("x")
+	assert.EqualError(t, err, "xml: cannot determine the element name of an unnamed value")
+
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	require.NoError(t, e.EncodeElement([]int{1, 2}, xml.StartElement{Name: xml.Name{Local: "n"}}))
+	require.NoError(t, e.EncodeElement(map[string]any{"b": true, "a": []string{"x", "y"}},
+		xml.StartElement{Name: xml.Name{Local: "m"}, Attr: []xml.Attr{{Name: xml.Name{Local: "v"}, Value: "1"}}}))
+	assert.Equal(t, `<n>1</n><n>2</n><m v="1"><a>x</a><a>y</a><b>true</b></m>`, buf.String())
+
+	_, err = Marshal(struct {
+		A []int `xml:",attr"`
+	}{})
+	assert.EqualError(t, err, `xml: field "A" cannot be encoded as an attribute`)
+}
+
+func TestUnmarshal(t *testing.T) {
+	const doc = `<?xml version="1.0"?>
+<!-- an order -->
+<o:Order xmlns:o="urn:orders" xmlns:x="http://www.w3.org/XML/1998/namespace" id=" 7 " x:lang="en">
+  <customer>Ann &amp; Bob</customer>
+  <Item sku="a-1" price="1.5">Widget</Item>
+  <unknown>ignored</unknown>
+  <Item sku="b-2"><![CDATA[Gad]]>get</Item>
+  <Note>first</Note>
+  <express> true </express>
+  <data>aGk=</data>
+  <labels><a>2</a><z>1</z></labels>
+</o:Order>`
+
+	var o order
+	require.NoError(t, Unmarshal([]byte(doc), &o))
+	express := true
+	assert.Equal(t, order{
+		XMLName:  xml.Name{Space: "urn:orders", Local: "Order"},
+		ID:       7,
+		Lang:     "en",
+		Customer: "Ann & Bob",
+		Items:    []item{{SKU: "a-1", Price: 1.5, Name: "Widget"}, {SKU: "b-2", Name: "Gadget"}},
+		Notes:    []string{"first"},
+		Express:  &express,
+		Data:     []byte("hi"),
+		Labels:   map[string]string{"a": "2", "z": "1"},
+	}, o)
+
+	// Elements are decoded as maps if they have attributes or children, and as strings otherwise. Repeated elements
+	// are decoded as sequences.
+	var v any
+	require.NoError(t, Unmarshal([]byte(`<r a="1"><b>x</b><c/><b>y</b>text</r>`), &v))
+	assert.Equal(t, map[string]any{"a": "1", "b": []any{"x", "y"}, "c": "", "#text": "text"}, v)
+
+	d := NewDecoder(strings.NewReader(`<item sku="x" color="red"/>`))
+	d.DisallowUnknownFields()
+	var i item
+	assert.EqualError(t, d.Decode(&i), `codec: unknown field "color" in Go struct item`)
+
+	err := Unmarshal([]byte(`<item price="cheap"/>`), &i)
+	assert.EqualError(t, err, "codec: cannot unmarshal string into Go struct field item.price of type float64")
+
+	err = Unmarshal([]byte(`<item><a></item>`), &i)
+	assert.EqualError(t, err, "XML syntax error on line 1: element <a> closed by </item>")
+
+	assert.Equal(t, io.EOF, Unmarshal([]byte(" "), &i))
+}
+
+func TestRoundTrip(t *testing.T) {
+	yes := false
+	expected := order{
+		XMLName:  xml.Name{Space: "urn:orders", Local: "Order"},
+		ID:       42,
+		Lang:     "fr",
+		Customer: "<Zoë>",
+		Items:    []item{{SKU: "a", Price: 0.25, Name: " spaced "}},
+		Notes:    []string{"a", "b"},
+		Express:  &yes,
+		Labels:   map[string]string{"k": "v"},
+	}
+
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	require.NoError(t, e.Encode(expected))
+	require.NoError(t, e.Encode(expected))
+
+	d := NewDecoder(&buf)
+	for i := 0; i < 2; i++ {
+		var actual order
+		require.NoError(t, d.Decode(&actual))
+		assert.Equal(t, expected, actual)
+	}
+	var actual order
+	assert.Equal(t, io.EOF, d.Decode(&actual))
+}
+
+func TestVariant(t *testing.T) {
+	type options struct {
+		Options []codectest.Option `codec:"option"`
+	}
+
+	expected := options{Options: []codectest.Option{{Valid: true, Value: "x"}, {}}}
+	b, err := Marshal(expected)
+	require.NoError(t, err)
+	assert.Equal(t, "<options><option><Some>x</Some></option><option>None</option></options>", string(b))
+
+	var actual options
+	require.NoError(t, Unmarshal(b, &actual))
+	assert.Equal(t, expected, actual)
+}
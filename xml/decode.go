@@ -0,0 +1,445 @@
+package xml
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pgavlin/codec"
+)
+
+// An element is a decoded XML element.
+type element struct {
+	name     xml.Name
+	attrs    []xml.Attr // the element's attributes, excluding namespace declarations
+	children []*element
+	text     string // the character data directly within the element
+}
+
+// readElement reads the remainder of the element that begins with start.
+func readElement(d *xml.Decoder, start xml.StartElement) (*element, error) {
+	el := &element{name: start.Name}
+	for _, a := range start.Attr {
+		if a.Name.Space != "xmlns" && !(a.Name.Space == "" && a.Name.Local == "xmlns") {
+			el.attrs = append(el.attrs, a)
+		}
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			child, err := readElement(d, tok)
+			if err != nil {
+				return nil, err
+			}
+			el.children = append(el.children, child)
+		case xml.CharData:
+			text.Write(tok)
+		case xml.EndElement:
+			el.text = text.String()
+			return el, nil
+		}
+	}
+}
+
+// A nodeKind identifies the type of a decoded node.
+type nodeKind int
+
+const (
+	nodeElement nodeKind = iota
+	nodeText             // an attribute value or character data
+	nodeSeq              // repeated elements
+	nodeName             // the name of an element, which is decoded into XMLName fields
+)
+
+// A node is a value to decode.
+type node struct {
+	kind  nodeKind
+	elem  *element
+	elems []*element
+	text  string
+	name  xml.Name
+}
+
+// A Decoder decodes values from XML elements. Elements that contain neither attributes nor child elements are decoded
+// as scalars, and other elements are decoded as maps. When a struct is decoded, the xml tags of its fields are used to
+// map the element's attributes, children, and character data to the struct's fields; the field matching of the struct
+// codec is then applied to the results.
+//
+// Decoders returned by NewDecoder read a stream of elements; each call to Decode decodes the next element.
+type Decoder struct {
+	n       node
+	options codec.DecodeOptions
+	d       *xml.Decoder // non-nil if the decoder was returned by NewDecoder
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{d: xml.NewDecoder(r)}
+}
+
+// DisallowUnknownFields causes the Decoder to return an error when the destination is a struct and the input contains
+// attributes or elements which do not match any of the struct's fields.
+func (d *Decoder) DisallowUnknownFields() {
+	d.options.DisallowUnknownFields = true
+}
+
+// Decode reads the next element from its input and stores it in the value pointed to by v. At the end of the input,
+// Decode returns io.EOF.
+//
+// Decode may only be called on decoders returned by NewDecoder.
+func (d *Decoder) Decode(v any) error {
+	for {
+		tok, err := d.d.Token()
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			el, err := readElement(d.d, start)
+			if err != nil {
+				return err
+			}
+			return codec.GetDeserializer(v, Format).Deserialize(Decoder{n: node{elem: el}, options: d.options})
+		}
+	}
+}
+
+func (d Decoder) with(n node) Decoder {
+	return Decoder{n: n, options: d.options}
+}
+
+// text returns the text of an attribute, character data, or an element without child elements.
+func (d Decoder) text() (string, bool) {
+	switch {
+	case d.n.kind == nodeText:
+		return d.n.text, true
+	case d.n.kind == nodeElement && len(d.n.elem.children) == 0:
+		return d.n.elem.text, true
+	default:
+		return "", false
+	}
+}
+
+// Format returns the XML format.
+func (d Decoder) Format() *codec.Format {
+	return Format
+}
+
+func (d Decoder) DecodeAny(v codec.Visitor) error {
+	switch d.n.kind {
+	case nodeText:
+		return v.VisitString(d.n.text)
+	case nodeSeq:
+		return v.VisitSeq(&SeqDecoder{d: d, elems: d.n.elems})
+	case nodeName:
+		return v.VisitString(d.n.name.Local)
+	}
+
+	el := d.n.elem
+	if len(el.attrs) == 0 && len(el.children) == 0 {
+		return v.VisitString(el.text)
+	}
+	return v.VisitMap(&MapDecoder{d: d, entries: mapEntries(el)})
+}
+
+// mapEntries returns the entries of an element that is decoded as a map. Attributes and child elements are keyed by
+// their local names, and repeated child elements are grouped into sequences. Character data that is not whitespace is
+// keyed by "#text".
+func mapEntries(el *element) []mapEntry {
+	entries := make([]mapEntry, 0, len(el.attrs)+len(el.children))
+	for _, a := range el.attrs {
+		entries = append(entries, mapEntry{key: a.Name.Local, n: node{kind: nodeText, text: a.Value}})
+	}
+
+	index := map[string]int{}
+	for _, c := range el.children {
+		i, ok := index[c.name.Local]
+		if !ok {
+			index[c.name.Local] = len(entries)
+			entries = append(entries, mapEntry{key: c.name.Local, n: node{elem: c}})
+			continue
+		}
+		n := &entries[i].n
+		if n.kind == nodeElement {
+			*n = node{kind: nodeSeq, elems: []*element{n.elem}}
+		}
+		n.elems = append(n.elems, c)
+	}
+
+	if strings.TrimSpace(el.text) != "" {
+		entries = append(entries, mapEntry{key: "#text", n: node{kind: nodeText, text: el.text}})
+	}
+	return entries
+}
+
+// structEntries returns the entries of an element that is decoded as a struct with the given fields. Attributes,
+// child elements, and character data that match fields are keyed by the fields' keys, and the values of repeated
+// fields are grouped into sequences. Other attributes and child elements are keyed by their local names.
+func structEntries(el *element, fields []codec.StructField) []mapEntry {
+	infos := make([]fieldInfo, len(fields))
+	for i := range fields {
+		infos[i] = parseField(&fields[i])
+	}
+	find := func(mode fieldMode, name xml.Name) int {
+		for i, info := range infos {
+			if info.mode == mode && info.matches(&fields[i], name) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	var entries []mapEntry
+	for i, info := range infos {
+		switch info.mode {
+		case modeName:
+			entries = append(entries, mapEntry{key: fields[i].Name, n: node{kind: nodeName, name: el.name}})
+		case modeCharData:
+			entries = append(entries, mapEntry{key: fields[i].Name, n: node{kind: nodeText, text: el.text}})
+		}
+	}
+
+	for _, a := range el.attrs {
+		key := a.Name.Local
+		if i := find(modeAttr, a.Name); i >= 0 {
+			key = fields[i].Name
+		}
+		entries = append(entries, mapEntry{key: key, n: node{kind: nodeText, text: a.Value}})
+	}
+
+	repeated := map[int]int{}
+	for _, c := range el.children {
+		i := find(modeElement, c.name)
+		switch {
+		case i < 0:
+			entries = append(entries, mapEntry{key: c.name.Local, n: node{elem: c}})
+		case isRepeated(fields[i].Type):
+			if j, ok := repeated[i]; ok {
+				entries[j].n.elems = append(entries[j].n.elems, c)
+			} else {
+				repeated[i] = len(entries)
+				entries = append(entries, mapEntry{key: fields[i].Name, n: node{kind: nodeSeq, elems: []*element{c}}})
+			}
+		default:
+			entries = append(entries, mapEntry{key: fields[i].Name, n: node{elem: c}})
+		}
+	}
+	return entries
+}
+
+func (d Decoder) DecodeNil(v codec.Visitor) error        { return d.DecodeAny(v) }
+func (d Decoder) DecodeComplex64(v codec.Visitor) error  { return d.DecodeAny(v) }
+func (d Decoder) DecodeComplex128(v codec.Visitor) error { return d.DecodeAny(v) }
+func (d Decoder) DecodeInt(v codec.Visitor) error        { return d.decodeInt(v) }
+func (d Decoder) DecodeInt8(v codec.Visitor) error       { return d.decodeInt(v) }
+func (d Decoder) DecodeInt16(v codec.Visitor) error      { return d.decodeInt(v) }
+func (d Decoder) DecodeInt32(v codec.Visitor) error      { return d.decodeInt(v) }
+func (d Decoder) DecodeInt64(v codec.Visitor) error      { return d.decodeInt(v) }
+func (d Decoder) DecodeUint(v codec.Visitor) error       { return d.decodeUint(v) }
+func (d Decoder) DecodeUint8(v codec.Visitor) error      { return d.decodeUint(v) }
+func (d Decoder) DecodeUint16(v codec.Visitor) error     { return d.decodeUint(v) }
+func (d Decoder) DecodeUint32(v codec.Visitor) error     { return d.decodeUint(v) }
+func (d Decoder) DecodeUint64(v codec.Visitor) error     { return d.decodeUint(v) }
+func (d Decoder) DecodeUintptr(v codec.Visitor) error    { return d.decodeUint(v) }
+func (d Decoder) DecodeFloat32(v codec.Visitor) error    { return d.decodeFloat(v) }
+func (d Decoder) DecodeFloat64(v codec.Visitor) error    { return d.decodeFloat(v) }
+
+// decodeInt decodes an integer from text. Text that is not an integer is visited as a string.
+func (d Decoder) decodeInt(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+			return v.VisitInt64(i)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// decodeUint decodes an unsigned integer from text. Text that is not an unsigned integer is visited as a string.
+func (d Decoder) decodeUint(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if u, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64); err == nil {
+			return v.VisitUint64(u)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// decodeFloat decodes a float from text. Text that is not a float is visited as a string.
+func (d Decoder) decodeFloat(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return v.VisitFloat64(f)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeBool decodes a boolean from text. Text that is not a boolean is visited as a string.
+func (d Decoder) DecodeBool(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+			return v.VisitBool(b)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeString decodes a string from text. The attributes of elements that do not contain child elements are ignored.
+func (d Decoder) DecodeString(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		return v.VisitString(s)
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeBytes decodes a byte slice from base64-encoded text.
+func (d Decoder) DecodeBytes(v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		return v.VisitBytes(b)
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeSeq decodes a sequence. Repeated elements are decoded as sequences of their elements, and other values are
+// decoded as single-element sequences.
+func (d Decoder) DecodeSeq(v codec.Visitor) error {
+	switch d.n.kind {
+	case nodeSeq:
+		return v.VisitSeq(&SeqDecoder{d: d, elems: d.n.elems})
+	case nodeElement:
+		return v.VisitSeq(&SeqDecoder{d: d, elems: []*element{d.n.elem}})
+	default:
+		return v.VisitSeq(&SeqDecoder{d: d, text: []string{d.n.text}})
+	}
+}
+
+// DecodeMap decodes an element as a map.
+func (d Decoder) DecodeMap(v codec.Visitor) error {
+	if d.n.kind != nodeElement {
+		return d.DecodeAny(v)
+	}
+	return v.VisitMap(&MapDecoder{d: d, entries: mapEntries(d.n.elem)})
+}
+
+// DecodeStruct decodes an element as a struct. If v describes the struct's fields, the fields' xml tags are used to
+// map the element's contents to the fields' keys.
+func (d Decoder) DecodeStruct(name string, v codec.Visitor) error {
+	sv, ok := v.(codec.StructVisitor)
+	if !ok || d.n.kind != nodeElement {
+		return d.DecodeMap(v)
+	}
+	return v.VisitMap(&MapDecoder{d: d, entries: structEntries(d.n.elem, sv.StructFields())})
+}
+
+// DecodeVariant decodes the externally tagged representation produced by Encoder.EncodeVariant: unit variants are
+// represented by their names, and other variants by elements with a single child element that is named by the variant
+// and holds its value. Other values are decoded as-is.
+func (d Decoder) DecodeVariant(enum string, variants []string, v codec.Visitor) error {
+	if s, ok := d.text(); ok {
+		return v.VisitVariant(VariantDecoder{name: strings.TrimSpace(s)})
+	}
+	if d.n.kind == nodeElement && len(d.n.elem.children) == 1 {
+		c := d.n.elem.children[0]
+		return v.VisitVariant(VariantDecoder{d: d.with(node{elem: c}), name: c.name.Local, value: true})
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodePtr decodes a pointer. XML has no null value, so the pointer's element is always decoded.
+func (d Decoder) DecodePtr(v codec.Visitor) error {
+	return v.VisitElem(ElemDecoder{d})
+}
+
+type ElemDecoder struct {
+	d Decoder
+}
+
+func (d ElemDecoder) Element(v any, ds codec.Deserializer) error {
+	return ds.Deserialize(d.d)
+}
+
+// A SeqDecoder decodes repeated elements or a single text value.
+type SeqDecoder struct {
+	d     Decoder
+	elems []*element
+	text  []string
+}
+
+func (d *SeqDecoder) Size() (int, bool) {
+	return len(d.elems) + len(d.text), true
+}
+
+func (d *SeqDecoder) NextElement(x any, ds codec.Deserializer) (bool, error) {
+	var n node
+	switch {
+	case len(d.elems) != 0:
+		n, d.elems = node{elem: d.elems[0]}, d.elems[1:]
+	case len(d.text) != 0:
+		n, d.text = node{kind: nodeText, text: d.text[0]}, d.text[1:]
+	default:
+		return false, nil
+	}
+	return true, ds.Deserialize(d.d.with(n))
+}
+
+type mapEntry struct {
+	key string
+	n   node
+}
+
+type MapDecoder struct {
+	d       Decoder
+	entries []mapEntry
+}
+
+func (d *MapDecoder) Size() (int, bool) {
+	return len(d.entries), true
+}
+
+func (d *MapDecoder) Options() codec.DecodeOptions {
+	return d.d.options
+}
+
+func (d *MapDecoder) NextKey(k any, ds codec.Deserializer) (bool, error) {
+	if len(d.entries) == 0 {
+		return false, nil
+	}
+	return true, ds.Deserialize(d.d.with(node{kind: nodeText, text: d.entries[0].key}))
+}
+
+func (d *MapDecoder) NextValue(v any, ds codec.Deserializer) error {
+	n := d.entries[0].n
+	d.entries = d.entries[1:]
+	return ds.Deserialize(d.d.with(n))
+}
+
+type VariantDecoder struct {
+	d     Decoder
+	name  string
+	value bool // true if the variant has a value
+}
+
+func (d VariantDecoder) Variant() string {
+	return d.name
+}
+
+func (d VariantDecoder) Value(v any, ds codec.Deserializer) error {
+	if !d.value {
+		return ds.Deserialize(d.d.with(node{kind: nodeText}))
+	}
+	return ds.Deserialize(d.d)
+}
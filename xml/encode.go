@@ -0,0 +1,413 @@
+package xml
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/pgavlin/codec"
+)
+
+// A kind identifies the type of an encoded value.
+type kind int
+
+const (
+	kindNil     kind = iota // nil values, which are omitted
+	kindText                // scalars
+	kindElement             // structs, maps, and variants, which are elements with attributes and children
+	kindSeq                 // sequences, which are encoded as repeated elements
+	kindName                // xml.Name values, which name their enclosing elements
+)
+
+// A value is an encoded value. Values are positioned by their containers: text may be written as an attribute, as
+// character data, or as an element, and sequences are written as one element per item.
+type value struct {
+	kind     kind
+	text     string   // the text of a scalar
+	name     xml.Name // the name of an element, if given by an XMLName field, or an xml.Name value
+	typeName string   // the name of an element's struct type
+	attrs    []xml.Attr
+	children []child  // the children of an element
+	elems    []*value // the items of a sequence
+}
+
+// A child is a child element or, if its name is empty, character data.
+type child struct {
+	name xml.Name
+	v    *value
+}
+
+// An Encoder encodes values as XML elements. Values are encoded into memory before they are written so that the
+// attributes of each element can be written before its children.
+//
+// Encoders returned by NewEncoder write elements to an io.Writer.
+type Encoder struct {
+	v *value
+	e *xml.Encoder // non-nil if the encoder was returned by NewEncoder
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{e: xml.NewEncoder(w)}
+}
+
+// Indent sets the encoder to generate XML in which each element begins on a new indented line that starts with prefix
+// and is followed by one or more copies of indent according to the nesting depth.
+//
+// Indent may only be called on encoders returned by NewEncoder.
+func (e *Encoder) Indent(prefix, indent string) {
+	e.e.Indent(prefix, indent)
+}
+
+// Encode writes the XML encoding of v to the stream.
+//
+// Encode may only be called on encoders returned by NewEncoder.
+func (e *Encoder) Encode(v any) error {
+	return e.EncodeElement(v, xml.StartElement{})
+}
+
+// EncodeElement writes the XML encoding of v to the stream using start as the outermost tag. If start has no name,
+// the element is named as described in the package documentation.
+//
+// EncodeElement may only be called on encoders returned by NewEncoder.
+func (e *Encoder) EncodeElement(v any, start xml.StartElement) error {
+	var root value
+	if err := codec.GetSerializer(v, Format).Serialize(Encoder{v: &root}); err != nil {
+		return err
+	}
+	if start.Name.Local != "" {
+		rename(&root, start)
+	}
+	if err := writeElement(e.e, xml.Name{}, &root); err != nil {
+		return err
+	}
+	return e.e.Flush()
+}
+
+// rename applies the name and attributes of start to the elements that encode v.
+func rename(v *value, start xml.StartElement) {
+	if v.kind == kindSeq {
+		for _, e := range v.elems {
+			rename(e, start)
+		}
+		return
+	}
+	v.name = start.Name
+	v.attrs = append(start.Attr[:len(start.Attr):len(start.Attr)], v.attrs...)
+}
+
+// writeElement writes v as an element. The element's name defaults to the given name.
+func writeElement(e *xml.Encoder, name xml.Name, v *value) error {
+	switch v.kind {
+	case kindNil, kindName:
+		return nil
+	case kindSeq:
+		for _, elem := range v.elems {
+			if err := writeElement(e, name, elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch {
+	case v.name.Local != "":
+		name = v.name
+	case name.Local == "":
+		name.Local = v.typeName
+	}
+	if name.Local == "" {
+		return errors.New("xml: cannot determine the element name of an unnamed value")
+	}
+
+	start := xml.StartElement{Name: name, Attr: v.attrs}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if v.kind == kindText {
+		if err := e.EncodeToken(xml.CharData(v.text)); err != nil {
+			return err
+		}
+	}
+	for _, c := range v.children {
+		var err error
+		if c.name.Local == "" {
+			err = e.EncodeToken(xml.CharData(c.v.text))
+		} else {
+			err = writeElement(e, c.name, c.v)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (e Encoder) text(s string) error {
+	*e.v = value{kind: kindText, text: s}
+	return nil
+}
+
+// Format returns the XML format.
+func (e Encoder) Format() *codec.Format {
+	return Format
+}
+
+func (e Encoder) EncodeNil() error {
+	*e.v = value{kind: kindNil}
+	return nil
+}
+
+func (e Encoder) EncodeBool(v bool) error {
+	return e.text(strconv.FormatBool(v))
+}
+
+func (e Encoder) EncodeInt(v int) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt8(v int8) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt16(v int16) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt32(v int32) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt64(v int64) error {
+	return e.text(strconv.FormatInt(v, 10))
+}
+
+func (e Encoder) EncodeUint(v uint) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint8(v uint8) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint16(v uint16) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint32(v uint32) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint64(v uint64) error {
+	return e.text(strconv.FormatUint(v, 10))
+}
+
+func (e Encoder) EncodeUintptr(v uintptr) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeFloat32(v float32) error {
+	return e.text(strconv.FormatFloat(float64(v), 'g', -1, 32))
+}
+
+func (e Encoder) EncodeFloat64(v float64) error {
+	return e.text(strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+func (e Encoder) EncodeComplex64(v complex64) error {
+	return &codec.UnsupportedTypeError{Type: reflect.TypeOf(v)}
+}
+
+func (e Encoder) EncodeComplex128(v complex128) error {
+	return &codec.UnsupportedTypeError{Type: reflect.TypeOf(v)}
+}
+
+func (e Encoder) EncodeString(v string) error {
+	return e.text(v)
+}
+
+// EncodeBytes encodes a byte slice as base64-encoded text.
+func (e Encoder) EncodeBytes(v []byte) error {
+	return e.text(base64.StdEncoding.EncodeToString(v))
+}
+
+func (e Encoder) EncodeElem(v any, s codec.Serializer) error {
+	return s.Serialize(e)
+}
+
+func (e Encoder) EncodeSeq(len int) (codec.SeqEncoder, error) {
+	*e.v = value{kind: kindSeq}
+	if len != 0 {
+		e.v.elems = make([]*value, 0, len)
+	}
+	return &SeqEncoder{v: e.v}, nil
+}
+
+func (e Encoder) EncodeMap(len int) (codec.MapEncoder, error) {
+	*e.v = value{kind: kindElement}
+	if len != 0 {
+		e.v.children = make([]child, 0, len)
+	}
+	return &MapEncoder{v: e.v}, nil
+}
+
+func (e Encoder) EncodeStruct(name string) (codec.StructEncoder, error) {
+	*e.v = value{kind: kindElement, typeName: name}
+	return &StructEncoder{v: e.v}, nil
+}
+
+// EncodeVariant encodes a variant of a sum type using the externally tagged representation: unit variants are encoded
+// as their names, and other variants are encoded as elements with a single child element that is named by the variant
+// and holds its value.
+func (e Encoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	return &VariantEncoder{v: e.v, name: variant}, nil
+}
+
+func encodeValue(ser codec.Serializer) (*value, error) {
+	var v value
+	if err := ser.Serialize(Encoder{v: &v}); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+type SeqEncoder struct {
+	v *value
+}
+
+func (e *SeqEncoder) Close() error {
+	return nil
+}
+
+func (e *SeqEncoder) EncodeElement(x any, ser codec.Serializer) error {
+	v, err := encodeValue(ser)
+	if err != nil {
+		return err
+	}
+	e.v.elems = append(e.v.elems, v)
+	return nil
+}
+
+// A MapEncoder encodes a map as an element whose children are named by the map's keys. The children are sorted by
+// name when the encoder is closed so that the output does not depend on the iteration order of the map being encoded.
+type MapEncoder struct {
+	v   *value
+	key string
+}
+
+func (e *MapEncoder) Close() error {
+	sort.SliceStable(e.v.children, func(i, j int) bool { return e.v.children[i].name.Local < e.v.children[j].name.Local })
+	return nil
+}
+
+func (e *MapEncoder) EncodeKey(x any, ser codec.Serializer) error {
+	k, err := encodeValue(ser)
+	if err != nil {
+		return err
+	}
+	if k.kind != kindText {
+		return errors.New("xml: map keys must be scalars")
+	}
+	e.key = k.text
+	return nil
+}
+
+func (e *MapEncoder) EncodeValue(x any, ser codec.Serializer) error {
+	v, err := encodeValue(ser)
+	if err != nil {
+		return err
+	}
+	e.v.children = append(e.v.children, child{name: xml.Name{Local: e.key}, v: v})
+	return nil
+}
+
+// A StructEncoder encodes a struct as an element. Fields encoded using EncodeStructField are positioned according to
+// their xml tags; fields encoded using EncodeField are encoded as child elements.
+type StructEncoder struct {
+	v *value
+}
+
+func (e *StructEncoder) Close() error {
+	return nil
+}
+
+func (e *StructEncoder) EncodeField(key string, x any, ser codec.Serializer) error {
+	return e.encodeField(key, fieldInfo{name: xml.Name{Local: key}}, ser)
+}
+
+func (e *StructEncoder) EncodeStructField(f *codec.StructField, x any, ser codec.Serializer) error {
+	info := parseField(f)
+	switch {
+	case info.mode == modeSkip || info.omitEmpty && isEmpty(x):
+		return nil
+	case info.mode == modeName:
+		// The name given by an XMLName field's tag takes precedence over the field's value.
+		if info.name.Local != "" {
+			e.v.name = info.name
+		} else {
+			e.v.name = x.(xml.Name)
+		}
+		return nil
+	}
+	info.name = info.elementName(f)
+	return e.encodeField(f.Name, info, ser)
+}
+
+func (e *StructEncoder) encodeField(key string, info fieldInfo, ser codec.Serializer) error {
+	v, err := encodeValue(ser)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case v.kind == kindNil:
+		return nil
+	case v.kind == kindName:
+		if e.v.name.Local == "" {
+			e.v.name = v.name
+		}
+		return nil
+	case info.mode == modeAttr:
+		if v.kind != kindText {
+			return fmt.Errorf("xml: field %q cannot be encoded as an attribute", key)
+		}
+		e.v.attrs = append(e.v.attrs, xml.Attr{Name: info.name, Value: v.text})
+	case info.mode == modeCharData:
+		if v.kind != kindText {
+			return fmt.Errorf("xml: field %q cannot be encoded as character data", key)
+		}
+		e.v.children = append(e.v.children, child{v: v})
+	default:
+		e.v.children = append(e.v.children, child{name: info.name, v: v})
+	}
+	return nil
+}
+
+type VariantEncoder struct {
+	v     *value
+	name  string
+	value *value
+}
+
+func (e *VariantEncoder) Close() error {
+	if e.value == nil {
+		*e.v = value{kind: kindText, text: e.name}
+	} else {
+		*e.v = value{kind: kindElement, children: []child{{name: xml.Name{Local: e.name}, v: e.value}}}
+	}
+	return nil
+}
+
+func (e *VariantEncoder) EncodeValue(x any, ser codec.Serializer) error {
+	v, err := encodeValue(ser)
+	if err != nil {
+		return err
+	}
+	e.value = v
+	return nil
+}
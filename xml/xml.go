@@ -0,0 +1,190 @@
+// Package xml implements XML encoding and decoding on top of encoding/xml's tokenizer. The role of each struct field
+// is chosen by its xml tag, which follows the conventions of encoding/xml:
+//
+//	type Envelope struct {
+//		XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+//		ID      string   `codec:"id" xml:",attr"`
+//		Lang    string   `codec:"lang" xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+//		Items   []Item   `codec:"items" xml:"Item"`
+//		Note    string   `codec:"note" xml:",chardata"`
+//	}
+//
+// The first element of an xml tag is the field's XML name, which may be preceded by a namespace URL and a space. If the
+// name is omitted, the field's codec key is used. The attr option encodes the field as an attribute, and the chardata
+// option encodes the field as the character data of its struct's element; fields without either option are encoded as
+// child elements. The omitempty option omits attributes and elements whose values are empty, and fields whose xml tags
+// are "-" are ignored. A field named XMLName of type xml.Name holds the name of its struct's element.
+//
+// Sequences are encoded as repeated elements, maps are encoded as elements whose children are named by the maps' keys,
+// and nil values are omitted. Byte slices are encoded as base64-encoded text.
+//
+// Elements are named by, in priority order, the XMLName field of the value being encoded, the name of the struct field
+// that holds the value, and the name of the value's struct type. Top-level values that are not structs must be encoded
+// using Encoder.EncodeElement in order to name their elements.
+//
+// Tags are only visible to the format through codec.FieldStructEncoder and codec.StructVisitor, which are implemented
+// by the reflection-based struct codec. Structs with other codecs encode all of their fields as elements.
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+
+	"github.com/pgavlin/codec"
+)
+
+// Format is the codec format for XML. Values of type xml.Name are used to name elements.
+var Format = codec.NewFormat("xml")
+
+func init() {
+	codec.Override[xml.Name](Format, nameCodec{})
+}
+
+// Marshal returns the XML encoding of x.
+func Marshal(x any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(x); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalIndent is like Marshal but applies Indent to format the output: each XML element begins on a new indented
+// line that starts with prefix and is followed by one or more copies of indent according to the nesting depth.
+func MarshalIndent(x any, prefix, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.Indent(prefix, indent)
+	if err := e.Encode(x); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the first element in b into the value pointed to by x.
+func Unmarshal(b []byte, x any) error {
+	return NewDecoder(bytes.NewReader(b)).Decode(x)
+}
+
+// A fieldMode identifies the role of a struct field.
+type fieldMode int
+
+const (
+	modeElement fieldMode = iota
+	modeAttr
+	modeCharData
+	modeName // the XMLName field
+	modeSkip // fields tagged with "-"
+)
+
+// fieldInfo describes a struct field as given by its xml tag.
+type fieldInfo struct {
+	name      xml.Name // the field's XML name; empty if the tag does not name the field
+	mode      fieldMode
+	omitEmpty bool
+}
+
+var nameType = reflect.TypeOf(xml.Name{})
+
+func parseField(f *codec.StructField) fieldInfo {
+	tag := f.Tag.Get("xml")
+	if tag == "-" {
+		return fieldInfo{mode: modeSkip}
+	}
+
+	var info fieldInfo
+	name, opts, _ := strings.Cut(tag, ",")
+	if space, local, ok := strings.Cut(name, " "); ok {
+		info.name = xml.Name{Space: space, Local: local}
+	} else {
+		info.name = xml.Name{Local: name}
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		switch opt {
+		case "attr":
+			info.mode = modeAttr
+		case "chardata":
+			info.mode = modeCharData
+		case "omitempty":
+			info.omitEmpty = true
+		}
+	}
+	if f.Type == nameType && f.Name == "XMLName" {
+		info.mode = modeName
+	}
+	return info
+}
+
+// elementName returns the XML name of the field f, which defaults to its key.
+func (info fieldInfo) elementName(f *codec.StructField) xml.Name {
+	if info.name.Local == "" {
+		return xml.Name{Space: info.name.Space, Local: f.Name}
+	}
+	return info.name
+}
+
+// matches returns true if the XML name n matches the name of the field f. Fields that do not specify namespaces match
+// names in any namespace.
+func (info fieldInfo) matches(f *codec.StructField, n xml.Name) bool {
+	name := info.elementName(f)
+	return name.Local == n.Local && (name.Space == "" || name.Space == n.Space)
+}
+
+// isEmpty returns true if v is false, 0, a nil pointer or interface value, or an array, slice, map, or string of
+// length zero.
+func isEmpty(v any) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return rv.IsZero()
+	default:
+		return false
+	}
+}
+
+// isRepeated returns true if values of type t are decoded from repeated elements.
+func isRepeated(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8
+}
+
+// nameCodec represents xml.Name values as the names of elements.
+type nameCodec struct {
+	codec.DefaultVisitor
+	value *xml.Name
+}
+
+func (nameCodec) New(v *xml.Name) codec.Codec[xml.Name] {
+	return nameCodec{value: v}
+}
+
+func (c nameCodec) VisitString(v string) error {
+	*c.value = xml.Name{Local: v}
+	return nil
+}
+
+func (c nameCodec) Deserialize(d codec.Decoder) error {
+	if d, ok := d.(Decoder); ok && d.n.kind == nodeName {
+		*c.value = d.n.name
+		return nil
+	}
+	return d.DecodeString(c)
+}
+
+func (c nameCodec) Serialize(e codec.Encoder) error {
+	if e, ok := e.(Encoder); ok {
+		*e.v = value{kind: kindName, name: *c.value}
+		return nil
+	}
+	return e.EncodeString(c.value.Local)
+}
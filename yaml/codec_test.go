@@ -0,0 +1,264 @@
+package yaml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pgavlin/codec/internal/codectest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type boolStruct struct {
+	Field bool `codec:"field"`
+}
+
+type testStruct struct {
+	Name   string            `codec:"name"`
+	Count  int               `codec:"count"`
+	Ratio  float64           `codec:"ratio"`
+	Tags   []string          `codec:"tags,omitempty"`
+	Labels map[string]string `codec:"labels,omitempty"`
+	Data   []byte            `codec:"data,omitempty"`
+	Next   *testStruct       `codec:"next,omitempty"`
+}
+
+func TestMarshal(t *testing.T) {
+	b, err := Marshal(testStruct{
+		Name:   "true",
+		Count:  42,
+		Ratio:  1,
+		Tags:   []string{"a", "b"},
+		Labels: map[string]string{"z": "1", "a": "multi\nline\n"},
+		Data:   []byte("hello"),
+		Next:   &testStruct{Name: "next", Ratio: math.Inf(1)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `name: "true"
+count: 42
+ratio: 1.0
+tags:
+    - a
+    - b
+labels:
+    a: |
+        multi
+        line
+    z: "1"
+data: !!binary aGVsbG8=
+next:
+    name: next
+    count: 0
+    ratio: .inf
+`, string(b))
+
+	var s testStruct
+	require.NoError(t, Unmarshal(b, &s))
+	assert.Equal(t, "true", s.Name)
+	assert.Equal(t, 42, s.Count)
+	assert.Equal(t, 1.0, s.Ratio)
+	assert.Equal(t, []string{"a", "b"}, s.Tags)
+	assert.Equal(t, map[string]string{"z": "1", "a": "multi\nline\n"}, s.Labels)
+	assert.Equal(t, []byte("hello"), s.Data)
+	require.NotNil(t, s.Next)
+	assert.True(t, math.IsInf(s.Next.Ratio, 1))
+
+	b, err = Marshal(map[int]bool{10: true, 9: false})
+	require.NoError(t, err)
+	assert.Equal(t, "9: false\n10: true\n", string(b))
+
+	b, err = Marshal(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "null\n", string(b))
+}
+
+func TestScalars(t *testing.T) {
+	var v any
+	require.NoError(t, Unmarshal([]byte(`
+nil: ~
+bool: true
+int: 0x1F
+big: 18446744073709551615
+neg: -7
+float: 1.5e3
+inf: -.inf
+str: "42"
+plain: hello
+binary: !!binary aGVsbG8=
+list: [1, two, 3.0]
+`), &v))
+	assert.Equal(t, map[string]any{
+		"nil":    nil,
+		"bool":   true,
+		"int":    int64(31),
+		"big":    uint64(math.MaxUint64),
+		"neg":    int64(-7),
+		"float":  1500.0,
+		"inf":    math.Inf(-1),
+		"str":    "42",
+		"plain":  "hello",
+		"binary": []byte("hello"),
+		"list":   []any{int64(1), "two", 3.0},
+	}, v)
+
+	// Like gopkg.in/yaml.v3, any non-null scalar may be decoded into a string.
+	var labels map[string]string
+	require.NoError(t, Unmarshal([]byte("1: true\nversion: 1.10\n"), &labels))
+	assert.Equal(t, map[string]string{"1": "true", "version": "1.10"}, labels)
+
+	var i8 int8
+	require.NoError(t, Unmarshal([]byte("5.0"), &i8))
+	assert.Equal(t, int8(5), i8)
+
+	err := Unmarshal([]byte("300"), &i8)
+	assert.EqualError(t, err, "codec: cannot unmarshal number 300 into Go value of type int8")
+
+	var s boolStruct
+	err = Unmarshal([]byte("field: yes"), &s)
+	assert.EqualError(t, err, "codec: cannot unmarshal string into Go struct field boolStruct.field of type bool")
+}
+
+func TestAliases(t *testing.T) {
+	input := `
+base: &base
+  name: base
+  count: 1
+  tags: &tags [a, b]
+other: &other
+  ratio: 2.5
+  count: 3
+derived:
+  <<: [*base, *other]
+  name: derived
+  tags: *tags
+`
+	var v map[string]testStruct
+	require.NoError(t, Unmarshal([]byte(input), &v))
+	assert.Equal(t, testStruct{Name: "base", Count: 1, Tags: []string{"a", "b"}}, v["base"])
+	assert.Equal(t, testStruct{Name: "derived", Count: 1, Ratio: 2.5, Tags: []string{"a", "b"}}, v["derived"])
+
+	var expected, actual any
+	require.NoError(t, yaml.Unmarshal([]byte(input), &expected))
+	require.NoError(t, Unmarshal([]byte(input), &actual))
+	assert.Equal(t, fmt.Sprint(expected), fmt.Sprint(actual))
+
+	err := Unmarshal([]byte("a: &a 1\nb:\n  <<: *a\n"), &actual)
+	assert.EqualError(t, err, "yaml: line 1: map merge requires a mapping or a sequence of mappings")
+}
+
+func TestExcessiveAliasing(t *testing.T) {
+	laughs := `
+a: &a [lol, lol, lol, lol, lol, lol, lol, lol, lol]
+b: &b [*a, *a, *a, *a, *a, *a, *a, *a, *a]
+c: &c [*b, *b, *b, *b, *b, *b, *b, *b, *b]
+d: &d [*c, *c, *c, *c, *c, *c, *c, *c, *c]
+e: &e [*d, *d, *d, *d, *d, *d, *d, *d, *d]
+f: &f [*e, *e, *e, *e, *e, *e, *e, *e, *e]
+g: &g [*f, *f, *f, *f, *f, *f, *f, *f, *f]
+h: &h [*g, *g, *g, *g, *g, *g, *g, *g, *g]
+i: &i [*h, *h, *h, *h, *h, *h, *h, *h, *h]
+`
+	var v any
+	assert.EqualError(t, Unmarshal([]byte(laughs), &v), "yaml: document contains excessive aliasing")
+	assert.EqualError(t, yaml.Unmarshal([]byte(laughs), &v), "yaml: document contains excessive aliasing")
+
+	// Merge keys are expanded eagerly, so they are limited even if the merged pairs are never decoded.
+	merges := "a: &a {k: v}\n"
+	for c := 'b'; c <= 'i'; c++ {
+		merges += fmt.Sprintf("%c: &%c {<<: [*%c, *%c, *%c, *%c, *%c, *%c, *%c, *%c, *%c]}\n", c, c, c-1, c-1, c-1, c-1, c-1, c-1, c-1, c-1, c-1)
+	}
+	assert.EqualError(t, Unmarshal([]byte(merges), &v), "yaml: document contains excessive aliasing")
+
+	// Documents that reuse an anchor many times are accepted.
+	var b strings.Builder
+	b.WriteString("base: &base {name: base}\nitems:\n")
+	for i := 0; i < 2000; i++ {
+		b.WriteString("- *base\n")
+	}
+	var items struct {
+		Items []testStruct `codec:"items"`
+	}
+	require.NoError(t, Unmarshal([]byte(b.String()), &items))
+	assert.Len(t, items.Items, 2000)
+	require.NoError(t, yaml.Unmarshal([]byte(b.String()), &v))
+}
+
+func TestStream(t *testing.T) {
+	d := NewDecoder(strings.NewReader("field: true\n---\nfield: false\n"))
+
+	var docs []boolStruct
+	for {
+		var s boolStruct
+		err := d.Decode(&s)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		docs = append(docs, s)
+	}
+	assert.Equal(t, []boolStruct{{Field: true}, {Field: false}}, docs)
+
+	d = NewDecoder(strings.NewReader("field: true\nextra: 1\n"))
+	d.KnownFields(true)
+	var s boolStruct
+	assert.EqualError(t, d.Decode(&s), `codec: unknown field "extra" in Go struct boolStruct`)
+
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.SetIndent(2)
+	require.NoError(t, e.Encode(boolStruct{Field: true}))
+	require.NoError(t, e.Encode(map[string][]int{"a": {1}}))
+	require.NoError(t, e.Close())
+	assert.Equal(t, "field: true\n---\na:\n  - 1\n", buf.String())
+}
+
+type timeStruct struct {
+	Time time.Time `codec:"time"`
+}
+
+func TestTime(t *testing.T) {
+	ts := time.Date(2001, 12, 14, 21, 59, 43, 100000000, time.UTC)
+
+	b, err := Marshal(timeStruct{Time: ts})
+	require.NoError(t, err)
+	assert.Equal(t, "time: 2001-12-14T21:59:43.1Z\n", string(b))
+
+	var s timeStruct
+	require.NoError(t, Unmarshal(b, &s))
+	assert.True(t, ts.Equal(s.Time))
+
+	require.NoError(t, Unmarshal([]byte("time: 2002-12-14"), &s))
+	assert.True(t, time.Date(2002, 12, 14, 0, 0, 0, 0, time.UTC).Equal(s.Time))
+}
+
+type nodeStruct struct {
+	Name string    `codec:"name"`
+	Raw  yaml.Node `codec:"raw"`
+}
+
+func TestNode(t *testing.T) {
+	var s nodeStruct
+	require.NoError(t, Unmarshal([]byte("name: n\nraw: {a: [1, 2]}\n"), &s))
+	assert.Equal(t, "n", s.Name)
+	assert.Equal(t, yaml.MappingNode, s.Raw.Kind)
+
+	b, err := Marshal(s)
+	require.NoError(t, err)
+	assert.Equal(t, "name: n\nraw: {a: [1, 2]}\n", string(b))
+}
+
+func TestVariant(t *testing.T) {
+	b, err := Marshal([]codectest.Option{{Valid: true, Value: "x"}, {}})
+	require.NoError(t, err)
+	assert.Equal(t, "- Some: x\n- None\n", string(b))
+
+	var options []codectest.Option
+	require.NoError(t, Unmarshal(b, &options))
+	assert.Equal(t, []codectest.Option{{Valid: true, Value: "x"}, {}}, options)
+}
@@ -0,0 +1,426 @@
+package yaml
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pgavlin/codec"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	nullTag      = "!!null"
+	boolTag      = "!!bool"
+	intTag       = "!!int"
+	floatTag     = "!!float"
+	strTag       = "!!str"
+	binaryTag    = "!!binary"
+	timestampTag = "!!timestamp"
+	mergeTag     = "!!merge"
+)
+
+// A Decoder decodes values from a YAML node. Aliases are resolved to the nodes they refer to, and merge keys ("<<")
+// are expanded. As in gopkg.in/yaml.v3, documents whose values are mostly reached through aliases are rejected in order
+// to bound the work done to expand them.
+//
+// Decoders returned by NewDecoder read a stream of YAML documents; each call to Decode decodes the next document.
+type Decoder struct {
+	n       *yaml.Node
+	options codec.DecodeOptions
+	state   *decodeState
+	alias   bool          // true if the node was reached through an alias
+	s       *yaml.Decoder // non-nil if the decoder was returned by NewDecoder
+}
+
+// Limits on alias expansion. These are the limits used by gopkg.in/yaml.v3: once a document has decoded more than
+// 1000 nodes, at most 99% of the nodes decoded so far may have been reached through aliases, falling to 10% for
+// documents with 4,000,000 nodes or more.
+const (
+	aliasRatioRangeLow  = 400000
+	aliasRatioRangeHigh = 4000000
+	aliasRatioRange     = float64(aliasRatioRangeHigh - aliasRatioRangeLow)
+)
+
+// allowedAliasRatio returns the largest fraction of decoded nodes that may have been reached through aliases.
+func allowedAliasRatio(decodeCount int) float64 {
+	switch {
+	case decodeCount <= aliasRatioRangeLow:
+		return 0.99
+	case decodeCount >= aliasRatioRangeHigh:
+		return 0.10
+	default:
+		return 0.99 - 0.89*(float64(decodeCount-aliasRatioRangeLow)/aliasRatioRange)
+	}
+}
+
+// decodeState is shared by the decoders of a single document. It counts the nodes that have been decoded in order to
+// detect excessive aliasing, e.g. the "billion laughs" attack.
+type decodeState struct {
+	decodeCount int
+	aliasCount  int
+}
+
+// count records the decoding of a node, and returns an error if the document contains excessive aliasing.
+func (s *decodeState) count(alias bool) error {
+	s.decodeCount++
+	if alias {
+		s.aliasCount++
+	}
+	if s.aliasCount > 100 && s.decodeCount > 1000 &&
+		float64(s.aliasCount)/float64(s.decodeCount) > allowedAliasRatio(s.decodeCount) {
+		return errors.New("yaml: document contains excessive aliasing")
+	}
+	return nil
+}
+
+// NewDecoder returns a new decoder that reads a stream of YAML documents from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{s: yaml.NewDecoder(r)}
+}
+
+// NewNodeDecoder returns a new decoder that decodes values from n.
+func NewNodeDecoder(n *yaml.Node) Decoder {
+	return Decoder{n: n, state: &decodeState{}}
+}
+
+// WithOptions returns a copy of d that uses the given decode options.
+func (d Decoder) WithOptions(options codec.DecodeOptions) Decoder {
+	d.options = options
+	return d
+}
+
+// KnownFields causes the Decoder to return an error when the destination is a struct and the input contains mapping
+// keys which do not match any of the struct's fields.
+func (d *Decoder) KnownFields(enable bool) {
+	d.options.DisallowUnknownFields = enable
+}
+
+// Decode reads the next YAML document from its input and stores it in the value pointed to by v. At the end of the
+// input, Decode returns io.EOF.
+//
+// Decode may only be called on decoders returned by NewDecoder.
+func (d *Decoder) Decode(v any) error {
+	var n yaml.Node
+	if err := d.s.Decode(&n); err != nil {
+		return err
+	}
+	return Decoder{n: &n, options: d.options, state: &decodeState{}}.decode(v, codec.GetDeserializer(v, Format))
+}
+
+// child returns a decoder for a node nested within the decoder's node.
+func (d Decoder) child(n *yaml.Node) Decoder {
+	return Decoder{n: n, options: d.options, state: d.state, alias: d.alias}
+}
+
+// node returns the node to decode with any document nodes unwrapped and any aliases resolved. A nil node represents an
+// empty document.
+func (d Decoder) node() *yaml.Node {
+	n := d.n
+	for n != nil {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			if len(n.Content) == 0 {
+				return nil
+			}
+			n = n.Content[0]
+		case yaml.AliasNode:
+			n = n.Alias
+		default:
+			return n
+		}
+	}
+	return nil
+}
+
+// Format returns the YAML format.
+func (d Decoder) Format() *codec.Format {
+	return Format
+}
+
+func (d Decoder) DecodeAny(v codec.Visitor) error {
+	n := d.node()
+	if n == nil {
+		return v.VisitNil()
+	}
+
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return d.decodeScalar(n, v)
+	case yaml.SequenceNode:
+		return v.VisitSeq(&SeqDecoder{v: n.Content, d: d})
+	case yaml.MappingNode:
+		pairs, err := d.mappingPairs(n)
+		if err != nil {
+			return err
+		}
+		return v.VisitMap(&MapDecoder{pairs: pairs, d: d})
+	default:
+		return fmt.Errorf("yaml: line %v: cannot decode node of kind %v", n.Line, n.Kind)
+	}
+}
+
+// decodeScalar visits the value of a scalar node according to its resolved tag. Integers that fit in an int64 are
+// visited using VisitInt64, and larger integers using VisitUint64. Scalars with unknown tags are visited as strings.
+func (d Decoder) decodeScalar(n *yaml.Node, v codec.Visitor) error {
+	switch n.ShortTag() {
+	case nullTag:
+		return v.VisitNil()
+	case boolTag:
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return err
+		}
+		return v.VisitBool(b)
+	case intTag:
+		var i int64
+		if err := n.Decode(&i); err == nil {
+			return v.VisitInt64(i)
+		}
+		var u uint64
+		if err := n.Decode(&u); err != nil {
+			return err
+		}
+		return v.VisitUint64(u)
+	case floatTag:
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return err
+		}
+		return v.VisitFloat64(f)
+	case binaryTag:
+		// gopkg.in/yaml.v3 decodes binary data into strings rather than byte slices.
+		var b string
+		if err := n.Decode(&b); err != nil {
+			return err
+		}
+		return v.VisitBytes([]byte(b))
+	default:
+		return v.VisitString(n.Value)
+	}
+}
+
+func (d Decoder) DecodeNil(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d Decoder) DecodeBool(v codec.Visitor) error                { return d.DecodeAny(v) }
+func (d Decoder) DecodeInt(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d Decoder) DecodeInt8(v codec.Visitor) error                { return d.DecodeAny(v) }
+func (d Decoder) DecodeInt16(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d Decoder) DecodeInt32(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d Decoder) DecodeInt64(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d Decoder) DecodeUint(v codec.Visitor) error                { return d.DecodeAny(v) }
+func (d Decoder) DecodeUint8(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d Decoder) DecodeUint16(v codec.Visitor) error              { return d.DecodeAny(v) }
+func (d Decoder) DecodeUint32(v codec.Visitor) error              { return d.DecodeAny(v) }
+func (d Decoder) DecodeUint64(v codec.Visitor) error              { return d.DecodeAny(v) }
+func (d Decoder) DecodeUintptr(v codec.Visitor) error             { return d.DecodeAny(v) }
+func (d Decoder) DecodeFloat32(v codec.Visitor) error             { return d.DecodeAny(v) }
+func (d Decoder) DecodeFloat64(v codec.Visitor) error             { return d.DecodeAny(v) }
+func (d Decoder) DecodeComplex64(v codec.Visitor) error           { return d.DecodeAny(v) }
+func (d Decoder) DecodeComplex128(v codec.Visitor) error          { return d.DecodeAny(v) }
+func (d Decoder) DecodeBytes(v codec.Visitor) error               { return d.DecodeAny(v) }
+func (d Decoder) DecodeSeq(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d Decoder) DecodeMap(v codec.Visitor) error                 { return d.DecodeAny(v) }
+func (d Decoder) DecodeStruct(name string, v codec.Visitor) error { return d.DecodeAny(v) }
+
+// DecodeString decodes a string. Like gopkg.in/yaml.v3, DecodeString accepts any non-null scalar other than binary
+// data, and visits its text as written: e.g. the scalar 1 decodes as the string "1".
+func (d Decoder) DecodeString(v codec.Visitor) error {
+	if n := d.node(); n != nil && n.Kind == yaml.ScalarNode {
+		if tag := n.ShortTag(); tag != nullTag && tag != binaryTag {
+			return v.VisitString(n.Value)
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+// DecodeVariant decodes the externally tagged representation produced by Encoder.EncodeVariant: unit variants are
+// represented by their names, and other variants by single-entry mappings from their names to their values. Other
+// values are decoded as-is.
+func (d Decoder) DecodeVariant(enum string, variants []string, v codec.Visitor) error {
+	n := d.node()
+	if n != nil {
+		switch n.Kind {
+		case yaml.ScalarNode:
+			if n.ShortTag() == strTag {
+				return v.VisitVariant(VariantDecoder{name: n.Value, d: d.child(nil)})
+			}
+		case yaml.MappingNode:
+			pairs, err := d.mappingPairs(n)
+			if err != nil {
+				return err
+			}
+			if len(pairs) == 2 {
+				if k := (Decoder{n: pairs[0]}).node(); k.Kind == yaml.ScalarNode {
+					return v.VisitVariant(VariantDecoder{name: k.Value, d: d.child(pairs[1])})
+				}
+			}
+		}
+	}
+	return d.DecodeAny(v)
+}
+
+func (d Decoder) DecodePtr(v codec.Visitor) error {
+	if n := d.node(); n == nil || n.Kind == yaml.ScalarNode && n.ShortTag() == nullTag {
+		return v.VisitNil()
+	}
+	return v.VisitElem(ElemDecoder{d})
+}
+
+// decode decodes the decoder's node into v using ds. The node is counted against the document's aliasing limits. As in
+// gopkg.in/yaml.v3, an alias node counts as both itself and the node it refers to, and the referred-to node and its
+// descendants count as reached through an alias.
+func (d Decoder) decode(v any, ds codec.Deserializer) error {
+	if err := d.state.count(d.alias); err != nil {
+		return err
+	}
+	if d.n != nil && d.n.Kind == yaml.AliasNode {
+		d.alias = true
+		if err := d.state.count(true); err != nil {
+			return err
+		}
+	}
+
+	switch v := v.(type) {
+	case *yaml.Node:
+		if n := d.node(); n != nil {
+			*v = *n
+		}
+		return nil
+	case yaml.Unmarshaler:
+		n := d.node()
+		if n == nil {
+			return nil
+		}
+		return v.UnmarshalYAML(n)
+	default:
+		return ds.Deserialize(d)
+	}
+}
+
+// mappingPairs returns the alternating keys and values of the mapping node n with any merge keys ("<<") expanded. The
+// keys of n take precedence over merged keys, and earlier merged mappings take precedence over later ones. Merged pairs
+// whose keys are overridden are omitted. Each merged pair is counted against the document's aliasing limits.
+func (d Decoder) mappingPairs(n *yaml.Node) ([]*yaml.Node, error) {
+	var pairs, merges []*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.Kind == yaml.ScalarNode && k.ShortTag() == mergeTag {
+			merges = append(merges, v)
+		} else {
+			pairs = append(pairs, k, v)
+		}
+	}
+	if len(merges) == 0 {
+		return pairs, nil
+	}
+
+	type scalarKey struct{ tag, value string }
+	seen := map[scalarKey]bool{}
+	add := func(pairs, src []*yaml.Node, merged bool) ([]*yaml.Node, error) {
+		for i := 0; i < len(src); i += 2 {
+			if merged {
+				if err := d.state.count(true); err != nil {
+					return nil, err
+				}
+			}
+			k := Decoder{n: src[i]}.node()
+			if k.Kind == yaml.ScalarNode {
+				key := scalarKey{k.ShortTag(), k.Value}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			pairs = append(pairs, src[i], src[i+1])
+		}
+		return pairs, nil
+	}
+
+	result, _ := add(nil, pairs, false)
+	for _, m := range merges {
+		m = Decoder{n: m}.node()
+
+		sources := []*yaml.Node{m}
+		if m.Kind == yaml.SequenceNode {
+			sources = m.Content
+		}
+		for _, s := range sources {
+			if s = (Decoder{n: s}).node(); s.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("yaml: line %v: map merge requires a mapping or a sequence of mappings", s.Line)
+			}
+			merged, err := d.mappingPairs(s)
+			if err != nil {
+				return nil, err
+			}
+			if result, err = add(result, merged, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+type ElemDecoder struct {
+	d Decoder
+}
+
+func (d ElemDecoder) Element(v any, ds codec.Deserializer) error {
+	return d.d.decode(v, ds)
+}
+
+type SeqDecoder struct {
+	v []*yaml.Node
+	d Decoder
+}
+
+func (d *SeqDecoder) Size() (int, bool) {
+	return len(d.v), true
+}
+
+func (d *SeqDecoder) NextElement(x any, ds codec.Deserializer) (bool, error) {
+	if len(d.v) == 0 {
+		return false, nil
+	}
+	v := d.v[0]
+	d.v = d.v[1:]
+	return true, d.d.child(v).decode(x, ds)
+}
+
+type MapDecoder struct {
+	pairs []*yaml.Node
+	d     Decoder
+}
+
+func (d *MapDecoder) Size() (int, bool) {
+	return len(d.pairs) / 2, true
+}
+
+func (d *MapDecoder) Options() codec.DecodeOptions {
+	return d.d.options
+}
+
+func (d *MapDecoder) NextKey(k any, ds codec.Deserializer) (bool, error) {
+	if len(d.pairs) == 0 {
+		return false, nil
+	}
+	return true, d.d.child(d.pairs[0]).decode(k, ds)
+}
+
+func (d *MapDecoder) NextValue(v any, ds codec.Deserializer) error {
+	n := d.pairs[1]
+	d.pairs = d.pairs[2:]
+	return d.d.child(n).decode(v, ds)
+}
+
+type VariantDecoder struct {
+	name string
+	d    Decoder
+}
+
+func (d VariantDecoder) Variant() string {
+	return d.name
+}
+
+func (d VariantDecoder) Value(v any, ds codec.Deserializer) error {
+	return d.d.decode(v, ds)
+}
@@ -0,0 +1,326 @@
+package yaml
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pgavlin/codec"
+	"gopkg.in/yaml.v3"
+)
+
+// An Encoder encodes values into a YAML node.
+//
+// Encoders returned by NewEncoder write a stream of YAML documents; each call to Encode writes a new document.
+type Encoder struct {
+	n *yaml.Node
+	s *yaml.Encoder // non-nil if the encoder was returned by NewEncoder
+}
+
+// NewEncoder returns a new encoder that writes a stream of YAML documents to w. The caller must call Close to flush
+// any buffered output.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{s: yaml.NewEncoder(w)}
+}
+
+// NewNodeEncoder returns a new encoder that encodes values into n.
+func NewNodeEncoder(n *yaml.Node) Encoder {
+	return Encoder{n: n}
+}
+
+// SetIndent sets the number of spaces used for each level of indentation.
+//
+// SetIndent may only be called on encoders returned by NewEncoder.
+func (e *Encoder) SetIndent(spaces int) {
+	e.s.SetIndent(spaces)
+}
+
+// Encode writes the YAML encoding of v to the stream as a new document.
+//
+// Encode may only be called on encoders returned by NewEncoder.
+func (e *Encoder) Encode(v any) error {
+	var n yaml.Node
+	if err := (Encoder{n: &n}).encode(v, codec.GetSerializer(v, Format)); err != nil {
+		return err
+	}
+	return e.s.Encode(&n)
+}
+
+// Close flushes any buffered output to the underlying writer.
+//
+// Close may only be called on encoders returned by NewEncoder.
+func (e *Encoder) Close() error {
+	return e.s.Close()
+}
+
+func (e Encoder) encode(v any, s codec.Serializer) error {
+	switch v := v.(type) {
+	case yaml.Node:
+		*e.n = v
+		return nil
+	case *yaml.Node:
+		if v == nil {
+			return e.EncodeNil()
+		}
+		*e.n = *v
+		return nil
+	case yaml.Marshaler:
+		x, err := v.MarshalYAML()
+		if err != nil {
+			return err
+		}
+		return e.encode(x, codec.GetSerializer(x, Format))
+	default:
+		return s.Serialize(e)
+	}
+}
+
+func (e Encoder) scalar(tag, value string) error {
+	*e.n = yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+	return nil
+}
+
+// Format returns the YAML format.
+func (e Encoder) Format() *codec.Format {
+	return Format
+}
+
+func (e Encoder) EncodeNil() error {
+	return e.scalar(nullTag, "null")
+}
+
+func (e Encoder) EncodeBool(v bool) error {
+	return e.scalar(boolTag, strconv.FormatBool(v))
+}
+
+func (e Encoder) EncodeInt(v int) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt8(v int8) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt16(v int16) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt32(v int32) error {
+	return e.EncodeInt64(int64(v))
+}
+
+func (e Encoder) EncodeInt64(v int64) error {
+	return e.scalar(intTag, strconv.FormatInt(v, 10))
+}
+
+func (e Encoder) EncodeUint(v uint) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint8(v uint8) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint16(v uint16) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint32(v uint32) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeUint64(v uint64) error {
+	return e.scalar(intTag, strconv.FormatUint(v, 10))
+}
+
+func (e Encoder) EncodeUintptr(v uintptr) error {
+	return e.EncodeUint64(uint64(v))
+}
+
+func (e Encoder) EncodeFloat32(v float32) error {
+	return e.scalar(floatTag, formatFloat(float64(v), 32))
+}
+
+func (e Encoder) EncodeFloat64(v float64) error {
+	return e.scalar(floatTag, formatFloat(v, 64))
+}
+
+// formatFloat formats v so that it resolves as a float: infinities and NaN use the YAML spellings, and integral values
+// have a trailing ".0".
+func formatFloat(v float64, bits int) string {
+	switch {
+	case math.IsInf(v, 1):
+		return ".inf"
+	case math.IsInf(v, -1):
+		return "-.inf"
+	case math.IsNaN(v):
+		return ".nan"
+	}
+	s := strconv.FormatFloat(v, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func (e Encoder) EncodeComplex64(v complex64) error {
+	return errors.New("unsupported")
+}
+
+func (e Encoder) EncodeComplex128(v complex128) error {
+	return errors.New("unsupported")
+}
+
+func (e Encoder) EncodeString(v string) error {
+	return e.scalar(strTag, v)
+}
+
+func (e Encoder) EncodeBytes(v []byte) error {
+	return e.scalar(binaryTag, base64.StdEncoding.EncodeToString(v))
+}
+
+func (e Encoder) EncodeElem(v any, s codec.Serializer) error {
+	return e.encode(v, s)
+}
+
+func (e Encoder) EncodeSeq(len int) (codec.SeqEncoder, error) {
+	*e.n = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	if len != 0 {
+		e.n.Content = make([]*yaml.Node, 0, len)
+	}
+	return &SeqEncoder{n: e.n}, nil
+}
+
+func (e Encoder) EncodeMap(len int) (codec.MapEncoder, error) {
+	*e.n = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len != 0 {
+		e.n.Content = make([]*yaml.Node, 0, 2*len)
+	}
+	return &MapEncoder{n: e.n}, nil
+}
+
+func (e Encoder) EncodeStruct(name string) (codec.StructEncoder, error) {
+	*e.n = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	return &StructEncoder{n: e.n}, nil
+}
+
+// EncodeVariant encodes a variant of a sum type using the externally tagged representation: unit variants are encoded
+// as their names, and other variants are encoded as single-entry mappings from their names to their values.
+func (e Encoder) EncodeVariant(enum, variant string, index int) (codec.VariantEncoder, error) {
+	return &VariantEncoder{n: e.n, name: variant}, nil
+}
+
+type SeqEncoder struct {
+	n *yaml.Node
+}
+
+func (e *SeqEncoder) Close() error {
+	return nil
+}
+
+func (e *SeqEncoder) EncodeElement(x any, ser codec.Serializer) error {
+	var v yaml.Node
+	if err := (Encoder{n: &v}).encode(x, ser); err != nil {
+		return err
+	}
+	e.n.Content = append(e.n.Content, &v)
+	return nil
+}
+
+// A MapEncoder encodes a mapping. Like gopkg.in/yaml.v3, the mapping's entries are sorted by key when the encoder is
+// closed so that the output does not depend on the iteration order of the map being encoded.
+type MapEncoder struct {
+	n *yaml.Node
+}
+
+func (e *MapEncoder) Close() error {
+	pairs := make([][2]*yaml.Node, len(e.n.Content)/2)
+	for i := range pairs {
+		pairs[i] = [2]*yaml.Node{e.n.Content[2*i], e.n.Content[2*i+1]}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return keyLess(pairs[i][0], pairs[j][0]) })
+	for i, p := range pairs {
+		e.n.Content[2*i], e.n.Content[2*i+1] = p[0], p[1]
+	}
+	return nil
+}
+
+// keyLess orders mapping keys. Numeric keys sort numerically and before other keys, which sort by their text.
+func keyLess(a, b *yaml.Node) bool {
+	af, aerr := strconv.ParseFloat(a.Value, 64)
+	bf, berr := strconv.ParseFloat(b.Value, 64)
+	aNum := aerr == nil && (a.Tag == intTag || a.Tag == floatTag)
+	bNum := berr == nil && (b.Tag == intTag || b.Tag == floatTag)
+	switch {
+	case aNum && bNum:
+		return af < bf
+	case aNum != bNum:
+		return aNum
+	default:
+		return a.Value < b.Value
+	}
+}
+
+func (e *MapEncoder) EncodeKey(x any, ser codec.Serializer) error {
+	var k yaml.Node
+	if err := (Encoder{n: &k}).encode(x, ser); err != nil {
+		return err
+	}
+	e.n.Content = append(e.n.Content, &k)
+	return nil
+}
+
+func (e *MapEncoder) EncodeValue(x any, ser codec.Serializer) error {
+	var v yaml.Node
+	if err := (Encoder{n: &v}).encode(x, ser); err != nil {
+		return err
+	}
+	e.n.Content = append(e.n.Content, &v)
+	return nil
+}
+
+type StructEncoder struct {
+	n *yaml.Node
+}
+
+func (e *StructEncoder) Close() error {
+	return nil
+}
+
+func (e *StructEncoder) EncodeField(key string, x any, ser codec.Serializer) error {
+	var v yaml.Node
+	if err := (Encoder{n: &v}).encode(x, ser); err != nil {
+		return err
+	}
+	e.n.Content = append(e.n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: strTag, Value: key}, &v)
+	return nil
+}
+
+type VariantEncoder struct {
+	n     *yaml.Node
+	name  string
+	value *yaml.Node
+}
+
+func (e *VariantEncoder) Close() error {
+	name := yaml.Node{Kind: yaml.ScalarNode, Tag: strTag, Value: e.name}
+	if e.value == nil {
+		*e.n = name
+	} else {
+		*e.n = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{&name, e.value}}
+	}
+	return nil
+}
+
+func (e *VariantEncoder) EncodeValue(x any, ser codec.Serializer) error {
+	var v yaml.Node
+	if err := (Encoder{n: &v}).encode(x, ser); err != nil {
+		return err
+	}
+	e.value = &v
+	return nil
+}
@@ -0,0 +1,84 @@
+package yaml
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/pgavlin/codec"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the codec format for YAML. Values of type *yaml.Node are passed through as-is, and types that implement
+// yaml.Marshaler or yaml.Unmarshaler are encoded and decoded using those methods.
+var Format = codec.NewFormat("yaml")
+
+func init() {
+	codec.Override[time.Time](Format, timeCodec{})
+}
+
+// Marshal returns the YAML encoding of x as a single document.
+func Marshal(x any) ([]byte, error) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	if err := e.Encode(x); err != nil {
+		return nil, err
+	}
+	if err := e.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the first document in b into the value pointed to by x. Any documents after the first are
+// ignored. If b contains no documents, x is left unchanged.
+func Unmarshal(b []byte, x any) error {
+	var n yaml.Node
+	if err := yaml.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	if n.Kind == 0 {
+		return nil
+	}
+	return Decoder{n: &n, state: &decodeState{}}.decode(x, codec.GetDeserializer(x, Format))
+}
+
+// timestampFormats are the layouts accepted for YAML timestamps. These are the layouts accepted by gopkg.in/yaml.v3.
+var timestampFormats = []string{
+	"2006-1-2T15:4:5.999999999Z07:00", // RFC 3339 with relaxed digits
+	"2006-1-2t15:4:5.999999999Z07:00", // RFC 3339 with a lowercase "t"
+	"2006-1-2 15:4:5.999999999",       // space separated with no time zone
+	"2006-1-2",                        // date only
+}
+
+// timeCodec represents time.Time values as YAML timestamps.
+type timeCodec struct {
+	codec.DefaultVisitor
+	value *time.Time
+}
+
+func (timeCodec) New(v *time.Time) codec.Codec[time.Time] {
+	return timeCodec{value: v}
+}
+
+func (c timeCodec) VisitString(v string) error {
+	var err error
+	for _, layout := range timestampFormats {
+		var t time.Time
+		if t, err = time.Parse(layout, v); err == nil {
+			*c.value = t
+			return nil
+		}
+	}
+	return err
+}
+
+func (c timeCodec) Deserialize(d codec.Decoder) error {
+	return d.DecodeString(c)
+}
+
+func (c timeCodec) Serialize(e codec.Encoder) error {
+	if e, ok := e.(Encoder); ok {
+		return e.scalar(timestampTag, c.value.Format(time.RFC3339Nano))
+	}
+	return e.EncodeString(c.value.Format(time.RFC3339Nano))
+}